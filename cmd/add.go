@@ -2,29 +2,70 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vaultctl/vaultctl/internal/crypto"
-	"golang.org/x/term"
+	"github.com/vaultctl/vaultctl/internal/importer"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"github.com/vaultctl/vaultctl/internal/vaultctl"
 )
 
 var (
-	addName       string
-	addUsername   string
-	addURL        string
-	addNotes      string
-	addBackupCodes string
+	addName         string
+	addUsername     string
+	addURL          string
+	addNotes        string
+	addBackupCodes  string
+	addTags         string
+	addFolder       string
+	addFields       []string
+	addSecretFields []string
+	addGenerate     bool
+	addNoConfirm    bool
+	addOnDuplicate  string
+	addType         string
+	addExpires      string
+	addRotateEvery  string
+	addEntryFile    string
 )
 
+// addEntryFlags are the flags describing a single entry, which --entry-file
+// replaces
+var addEntryFlags = []string{"name", "type", "username", "url", "notes", "backup-codes", "tags", "folder",
+	"field", "secret-field", "expires", "rotate-every", "generate", "no-confirm"}
+
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new password entry",
-	Long:  `Add a new password entry to the vault.`,
+	Long: `Add a new entry to the vault.
+Logins prompt for a password. Notes store their body in --notes. Cards and
+identities prompt for any required fields not given with --field/--secret-field.
+
+--entry-file adds a batch of logins from a JSON array instead, or a YAML
+list if the file ends in .yaml or .yml, each with a name, a password or
+"generate": true, and optionally a username, url, notes and tags:
+
+  [{"name": "github", "username": "me", "generate": true, "tags": ["dev"]}]
+
+Each entry follows --on-duplicate (failing by default). An entry that can't
+be added is reported and the rest are still added; the vault is saved and
+synced once at the end.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if addEntryFile != "" {
+			for _, name := range addEntryFlags {
+				if cmd.Flags().Changed(name) {
+					return fmt.Errorf("--entry-file cannot be combined with --%s", name)
+				}
+			}
+			return addFromEntryFile(cmd, addEntryFile)
+		}
+
 		if err := ensureUnlocked(cmd); err != nil {
 			return err
 		}
@@ -33,62 +74,103 @@ var addCmd = &cobra.Command{
 			return fmt.Errorf("--name is required")
 		}
 
-		// Check if entry already exists
-		if unlockedVault.GetEntry(addName) != nil {
-			return fmt.Errorf("entry with name '%s' already exists", addName)
+		if addOnDuplicate != "" {
+			if err := checkDuplicatePolicy(addOnDuplicate, duplicateSkip, duplicateRename, duplicateOverwrite); err != nil {
+				return err
+			}
+		}
+
+		// Decide what to do if the name is taken before prompting for anything
+		lib := library()
+		name, err := lib.AddName(addName, addOnDuplicate)
+		switch {
+		case errors.Is(err, vaultctl.ErrEntryExists):
+			return fmt.Errorf("entry with name '%s' already exists (see --on-duplicate)", addName)
+		case errors.Is(err, vaultctl.ErrEntrySkipped):
+			fmt.Printf("Entry '%s' already exists, skipped\n", addName)
+			return nil
+		case err != nil:
+			return err
+		case name != addName:
+			fmt.Printf("Entry '%s' already exists, adding as '%s'\n", addName, name)
 		}
 
-		// Prompt for password
-		fmt.Print("Enter password: ")
-		password, err := term.ReadPassword(int(syscall.Stdin))
+		// Parse custom fields
+		fields, err := parseFields(addFields, false)
 		if err != nil {
-			return fmt.Errorf("failed to read password: %w", err)
-		}
-		fmt.Println()
-
-		// Parse backup codes
-		var backupCodes []string
-		if addBackupCodes != "" {
-			// Split by comma, semicolon, or newline
-			codes := strings.FieldsFunc(addBackupCodes, func(r rune) bool {
-				return r == ',' || r == ';' || r == '\n'
-			})
-			// Trim whitespace from each code
-			for _, code := range codes {
-				trimmed := strings.TrimSpace(code)
-				if trimmed != "" {
-					backupCodes = append(backupCodes, trimmed)
-				}
+			return err
+		}
+		secretFields, err := parseFields(addSecretFields, true)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, secretFields...)
+
+		var expiresAt *time.Time
+		if addExpires != "" {
+			t, err := parseExpiry(addExpires)
+			if err != nil {
+				return err
+			}
+			expiresAt = &t
+		}
+		var rotateEvery time.Duration
+		if addRotateEvery != "" {
+			if rotateEvery, err = parseRotationInterval(addRotateEvery); err != nil {
+				return err
+			}
+		}
+
+		entryType, err := vault.ParseEntryType(addType)
+		if err != nil {
+			return err
+		}
+		if entryType == vault.TypeNote && addNotes == "" {
+			return fmt.Errorf("--notes is required for note entries")
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+
+		// Generate or prompt for password
+		var password []byte
+		if !entryType.HasPassword() {
+			// Notes, cards and identities have no password
+		} else if addGenerate {
+			password, err = generateEntryPassword()
+			if err != nil {
+				return err
 			}
 		} else {
-			// Prompt interactively for backup codes (optional)
-			fmt.Print("Enter backup codes? (y/n, or press Enter to skip): ")
-			reader := bufio.NewReader(os.Stdin)
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-			
-			if response == "y" || response == "yes" {
-				fmt.Println("Enter backup codes (one per line, empty line to finish):")
-				for {
-					fmt.Print("  Code: ")
-					code, err := reader.ReadString('\n')
-					if err != nil {
-						break
-					}
-					code = strings.TrimSpace(code)
-					if code == "" {
-						break
-					}
-					backupCodes = append(backupCodes, code)
+			password, err = prompt.ReadPassword("Enter password: ")
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+
+			if !addNoConfirm {
+				confirm, err := prompt.ReadPassword("Confirm password: ")
+				if err != nil {
+					crypto.Zeroize(password)
+					return fmt.Errorf("failed to read password: %w", err)
+				}
+
+				match := crypto.ConstantTimeCompare(password, confirm)
+				crypto.Zeroize(confirm)
+				if !match {
+					crypto.Zeroize(password)
+					return fmt.Errorf("passwords do not match")
 				}
 			}
 		}
 
-		// Add entry (password is []byte, no conversion to string)
-		unlockedVault.AddEntry(addName, addUsername, password, addURL, addNotes, backupCodes)
-		
-		// Zeroize password from memory
-		crypto.Zeroize(password)
+		// Fill in the rest and add the entry, which wipes the password
+		err = addPromptedEntry(lib, name, entryType, password, fields, vault.EntryOptions{
+			Folder:      vault.NormalizeFolder(addFolder),
+			ExpiresAt:   expiresAt,
+			RotateEvery: rotateEvery,
+		}, reader)
+		if err != nil {
+			return err
+		}
 
 		// Save vault
 		sync := !cmd.Flags().Changed("no-sync")
@@ -96,18 +178,237 @@ var addCmd = &cobra.Command{
 			return fmt.Errorf("failed to save vault: %w", err)
 		}
 
-		fmt.Printf("Entry '%s' added successfully\n", addName)
+		fmt.Printf("Entry '%s' added successfully\n", name)
 		return nil
 	},
 }
 
+// addPromptedEntry completes a new entry, prompting on reader for template
+// fields and backup codes that weren't given as flags, and adds it under
+// name following --on-duplicate. It takes over password and wipes it on
+// every path out; the vault keeps its own copy.
+func addPromptedEntry(lib *vaultctl.Vault, name string, entryType vault.EntryType, password []byte, fields []vault.CustomField, opts vault.EntryOptions, reader *bufio.Reader) error {
+	defer crypto.Zeroize(password)
+
+	// Fill in the fields this entry type expects
+	fields, err := promptTemplateFields(entryType, fields, reader)
+	if err != nil {
+		return err
+	}
+
+	// Parse backup codes
+	var backupCodes []string
+	if addBackupCodes != "" {
+		backupCodes = splitList(addBackupCodes)
+	} else if entryType == vault.TypeLogin {
+		// Prompt interactively for backup codes (optional)
+		fmt.Print("Enter backup codes? (y/n, or press Enter to skip): ")
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+
+		if response == "y" || response == "yes" {
+			fmt.Println("Enter backup codes (one per line, empty line to finish):")
+			for {
+				fmt.Print("  Code: ")
+				code, err := reader.ReadString('\n')
+				if err != nil {
+					break
+				}
+				code = strings.TrimSpace(code)
+				if code == "" {
+					break
+				}
+				backupCodes = append(backupCodes, code)
+			}
+		}
+	}
+
+	// Add entry (password is []byte, no conversion to string). A replaced
+	// entry goes to the trash.
+	opts.Type = entryType
+	opts.Fields = fields
+	entry, err := lib.AddWith(name, addUsername, password, addURL, addNotes, backupCodes, splitList(addTags), opts, addOnDuplicate)
+	if err != nil {
+		return err
+	}
+	crypto.Zeroize(entry.Password)
+	return nil
+}
+
+// generateEntryPassword generates a password with the vault's password
+// policy, or the default policy if none is set
+func generateEntryPassword() ([]byte, error) {
+	policy := crypto.DefaultPolicy()
+	if stored := unlockedVault.PasswordPolicyFor(nil); stored != nil {
+		policy = *stored
+	}
+	password, err := crypto.GeneratePassword(policy.Length(), policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	return password, nil
+}
+
+// addFromEntryFile adds the logins listed in an entry file, reporting each
+// one, and saves once. It fails after saving if any entry was not added.
+func addFromEntryFile(cmd *cobra.Command, path string) error {
+	if addOnDuplicate != "" {
+		if err := checkDuplicatePolicy(addOnDuplicate, duplicateSkip, duplicateRename, duplicateOverwrite); err != nil {
+			return err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open entry file: %w", err)
+	}
+	records, err := importer.ParseEntryFile(path, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, record := range records {
+			crypto.Zeroize(record.Password)
+		}
+	}()
+	if len(records) == 0 {
+		fmt.Println("No entries in the entry file")
+		return nil
+	}
+
+	if err := ensureUnlocked(cmd); err != nil {
+		return err
+	}
+	lib := library()
+
+	added, skipped, failed := 0, 0, 0
+	for i, record := range records {
+		label := record.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		fail := func(err error) {
+			failed++
+			fmt.Printf("  failed   %s: %v\n", label, err)
+		}
+
+		if err := record.Validate(); err != nil {
+			fail(err)
+			continue
+		}
+
+		name, err := lib.AddName(record.Name, addOnDuplicate)
+		switch {
+		case errors.Is(err, vaultctl.ErrEntryExists):
+			fail(fmt.Errorf("entry already exists (see --on-duplicate)"))
+			continue
+		case errors.Is(err, vaultctl.ErrEntrySkipped):
+			skipped++
+			fmt.Printf("  skipped  %s: entry already exists\n", label)
+			continue
+		case err != nil:
+			fail(err)
+			continue
+		}
+
+		password := record.Password
+		if record.Generate {
+			if password, err = generateEntryPassword(); err != nil {
+				fail(err)
+				continue
+			}
+		}
+
+		// A replaced entry goes to the trash
+		_, err = lib.AddWith(name, record.Username, password, record.URL, record.Notes, nil, record.Tags, vault.EntryOptions{}, addOnDuplicate)
+		if record.Generate {
+			crypto.Zeroize(password)
+		}
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		added++
+		if name != record.Name {
+			fmt.Printf("  added    %s as '%s'\n", label, name)
+		} else {
+			fmt.Printf("  added    %s\n", label)
+		}
+	}
+
+	if added > 0 {
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+	}
+
+	fmt.Printf("Added %d, skipped %d, failed %d entries\n", added, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries could not be added", failed, len(records))
+	}
+	return nil
+}
+
+// promptTemplateFields prompts for any required fields of the entry type that
+// were not given on the command line, and marks template fields secret where
+// the type expects it
+func promptTemplateFields(entryType vault.EntryType, fields []vault.CustomField, reader *bufio.Reader) ([]vault.CustomField, error) {
+	for _, tf := range entryType.TemplateFields() {
+		found := false
+		for i := range fields {
+			if fields[i].Name == tf.Name {
+				fields[i].Secret = fields[i].Secret || tf.Secret
+				found = true
+			}
+		}
+		if found || !tf.Required {
+			continue
+		}
+
+		label := fmt.Sprintf("Enter %s: ", tf.Name)
+		var value string
+		if tf.Secret {
+			b, err := prompt.ReadPassword(label)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", tf.Name, err)
+			}
+			value = string(b)
+			crypto.Zeroize(b)
+		} else {
+			fmt.Print(label)
+			line, err := reader.ReadString('\n')
+			if err != nil && line == "" {
+				return nil, fmt.Errorf("failed to read %s: %w", tf.Name, err)
+			}
+			value = strings.TrimSpace(line)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("%s is required for %s entries", tf.Name, entryType)
+		}
+		fields = append(fields, vault.CustomField{Name: tf.Name, Value: value, Secret: tf.Secret})
+	}
+	return fields, nil
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
-	addCmd.Flags().StringVar(&addName, "name", "", "Entry name (required)")
+	addCmd.Flags().StringVar(&addName, "name", "", "Entry name (required unless --entry-file is given)")
+	addCmd.Flags().StringVar(&addType, "type", string(vault.TypeLogin), "Entry type (login, note, card, identity)")
 	addCmd.Flags().StringVar(&addUsername, "username", "", "Username")
 	addCmd.Flags().StringVar(&addURL, "url", "", "URL")
 	addCmd.Flags().StringVar(&addNotes, "notes", "", "Notes")
 	addCmd.Flags().StringVar(&addBackupCodes, "backup-codes", "", "2FA backup codes (comma or semicolon separated, or leave empty for interactive input)")
+	addCmd.Flags().StringVar(&addTags, "tags", "", "Tags (comma or semicolon separated)")
+	addCmd.Flags().StringVar(&addFolder, "folder", "", "Folder, as a slash-separated path such as work/aws")
+	addCmd.Flags().StringArrayVar(&addFields, "field", nil, "Custom field as NAME=VALUE (repeatable)")
+	addCmd.Flags().StringArrayVar(&addSecretFields, "secret-field", nil, "Secret custom field as NAME=VALUE, masked when displayed (repeatable)")
+	addCmd.Flags().StringVar(&addExpires, "expires", "", "Date the password must be changed by (YYYY-MM-DD)")
+	addCmd.Flags().StringVar(&addRotateEvery, "rotate-every", "", "Change the password this often, e.g. 90d or 12w")
+	addCmd.Flags().BoolVar(&addGenerate, "generate", false, "Generate a random password instead of prompting")
+	addCmd.Flags().BoolVar(&addNoConfirm, "no-confirm", false, "Don't ask for the password a second time")
+	addCmd.Flags().StringVar(&addOnDuplicate, "on-duplicate", "", "What to do if the name already exists (skip, rename or overwrite; default: fail)")
+	addCmd.Flags().StringVar(&addEntryFile, "entry-file", "", "Add the entries listed in a JSON or YAML file instead of a single entry")
 	addCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
 }
-
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"github.com/vaultctl/vaultctl/internal/vaultctl"
+)
+
+func TestAddPromptedEntryWipesPassword(t *testing.T) {
+	const secret = "correct horse battery staple"
+	tests := []struct {
+		name     string
+		existing bool // An entry called github is already in the vault
+		wantErr  error
+	}{
+		{"added", false, nil},
+		{"name taken", true, vaultctl.ErrEntryExists},
+	}
+
+	oldCodes, oldUsername := addBackupCodes, addUsername
+	t.Cleanup(func() { addBackupCodes, addUsername = oldCodes, oldUsername })
+	addBackupCodes, addUsername = "111;222", "alice"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := vault.NewVault()
+			if tt.existing {
+				data.AddEntry("github", "bob", []byte("old"), "", "", nil, nil)
+			}
+			local := storage.NewLocalStorage(filepath.Join(t.TempDir(), "vault.db"))
+			lib := vaultctl.New(local, nil, data, make([]byte, 32))
+
+			password := []byte(secret)
+			err := addPromptedEntry(lib, "github", vault.TypeLogin, password, nil, vault.EntryOptions{},
+				bufio.NewReader(strings.NewReader("")))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("addPromptedEntry: err = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(password, make([]byte, len(secret))) {
+				t.Errorf("caller's password = %q, want it zeroed", password)
+			}
+
+			entry, err := lib.Get("github")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if tt.wantErr != nil {
+				if entry.Username != "bob" {
+					t.Errorf("existing entry replaced by %s", entry.Username)
+				}
+				return
+			}
+			if !bytes.Equal(entry.Password, []byte(secret)) {
+				t.Errorf("stored password = %q, want %q", entry.Password, secret)
+			}
+			if entry.Username != "alice" || !slices.Equal(entry.BackupCodes, []string{"111", "222"}) {
+				t.Errorf("stored entry = %s with codes %v, want alice with codes [111 222]", entry.Username, entry.BackupCodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	attachName   string
+	attachOutput string
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <name_or_id> <file>",
+	Short: "Attach a file to an entry",
+	Long: `Attach a file, such as a recovery key image or PDF, to an entry.
+Files up to attachment_inline_max bytes (32KB by default) are stored in the
+vault. Larger files are encrypted with their own key and uploaded to the S3
+bucket set in attachment_bucket; the vault keeps a reference and the key.
+Attaching a file with an existing attachment's name replaces it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		defer crypto.Zeroize(data)
+
+		name := attachName
+		if name == "" {
+			name = filepath.Base(args[1])
+		}
+
+		attachment := vault.Attachment{
+			Name:      name,
+			Size:      int64(len(data)),
+			CreatedAt: time.Now(),
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if len(data) <= cfg.GetAttachmentInlineMax() {
+			attachment.Data = append([]byte(nil), data...)
+		} else {
+			store, err := newAttachmentStore()
+			if err != nil {
+				return fmt.Errorf("%s is %d bytes, larger than the %d byte inline limit: %w",
+					name, len(data), cfg.GetAttachmentInlineMax(), err)
+			}
+			objectKey := fmt.Sprintf("attachments/%s/%s", cfg.RemoteUserID(), uuid.New().String())
+			ciphertext, err := storage.SealAttachment(data, vaultKey, objectKey, &attachment)
+			if err != nil {
+				return err
+			}
+			if err := store.PutAttachment(ctx, objectKey, ciphertext); err != nil {
+				return err
+			}
+		}
+
+		var replaced *vault.Attachment
+		err = unlockedVault.EditEntry(entry.ID, func(e *vault.Entry) error {
+			replaced = e.SetAttachment(attachment)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		// The replaced object is unreferenced once the vault is saved
+		if replaced != nil && !replaced.IsInline() {
+			if store, err := newAttachmentStore(); err == nil {
+				err = store.DeleteAttachment(ctx, replaced.ObjectKey)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to delete replaced attachment: %v\n", err)
+				}
+			}
+		}
+
+		where := "in the vault"
+		if !attachment.IsInline() {
+			where = "in S3"
+		}
+		fmt.Printf("Attached '%s' (%s) to '%s', stored %s\n", name, formatFileSize(attachment.Size), entry.Name, where)
+		return nil
+	},
+}
+
+var attachmentCmd = &cobra.Command{
+	Use:   "attachment",
+	Short: "Manage entry attachments",
+	Long:  `Retrieve files attached to entries with 'vaultctl attach'.`,
+}
+
+var attachmentGetCmd = &cobra.Command{
+	Use:   "get <name_or_id> <attachment>",
+	Short: "Save an attachment to a file",
+	Long: `Decrypt an attachment and write it to --output, or to a file named after
+the attachment in the current directory. Use --output - to write to stdout.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+		attachment := entry.GetAttachment(args[1])
+		if attachment == nil {
+			return fmt.Errorf("attachment not found: %s", args[1])
+		}
+
+		outputPath := attachOutput
+		if outputPath == "" {
+			outputPath = filepath.Base(attachment.Name)
+		}
+		if outputPath != "-" {
+			if _, err := os.Stat(outputPath); err == nil {
+				return fmt.Errorf("%s already exists; choose another path with --output", outputPath)
+			}
+		}
+
+		data := attachment.Data
+		if !attachment.IsInline() {
+			store, err := newAttachmentStore()
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			ciphertext, err := store.GetAttachment(ctx, attachment.ObjectKey)
+			if err != nil {
+				return err
+			}
+			data, err = storage.OpenAttachment(ciphertext, vaultKey, attachment)
+			if err != nil {
+				return err
+			}
+			defer crypto.Zeroize(data)
+		}
+
+		if outputPath == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(outputPath, data, 0600); err != nil {
+			return fmt.Errorf("failed to write attachment: %w", err)
+		}
+		fmt.Printf("Saved '%s' to %s\n", attachment.Name, outputPath)
+		return nil
+	},
+}
+
+// newAttachmentStore connects to the configured attachment bucket
+func newAttachmentStore() (*storage.S3AttachmentStore, error) {
+	if offlineMode() {
+		return nil, fmt.Errorf("the attachment bucket can't be used in offline mode")
+	}
+	if cfg.AttachmentBucket == "" {
+		return nil, fmt.Errorf("no attachment bucket configured; set attachment_bucket in %s", cfg.ConfigPath)
+	}
+	return storage.NewS3AttachmentStore(cfg.AttachmentBucket, cfg.AWSRegion)
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+	rootCmd.AddCommand(attachmentCmd)
+	attachmentCmd.AddCommand(attachmentGetCmd)
+	attachCmd.Flags().StringVar(&attachName, "name", "", "Attachment name (default: the file's base name)")
+	attachCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+	attachmentGetCmd.Flags().StringVarP(&attachOutput, "output", "o", "", "Output path, or - for stdout (default: the attachment name)")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	auditMinEntropy float64
+	auditMaxAge     time.Duration
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Report weak, reused and old passwords",
+	Long: `Audit the passwords in the vault.
+Reports passwords whose estimated entropy is below --min-entropy, passwords
+shared by more than one entry, passwords not changed within --max-age, and
+passwords past the expiry or rotation interval set with --expires or
+--rotate-every.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		report := unlockedVault.Audit(vault.AuditOptions{
+			MinEntropy: auditMinEntropy,
+			MaxAge:     auditMaxAge,
+		})
+
+		if len(report.Issues) == 0 {
+			fmt.Printf("No issues found (%d entries checked)\n", report.EntriesChecked)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tNAME\tISSUE\tDETAIL")
+		for _, issue := range report.Issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				strings.ToUpper(issue.Severity.String()),
+				issue.EntryName,
+				issue.Kind,
+				issue.Detail)
+		}
+		w.Flush()
+
+		fmt.Printf("\n%d issues found (%d entries checked)\n", len(report.Issues), report.EntriesChecked)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().Float64Var(&auditMinEntropy, "min-entropy", vault.DefaultAuditMinEntropy, "Flag passwords with less estimated entropy than this many bits")
+	auditCmd.Flags().DurationVar(&auditMaxAge, "max-age", vault.DefaultAuditMaxAge, "Flag passwords not changed within this duration (0 to disable)")
+}
@@ -7,35 +7,49 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/atomic"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/storage"
+)
+
+var (
+	backupKeep     int
+	backupKeepDays int
+	backupProtect  bool
 )
 
 var backupCmd = &cobra.Command{
 	Use:   "backup [output_path]",
 	Short: "Create a backup of the vault",
-	Long:  `Create an encrypted backup of the vault.`,
+	Long: `Create an encrypted backup of the vault.
+When writing to the default backup directory, older backups beyond --keep
+or older than --keep-days are deleted afterwards.
+
+With --passphrase the backup is additionally encrypted with a separate
+backup passphrase, so it does not rely on the master password alone.`,
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !localStore.Exists() {
-			return fmt.Errorf("vault not found. Run 'vaultctl init' first")
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
 		}
 
 		// Load encrypted vault
-		ev, err := localStore.LoadEncryptedVault()
+		ev, err := loadLocalVault(cmd)
 		if err != nil {
 			return fmt.Errorf("failed to load vault: %w", err)
 		}
 
 		// Determine output path
 		var outputPath string
+		prune := len(args) == 0
 		if len(args) > 0 {
 			outputPath = args[0]
 		} else {
-			homeDir, _ := os.UserHomeDir()
-			backupDir := filepath.Join(homeDir, ".vaultctl", "backups")
-			if err := os.MkdirAll(backupDir, 0700); err != nil {
+			backupDir := cfg.GetBackupDir()
+			if err := atomic.EnsureDir(backupDir, 0700); err != nil {
 				return fmt.Errorf("failed to create backup directory: %w", err)
 			}
-			timestamp := time.Now().Format("2006-01-02T15-04-05Z")
+			timestamp := time.Now().UTC().Format("2006-01-02T15-04-05Z")
 			outputPath = filepath.Join(backupDir, fmt.Sprintf("vault-%s.enc", timestamp))
 		}
 
@@ -45,16 +59,125 @@ var backupCmd = &cobra.Command{
 			return fmt.Errorf("failed to serialize vault: %w", err)
 		}
 
+		if backupProtect {
+			passphrase, err := readNewPassphrase("backup")
+			if err != nil {
+				return err
+			}
+			data, err = storage.ProtectBackup(data, passphrase)
+			crypto.Zeroize(passphrase)
+			if err != nil {
+				return err
+			}
+		}
+
 		if err := os.WriteFile(outputPath, data, 0600); err != nil {
 			return fmt.Errorf("failed to write backup: %w", err)
 		}
 
 		fmt.Printf("Backup created at: %s\n", outputPath)
+
+		// Only prune our own backup directory, never around a custom path
+		if prune {
+			keep, keepDays := backupKeep, backupKeepDays
+			if !cmd.Flags().Changed("keep") {
+				keep = cfg.BackupKeep
+			}
+			if !cmd.Flags().Changed("keep-days") {
+				keepDays = cfg.BackupKeepDays
+			}
+			removed, err := pruneBackups(filepath.Dir(outputPath), keep, keepDays, outputPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to prune old backups: %v\n", err)
+			}
+			if len(removed) > 0 {
+				fmt.Printf("Removed %d old backup(s)\n", len(removed))
+			}
+		}
 		return nil
 	},
 }
 
+// snapshotVault copies the local vault file into the backup directory as
+// vault-<label>-<timestamp>.enc and returns the path
+func snapshotVault(label string) (string, error) {
+	backupDir := cfg.GetBackupDir()
+	if err := atomic.EnsureDir(backupDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	timestamp := time.Now().UTC().Format("2006-01-02T15-04-05Z")
+	path := filepath.Join(backupDir, fmt.Sprintf("vault-%s-%s.enc", label, timestamp))
+
+	ev, err := localStore.LoadEncryptedVault()
+	if err != nil {
+		return "", fmt.Errorf("failed to load current vault: %w", err)
+	}
+	data, err := ev.ToJSON()
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize vault: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to create backup: %w", err)
+	}
+	return path, nil
+}
+
+// backupBeforeWrite backs up the vault before a risky operation if
+// backup_before_write is set, pruning old backups by the configured
+// retention. A failed backup stops the operation.
+func backupBeforeWrite(operation string) error {
+	if !cfg.BackupBeforeWrite || !localStore.Exists() {
+		return nil
+	}
+
+	path, err := snapshotVault("before-" + operation)
+	if err != nil {
+		return fmt.Errorf("failed to back up vault before %s (unset backup_before_write to skip): %w", operation, err)
+	}
+	fmt.Printf("Vault backed up to: %s\n", path)
+
+	if _, err := pruneBackups(filepath.Dir(path), cfg.BackupKeep, cfg.BackupKeepDays, path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to prune old backups: %v\n", err)
+	}
+	return nil
+}
+
+// pruneBackups deletes backups in backupDir beyond the newest keep, and
+// backups older than keepDays days. Zero disables either rule. The backup at
+// current is never deleted. It returns the paths removed.
+func pruneBackups(backupDir string, keep, keepDays int, current string) ([]string, error) {
+	if keep <= 0 && keepDays <= 0 {
+		return nil, nil
+	}
+
+	backups, err := findBackups(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -keepDays)
+	var removed []string
+	for i, backup := range backups {
+		if backup.Path == current {
+			continue
+		}
+		tooMany := keep > 0 && i >= keep
+		tooOld := keepDays > 0 && backup.CreatedAt.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(backup.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", backup.Path, err)
+		}
+		removed = append(removed, backup.Path)
+	}
+	return removed, nil
+}
+
 func init() {
 	rootCmd.AddCommand(backupCmd)
+	backupCmd.Flags().IntVar(&backupKeep, "keep", 0, "Keep only the newest N backups (0 keeps all; default from config backup_keep)")
+	backupCmd.Flags().BoolVar(&backupProtect, "passphrase", false, "Encrypt the backup with a separate backup passphrase")
+	backupCmd.Flags().IntVar(&backupKeepDays, "keep-days", 0, "Delete backups older than D days (0 disables; default from config backup_keep_days)")
 }
 
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCodeCmd = &cobra.Command{
+	Use:   "backup-code",
+	Short: "Use an entry's 2FA backup codes",
+	Long:  `Reveal 2FA backup codes one at a time and keep track of which have been used.`,
+}
+
+var backupCodeUseCmd = &cobra.Command{
+	Use:   "use <name_or_id>",
+	Short: "Reveal the next unused backup code and mark it used",
+	Long: `Print the entry's next unused backup code and mark it used, so it isn't
+offered again. Codes replaced with 'vaultctl update --backup-codes' start
+out unused.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		code, err := unlockedVault.UseBackupCode(entry.ID)
+		if err != nil {
+			return err
+		}
+
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		remaining := len(entry.UnusedBackupCodes()) - 1
+		fmt.Printf("Backup code for '%s': %s\n", entry.Name, code)
+		switch remaining {
+		case 0:
+			fmt.Println("That was the last unused code. Generate new codes with the service and save them with 'vaultctl update --backup-codes'")
+		case 1:
+			fmt.Println("1 unused code left")
+		default:
+			fmt.Printf("%d unused codes left\n", remaining)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCodeCmd)
+	backupCodeCmd.AddCommand(backupCodeUseCmd)
+	backupCodeUseCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
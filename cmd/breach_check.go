@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/breach"
+)
+
+var (
+	breachOffline   bool
+	breachRateLimit time.Duration
+)
+
+var breachCheckCmd = &cobra.Command{
+	Use:   "breach-check",
+	Short: "Check passwords against known data breaches",
+	Long: `Check each password against the Have I Been Pwned password database.
+Only the first five characters of each password's SHA-1 hash are sent; the
+match is done locally against the returned list of suffixes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		if breachOffline {
+			fmt.Println("Offline mode: skipping breach lookups")
+			return nil
+		}
+
+		checker := breach.NewChecker(nil)
+		checker.SetRateLimit(breachRateLimit)
+
+		type result struct {
+			name  string
+			count int
+		}
+		var breached []result
+		checked, failed := 0, 0
+
+		for i := range unlockedVault.Entries {
+			entry := &unlockedVault.Entries[i]
+			if !entry.Type.HasPassword() || len(entry.Password) == 0 {
+				continue
+			}
+			checked++
+
+			count, err := checker.Count(cmd.Context(), entry.Password)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to check '%s': %v\n", entry.Name, err)
+				failed++
+				continue
+			}
+			if count > 0 {
+				breached = append(breached, result{name: entry.Name, count: count})
+			}
+		}
+
+		if failed == checked && checked > 0 {
+			return fmt.Errorf("failed to check any passwords")
+		}
+
+		if len(breached) == 0 {
+			fmt.Printf("No breached passwords found (%d entries checked)\n", checked-failed)
+			return nil
+		}
+
+		sort.Slice(breached, func(i, j int) bool {
+			return breached[i].count > breached[j].count
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTIMES SEEN")
+		for _, r := range breached {
+			fmt.Fprintf(w, "%s\t%d\n", r.name, r.count)
+		}
+		w.Flush()
+
+		fmt.Printf("\n%d of %d passwords appear in known breaches\n", len(breached), checked-failed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(breachCheckCmd)
+	breachCheckCmd.Flags().BoolVar(&breachOffline, "offline", false, "Skip network lookups")
+	breachCheckCmd.Flags().DurationVar(&breachRateLimit, "rate-limit", breach.DefaultRateLimit, "Minimum delay between API requests")
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/config"
+	"github.com/vaultctl/vaultctl/internal/storage"
+)
+
+var changeUserDeleteOld bool
+
+var changeUserCmd = &cobra.Command{
+	Use:   "change-user <new_user_id>",
+	Short: "Move the remote vault to a new user ID",
+	Long: `Copy the remote vault from the current user_id to a new one and switch
+the config over to it, e.g. to move from "default" to an email address.
+
+The vault is written under the new user ID with the next version, and only
+if no vault exists there yet, so an existing vault is never overwritten.
+The local vault must be in sync with remote storage first.
+
+With --delete-old the vault under the old user ID is deleted afterwards,
+unless another device has changed it in the meantime. Set user_id on your
+other devices too, or they keep syncing with the old vault (and, after
+--delete-old, create it again).`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newUserID := args[0]
+		if newUserID == "" {
+			return fmt.Errorf("user ID cannot be empty")
+		}
+		if newUserID == cfg.UserID {
+			return fmt.Errorf("user ID is already %s", cfg.UserID)
+		}
+		// The new user_id would be saved but ignored while the variable is set
+		if os.Getenv(config.UserIDEnvVar) != "" {
+			return fmt.Errorf("user ID is set by $%s. Unset it to change user_id in config, or copy the vault and set the variable to the new user ID", config.UserIDEnvVar)
+		}
+		if remoteStore == nil {
+			if offlineMode() {
+				return fmt.Errorf("change-user needs remote storage, which is disabled offline")
+			}
+			return fmt.Errorf("change-user needs remote storage, which is not configured")
+		}
+		if !localStore.Exists() {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		localEV, err := loadLocalVault(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+		pending, err := localStore.LoadPendingWrites()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d local changes are queued. Run 'vaultctl sync' first", len(pending))
+		}
+
+		oldEV, err := remoteStore.LoadVault(ctx)
+		switch {
+		case errors.Is(err, storage.ErrRemoteVaultNotFound):
+			oldEV = nil
+		case err != nil:
+			return fmt.Errorf("failed to load vault from remote storage: %w", err)
+		case oldEV.Ciphertext != localEV.Ciphertext:
+			return fmt.Errorf("local and remote vaults differ. Run 'vaultctl sync' first")
+		}
+
+		newStore, err := newRemoteStore(newUserID)
+		if err != nil {
+			return err
+		}
+		if _, err := newStore.LoadVault(ctx); err == nil {
+			return fmt.Errorf("a vault already exists for user ID %s", newUserID)
+		} else if !errors.Is(err, storage.ErrRemoteVaultNotFound) {
+			return fmt.Errorf("failed to check for a vault under the new user ID: %w", err)
+		}
+
+		newEV := *localEV
+		newEV.Version++
+		newEV.SetModifiedAt(time.Now())
+		if err := newEV.Sign(vaultKey); err != nil {
+			return fmt.Errorf("failed to sign vault: %w", err)
+		}
+
+		// Versions start at 1, so expecting version 0 only succeeds when no
+		// vault exists under the new user ID, even if one appeared since the
+		// check above
+		if err := newStore.SaveVault(ctx, &newEV, 0); err != nil {
+			if errors.Is(err, storage.ErrVersionConflict) {
+				return fmt.Errorf("a vault already exists for user ID %s", newUserID)
+			}
+			return fmt.Errorf("failed to save vault under the new user ID: %w", err)
+		}
+
+		if err := localStore.SaveEncryptedVault(&newEV); err != nil {
+			return fmt.Errorf("failed to save vault locally: %w", err)
+		}
+		if err := localStore.SaveSyncBase(&newEV); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		// Reload the config so flag overrides such as --vault-path aren't saved
+		saved, err := config.LoadProfileConfig(cfg.Profile)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		oldUserID := saved.UserID
+		saved.UserID = newUserID
+		if err := saved.SaveConfig(); err != nil {
+			return fmt.Errorf("vault copied to user ID %s but failed to update config, set user_id by hand: %w", newUserID, err)
+		}
+		cfg.UserID = newUserID
+		oldStore := remoteStore
+		remoteStore = newStore
+		fmt.Printf("Vault moved from user ID %s to %s (version %d)\n", oldUserID, newUserID, newEV.Version)
+
+		if !changeUserDeleteOld || oldEV == nil {
+			return nil
+		}
+		deleter, ok := oldStore.(storage.VaultDeleter)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Warning: this remote backend can't delete vaults; the old one was kept")
+			return nil
+		}
+		if err := deleter.DeleteVault(ctx, oldEV.Version); errors.Is(err, storage.ErrVersionConflict) {
+			fmt.Fprintf(os.Stderr, "Warning: the vault under user ID %s changed since it was copied and was kept\n", oldUserID)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to delete the vault under user ID %s: %v\n", oldUserID, err)
+		} else {
+			fmt.Printf("Deleted the vault under user ID %s\n", oldUserID)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changeUserCmd)
+	changeUserCmd.Flags().BoolVar(&changeUserDeleteOld, "delete-old", false, "Delete the vault under the old user ID afterwards")
+}
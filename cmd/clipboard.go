@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/clipboard"
+)
+
+// DefaultClipboardClearAfter is how long copied secrets stay on the clipboard
+const DefaultClipboardClearAfter = 15 * time.Second
+
+// copyWithAutoClear copies a secret to the clipboard and blocks until
+// clearAfter has elapsed (or the user interrupts), then clears the clipboard.
+// A zero duration leaves the secret on the clipboard.
+func copyWithAutoClear(secret []byte, clearAfter time.Duration) error {
+	if err := clipboard.Copy(secret); err != nil {
+		return err
+	}
+
+	if clearAfter <= 0 {
+		fmt.Println("Copied to clipboard")
+		return nil
+	}
+
+	fmt.Printf("Copied to clipboard. Clearing in %s (Ctrl+C to clear now)...\n", clearAfter)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	select {
+	case <-time.After(clearAfter):
+	case <-ctx.Done():
+	}
+
+	if err := clipboard.Clear(); err != nil {
+		return fmt.Errorf("failed to clear clipboard: %w", err)
+	}
+	fmt.Println("Clipboard cleared")
+	return nil
+}
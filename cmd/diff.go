@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	diffRemote bool
+	diffReveal bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [vault_a] [vault_b]",
+	Short: "Show the entries that differ between two vaults",
+	Long: `Compare two vaults and list the entries, matched by ID, that were added,
+removed or changed, with the fields that differ. Run it before a restore or
+sync to see what you would gain or lose.
+
+Each side is a vault file or backup, or with --remote the remote vault:
+
+  vaultctl diff backup.enc           the current vault against a backup
+  vaultctl diff a.db b.db            two vault files
+  vaultctl diff --remote             the current vault against the remote one
+  vaultctl diff --remote backup.enc  a backup against the remote vault
+
+Changes are listed as what it takes to turn the first vault into the second.
+Files sealed with the current vault key open with the session; others ask
+for their master password, and protected backups for their passphrase.
+
+Passwords, notes, backup codes and secret custom fields are only reported
+as changed. --reveal shows their values too.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if diffRemote && len(args) == 2 {
+			return fmt.Errorf("--remote compares against one vault file at most")
+		}
+		if !diffRemote && len(args) == 0 {
+			return fmt.Errorf("give one or two vault files to compare, or --remote")
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		// Files sealed with the current vault key open without a password
+		if localStore.Exists() {
+			if err := ensureUnlocked(cmd); err != nil {
+				return err
+			}
+		} else if len(args) < 2 {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+
+		var sides []*vault.Vault
+		var labels []string
+		if len(args) < 2 && !(diffRemote && len(args) == 1) {
+			sides = append(sides, unlockedVault)
+			labels = append(labels, "current vault")
+		}
+		for _, path := range args {
+			v, err := openVaultFile(path)
+			if err != nil {
+				return err
+			}
+			sides = append(sides, v)
+			labels = append(labels, path)
+		}
+		if diffRemote {
+			if remoteStore == nil {
+				return fmt.Errorf("remote storage is not configured")
+			}
+			ev, err := remoteStore.LoadVault(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to load remote vault: %w", err)
+			}
+			v, err := openVaultEnvelope(ev, "the remote vault")
+			if err != nil {
+				return err
+			}
+			sides = append(sides, v)
+			labels = append(labels, "remote vault")
+		}
+
+		from, to := sides[0], sides[1]
+		fmt.Printf("Comparing %s with %s\n", labels[0], labels[1])
+		printDiff(from, to, vault.Diff(from, to))
+		return nil
+	},
+}
+
+// openVaultFile reads and decrypts a vault file or backup
+func openVaultFile(path string) (*vault.Vault, error) {
+	if err := storage.CheckFilePermissions(path, strictPerms); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if storage.IsProtectedBackup(data) {
+		passphrase, err := prompt.ReadPassword(fmt.Sprintf("Enter backup passphrase for %s: ", path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		data, err = storage.UnprotectBackup(data, passphrase)
+		crypto.Zeroize(passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ev, err := storage.EncryptedVaultFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a vault file: %w", path, err)
+	}
+	if err := ev.Validate(); err != nil {
+		return nil, fmt.Errorf("%s is invalid or corrupted: %w", path, err)
+	}
+	return openVaultEnvelope(ev, path)
+}
+
+// openVaultEnvelope decrypts an envelope with the current vault key if it
+// was sealed with it, and otherwise asks for its master password
+func openVaultEnvelope(ev *storage.EncryptedVault, label string) (*vault.Vault, error) {
+	if vaultKey != nil {
+		v, err := storage.OpenVault(ev, vaultKey)
+		if err == nil || errors.Is(err, vault.ErrSchemaTooNew) {
+			return v, err
+		}
+	}
+
+	password, err := prompt.ReadPassword(fmt.Sprintf("Enter master password of %s: ", label))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	lockSecret(password)
+	defer releaseSecret(password)
+
+	keyFile, err := readKeyFile()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseSecret(keyFile)
+
+	v, key, err := storage.UnlockVault(ev, password, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", label, err)
+	}
+	releaseSecret(key)
+	return v, nil
+}
+
+// printDiff lists the changes from one vault to another, with the fields
+// of each changed entry
+func printDiff(from, to *vault.Vault, c vault.Changes) {
+	if c.Empty() {
+		fmt.Println("No entry changes")
+		return
+	}
+
+	fromIDs, toIDs := from.ShortIDs(), to.ShortIDs()
+	for _, e := range c.Added {
+		fmt.Printf("+ %s [%s] (added)\n", e.Name, toIDs[e.ID])
+	}
+	for _, change := range c.Updated {
+		fmt.Printf("~ %s [%s] (updated)\n", change.Name, toIDs[change.ID])
+		old, updated := from.GetEntry(change.ID), to.GetEntry(change.ID)
+		for _, field := range change.Fields {
+			oldValue, oldSecret, ok := diffFieldValue(old, field)
+			newValue, newSecret, _ := diffFieldValue(updated, field)
+			switch {
+			case !ok:
+				fmt.Printf("    %s: changed\n", field)
+			case (oldSecret || newSecret) && !diffReveal:
+				fmt.Printf("    %s: changed (use --reveal to show)\n", field)
+			default:
+				fmt.Printf("    %s: %s -> %s\n", field, oldValue, newValue)
+			}
+		}
+		crypto.Zeroize(old.Password)
+		crypto.Zeroize(updated.Password)
+	}
+	for _, e := range c.Removed {
+		fmt.Printf("- %s [%s] (removed)\n", e.Name, fromIDs[e.ID])
+	}
+}
+
+// diffFieldValue returns an entry's field, as named by vault.ChangedFields,
+// formatted for diff, and whether it is secret. ok is false for fields diff
+// only reports as changed.
+func diffFieldValue(e *vault.Entry, field string) (value string, secret, ok bool) {
+	if name, isCustom := strings.CutPrefix(field, "fields."); isCustom {
+		f := e.GetField(name)
+		if f == nil {
+			return "(none)", false, true
+		}
+		return quoteDiffValue(f.Value), f.Secret, true
+	}
+
+	switch field {
+	case "name":
+		return quoteDiffValue(e.Name), false, true
+	case "type":
+		return string(e.Type), false, true
+	case "username":
+		return quoteDiffValue(e.Username), false, true
+	case "password":
+		return quoteDiffValue(string(e.Password)), true, true
+	case "url":
+		return quoteDiffValue(e.URL), false, true
+	case "notes":
+		return quoteDiffValue(e.Notes), true, true
+	case "backup_codes":
+		return quoteDiffValue(strings.Join(e.BackupCodes, ", ")), true, true
+	case "used_backup_codes":
+		return quoteDiffValue(strings.Join(e.UsedBackupCodes, ", ")), true, true
+	case "tags":
+		return quoteDiffValue(strings.Join(e.Tags, ", ")), false, true
+	case "folder":
+		return quoteDiffValue(e.Folder), false, true
+	case "favorite":
+		return strconv.FormatBool(e.Favorite), false, true
+	case "created_at":
+		return formatTime(e.CreatedAt), false, true
+	case "updated_at":
+		return formatTime(e.UpdatedAt), false, true
+	}
+	return "", false, false
+}
+
+// quoteDiffValue quotes a value so empty values and whitespace show
+func quoteDiffValue(s string) string {
+	return strconv.Quote(s)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVar(&diffRemote, "remote", false, "Compare against the remote vault")
+	diffCmd.Flags().BoolVar(&diffReveal, "reveal", false, "Show the old and new values of secret fields")
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var execEnv []string
+
+// envVarPattern matches portable environment variable names
+var envVarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Environment variables exec sets when no --env is given
+const (
+	execPasswordVar = "VAULTCTL_PASSWORD"
+	execUsernameVar = "VAULTCTL_USERNAME"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <name_or_id> -- <command> [args...]",
+	Short: "Run a command with an entry's fields in its environment",
+	Long: `Run a command with fields of an entry exported as environment variables,
+so secrets reach a program without being written to disk or shown:
+
+  vaultctl exec prod-db --env password=PGPASSWORD -- psql -h db.example.com
+
+Each --env FIELD=VAR sets VAR to a field: username, password, url, notes
+or the name of a custom field. Without --env the password is exported as
+` + execPasswordVar + ` and the username, if any, as ` + execUsernameVar + `.
+
+The variables are only set in the child's environment, never in the
+calling shell, and vaultctl drops its copies and the vault key once the
+command has started. Ctrl-C goes to the command, and vaultctl exits with
+the command's exit code.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() != 1 || len(args) < 2 {
+			return fmt.Errorf("expected an entry and a command after --, e.g. vaultctl exec <name_or_id> -- <command>")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mapping, err := parseEnvMapping(execEnv)
+		if err != nil {
+			return err
+		}
+
+		// The command's output is often captured, so keep prompts off stdout
+		if err := ensureUnlockedQuietly(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+		defer crypto.Zeroize(entry.Password)
+
+		if mapping == nil {
+			mapping = defaultEnvMapping(entry)
+			if len(mapping) == 0 {
+				return fmt.Errorf("'%s' has no username or password; use --env to choose fields", entry.Name)
+			}
+		}
+
+		env := os.Environ()
+		var values [][]byte
+		defer func() {
+			for _, value := range values {
+				crypto.Zeroize(value)
+			}
+		}()
+		for _, m := range mapping {
+			value, err := entry.FieldValue(m.field)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+			if len(value) == 0 {
+				return fmt.Errorf("'%s' has no %s", entry.Name, m.field)
+			}
+			env = append(env, m.variable+"="+string(value))
+		}
+
+		// Recorded now, as the vault key is dropped once the command starts
+		recordUse(entry.ID)
+		saveUsage()
+
+		child := exec.Command(args[1], args[2:]...)
+		child.Env = env
+		child.Stdin = os.Stdin
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+
+		// The terminal sends Ctrl-C to the child too; leave it to the child
+		// and keep waiting for it. Termination requests are passed on.
+		signal.Stop(interrupts)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		if err := child.Start(); err != nil {
+			return fmt.Errorf("failed to run %s: %w", args[1], err)
+		}
+
+		// Nothing more is needed from the vault while the child runs
+		child.Env = nil
+		for _, value := range values {
+			crypto.Zeroize(value)
+		}
+		values = nil
+		crypto.Zeroize(entry.Password)
+		unlockedVault = nil
+		if vaultKey != nil {
+			releaseSecret(vaultKey)
+			vaultKey = nil
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- child.Wait() }()
+		for {
+			select {
+			case sig := <-signals:
+				if sig != os.Interrupt {
+					child.Process.Signal(sig)
+				}
+			case err := <-done:
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					// The child has reported its own failure
+					cmd.SilenceErrors = true
+					cmd.SilenceUsage = true
+					return &childExitError{code: exitErr.ExitCode()}
+				}
+				if err != nil {
+					return fmt.Errorf("failed to run %s: %w", args[1], err)
+				}
+				return nil
+			}
+		}
+	},
+}
+
+// envMapping exports one entry field as an environment variable
+type envMapping struct {
+	field    string
+	variable string
+}
+
+// parseEnvMapping parses repeatable FIELD=VAR flag values. It returns nil if
+// there are none.
+func parseEnvMapping(values []string) ([]envMapping, error) {
+	var mapping []envMapping
+	seen := make(map[string]bool)
+	for _, v := range values {
+		field, variable, ok := strings.Cut(v, "=")
+		field = strings.TrimSpace(field)
+		variable = strings.TrimSpace(variable)
+		if !ok || field == "" || variable == "" {
+			return nil, fmt.Errorf("invalid --env %q: expected FIELD=VAR", v)
+		}
+		if !envVarPattern.MatchString(variable) {
+			return nil, fmt.Errorf("invalid environment variable name %q", variable)
+		}
+		if seen[variable] {
+			return nil, fmt.Errorf("environment variable %s is set more than once", variable)
+		}
+		seen[variable] = true
+		mapping = append(mapping, envMapping{field: field, variable: variable})
+	}
+	return mapping, nil
+}
+
+// defaultEnvMapping exports the password and username of entry, where set
+func defaultEnvMapping(entry *vault.Entry) []envMapping {
+	var mapping []envMapping
+	if entry.Type.HasPassword() && len(entry.Password) > 0 {
+		mapping = append(mapping, envMapping{field: "password", variable: execPasswordVar})
+	}
+	if entry.Username != "" {
+		mapping = append(mapping, envMapping{field: "username", variable: execUsernameVar})
+	}
+	return mapping
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().StringArrayVar(&execEnv, "env", nil, "Export a field as FIELD=VAR, e.g. password=DB_PASS (repeatable)")
+}
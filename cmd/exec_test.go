@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEnvMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []envMapping
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"one", []string{"password=PGPASSWORD"}, []envMapping{{"password", "PGPASSWORD"}}, false},
+		{"several in order", []string{"username=DB_USER", "api key=API_KEY"},
+			[]envMapping{{"username", "DB_USER"}, {"api key", "API_KEY"}}, false},
+		{"spaces trimmed", []string{" password = PASS "}, []envMapping{{"password", "PASS"}}, false},
+		{"field used twice", []string{"password=A", "password=B"},
+			[]envMapping{{"password", "A"}, {"password", "B"}}, false},
+		{"no equals sign", []string{"password"}, nil, true},
+		{"empty field", []string{"=PASS"}, nil, true},
+		{"empty variable", []string{"password="}, nil, true},
+		{"variable starts with digit", []string{"password=1PASS"}, nil, true},
+		{"variable with dash", []string{"password=DB-PASS"}, nil, true},
+		{"variable with equals sign", []string{"password=A=B"}, nil, true},
+		{"variable set twice", []string{"password=PASS", "username=PASS"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnvMapping(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnvMapping(%q) error = %v, want error %v", tt.values, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEnvMapping(%q) = %+v, want %+v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
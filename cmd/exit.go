@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/session"
+	"github.com/vaultctl/vaultctl/internal/storage"
+)
+
+// Exit codes for failures that scripts may want to handle
+const (
+	ExitError           = 1
+	ExitVaultNotFound   = 3
+	ExitWrongPassword   = 4
+	ExitVersionConflict = 5
+	ExitSessionExpired  = 6
+	ExitVaultCorrupt    = 7
+	ExitVaultInUse      = 8
+	ExitInterrupted     = 130 // Ctrl-C, as shells report SIGINT
+)
+
+// childExitError is returned by exec when the child process fails, so
+// vaultctl exits with the child's exit code
+type childExitError struct {
+	code int
+}
+
+func (e *childExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.code)
+}
+
+// ExitCode maps an error returned by Execute to the process exit code
+func ExitCode(err error) int {
+	var childErr *childExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &childErr):
+		return childErr.code
+	case errors.Is(err, storage.ErrVaultNotFound):
+		return ExitVaultNotFound
+	case errors.Is(err, storage.ErrWrongPassword), errors.Is(err, storage.ErrWrongKeyFile), errors.Is(err, storage.ErrKeyFileRequired):
+		return ExitWrongPassword
+	case errors.Is(err, storage.ErrVersionConflict):
+		return ExitVersionConflict
+	case errors.Is(err, session.ErrSessionExpired), errors.Is(err, session.ErrSessionTokenMissing), errors.Is(err, session.ErrSessionStale), errors.Is(err, session.ErrSessionKeySource):
+		return ExitSessionExpired
+	case errors.Is(err, storage.ErrVaultDataCorrupt), errors.Is(err, storage.ErrCorruptVault):
+		return ExitVaultCorrupt
+	case errors.Is(err, storage.ErrVaultInUse):
+		return ExitVaultInUse
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/exporter"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+)
+
+var (
+	exportFormat  string
+	exportEncrypt bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <output_path>",
+	Short: "Export vault entries to a file",
+	Long: `Export the decrypted vault entries to JSON or CSV.
+CSV columns are name, username, password, url, notes. JSON exports can be
+encrypted with a passphrase using --encrypt; otherwise the file contains
+plaintext passwords.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputPath := args[0]
+
+		if exportFormat != exporter.FormatJSON && exportFormat != exporter.FormatCSV {
+			return fmt.Errorf("unsupported export format: %s (use json or csv)", exportFormat)
+		}
+		if exportEncrypt && exportFormat != exporter.FormatJSON {
+			return fmt.Errorf("--encrypt is only supported with --format json")
+		}
+		if err := checkExportPath(outputPath); err != nil {
+			return err
+		}
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		var data []byte
+		switch exportFormat {
+		case exporter.FormatJSON:
+			plaintext, err := exporter.MarshalJSON(unlockedVault.Entries)
+			if err != nil {
+				return err
+			}
+			if exportEncrypt {
+				passphrase, err := readNewPassphrase("export")
+				if err != nil {
+					crypto.Zeroize(plaintext)
+					return err
+				}
+				data, err = exporter.Encrypt(plaintext, passphrase)
+				crypto.Zeroize(passphrase)
+				crypto.Zeroize(plaintext)
+				if err != nil {
+					return err
+				}
+			} else {
+				data = plaintext
+			}
+		case exporter.FormatCSV:
+			var buf bytes.Buffer
+			if err := exporter.WriteCSV(&buf, unlockedVault.Entries); err != nil {
+				return err
+			}
+			data = buf.Bytes()
+		}
+		defer crypto.Zeroize(data)
+
+		if !exportEncrypt {
+			fmt.Fprintln(os.Stderr, "WARNING: this export contains UNENCRYPTED passwords.")
+			fmt.Fprintln(os.Stderr, "WARNING: anyone who can read the file can read every secret in your vault.")
+			fmt.Fprintln(os.Stderr, "WARNING: delete it securely once you no longer need it.")
+		}
+
+		if err := os.WriteFile(outputPath, data, 0600); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+
+		fmt.Printf("Exported %d entries to %s\n", len(unlockedVault.Entries), outputPath)
+		return nil
+	},
+}
+
+// checkExportPath refuses to write an export into a file that other users
+// can read. New files are created with mode 0600.
+func checkExportPath(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat export path: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("export path is a directory: %s", path)
+	}
+	if info.Mode().Perm()&0044 != 0 {
+		return fmt.Errorf("refusing to export to %s: file is readable by other users (mode %04o)", path, info.Mode().Perm())
+	}
+	return nil
+}
+
+// readNewPassphrase prompts for a new passphrase and its confirmation. kind
+// names what the passphrase protects, e.g. "export" or "backup".
+func readNewPassphrase(kind string) ([]byte, error) {
+	passphrase1, err := prompt.ReadPassword(fmt.Sprintf("Enter %s passphrase: ", kind))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	passphrase2, err := prompt.ReadPassword(fmt.Sprintf("Confirm %s passphrase: ", kind))
+	if err != nil {
+		crypto.Zeroize(passphrase1)
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	defer crypto.Zeroize(passphrase2)
+	if !crypto.ConstantTimeCompare(passphrase1, passphrase2) {
+		crypto.Zeroize(passphrase1)
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	if len(passphrase1) == 0 {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	return passphrase1, nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", exporter.FormatJSON, "Export format (json or csv)")
+	exportCmd.Flags().BoolVar(&exportEncrypt, "encrypt", false, "Encrypt the JSON export with a passphrase")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var favoriteCmd = &cobra.Command{
+	Use:   "favorite <name_or_id>",
+	Short: "Pin an entry to the top of list",
+	Long:  `Mark an entry as a favorite. Favorites are listed first and can be shown alone with 'vaultctl list --favorites'.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setFavorite(cmd, args[0], true)
+	},
+}
+
+var unfavoriteCmd = &cobra.Command{
+	Use:   "unfavorite <name_or_id>",
+	Short: "Unpin a favorite entry",
+	Long:  `Remove an entry from the favorites.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setFavorite(cmd, args[0], false)
+	},
+}
+
+// setFavorite pins or unpins an entry and saves the vault
+func setFavorite(cmd *cobra.Command, identifier string, favorite bool) error {
+	if err := ensureUnlocked(cmd); err != nil {
+		return err
+	}
+
+	entry, err := unlockedVault.LookupEntry(identifier)
+	if err != nil {
+		return err
+	}
+
+	if entry.Favorite == favorite {
+		if favorite {
+			fmt.Printf("'%s' is already a favorite\n", entry.Name)
+		} else {
+			fmt.Printf("'%s' is not a favorite\n", entry.Name)
+		}
+		return nil
+	}
+
+	if err := unlockedVault.SetFavorite(entry.ID, favorite); err != nil {
+		return err
+	}
+
+	sync := !cmd.Flags().Changed("no-sync")
+	if err := saveVault(cmd, sync); err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+
+	if favorite {
+		fmt.Printf("Added '%s' to favorites\n", entry.Name)
+	} else {
+		fmt.Printf("Removed '%s' from favorites\n", entry.Name)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(favoriteCmd)
+	rootCmd.AddCommand(unfavoriteCmd)
+	favoriteCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+	unfavoriteCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/clipboard"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/passphrase"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	generateLength           int
+	generateNoUppercase      bool
+	generateNoLowercase      bool
+	generateNoDigits         bool
+	generateNoSymbols        bool
+	generateExcludeAmbiguous bool
+	generateSymbols          string
+	generateUsePolicy        bool
+	generateEntry            string
+	generateWords            int
+	generateSeparator        string
+	generateCopy             bool
+	generateClearAfter       time.Duration
+)
+
+// policyClassFlags are the flags that shape a password policy. generate
+// refuses them alongside a stored policy rather than silently weakening it.
+var policyClassFlags = []string{"no-uppercase", "no-lowercase", "no-digits", "no-symbols", "symbols", "exclude-ambiguous"}
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a random password",
+	Long: `Generate a strong random password. No vault access is required.
+
+With --policy the vault's password policy is used, and with --entry the
+entry's own policy (or the vault's if it has none). Both unlock the vault.
+See 'vaultctl policy'.
+
+With --words a diceware-style passphrase is generated instead, such as
+"maple-orbit-sugar-canyon-tulip-drift". Each word adds 11 bits of entropy;
+the default of 6 words gives 66 bits.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var password []byte
+		var err error
+		if cmd.Flags().Changed("words") || cmd.Flags().Changed("separator") {
+			for _, name := range append([]string{"length", "policy", "entry"}, policyClassFlags...) {
+				if cmd.Flags().Changed(name) {
+					return fmt.Errorf("--%s can't be combined with --words", name)
+				}
+			}
+			password, err = passphrase.GeneratePassphrase(generateWords, generateSeparator)
+			if err != nil {
+				return fmt.Errorf("failed to generate passphrase: %w", err)
+			}
+		} else {
+			policy, err := generatePolicy(cmd)
+			if err != nil {
+				return err
+			}
+
+			length := generateLength
+			if !cmd.Flags().Changed("length") {
+				length = policy.Length()
+			}
+
+			password, err = crypto.GeneratePassword(length, policy)
+			if err != nil {
+				return fmt.Errorf("failed to generate password: %w", err)
+			}
+		}
+
+		defer crypto.Zeroize(password)
+
+		if generateCopy {
+			if clipboard.Available() {
+				return copyWithAutoClear(password, generateClearAfter)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %v. Printing password instead\n", clipboard.ErrUnavailable)
+		}
+
+		fmt.Println(string(password))
+		return nil
+	},
+}
+
+// generatePolicy returns the vault's or an entry's stored policy when
+// --policy or --entry is given, otherwise the defaults narrowed by the flags
+func generatePolicy(cmd *cobra.Command) (crypto.Policy, error) {
+	if generateUsePolicy || generateEntry != "" {
+		if err := ensureUnlockedQuietly(cmd); err != nil {
+			return crypto.Policy{}, err
+		}
+		var entry *vault.Entry
+		if generateEntry != "" {
+			var err error
+			if entry, err = unlockedVault.LookupEntry(generateEntry); err != nil {
+				return crypto.Policy{}, err
+			}
+		}
+		if stored := unlockedVault.PasswordPolicyFor(entry); stored != nil {
+			for _, name := range policyClassFlags {
+				if cmd.Flags().Changed(name) {
+					return crypto.Policy{}, fmt.Errorf("--%s can't be combined with a stored password policy; change the policy with 'vaultctl policy set'", name)
+				}
+			}
+			return *stored, nil
+		}
+		fmt.Fprintln(os.Stderr, "No password policy is set; using the defaults")
+	}
+
+	policy := crypto.DefaultPolicy()
+	policy.Uppercase = !generateNoUppercase
+	policy.Lowercase = !generateNoLowercase
+	policy.Digits = !generateNoDigits
+	policy.Symbols = !generateNoSymbols
+	policy.ExcludeAmbiguous = generateExcludeAmbiguous
+	if cmd.Flags().Changed("symbols") {
+		policy.SymbolSet = generateSymbols
+	}
+	return policy, nil
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	generateCmd.Flags().IntVar(&generateLength, "length", crypto.DefaultPasswordLength, "Password length")
+	generateCmd.Flags().BoolVar(&generateNoUppercase, "no-uppercase", false, "Exclude uppercase letters")
+	generateCmd.Flags().BoolVar(&generateNoLowercase, "no-lowercase", false, "Exclude lowercase letters")
+	generateCmd.Flags().BoolVar(&generateNoDigits, "no-digits", false, "Exclude digits")
+	generateCmd.Flags().BoolVar(&generateNoSymbols, "no-symbols", false, "Exclude symbols")
+	generateCmd.Flags().BoolVar(&generateExcludeAmbiguous, "exclude-ambiguous", false, "Exclude ambiguous characters such as I, l, 1, O and 0")
+	generateCmd.Flags().StringVar(&generateSymbols, "symbols", crypto.SymbolChars, "Symbols to choose from")
+	generateCmd.Flags().BoolVar(&generateUsePolicy, "policy", false, "Use the vault's password policy")
+	generateCmd.Flags().StringVar(&generateEntry, "entry", "", "Use this entry's password policy, or the vault's if it has none")
+	generateCmd.Flags().IntVar(&generateWords, "words", passphrase.DefaultWords, fmt.Sprintf("Generate a passphrase of this many words (%d bits each)", passphrase.BitsPerWord))
+	generateCmd.Flags().StringVar(&generateSeparator, "separator", passphrase.DefaultSeparator, "Separator between passphrase words")
+	generateCmd.Flags().BoolVar(&generateCopy, "copy", false, "Copy the password to the clipboard instead of printing it")
+	generateCmd.Flags().DurationVar(&generateClearAfter, "clear-after", DefaultClipboardClearAfter, "Clear the clipboard after this duration (0 to keep)")
+}
@@ -1,49 +1,244 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/clipboard"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/reveal"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"golang.org/x/term"
+)
+
+var (
+	getCopy       bool
+	getCopyField  string
+	getClearAfter time.Duration
+	getReveal     bool
+	getOverlay    bool
+	getPassOnly   bool
 )
 
 var getCmd = &cobra.Command{
 	Use:   "get <name_or_id>",
 	Short: "Get a password entry",
-	Long:  `Get and display a password entry by name or ID.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Get and display a password entry by name or ID.
+
+The password, secret custom fields and backup codes are hidden unless
+--reveal is given, so the username and URL can be checked without exposing
+anything. --copy copies the password instead. Set "reveal_passwords": true in
+the config to always show the password, as older versions did.
+
+With --overlay the password, and secret fields shown with --reveal, are
+displayed on a full-screen overlay that is cleared on a keypress instead of
+being left in scrollback. Without a terminal they are printed as usual.
+
+--copy-field copies any one field to the clipboard instead of showing the
+entry: username, password, url, notes or the name of a custom field. The
+value is never printed, so it fails if no clipboard tool is available.
+
+--password-only prints the password and nothing else, for scripts such as
+pw=$(vaultctl get gmail --password-only). Prompts and warnings go to stderr,
+and no newline is added unless stdout is a terminal.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if getPassOnly {
+			if getCopy || getCopyField != "" || getReveal || getOverlay {
+				return fmt.Errorf("--password-only cannot be used with --copy, --copy-field, --reveal or --overlay")
+			}
+			return printPasswordOnly(cmd, args[0])
+		}
+
 		if err := ensureUnlocked(cmd); err != nil {
 			return err
 		}
 
-		entry := unlockedVault.GetEntry(args[0])
-		if entry == nil {
-			return fmt.Errorf("entry not found: %s", args[0])
+		entry, err := library().Get(args[0])
+		if err != nil {
+			return err
+		}
+		defer crypto.Zeroize(entry.Password)
+
+		if getCopyField != "" {
+			if getCopy {
+				return fmt.Errorf("--copy and --copy-field cannot be used together")
+			}
+			return copyField(entry, getCopyField)
+		}
+
+		if getCopy && !entry.Type.HasPassword() {
+			return fmt.Errorf("%s entries have no password to copy", entry.Type)
+		}
+
+		// Fall back to printing if no clipboard tool is available
+		copyPassword := getCopy
+		if copyPassword && !clipboard.Available() {
+			fmt.Fprintf(os.Stderr, "Warning: %v. Printing password instead\n", clipboard.ErrUnavailable)
+			copyPassword = false
 		}
 
+		// Asking for the overlay is asking to see the password
+		showPassword := getReveal || getOverlay || cfg.RevealPasswords
+
+		// Secrets held back for the overlay
+		var overlay []reveal.Line
+
 		fmt.Printf("Name: %s\n", entry.Name)
-		fmt.Printf("Username: %s\n", entry.Username)
-		fmt.Printf("Password: %s\n", string(entry.Password))
+		fmt.Printf("Type: %s\n", entry.Type)
+		if entry.Type.HasPassword() {
+			fmt.Printf("Username: %s\n", entry.Username)
+			if !copyPassword {
+				if !showPassword {
+					fmt.Printf("Password: ******** (use --reveal to show or --copy to copy)\n")
+				} else if getOverlay {
+					overlay = append(overlay, reveal.Line{Label: "Password", Value: entry.Password})
+				} else {
+					fmt.Printf("Password: %s\n", string(entry.Password))
+				}
+			}
+		} else if entry.Username != "" {
+			fmt.Printf("Username: %s\n", entry.Username)
+		}
 		if entry.URL != "" {
 			fmt.Printf("URL: %s\n", entry.URL)
 		}
-		if entry.Notes != "" {
+		if entry.Type == vault.TypeNote {
+			fmt.Printf("Note:\n%s\n", entry.Notes)
+		} else if entry.Notes != "" {
 			fmt.Printf("Notes: %s\n", entry.Notes)
 		}
+		if len(entry.Fields) > 0 {
+			fmt.Printf("Fields:\n")
+			for _, field := range entry.Fields {
+				value := field.Value
+				if field.Secret && !getReveal {
+					value = "********"
+				} else if field.Secret && getOverlay {
+					overlay = append(overlay, reveal.Line{Label: field.Name, Value: []byte(field.Value)})
+					value = "(shown on overlay)"
+				}
+				fmt.Printf("  %s: %s\n", field.Name, value)
+			}
+		}
 		if len(entry.BackupCodes) > 0 {
-			fmt.Printf("Backup Codes:\n")
-			for i, code := range entry.BackupCodes {
-				fmt.Printf("  %d. %s\n", i+1, code)
+			unused := len(entry.UnusedBackupCodes())
+			if !getReveal {
+				fmt.Printf("Backup Codes: %d of %d unused (use --reveal to show, or 'vaultctl backup-code use')\n", unused, len(entry.BackupCodes))
+			} else {
+				fmt.Printf("Backup Codes:\n")
+				for i, code := range entry.BackupCodes {
+					status := ""
+					if entry.IsBackupCodeUsed(code) {
+						status = " (used)"
+					}
+					if getOverlay {
+						overlay = append(overlay, reveal.Line{Label: fmt.Sprintf("Backup code %d%s", i+1, status), Value: []byte(code)})
+						code = "(shown on overlay)"
+					}
+					fmt.Printf("  %d. %s%s\n", i+1, code, status)
+				}
+			}
+		}
+		if due, ok := entry.RotationDue(); ok {
+			status := ""
+			if entry.IsRotationDue(time.Now()) {
+				status = " (overdue)"
+			}
+			fmt.Printf("Password change due: %s%s\n", formatDate(due), status)
+		}
+		if len(entry.Attachments) > 0 {
+			fmt.Printf("Attachments:\n")
+			for _, attachment := range entry.Attachments {
+				fmt.Printf("  %s (%s)\n", attachment.Name, formatFileSize(attachment.Size))
+			}
+		}
+		fmt.Printf("Created: %s\n", formatTime(entry.CreatedAt))
+		fmt.Printf("Updated: %s\n", formatTime(entry.UpdatedAt))
+		if entry.Type.HasPassword() && (showPassword || getCopy) {
+			recordUse(entry.ID)
+		}
+
+		if copyPassword {
+			if err := copyWithAutoClear(entry.Password, getClearAfter); err != nil {
+				if !errors.Is(err, clipboard.ErrUnavailable) {
+					return err
+				}
+				if !getOverlay {
+					fmt.Printf("Password: %s\n", string(entry.Password))
+					return nil
+				}
+				overlay = append([]reveal.Line{{Label: "Password", Value: entry.Password}}, overlay...)
+			}
+		}
+
+		if len(overlay) > 0 {
+			if err := reveal.Show(overlay...); err != nil {
+				return fmt.Errorf("failed to show overlay: %w", err)
 			}
 		}
-		fmt.Printf("Created: %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", entry.UpdatedAt.Format("2006-01-02 15:04:05"))
 
 		return nil
 	},
 }
 
+// printPasswordOnly writes an entry's password, and nothing else, to stdout
+func printPasswordOnly(cmd *cobra.Command, identifier string) error {
+	if err := ensureUnlockedQuietly(cmd); err != nil {
+		return err
+	}
+
+	entry, err := library().Get(identifier)
+	if err != nil {
+		return err
+	}
+	defer crypto.Zeroize(entry.Password)
+
+	if !entry.Type.HasPassword() {
+		return fmt.Errorf("%s entries have no password", entry.Type)
+	}
+
+	// Keep the shell prompt off the password's line when printing to a
+	// terminal; $(...) would strip the newline anyway
+	if _, err := os.Stdout.Write(entry.Password); err != nil {
+		return err
+	}
+	recordUse(entry.ID)
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Println()
+	}
+	return nil
+}
+
+// copyField copies one field of entry to the clipboard without printing it
+func copyField(entry *vault.Entry, name string) error {
+	value, err := entry.FieldValue(name)
+	if err != nil {
+		return err
+	}
+	defer crypto.Zeroize(value)
+
+	if len(value) == 0 {
+		return fmt.Errorf("'%s' has no %s", entry.Name, name)
+	}
+	if !clipboard.Available() {
+		return fmt.Errorf("%w; the value is not printed with --copy-field", clipboard.ErrUnavailable)
+	}
+
+	fmt.Printf("Copying %s of '%s'\n", name, entry.Name)
+	recordUse(entry.ID)
+	return copyWithAutoClear(value, getClearAfter)
+}
+
 func init() {
 	rootCmd.AddCommand(getCmd)
+	getCmd.Flags().BoolVar(&getCopy, "copy", false, "Copy the password to the clipboard instead of printing it")
+	getCmd.Flags().StringVar(&getCopyField, "copy-field", "", "Copy a field (username, password, url, notes or a custom field) to the clipboard without printing it")
+	getCmd.Flags().BoolVar(&getReveal, "reveal", false, "Show the password, the values of secret custom fields and the backup codes")
+	getCmd.Flags().BoolVar(&getOverlay, "overlay", false, "Show the password and revealed fields on a full-screen overlay cleared on a keypress")
+	getCmd.Flags().BoolVar(&getPassOnly, "password-only", false, "Print only the password, for capturing with $(...)")
+	getCmd.Flags().DurationVar(&getClearAfter, "clear-after", DefaultClipboardClearAfter, "Clear the clipboard after this duration (0 to keep)")
 }
-
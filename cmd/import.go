@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/importer"
+)
+
+var (
+	importFormat      string
+	importMapping     []string
+	importOnDuplicate string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <input_path>",
+	Short: "Import entries from another password manager",
+	Long: `Import entries from a CSV export.
+Columns are detected from common header names (LastPass, Bitwarden and
+1Password exports work out of the box) or mapped explicitly with
+--map FIELD=COLUMN. Entries whose name already exists are skipped by
+default. With --on-duplicate they can instead be merged into the existing
+entry (merge), added under the name with a numeric suffix such as gmail-2
+(rename), or replace the existing entry, which is moved to the trash
+(overwrite).`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := checkDuplicatePolicy(importOnDuplicate, duplicateSkip, duplicateMerge, duplicateRename, duplicateOverwrite)
+		if err != nil {
+			return err
+		}
+
+		mapping, err := importer.ParseMapping(importMapping)
+		if err != nil {
+			return err
+		}
+		adapter, err := importer.NewAdapter(importFormat, mapping)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		records, err := adapter.Parse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse import file: %w", err)
+		}
+		defer func() {
+			for _, record := range records {
+				crypto.Zeroize(record.Password)
+			}
+		}()
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		imported, skipped, merged, replaced := 0, 0, 0, 0
+		for _, record := range records {
+			name := record.Name
+			if unlockedVault.HasName(name) {
+				switch importOnDuplicate {
+				case duplicateSkip:
+					skipped++
+					continue
+				case duplicateMerge:
+					// Blank values in the import keep what the entry already has
+					existing := unlockedVault.GetEntry(name)
+					unlockedVault.UpdateEntry(existing.ID, "", optionalString(record.Username), record.Password,
+						optionalString(record.URL), optionalString(record.Notes), nil, record.Tags)
+					merged++
+					continue
+				case duplicateRename:
+					name = unlockedVault.UniqueName(name)
+				case duplicateOverwrite:
+					// The replaced entry goes to the trash
+					unlockedVault.RemoveEntry(unlockedVault.GetEntry(name).ID)
+					unlockedVault.AddEntry(name, record.Username, record.Password, record.URL, record.Notes, nil, record.Tags)
+					replaced++
+					continue
+				}
+			}
+
+			unlockedVault.AddEntry(name, record.Username, record.Password, record.URL, record.Notes, nil, record.Tags)
+			imported++
+		}
+
+		if imported+merged+replaced > 0 {
+			sync := !cmd.Flags().Changed("no-sync")
+			if err := saveVault(cmd, sync); err != nil {
+				return fmt.Errorf("failed to save vault: %w", err)
+			}
+		}
+
+		fmt.Printf("Imported %d, skipped %d, merged %d, replaced %d entries\n", imported, skipped, merged, replaced)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importFormat, "format", importer.FormatCSV, "Import format ("+strings.Join(importer.Formats(), ", ")+")")
+	importCmd.Flags().StringArrayVar(&importMapping, "map", nil, "Map a field to a CSV column as FIELD=COLUMN (repeatable; fields: name, username, password, url, notes, tags)")
+	importCmd.Flags().StringVar(&importOnDuplicate, "on-duplicate", duplicateSkip, "What to do with entries whose name already exists (skip, merge, rename or overwrite)")
+	importCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
@@ -1,41 +1,107 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"syscall"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/prompt"
 	"github.com/vaultctl/vaultctl/internal/storage"
 	"github.com/vaultctl/vaultctl/internal/vault"
-	"golang.org/x/term"
+)
+
+var (
+	initCipher         string
+	initKDFAlgo        string
+	initKDFMemory      uint32
+	initKDFIterations  uint32
+	initKDFParallelism uint8
+	initKDFAuto        bool
+	initFormat         string
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new vault",
-	Long:  `Initialize a new encrypted vault with a master password.`,
+	Long: `Initialize a new encrypted vault with a master password.
+
+With --key-file the vault also needs a key file to unlock, such as one kept
+on a USB stick, so the password alone doesn't open it. A new random key file
+is written if the path doesn't exist, otherwise the file is used as it is.
+Its path is saved as key_file in the config; copy the file to each device
+and keep a backup, as the vault can't be opened without it.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if localStore.Exists() {
 			return fmt.Errorf("vault already exists at %s. Use 'vaultctl unlock' to access it", cfg.VaultPath)
 		}
 
+		// A vault set up on another device must not be replaced by a new one
+		if remoteStore != nil {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			ev, err := remoteStore.LoadVault(ctx)
+			switch {
+			case err == nil:
+				return fmt.Errorf("a vault for user '%s' already exists in remote storage (version %d). Run 'vaultctl unlock' to download it", cfg.RemoteUserID(), ev.Version)
+			case !errors.Is(err, storage.ErrRemoteVaultNotFound):
+				fmt.Fprintf(os.Stderr, "Warning: couldn't check remote storage for an existing vault: %v\n", err)
+			}
+		}
+
+		// Validate KDF parameters before prompting for anything
+		kdfParams := crypto.DefaultKDFParams()
+		kdfParams.Algo = initKDFAlgo
+		kdfParams.Memory = initKDFMemory
+		kdfParams.Iterations = initKDFIterations
+		kdfParams.Parallelism = initKDFParallelism
+		if initKDFAuto {
+			if cmd.Flags().Changed("kdf-memory") || cmd.Flags().Changed("kdf-iterations") {
+				return fmt.Errorf("--kdf-auto cannot be combined with --kdf-memory or --kdf-iterations")
+			}
+			if kdfParams.Algo != crypto.AlgoArgon2id {
+				return fmt.Errorf("--kdf-auto only supports %s", crypto.AlgoArgon2id)
+			}
+			fmt.Println("Calibrating KDF parameters...")
+			calibrated := crypto.CalibrateKDF(crypto.DefaultCalibrationTarget)
+			kdfParams.Memory = calibrated.Memory
+			kdfParams.Iterations = calibrated.Iterations
+			fmt.Printf("Using %s with %d KiB memory, %d iterations\n", kdfParams.Algo, kdfParams.Memory, kdfParams.Iterations)
+		}
+		if err := kdfParams.Validate(); err != nil {
+			return fmt.Errorf("invalid KDF parameters: %w", err)
+		}
+		if err := crypto.ValidateCipher(initCipher); err != nil {
+			return err
+		}
+		schemaVersion, err := storage.SchemaVersionFor(initFormat)
+		if err != nil {
+			return err
+		}
+		var keyFile []byte
+		if keyFilePath != "" {
+			if keyFile, err = createKeyFile(keyFilePath); err != nil {
+				return err
+			}
+			defer releaseSecret(keyFile)
+		}
+
 		// Prompt for master password
-		fmt.Print("Enter master password: ")
-		password1, err := term.ReadPassword(int(syscall.Stdin))
+		password1, err := prompt.ReadPassword("Enter master password: ")
 		if err != nil {
 			return fmt.Errorf("failed to read password: %w", err)
 		}
-		fmt.Println()
 
-		fmt.Print("Confirm master password: ")
-		password2, err := term.ReadPassword(int(syscall.Stdin))
+		password2, err := prompt.ReadPassword("Confirm master password: ")
 		if err != nil {
 			return fmt.Errorf("failed to read password: %w", err)
 		}
-		fmt.Println()
 
 		if !crypto.ConstantTimeCompare(password1, password2) {
 			crypto.Zeroize(password1)
@@ -54,71 +120,78 @@ var initCmd = &cobra.Command{
 			return fmt.Errorf("failed to generate vault key: %w", err)
 		}
 
-		// Derive master key
-		kdfParams := crypto.DefaultKDFParams()
-		masterKey := crypto.DeriveMasterKey(password1, salt, kdfParams)
-
-		// Encrypt vault key
-		encVaultKey, vaultKeyNonce, err := crypto.EncryptVaultKey(vaultKey, masterKey)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt vault key: %w", err)
-		}
-
 		// Create empty vault
 		v := vault.NewVault()
 
-		// Encrypt vault
-		plaintext, err := v.ToJSON()
-		if err != nil {
-			return fmt.Errorf("failed to serialize vault: %w", err)
-		}
-
-		ciphertext, nonce, err := crypto.Encrypt(plaintext, vaultKey)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt vault: %w", err)
-		}
-
 		// Create encrypted vault structure
 		ev := &storage.EncryptedVault{
-			SchemaVersion: vault.SchemaVersion,
+			SchemaVersion: schemaVersion,
 			VaultID:       v.VaultID,
 			SaltMaster:    crypto.EncodeBase64(salt),
-			EncVaultKey:   crypto.EncodeBase64(encVaultKey),
-			VaultKeyNonce: crypto.EncodeBase64(vaultKeyNonce),
 			KDFParams: storage.KDFParams{
 				Algo:       kdfParams.Algo,
 				Memory:     kdfParams.Memory,
 				Iterations: kdfParams.Iterations,
 				Parallelism: kdfParams.Parallelism,
 			},
-			Cipher:     "xchacha20poly1305",
-			Ciphertext: crypto.EncodeBase64(ciphertext),
-			Nonce:      crypto.EncodeBase64(nonce),
-			Version:    1,
+			Cipher:  initCipher,
+			Version: 1,
+		}
+		if keyFile != nil {
+			if err := ev.SetKeyFile(keyFile); err != nil {
+				return err
+			}
+		}
+
+		// Derive master key
+		masterKey, err := crypto.DeriveMasterKey(password1, salt, kdfParams)
+		if err != nil {
+			return fmt.Errorf("failed to derive master key: %w", err)
+		}
+		masterKey, err = ev.WithKeyFile(masterKey, keyFile)
+		if err != nil {
+			return err
+		}
+
+		// Encrypt vault key
+		encVaultKey, vaultKeyNonce, err := crypto.EncryptVaultKey(vaultKey, masterKey, initCipher)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt vault key: %w", err)
+		}
+		ev.EncVaultKey = crypto.EncodeBase64(encVaultKey)
+		ev.VaultKeyNonce = crypto.EncodeBase64(vaultKeyNonce)
+		if err := ev.SealVault(v, vaultKey); err != nil {
+			return fmt.Errorf("failed to encrypt vault: %w", err)
 		}
 		ev.SetModifiedAt(time.Now())
+		if err := ev.Sign(vaultKey); err != nil {
+			return fmt.Errorf("failed to sign vault: %w", err)
+		}
 
 		// Save locally
 		if err := localStore.SaveEncryptedVault(ev); err != nil {
 			return fmt.Errorf("failed to save vault locally: %w", err)
 		}
 
-		// Save to DynamoDB if available
-		if dynamoStore != nil {
+		// Save to remote storage if available
+		if remoteStore != nil {
 			ctx := cmd.Context()
 			if ctx == nil {
 				ctx = cmd.Root().Context()
 			}
-			if err := dynamoStore.SaveVault(ctx, ev, 0); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save to DynamoDB: %v\n", err)
+			if err := syncOrQueue(ctx, ev); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save to remote storage, change queued for next sync: %v\n", err)
 			} else {
-				fmt.Println("Vault initialized and synced to DynamoDB")
+				fmt.Println("Vault initialized and synced to remote storage")
 			}
 		} else {
 			fmt.Println("Vault initialized locally")
 		}
 
 		// Save config
+		if keyFile != nil {
+			fmt.Printf("Vault protected by key file %s. Keep a copy of it: the vault can't be unlocked without it\n", cfg.KeyFile)
+		}
 		if err := cfg.SaveConfig(); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to save config: %v\n", err)
 		}
@@ -131,7 +204,45 @@ var initCmd = &cobra.Command{
 	},
 }
 
+// createKeyFile reads the key file at path, or writes a new random one if
+// there is none, and records its absolute path in the config
+func createKeyFile(path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve key file path: %w", err)
+	}
+
+	f, err := os.OpenFile(abs, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		keyFile, err := crypto.GenerateKeyFile()
+		if err == nil {
+			_, err = f.Write(keyFile)
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		crypto.Zeroize(keyFile)
+		if err != nil {
+			os.Remove(abs)
+			return nil, fmt.Errorf("failed to write key file: %w", err)
+		}
+		fmt.Printf("Created key file %s\n", abs)
+	} else if !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("failed to create key file: %w", err)
+	}
+
+	cfg.KeyFile = abs
+	return readKeyFileAt(abs)
+}
+
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initCipher, "cipher", crypto.CipherXChaCha20Poly1305, "Vault cipher (xchacha20poly1305 or aes-256-gcm)")
+	initCmd.Flags().StringVar(&initKDFAlgo, "kdf-algo", crypto.AlgoArgon2id, "Key derivation function (argon2id or scrypt)")
+	initCmd.Flags().Uint32Var(&initKDFMemory, "kdf-memory", crypto.DefaultMemory, "KDF memory cost in KiB (minimum 8192)")
+	initCmd.Flags().Uint32Var(&initKDFIterations, "kdf-iterations", crypto.DefaultIterations, "KDF iterations, used as p for scrypt (minimum 1)")
+	initCmd.Flags().Uint8Var(&initKDFParallelism, "kdf-parallelism", crypto.DefaultParallelism, "Argon2id parallelism (minimum 1)")
+	initCmd.Flags().BoolVar(&initKDFAuto, "kdf-auto", false, "Benchmark this machine and pick Argon2id memory/iterations automatically")
+	initCmd.Flags().StringVar(&initFormat, "format", storage.FormatSingle, "Vault format: single or split (entry secrets encrypted separately)")
 }
 
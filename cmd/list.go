@@ -3,42 +3,212 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"github.com/vaultctl/vaultctl/internal/vaultctl"
+)
+
+var (
+	listTag       string
+	listDue       bool
+	listFavorites bool
+	listSort      string
+	listReverse   bool
+	listFolder    string
+	listTree      bool
+	listShowIDs   bool
+)
+
+// Sort orders for list
+const (
+	sortByName    = "name"
+	sortByUpdated = "updated"
+	sortByCreated = "created"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all password entries",
-	Long:  `List all password entries in the vault (without showing passwords).`,
+	Long: `List all password entries in the vault (without showing passwords).
+Favorites are listed first, marked with *, then entries are sorted by name
+(case-insensitive) or by --sort. Ties are broken by name and then ID, so the
+output is stable across runs and devices.
+
+--folder shows only a folder and its subfolders, and --tree draws the
+folder hierarchy instead of a table.
+
+--show-ids adds each entry's ID, shortened to the first 8 characters (more
+if needed to stay unique). Any command that takes an entry name also takes
+an ID or a unique ID prefix of at least 4 characters, which tells entries
+with the same name apart.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := ensureUnlocked(cmd); err != nil {
+		switch listSort {
+		case sortByName, sortByUpdated, sortByCreated:
+		default:
+			return fmt.Errorf("invalid --sort value: %s (use name, updated or created)", listSort)
+		}
+
+		if err := ensureUnlockedMetadata(cmd); err != nil {
 			return err
 		}
 
-		entries := unlockedVault.ListEntries()
+		lib := library()
+		filter := vaultctl.ListFilter{Favorites: listFavorites, Tag: listTag, Folder: listFolder}
+		if listDue {
+			filter.DueBy = time.Now()
+		}
+		entries, err := lib.List(filter)
+		if err != nil {
+			return err
+		}
 		if len(entries) == 0 {
 			fmt.Println("No entries found")
 			return nil
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "NAME\tUSERNAME\tURL\tUPDATED")
-		for _, entry := range entries {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-				entry.Name,
-				entry.Username,
-				entry.URL,
-				entry.UpdatedAt.Format("2006-01-02 15:04:05"))
+		var shortIDs map[string]string
+		if listShowIDs {
+			if shortIDs, err = lib.ShortIDs(); err != nil {
+				return err
+			}
 		}
-		w.Flush()
 
+		sortEntrySummaries(entries, listSort, listReverse)
+		if listTree {
+			printEntryTree(entries, vault.NormalizeFolder(listFolder), shortIDs)
+		} else {
+			printEntrySummaries(entries, shortIDs)
+		}
 		return nil
 	},
 }
 
+// sortEntrySummaries orders entries with favorites first, then by the sort
+// key, name and ID. reverse flips the order within favorites and the rest.
+func sortEntrySummaries(entries []vault.EntrySummary, by string, reverse bool) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Favorite != b.Favorite {
+			return a.Favorite
+		}
+		if reverse {
+			a, b = b, a
+		}
+		switch by {
+		case sortByUpdated:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		case sortByCreated:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+			return an < bn
+		}
+		return a.ID < b.ID
+	})
+}
+
+// printEntryTree prints sorted entries under their folders, subfolders first,
+// starting at root. Entries are followed by their short ID if shortIDs is
+// set.
+func printEntryTree(entries []vault.EntrySummary, root string, shortIDs map[string]string) {
+	byFolder := make(map[string][]vault.EntrySummary)
+	subfolders := make(map[string][]string)
+	for _, entry := range entries {
+		byFolder[entry.Folder] = append(byFolder[entry.Folder], entry)
+		// Register the folder with each of its parents up to root
+		for folder := entry.Folder; folder != root; {
+			parent := ""
+			if i := strings.LastIndex(folder, "/"); i >= 0 {
+				parent = folder[:i]
+			}
+			if slices.Contains(subfolders[parent], folder) {
+				break
+			}
+			subfolders[parent] = append(subfolders[parent], folder)
+			folder = parent
+		}
+	}
+
+	var walk func(folder string, depth int)
+	walk = func(folder string, depth int) {
+		indent := strings.Repeat("  ", depth)
+		children := subfolders[folder]
+		sort.Strings(children)
+		for _, child := range children {
+			fmt.Printf("%s%s/\n", indent, path.Base(child))
+			walk(child, depth+1)
+		}
+		for _, entry := range byFolder[folder] {
+			name := entry.Name
+			if entry.Favorite {
+				name = "* " + name
+			}
+			if entry.Username != "" {
+				name += " (" + entry.Username + ")"
+			}
+			if shortIDs != nil {
+				name += " [" + shortIDs[entry.ID] + "]"
+			}
+			fmt.Printf("%s%s\n", indent, name)
+		}
+	}
+
+	if root == "" {
+		walk(root, 0)
+		return
+	}
+	fmt.Printf("%s/\n", root)
+	walk(root, 1)
+}
+
+// printEntrySummaries prints entry summaries as a table, with an ID column
+// if shortIDs is set
+func printEntrySummaries(entries []vault.EntrySummary, shortIDs map[string]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if shortIDs != nil {
+		fmt.Fprint(w, "ID\t")
+	}
+	fmt.Fprintln(w, " \tNAME\tFOLDER\tTYPE\tUSERNAME\tURL\tTAGS\tUPDATED")
+	for _, entry := range entries {
+		marker := ""
+		if entry.Favorite {
+			marker = "*"
+		}
+		if shortIDs != nil {
+			fmt.Fprintf(w, "%s\t", shortIDs[entry.ID])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			marker,
+			entry.Name,
+			entry.Folder,
+			entry.Type,
+			entry.Username,
+			entry.URL,
+			strings.Join(entry.Tags, ","),
+			formatTime(entry.UpdatedAt))
+	}
+	w.Flush()
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVar(&listTag, "tag", "", "Only show entries with this tag")
+	listCmd.Flags().BoolVar(&listDue, "due", false, "Only show entries whose password is due to be changed")
+	listCmd.Flags().BoolVar(&listFavorites, "favorites", false, "Only show favorite entries")
+	listCmd.Flags().StringVar(&listSort, "sort", sortByName, "Sort by name, updated or created")
+	listCmd.Flags().BoolVar(&listReverse, "reverse", false, "Reverse the sort order")
+	listCmd.Flags().StringVar(&listFolder, "folder", "", "Only show entries in this folder and its subfolders")
+	listCmd.Flags().BoolVar(&listTree, "tree", false, "Show entries as a folder tree")
+	listCmd.Flags().BoolVar(&listShowIDs, "show-ids", false, "Show each entry's short ID")
 }
-
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -13,6 +14,9 @@ var lockCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Clear in-memory state
 		unlockedVault = nil
+		if vaultKey != nil {
+			releaseSecret(vaultKey)
+		}
 		vaultKey = nil
 
 		// Clear session
@@ -20,6 +24,13 @@ var lockCmd = &cobra.Command{
 			if err := sessionMgr.ClearSession(); err != nil {
 				return fmt.Errorf("failed to clear session: %w", err)
 			}
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if err := sessionMgr.ClearRemoteSession(ctx); err != nil {
+				return fmt.Errorf("failed to clear session: %w", err)
+			}
 		}
 
 		fmt.Println("Vault locked successfully")
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var moveCmd = &cobra.Command{
+	Use:   "move <name_or_id> <folder>",
+	Short: "Move an entry to another folder",
+	Long: `Move an entry to a folder, given as a slash-separated path such as
+work/aws. Use / to move it to the top level.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		folder := vault.NormalizeFolder(args[1])
+		if entry.Folder == folder {
+			fmt.Printf("'%s' is already in %s\n", entry.Name, describeFolder(folder))
+			return nil
+		}
+
+		if err := unlockedVault.EditEntry(entry.ID, func(e *vault.Entry) error {
+			e.Folder = folder
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		fmt.Printf("Moved '%s' to %s\n", entry.Name, describeFolder(folder))
+		return nil
+	},
+}
+
+// describeFolder names a folder for messages
+func describeFolder(folder string) string {
+	if folder == "" {
+		return "the top level"
+	}
+	return "'" + folder + "'"
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+	moveCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
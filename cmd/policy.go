@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	policyMinLength        int
+	policyNoUppercase      bool
+	policyNoLowercase      bool
+	policyNoDigits         bool
+	policyNoSymbols        bool
+	policySymbols          string
+	policyExcludeAmbiguous bool
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage password generation policies",
+	Long: `A password policy sets the minimum length, the required character classes
+and the allowed symbols for generated passwords. The vault's policy is used
+by 'add --generate' and 'generate --policy'. An entry's own policy, used by
+'generate --entry', takes precedence over the vault's, for sites that
+reject certain symbols.`,
+}
+
+var policyShowCmd = &cobra.Command{
+	Use:   "show [name_or_id]",
+	Short: "Show the vault's or an entry's password policy",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		var entry *vault.Entry
+		if len(args) == 1 {
+			var err error
+			if entry, err = unlockedVault.LookupEntry(args[0]); err != nil {
+				return err
+			}
+		}
+
+		policy := unlockedVault.PasswordPolicyFor(entry)
+		switch {
+		case policy == nil:
+			fmt.Println("No password policy is set; generated passwords use the defaults")
+			p := crypto.DefaultPolicy()
+			policy = &p
+		case entry != nil && entry.PasswordPolicy == nil:
+			fmt.Printf("'%s' has no policy of its own; the vault's policy applies\n", entry.Name)
+		}
+		printPolicy(*policy)
+		return nil
+	},
+}
+
+var policySetCmd = &cobra.Command{
+	Use:   "set [name_or_id]",
+	Short: "Set the vault's or an entry's password policy",
+	Long: `Set the password policy of the vault, or of an entry if one is named. The
+policy starts from the defaults (every class, the full symbol set) and is
+narrowed by the flags. Policies that no password can satisfy are refused.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		policy := crypto.DefaultPolicy()
+		policy.MinLength = policyMinLength
+		policy.Uppercase = !policyNoUppercase
+		policy.Lowercase = !policyNoLowercase
+		policy.Digits = !policyNoDigits
+		policy.Symbols = !policyNoSymbols
+		policy.ExcludeAmbiguous = policyExcludeAmbiguous
+		if cmd.Flags().Changed("symbols") {
+			policy.SymbolSet = policySymbols
+		}
+		if err := policy.Validate(policy.Length()); err != nil {
+			return fmt.Errorf("invalid password policy: %w", err)
+		}
+
+		target := "the vault"
+		if len(args) == 1 {
+			entry, err := unlockedVault.LookupEntry(args[0])
+			if err != nil {
+				return err
+			}
+			if err := unlockedVault.SetEntryPasswordPolicy(entry.ID, &policy); err != nil {
+				return err
+			}
+			target = fmt.Sprintf("'%s'", entry.Name)
+		} else {
+			unlockedVault.PasswordPolicy = &policy
+		}
+
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		fmt.Printf("Password policy set for %s\n", target)
+		printPolicy(policy)
+		return nil
+	},
+}
+
+var policyClearCmd = &cobra.Command{
+	Use:   "clear [name_or_id]",
+	Short: "Remove the vault's or an entry's password policy",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		target := "the vault"
+		if len(args) == 1 {
+			entry, err := unlockedVault.LookupEntry(args[0])
+			if err != nil {
+				return err
+			}
+			if entry.PasswordPolicy == nil {
+				fmt.Printf("'%s' has no password policy\n", entry.Name)
+				return nil
+			}
+			if err := unlockedVault.SetEntryPasswordPolicy(entry.ID, nil); err != nil {
+				return err
+			}
+			target = fmt.Sprintf("'%s'", entry.Name)
+		} else {
+			if unlockedVault.PasswordPolicy == nil {
+				fmt.Println("The vault has no password policy")
+				return nil
+			}
+			unlockedVault.PasswordPolicy = nil
+		}
+
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		fmt.Printf("Password policy removed from %s\n", target)
+		return nil
+	},
+}
+
+// printPolicy prints a policy's settings, one per line
+func printPolicy(p crypto.Policy) {
+	var classes []string
+	if p.Uppercase {
+		classes = append(classes, "uppercase")
+	}
+	if p.Lowercase {
+		classes = append(classes, "lowercase")
+	}
+	if p.Digits {
+		classes = append(classes, "digits")
+	}
+	if p.Symbols {
+		classes = append(classes, "symbols")
+	}
+
+	fmt.Printf("Minimum length:    %d (generates %d)\n", p.MinLength, p.Length())
+	fmt.Printf("Required classes:  %s\n", strings.Join(classes, ", "))
+	if p.Symbols {
+		fmt.Printf("Allowed symbols:   %s\n", p.SymbolSet)
+	}
+	if p.ExcludeAmbiguous {
+		fmt.Println("Ambiguous chars:   excluded")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyShowCmd)
+	policyCmd.AddCommand(policySetCmd)
+	policyCmd.AddCommand(policyClearCmd)
+
+	policySetCmd.Flags().IntVar(&policyMinLength, "min-length", 0, "Minimum password length")
+	policySetCmd.Flags().BoolVar(&policyNoUppercase, "no-uppercase", false, "Don't use uppercase letters")
+	policySetCmd.Flags().BoolVar(&policyNoLowercase, "no-lowercase", false, "Don't use lowercase letters")
+	policySetCmd.Flags().BoolVar(&policyNoDigits, "no-digits", false, "Don't use digits")
+	policySetCmd.Flags().BoolVar(&policyNoSymbols, "no-symbols", false, "Don't use symbols")
+	policySetCmd.Flags().StringVar(&policySymbols, "symbols", crypto.SymbolChars, "Symbols the site accepts")
+	policySetCmd.Flags().BoolVar(&policyExcludeAmbiguous, "exclude-ambiguous", false, "Exclude ambiguous characters such as I, l, 1, O and 0")
+	policySetCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+	policyClearCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	purgeLocalOnly bool
+	purgeBackups   bool
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete all of this vault's data from this machine and remote storage",
+	Long: `Permanently delete the vault: the local vault file and its sync state,
+the session, the config and, with --backups, the backup directory. The
+remote vault and any attachments uploaded to S3 are deleted too, unless
+--local-only is given.
+
+You are asked to type "purge" and then the master password. Remote data
+is deleted first, so if that fails nothing local has been removed yet and
+the command can be run again. Other devices keep their own copies.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if !purgeLocalOnly && remoteStore == nil && offlineMode() {
+			return fmt.Errorf("remote storage is disabled offline. Use --local-only to purge only this machine")
+		}
+		remote := remoteStore
+		if purgeLocalOnly {
+			remote = nil
+		}
+
+		// The remote vault can still be opened when there is no local one
+		ev, err := localStore.LoadEncryptedVault()
+		switch {
+		case errors.Is(err, storage.ErrVaultNotFound):
+			ev = nil
+		case errors.Is(err, storage.ErrCorruptVault):
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			ev = nil
+		case err != nil:
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+		var remoteEV *storage.EncryptedVault
+		if remote != nil {
+			remoteEV, err = remote.LoadVault(ctx)
+			if errors.Is(err, storage.ErrRemoteVaultNotFound) {
+				remoteEV = nil
+			} else if err != nil {
+				return fmt.Errorf("failed to load vault from remote storage: %w", err)
+			}
+			if ev == nil {
+				ev = remoteEV
+			}
+		}
+		var deleter storage.VaultDeleter
+		if remoteEV != nil {
+			var ok bool
+			if deleter, ok = remote.(storage.VaultDeleter); !ok {
+				return fmt.Errorf("this remote backend can't delete vaults. Use --local-only to purge only this machine")
+			}
+		}
+
+		fmt.Println("This permanently deletes:")
+		if localStore.Exists() {
+			fmt.Printf("  the local vault %s\n", localStore.VaultPath)
+		}
+		fmt.Printf("  the session %s\n", cfg.GetSessionPath())
+		fmt.Printf("  the config %s\n", cfg.ConfigPath)
+		if purgeBackups {
+			fmt.Printf("  the backups in %s\n", cfg.GetBackupDir())
+		}
+		if remoteEV != nil {
+			fmt.Printf("  the remote vault of user ID %s (version %d)\n", cfg.RemoteUserID(), remoteEV.Version)
+		}
+		fmt.Print(`Type "purge" to confirm: `)
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		if strings.TrimSpace(response) != "purge" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+
+		// Without any vault there is no password to check
+		var v *vault.Vault
+		if ev != nil {
+			if err := waitForUnlockAttempt(ctx); err != nil {
+				return err
+			}
+			password, err := prompt.ReadPassword("Enter master password: ")
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			lockSecret(password)
+			keyFile, err := readKeyFile()
+			if err != nil {
+				releaseSecret(password)
+				return err
+			}
+			var key []byte
+			v, key, err = storage.UnlockVault(ev, password, keyFile)
+			releaseSecret(password)
+			releaseSecret(keyFile)
+			if err != nil {
+				return unlockFailed(fmt.Errorf("failed to unlock vault: %w", err))
+			}
+			releaseSecret(key)
+			unlockSucceeded()
+		}
+
+		if remote != nil {
+			if v != nil {
+				purgeAttachments(ctx, v)
+			}
+			if remoteEV != nil {
+				if err := deleter.DeleteVault(ctx, remoteEV.Version); errors.Is(err, storage.ErrVersionConflict) {
+					return fmt.Errorf("the remote vault changed while purging. Run 'vaultctl purge' again")
+				} else if err != nil {
+					return fmt.Errorf("failed to delete remote vault: %w", err)
+				}
+				fmt.Printf("Deleted the remote vault of user ID %s\n", cfg.RemoteUserID())
+			}
+			if err := sessionMgr.ClearRemoteSession(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to delete remote session: %v\n", err)
+			}
+		}
+
+		if err := sessionMgr.ClearSession(); err != nil {
+			return fmt.Errorf("failed to clear session: %w", err)
+		}
+		unlockedVault = nil
+
+		// The lock file goes last, once the lock is released
+		paths := []string{
+			localStore.VaultPath,
+			localStore.SyncBasePath(),
+			localStore.PendingQueuePath(),
+			sessionMgr.UnlockAttemptsPath(),
+			localStore.VaultPath + ".corrupt",
+			cfg.ConfigPath,
+		}
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+		}
+		if purgeBackups {
+			if err := os.RemoveAll(cfg.GetBackupDir()); err != nil {
+				return fmt.Errorf("failed to delete backups: %w", err)
+			}
+		}
+		if releaseVaultLock != nil {
+			releaseVaultLock()
+			releaseVaultLock = nil
+		}
+		if err := os.Remove(localStore.LockPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %w", localStore.LockPath(), err)
+		}
+
+		// Directories are only removed once empty, so nothing else is lost
+		for _, dir := range []string{filepath.Dir(localStore.VaultPath), cfg.DataDir, filepath.Dir(cfg.ConfigPath)} {
+			os.Remove(dir)
+		}
+
+		fmt.Println("Local vault data deleted")
+		if !purgeBackups {
+			if _, err := os.Stat(cfg.GetBackupDir()); err == nil {
+				fmt.Printf("Backups were kept in %s; use --backups to delete them too\n", cfg.GetBackupDir())
+			}
+		}
+		return nil
+	},
+}
+
+// purgeAttachments deletes every attachment v stored in S3, including those
+// of entries in the trash. Failures are only warned about: the objects can't
+// be decrypted without the vault key, which is deleted with the vault.
+func purgeAttachments(ctx context.Context, v *vault.Vault) {
+	var keys []string
+	for _, entries := range [][]vault.Entry{v.Entries, v.DeletedEntries} {
+		for _, entry := range entries {
+			for _, a := range entry.Attachments {
+				if !a.IsInline() {
+					keys = append(keys, a.ObjectKey)
+				}
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	store, err := newAttachmentStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %d attachments were not deleted from S3: %v\n", len(keys), err)
+		return
+	}
+	deleted := 0
+	for _, key := range keys {
+		if err := store.DeleteAttachment(ctx, key); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to delete attachment %s: %v\n", key, err)
+			continue
+		}
+		deleted++
+	}
+	fmt.Printf("Deleted %d attachments from S3\n", deleted)
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+	purgeCmd.Flags().BoolVar(&purgeLocalOnly, "local-only", false, "Keep the remote vault and S3 attachments")
+	purgeCmd.Flags().BoolVar(&purgeBackups, "backups", false, "Delete the backup directory too")
+}
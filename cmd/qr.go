@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/qr"
+	"github.com/vaultctl/vaultctl/internal/reveal"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	qrField    string
+	qrPassword bool
+)
+
+var qrCmd = &cobra.Command{
+	Use:   "qr <name_or_id>",
+	Short: "Show an entry's TOTP secret or password as a QR code",
+	Long: `Show a QR code in the terminal for scanning with a phone.
+
+By default the code holds the entry's otpauth:// URI, for adding it to an
+authenticator app. The URI is taken from a custom field whose value starts
+with otpauth://, or built from a base32 secret in a field named "totp".
+Use --field to encode another custom field or --password to encode the
+password for a one-off transfer.
+
+The code is drawn on the alternate screen and cleared when a key is
+pressed, so it isn't left in scrollback.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if qrField != "" && qrPassword {
+			return fmt.Errorf("--field and --password cannot be used together")
+		}
+
+		if !reveal.IsTerminal() {
+			return fmt.Errorf("qr requires an interactive terminal")
+		}
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		var what string
+		switch {
+		case qrPassword:
+			if !entry.Type.HasPassword() || len(entry.Password) == 0 {
+				return fmt.Errorf("'%s' has no password", entry.Name)
+			}
+			data, what = entry.Password, "password"
+		case qrField != "":
+			field := entry.GetField(qrField)
+			if field == nil {
+				return fmt.Errorf("field not found: %s", qrField)
+			}
+			data, what = []byte(field.Value), "field "+field.Name
+		default:
+			uri, err := otpauthURI(entry)
+			if err != nil {
+				return err
+			}
+			data, what = []byte(uri), "TOTP secret"
+		}
+
+		code, err := qr.Encode(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode QR code: %w", err)
+		}
+
+		return reveal.Overlay([]byte(fmt.Sprintf("%s of '%s'\n\n%s", what, entry.Name, code.Terminal())))
+	},
+}
+
+// otpauthURI returns the entry's otpauth:// URI from a field holding one, or
+// builds it from a base32 secret in a "totp" field
+func otpauthURI(entry *vault.Entry) (string, error) {
+	for _, field := range entry.Fields {
+		if strings.HasPrefix(strings.ToLower(field.Value), "otpauth://") {
+			return field.Value, nil
+		}
+	}
+
+	field := entry.GetField("totp")
+	if field == nil {
+		return "", fmt.Errorf("'%s' has no otpauth:// URI or totp field; use --field or --password", entry.Name)
+	}
+	secret := strings.ToUpper(strings.ReplaceAll(field.Value, " ", ""))
+
+	label := url.PathEscape(entry.Name)
+	if entry.Username != "" {
+		label += ":" + url.PathEscape(entry.Username)
+	}
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", entry.Name)
+	return "otpauth://totp/" + label + "?" + query.Encode(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(qrCmd)
+	qrCmd.Flags().StringVar(&qrField, "field", "", "Encode the named custom field")
+	qrCmd.Flags().BoolVar(&qrPassword, "password", false, "Encode the password")
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/storage"
+)
+
+var (
+	rekdfAlgo        string
+	rekdfMemory      uint32
+	rekdfIterations  uint32
+	rekdfParallelism uint8
+	rekdfAuto        bool
+)
+
+var rekdfCmd = &cobra.Command{
+	Use:   "rekdf",
+	Short: "Upgrade the KDF parameters of the vault",
+	Long: `Re-derive the master key with new KDF parameters and re-encrypt the vault key.
+The master password stays the same. Without flags, the current defaults are applied.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !localStore.Exists() {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+
+		// Validate new KDF parameters before prompting for anything
+		kdfParams := crypto.DefaultKDFParams()
+		kdfParams.Algo = rekdfAlgo
+		kdfParams.Memory = rekdfMemory
+		kdfParams.Iterations = rekdfIterations
+		kdfParams.Parallelism = rekdfParallelism
+		if rekdfAuto {
+			if cmd.Flags().Changed("kdf-memory") || cmd.Flags().Changed("kdf-iterations") {
+				return fmt.Errorf("--kdf-auto cannot be combined with --kdf-memory or --kdf-iterations")
+			}
+			if kdfParams.Algo != crypto.AlgoArgon2id {
+				return fmt.Errorf("--kdf-auto only supports %s", crypto.AlgoArgon2id)
+			}
+			fmt.Println("Calibrating KDF parameters...")
+			calibrated := crypto.CalibrateKDF(crypto.DefaultCalibrationTarget)
+			kdfParams.Memory = calibrated.Memory
+			kdfParams.Iterations = calibrated.Iterations
+		}
+		if err := kdfParams.Validate(); err != nil {
+			return fmt.Errorf("invalid KDF parameters: %w", err)
+		}
+
+		// Load encrypted vault
+		ev, err := loadLocalVault(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if err := waitForUnlockAttempt(ctx); err != nil {
+			return err
+		}
+
+		// Prompt for master password
+		password, err := prompt.ReadPassword("Enter master password: ")
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		lockSecret(password)
+
+		keyFile, err := keyFileFor(ev)
+		if err != nil {
+			releaseSecret(password)
+			return err
+		}
+		defer releaseSecret(keyFile)
+
+		// Decrypt vault key with the current KDF parameters
+		vaultKey, err := storage.UnlockVaultKey(ev, password, keyFile)
+		if err != nil {
+			releaseSecret(password)
+			return unlockFailed(err)
+		}
+		lockSecret(vaultKey)
+		unlockSucceeded()
+
+		// Generate new salt and derive master key with the new parameters
+		newSalt, err := crypto.GenerateSalt()
+		if err != nil {
+			releaseSecret(password)
+			releaseSecret(vaultKey)
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+
+		newMasterKey, err := crypto.DeriveMasterKey(password, newSalt, kdfParams)
+		releaseSecret(password)
+		if err != nil {
+			releaseSecret(vaultKey)
+			return fmt.Errorf("failed to derive master key: %w", err)
+		}
+		newMasterKey, err = ev.WithKeyFile(newMasterKey, keyFile)
+		if err != nil {
+			releaseSecret(vaultKey)
+			return err
+		}
+		lockSecret(newMasterKey)
+
+		// Re-encrypt vault key with new master key
+		newEncVaultKey, newNonceVK, err := crypto.EncryptVaultKey(vaultKey, newMasterKey, ev.Cipher)
+		releaseSecret(newMasterKey)
+		if err != nil {
+			releaseSecret(vaultKey)
+			return fmt.Errorf("failed to encrypt vault key: %w", err)
+		}
+
+		oldParams := ev.KDFParams
+
+		// Update encrypted vault
+		ev.SaltMaster = crypto.EncodeBase64(newSalt)
+		ev.EncVaultKey = crypto.EncodeBase64(newEncVaultKey)
+		ev.VaultKeyNonce = crypto.EncodeBase64(newNonceVK)
+		ev.KDFParams = storage.KDFParams{
+			Algo:        kdfParams.Algo,
+			Memory:      kdfParams.Memory,
+			Iterations:  kdfParams.Iterations,
+			Parallelism: kdfParams.Parallelism,
+		}
+		ev.SetModifiedAt(time.Now())
+		ev.Version++
+		err = ev.Sign(vaultKey)
+		releaseSecret(vaultKey)
+		if err != nil {
+			return fmt.Errorf("failed to sign vault: %w", err)
+		}
+
+		// Save locally
+		if err := localStore.SaveEncryptedVault(ev); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		// Save to remote storage if available
+		if remoteStore != nil {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if err := syncOrQueue(ctx, ev); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save to remote storage, change queued for next sync: %v\n", err)
+			}
+		}
+
+		fmt.Printf("KDF parameters updated: %s %d KiB/%d iterations/%d lanes -> %s %d KiB/%d iterations/%d lanes\n",
+			oldParams.Algo, oldParams.Memory, oldParams.Iterations, oldParams.Parallelism,
+			kdfParams.Algo, kdfParams.Memory, kdfParams.Iterations, kdfParams.Parallelism)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rekdfCmd)
+	rekdfCmd.Flags().StringVar(&rekdfAlgo, "kdf-algo", crypto.AlgoArgon2id, "Key derivation function (argon2id or scrypt)")
+	rekdfCmd.Flags().Uint32Var(&rekdfMemory, "kdf-memory", crypto.DefaultMemory, "KDF memory cost in KiB (minimum 8192)")
+	rekdfCmd.Flags().Uint32Var(&rekdfIterations, "kdf-iterations", crypto.DefaultIterations, "KDF iterations, used as p for scrypt (minimum 1)")
+	rekdfCmd.Flags().Uint8Var(&rekdfParallelism, "kdf-parallelism", crypto.DefaultParallelism, "Argon2id parallelism (minimum 1)")
+	rekdfCmd.Flags().BoolVar(&rekdfAuto, "kdf-auto", false, "Benchmark this machine and pick Argon2id memory/iterations automatically")
+}
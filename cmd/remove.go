@@ -1,38 +1,68 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var removeForce bool
+
 var removeCmd = &cobra.Command{
 	Use:   "remove <name_or_id>",
 	Short: "Remove a password entry",
-	Long:  `Remove a password entry from the vault by name or ID.`,
+	Long: `Remove a password entry from the vault by name or ID.
+Removed entries are moved to the trash and can be restored with 'vaultctl restore-entry'.
+You are asked to confirm unless --force is given.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := ensureUnlocked(cmd); err != nil {
 			return err
 		}
 
-		if !unlockedVault.RemoveEntry(args[0]) {
-			return fmt.Errorf("entry not found: %s", args[0])
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		if !removeForce {
+			if entry.Username != "" {
+				fmt.Printf("Remove '%s' (%s)? (y/n): ", entry.Name, entry.Username)
+			} else {
+				fmt.Printf("Remove '%s'? (y/n): ", entry.Name)
+			}
+			reader := bufio.NewReader(os.Stdin)
+			response, _ := reader.ReadString('\n')
+			response = strings.TrimSpace(strings.ToLower(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("Cancelled")
+				return nil
+			}
 		}
 
+		if err := backupBeforeWrite("remove"); err != nil {
+			return err
+		}
+
+		unlockedVault.RemoveEntry(entry.ID)
+
 		// Save vault
 		sync := !cmd.Flags().Changed("no-sync")
 		if err := saveVault(cmd, sync); err != nil {
 			return fmt.Errorf("failed to save vault: %w", err)
 		}
 
-		fmt.Printf("Entry '%s' removed successfully\n", args[0])
+		fmt.Printf("Entry '%s' moved to trash. Use 'vaultctl restore-entry' to restore it\n", entry.Name)
 		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(removeCmd)
+	removeCmd.Flags().BoolVarP(&removeForce, "force", "f", false, "Remove without asking for confirmation")
 	removeCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
 }
 
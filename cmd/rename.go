@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <name_or_id> <new_name>",
+	Short: "Rename an entry",
+	Long: `Give an entry a new name. Unlike 'update --name', rename refuses a name
+that another entry already has.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newName := strings.TrimSpace(args[1])
+		if newName == "" {
+			return fmt.Errorf("new name cannot be empty")
+		}
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.LookupEntry(args[0])
+		if err != nil {
+			return err
+		}
+		if entry.Name == newName {
+			return fmt.Errorf("entry is already named '%s'", newName)
+		}
+		if unlockedVault.HasName(newName) {
+			return fmt.Errorf("entry with name '%s' already exists", newName)
+		}
+
+		if err := unlockedVault.EditEntry(entry.ID, func(e *vault.Entry) error {
+			e.Name = newName
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		fmt.Printf("Renamed '%s' to '%s'\n", entry.Name, newName)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+	renameCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
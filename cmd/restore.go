@@ -11,16 +11,41 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/atomic"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/prompt"
 	"github.com/vaultctl/vaultctl/internal/storage"
 )
 
+var (
+	restoreFromExport  bool
+	restoreOnDuplicate string
+)
+
 var restoreCmd = &cobra.Command{
 	Use:   "restore [backup_path]",
 	Short: "Restore vault from a backup",
 	Long: `Restore your vault from an encrypted backup file.
-If no backup path is provided, lists available backups for selection.`,
+If no backup path is provided, lists available backups for selection.
+Backups created with 'backup --passphrase' ask for the backup passphrase.
+
+With --from-export the file is a JSON export from 'vaultctl export', for
+example one shared by another user, plaintext or encrypted with
+--encrypt. Its entries are re-encrypted under this vault's key and merged
+into it instead of replacing it. Entries whose name already exists are
+skipped unless --on-duplicate says otherwise, as for import.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if restoreFromExport {
+			if len(args) == 0 {
+				return fmt.Errorf("--from-export needs the path of the export file")
+			}
+			return restoreExport(cmd, args[0])
+		}
+		if cmd.Flags().Changed("on-duplicate") {
+			return fmt.Errorf("--on-duplicate only applies with --from-export")
+		}
+
 		var backupPath string
 
 		if len(args) > 0 {
@@ -31,8 +56,7 @@ If no backup path is provided, lists available backups for selection.`,
 			}
 		} else {
 			// List and select from available backups
-			homeDir, _ := os.UserHomeDir()
-			backupDir := filepath.Join(homeDir, ".vaultctl", "backups")
+			backupDir := cfg.GetBackupDir()
 
 			// Check if backup directory exists
 			if _, err := os.Stat(backupDir); os.IsNotExist(err) {
@@ -55,7 +79,7 @@ If no backup path is provided, lists available backups for selection.`,
 			for i, backup := range backups {
 				info, _ := os.Stat(backup.Path)
 				fmt.Printf("  %d. %s\n", i+1, filepath.Base(backup.Path))
-				fmt.Printf("     Created: %s\n", backup.CreatedAt.Format("2006-01-02 15:04:05"))
+				fmt.Printf("     Created: %s\n", formatTime(backup.CreatedAt))
 				fmt.Printf("     Size: %s\n", formatFileSize(info.Size()))
 				fmt.Println()
 			}
@@ -83,36 +107,28 @@ If no backup path is provided, lists available backups for selection.`,
 			return fmt.Errorf("backup file not found: %s", backupPath)
 		}
 
-		// Check if current vault exists and offer to backup it first
-		if localStore.Exists() {
+		// A backup other users can read has already leaked the vault
+		if err := storage.CheckFilePermissions(backupPath, strictPerms); err != nil {
+			return err
+		}
+
+		// Back up the current vault first: always with backup_before_write,
+		// otherwise if the user wants to
+		if cfg.BackupBeforeWrite {
+			if err := backupBeforeWrite("restore"); err != nil {
+				return err
+			}
+		} else if localStore.Exists() {
 			fmt.Print("Current vault exists. Create a backup before restoring? (y/n): ")
 			reader := bufio.NewReader(os.Stdin)
 			response, _ := reader.ReadString('\n')
 			response = strings.TrimSpace(strings.ToLower(response))
 
 			if response == "y" || response == "yes" {
-				homeDir, _ := os.UserHomeDir()
-				backupDir := filepath.Join(homeDir, ".vaultctl", "backups")
-				if err := os.MkdirAll(backupDir, 0700); err != nil {
-					return fmt.Errorf("failed to create backup directory: %w", err)
-				}
-				timestamp := time.Now().Format("2006-01-02T15-04-05Z")
-				currentBackupPath := filepath.Join(backupDir, fmt.Sprintf("vault-before-restore-%s.enc", timestamp))
-
-				ev, err := localStore.LoadEncryptedVault()
-				if err != nil {
-					return fmt.Errorf("failed to load current vault: %w", err)
-				}
-
-				data, err := ev.ToJSON()
+				currentBackupPath, err := snapshotVault("before-restore")
 				if err != nil {
-					return fmt.Errorf("failed to serialize vault: %w", err)
-				}
-
-				if err := os.WriteFile(currentBackupPath, data, 0600); err != nil {
-					return fmt.Errorf("failed to create backup: %w", err)
+					return err
 				}
-
 				fmt.Printf("Current vault backed up to: %s\n", currentBackupPath)
 			}
 		}
@@ -123,12 +139,28 @@ If no backup path is provided, lists available backups for selection.`,
 			return fmt.Errorf("failed to read backup file: %w", err)
 		}
 
-		// Verify it's valid JSON (basic check)
-		// We'll do a more thorough check by trying to parse it
-		_, err = storage.EncryptedVaultFromJSON(backupData)
+		// Unwrap passphrase-protected backups
+		if storage.IsProtectedBackup(backupData) {
+			passphrase, err := prompt.ReadPassword("Enter backup passphrase: ")
+			if err != nil {
+				return fmt.Errorf("failed to read passphrase: %w", err)
+			}
+
+			backupData, err = storage.UnprotectBackup(backupData, passphrase)
+			crypto.Zeroize(passphrase)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Check the envelope is intact before overwriting the vault
+		restored, err := storage.EncryptedVaultFromJSON(backupData)
 		if err != nil {
 			return fmt.Errorf("backup file appears to be invalid or corrupted: %w", err)
 		}
+		if err := restored.Validate(); err != nil {
+			return fmt.Errorf("backup file appears to be invalid or corrupted: %w", err)
+		}
 
 		// Ensure vault directory exists
 		if err := localStore.EnsureDir(); err != nil {
@@ -136,7 +168,7 @@ If no backup path is provided, lists available backups for selection.`,
 		}
 
 		// Write backup to vault location
-		if err := os.WriteFile(cfg.VaultPath, backupData, 0600); err != nil {
+		if err := atomic.WriteFile(cfg.VaultPath, backupData, 0600); err != nil {
 			return fmt.Errorf("failed to write restored vault: %w", err)
 		}
 
@@ -208,5 +240,8 @@ func formatFileSize(size int64) string {
 
 func init() {
 	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().BoolVar(&restoreFromExport, "from-export", false, "Merge the entries of a JSON export into the vault instead of restoring a backup")
+	restoreCmd.Flags().StringVar(&restoreOnDuplicate, "on-duplicate", duplicateSkip, "With --from-export, what to do with entries whose name already exists (skip, merge, rename or overwrite)")
+	restoreCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
 }
 
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/exporter"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// restoreExport merges the entries of a JSON export, plaintext or encrypted
+// with a passphrase, into the current vault. Unlike a raw restore the entries
+// are re-encrypted under the current vault key and nothing is overwritten
+// unless --on-duplicate overwrite is given.
+func restoreExport(cmd *cobra.Command, exportPath string) error {
+	if err := checkDuplicatePolicy(restoreOnDuplicate, duplicateSkip, duplicateMerge, duplicateRename, duplicateOverwrite); err != nil {
+		return err
+	}
+	if !localStore.Exists() {
+		return fmt.Errorf("%w. Run 'vaultctl init' first, then restore the export into it", storage.ErrVaultNotFound)
+	}
+	if err := storage.CheckFilePermissions(exportPath, strictPerms); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read export file: %w", err)
+	}
+	defer crypto.Zeroize(data)
+
+	if exporter.IsEncrypted(data) {
+		passphrase, err := prompt.ReadPassword("Enter export passphrase: ")
+		if err != nil {
+			return fmt.Errorf("failed to read passphrase: %w", err)
+		}
+
+		plaintext, err := exporter.Decrypt(data, passphrase)
+		crypto.Zeroize(passphrase)
+		if err != nil {
+			return err
+		}
+		defer crypto.Zeroize(plaintext)
+		data = plaintext
+	}
+
+	doc, err := exporter.ParseJSON(data)
+	if err != nil {
+		return fmt.Errorf("export file appears to be invalid: %w", err)
+	}
+
+	if err := ensureUnlocked(cmd); err != nil {
+		return err
+	}
+	if cfg.BackupBeforeWrite {
+		if err := backupBeforeWrite("restore"); err != nil {
+			return err
+		}
+	}
+
+	added, skipped, merged, replaced := 0, 0, 0, 0
+	for _, e := range doc.Entries {
+		name := e.Name
+		if unlockedVault.HasName(name) {
+			switch restoreOnDuplicate {
+			case duplicateSkip:
+				skipped++
+				continue
+			case duplicateMerge:
+				// Blank values in the export keep what the entry already has
+				existing := unlockedVault.GetEntry(name)
+				var backupCodes []string
+				if len(e.BackupCodes) > 0 {
+					backupCodes = e.BackupCodes
+				}
+				unlockedVault.UpdateEntry(existing.ID, "", optionalString(e.Username), []byte(e.Password),
+					optionalString(e.URL), optionalString(e.Notes), backupCodes, e.Tags)
+				if len(e.Fields) > 0 {
+					unlockedVault.EditEntry(existing.ID, func(entry *vault.Entry) error {
+						entry.Fields = e.Fields
+						return nil
+					})
+				}
+				merged++
+				continue
+			case duplicateRename:
+				name = unlockedVault.UniqueName(name)
+			case duplicateOverwrite:
+				// The replaced entry goes to the trash
+				unlockedVault.RemoveEntry(unlockedVault.GetEntry(name).ID)
+				addExportedEntry(name, e)
+				replaced++
+				continue
+			}
+		}
+
+		addExportedEntry(name, e)
+		added++
+	}
+
+	if added+merged+replaced > 0 {
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+	}
+
+	fmt.Printf("Restored %d, skipped %d, merged %d, replaced %d entries from the export\n", added, skipped, merged, replaced)
+	return nil
+}
+
+// addExportedEntry adds an exported entry under name, keeping its type,
+// custom fields and timestamps
+func addExportedEntry(name string, e exporter.Entry) {
+	unlockedVault.AddEntryWith(name, e.Username, []byte(e.Password), e.URL, e.Notes, e.BackupCodes, e.Tags, vault.EntryOptions{
+		Type:      e.Type,
+		Fields:    e.Fields,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	})
+}
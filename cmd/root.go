@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/atomic"
 	"github.com/vaultctl/vaultctl/internal/config"
 	"github.com/vaultctl/vaultctl/internal/session"
 	"github.com/vaultctl/vaultctl/internal/storage"
@@ -13,10 +19,67 @@ import (
 var (
 	cfg         *config.Config
 	localStore  *storage.LocalStorage
-	dynamoStore *storage.DynamoDBStorage
+	remoteStore storage.RemoteStore
 	sessionMgr  *session.SessionManager
+	noMlock     bool
+	strictPerms bool
+	offline     bool
+	vaultName   string
+	vaultPath   string
+	readOnly    bool
+	useUTC      bool
+	keyFilePath string
+
+	// releaseVaultLock releases the vault lock if this command holds it
+	releaseVaultLock func()
+
+	// interrupts receives Ctrl-C while a command runs. exec stops it so the
+	// child process handles Ctrl-C itself.
+	interrupts = make(chan os.Signal, 1)
 )
 
+// errReadOnly is returned when --read-only is set and something would change
+// the vault, the session or remote storage
+var errReadOnly = errors.New("vault is opened read-only")
+
+// readOnlyCommands are the commands that still run with --read-only. Anything
+// not listed may change the vault and is refused before it starts.
+var readOnlyCommands = map[string]bool{
+	"attachment get": true,
+	"audit":          true,
+	"backup verify":  true,
+	"breach-check":   true,
+	"completion":     true,
+	"diff":           true,
+	"doctor":         true,
+	"exec":           true,
+	"export":         true,
+	"generate":       true,
+	"get":            true,
+	"help":           true,
+	"list":           true,
+	"lock":           true,
+	"policy":         true,
+	"policy show":    true,
+	"qr":             true,
+	"search":         true,
+	"stats":          true,
+	"status":         true,
+	"trash list":     true,
+	"tui":            true,
+	"unlock":         true,
+}
+
+// mayWriteVault reports whether cmd may save the vault, and so must hold the
+// vault lock from before it reads the vault until it exits. tui may be used
+// read-only but edits entries otherwise.
+func mayWriteVault(cmd *cobra.Command) bool {
+	if readOnly {
+		return false
+	}
+	return !allowedReadOnly(cmd) || cmd.Name() == "tui"
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "vaultctl",
@@ -24,38 +87,220 @@ var rootCmd = &cobra.Command{
 	Long: `vaultctl is a CLI password manager with client-side encryption.
 All encryption and decryption happens locally. The server (DynamoDB) only
 stores encrypted blobs and never sees your master password or decrypted data.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// A dry run writes nothing, so it runs as if --read-only were given
+		if isDryRun(cmd) {
+			readOnly = true
+		}
+		if readOnly && !allowedReadOnly(cmd) {
+			return fmt.Errorf("%w: '%s' may change the vault", errReadOnly, cmd.CommandPath())
+		}
+		if err := setup(); err != nil {
+			return err
+		}
+		sessionMgr.SetReadOnly(readOnly)
+		if mayWriteVault(cmd) {
+			release, err := localStore.Lock()
+			if err != nil {
+				return err
+			}
+			releaseVaultLock = release
+		}
+		return nil
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		saveUsage()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		<-interrupts
+		// Abort remote calls, and let a second Ctrl-C kill the process as
+		// usual. Password prompts don't watch the context, so exit anyway
+		// if the command hasn't returned shortly.
+		cancel()
+		signal.Stop(interrupts)
+		time.Sleep(interruptGrace)
+		os.Exit(ExitInterrupted)
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if releaseVaultLock != nil {
+		releaseVaultLock()
+	}
+	if errors.Is(err, atomic.ErrNotWritable) {
+		fmt.Fprintf(os.Stderr, "Set %s to a writable directory, or use --vault-path to move just the vault file\n", config.HomeEnvVar)
+	}
+	if errors.Is(err, storage.ErrKeyFileRequired) {
+		fmt.Fprintf(os.Stderr, "Pass the vault's key file with --key-file, or set key_file in %s\n", cfg.ConfigPath)
+	}
+	return err
+}
+
+// interruptGrace is how long a command has to return after Ctrl-C before the
+// process exits
+const interruptGrace = 2 * time.Second
+
+// allowedReadOnly reports whether cmd may run with --read-only
+func allowedReadOnly(cmd *cobra.Command) bool {
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	if readOnlyCommands[path] || isDryRun(cmd) {
+		return true
+	}
+	// Shell completion runs hidden commands under "completion" and "__complete"
+	return strings.HasPrefix(path, "completion ") || strings.HasPrefix(path, "__complete")
+}
+
+// isDryRun reports whether cmd was only asked to report what it would do
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	return err == nil && dryRun
+}
+
+// setup loads the selected vault's config and initializes storage and the
+// session manager. It runs after flags are parsed so --vault can take effect.
+func setup() error {
+	profile := vaultName
+	if profile == "" {
+		profile = os.Getenv(config.ProfileEnvVar)
+	}
+
 	var err error
-	cfg, err = config.LoadConfig()
+	cfg, err = config.LoadProfileConfig(profile)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if vaultPath != "" {
+		cfg.VaultPath = vaultPath
+	}
 	localStore = storage.NewLocalStorage(cfg.VaultPath)
+	localStore.StrictPerms = strictPerms
 
-	// Initialize session manager with AWS Secrets Manager support
+	sessionTimeout, err := cfg.GetSessionTimeout()
+	if err != nil {
+		return err
+	}
+	if sessionTimeout == 0 {
+		sessionTimeout = session.DefaultSessionTimeout
+	}
+
+	// Initialize session manager with AWS Secrets Manager support. Offline,
+	// the session master key is derived locally instead.
+	secretName, region := cfg.SessionSecretName, cfg.AWSRegion
+	if offlineMode() {
+		secretName, region = "", ""
+	}
 	sessionMgr = session.NewSessionManager(
 		cfg.GetSessionPath(),
-		session.DefaultSessionTimeout,
-		cfg.SessionSecretName,
-		cfg.AWSRegion,
+		sessionTimeout,
+		secretName,
+		region,
 	)
+	if sleepLockAfter, ok, err := cfg.GetSleepLockAfter(); err != nil {
+		return err
+	} else if ok {
+		sessionMgr.SetSleepThreshold(sleepLockAfter)
+	}
+	if runnerID := os.Getenv(config.RunnerIDEnvVar); runnerID != "" {
+		if offlineMode() {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s in offline mode\n", config.RunnerIDEnvVar)
+		} else if err := sessionMgr.SetRunner(runnerID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", config.RunnerIDEnvVar, err)
+		}
+	}
+
+	if err := initRemoteStore(); err != nil {
+		// Don't fail if remote storage isn't configured, just log
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	return nil
+}
+
+// offlineMode reports whether AWS must not be contacted, from --offline or
+// the offline config setting
+func offlineMode() bool {
+	return offline || cfg.Offline
+}
 
-	// Try to initialize DynamoDB storage, but don't fail if it's not configured
-	dynamoStore, err = storage.NewDynamoDBStorage(cfg.TableName, cfg.UserID)
+// initRemoteStore sets remoteStore to the configured backend. remoteStore is
+// only assigned on success so it stays a nil interface otherwise.
+func initRemoteStore() error {
+	if offlineMode() && (cfg.RemoteBackend == config.BackendDynamoDB || cfg.RemoteBackend == "") {
+		return nil
+	}
+	rs, err := newRemoteStore(cfg.UserID)
 	if err != nil {
-		// Don't fail if DynamoDB isn't configured, just log
-		fmt.Fprintf(os.Stderr, "Warning: DynamoDB not available: %v\n", err)
-		dynamoStore = nil
+		return err
 	}
+	remoteStore = rs
+	return nil
+}
 
-	return rootCmd.Execute()
+// newRemoteStore opens the configured backend for this vault of userID.
+// DynamoDB keeps named vaults under the user's partition key; the filesystem
+// backend under cfg.RemoteUserIDFor.
+func newRemoteStore(userID string) (storage.RemoteStore, error) {
+	switch cfg.RemoteBackend {
+	case config.BackendDynamoDB, "":
+		dynamoStore, err := storage.NewDynamoDBStorage(cfg.TableName, userID, cfg.Profile, cfg.GetDynamoDBEndpoint())
+		if err != nil {
+			return nil, fmt.Errorf("DynamoDB not available: %w", err)
+		}
+		dynamoStore.SetRetryPolicy(dynamoRetryPolicy())
+		if timeout, ok, err := cfg.GetDynamoDBTimeout(); err != nil {
+			return nil, err
+		} else if ok {
+			dynamoStore.SetTimeout(timeout)
+		}
+		return dynamoStore, nil
+	case config.BackendFilesystem:
+		fsStore, err := storage.NewFilesystemStorage(cfg.RemoteDir, cfg.RemoteUserIDFor(userID))
+		if err != nil {
+			return nil, fmt.Errorf("filesystem remote not available: %w", err)
+		}
+		return fsStore, nil
+	default:
+		return nil, fmt.Errorf("unknown remote backend: %s", cfg.RemoteBackend)
+	}
 }
 
-func init() {
-	// Flags will be set after config is loaded in Execute()
+// dynamoRetryPolicy returns the default retry policy with any overrides from config
+func dynamoRetryPolicy() storage.RetryPolicy {
+	policy := storage.DefaultRetryPolicy()
+	rc := cfg.DynamoDBRetry
+	if rc == nil {
+		return policy
+	}
+	if rc.MaxAttempts > 0 {
+		policy.MaxAttempts = rc.MaxAttempts
+	}
+	if rc.BaseDelayMS > 0 {
+		policy.BaseDelay = time.Duration(rc.BaseDelayMS) * time.Millisecond
+	}
+	if rc.MaxDelayMS > 0 {
+		policy.MaxDelay = time.Duration(rc.MaxDelayMS) * time.Millisecond
+	}
+	if rc.Jitter != nil && *rc.Jitter >= 0 && *rc.Jitter <= 1 {
+		policy.Jitter = *rc.Jitter
+	}
+	return policy
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&vaultName, "vault", "", "Name of the vault to use (default: $"+config.ProfileEnvVar+", or the default vault)")
+	rootCmd.PersistentFlags().StringVar(&vaultPath, "vault-path", "", "Path of the vault file, overriding vault_path in config")
+	rootCmd.PersistentFlags().BoolVar(&noMlock, "no-mlock", false, "Don't lock keys in memory (for systems with a small RLIMIT_MEMLOCK)")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "Never write the vault, a session or remote storage")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Never contact AWS; sync only with a filesystem remote, if configured")
+	rootCmd.PersistentFlags().BoolVar(&strictPerms, "strict-perms", false, "Refuse to read vault and backup files other users can access")
+	rootCmd.PersistentFlags().BoolVar(&useUTC, "utc", false, "Show times in UTC instead of local time")
+	rootCmd.PersistentFlags().StringVar(&keyFilePath, "key-file", "", "Key file also needed to unlock the vault, overriding key_file in config")
+}
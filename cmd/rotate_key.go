@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Replace the vault key",
+	Long: `Generate a new vault key, re-encrypt the vault with it and wrap it with the
+current master key. Unlike rotate-master, this makes the old vault key
+useless, so use it if the vault key or a session may have been exposed.
+
+Keys of attachments stored in S3 are re-wrapped with the new key; the
+objects themselves are not re-uploaded. Backups made before the rotation
+still open with the master password they were made with. Other devices
+pick up the new key on their next sync and must unlock again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !localStore.Exists() {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+
+		ev, err := loadLocalVault(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if err := waitForUnlockAttempt(ctx); err != nil {
+			return err
+		}
+
+		password, err := prompt.ReadPassword("Enter master password: ")
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		lockSecret(password)
+
+		keyFile, err := keyFileFor(ev)
+		if err != nil {
+			releaseSecret(password)
+			return err
+		}
+
+		// The master key is kept to wrap the new vault key
+		masterKey, err := ev.DeriveMasterKey(password, keyFile)
+		releaseSecret(password)
+		releaseSecret(keyFile)
+		if err != nil {
+			return err
+		}
+		lockSecret(masterKey)
+		defer releaseSecret(masterKey)
+
+		oldKey, err := ev.UnwrapVaultKey(masterKey)
+		if err != nil {
+			return unlockFailed(err)
+		}
+		lockSecret(oldKey)
+		unlockSucceeded()
+		defer releaseSecret(oldKey)
+
+		v, err := storage.OpenVault(ev, oldKey)
+		if err != nil {
+			return err
+		}
+		// The new key can't open the usage log, so add its uses now
+		uses, err := localStore.LoadUsageLog(oldKey)
+		if err != nil {
+			return err
+		}
+		v.ApplyUsage(uses)
+
+		newKey, err := crypto.GenerateVaultKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate vault key: %w", err)
+		}
+		lockSecret(newKey)
+		defer releaseSecret(newKey)
+
+		if err := rewrapAttachmentKeys(v, oldKey, newKey); err != nil {
+			return err
+		}
+
+		encVaultKey, vaultKeyNonce, err := crypto.EncryptVaultKey(newKey, masterKey, ev.Cipher)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt vault key: %w", err)
+		}
+		ev.EncVaultKey = crypto.EncodeBase64(encVaultKey)
+		ev.VaultKeyNonce = crypto.EncodeBase64(vaultKeyNonce)
+
+		if err := backupBeforeWrite("rotate-key"); err != nil {
+			return err
+		}
+
+		// Seals the payload with the new key, bumps the version and signs
+		if err := localStore.EncryptAndSave(v, newKey, ev); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		if err := localStore.ClearUsageLog(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		// Re-encrypt the sync base too, so a later merge can still open it
+		if err := rekeySyncBase(oldKey, newKey, ev); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		if remoteStore != nil {
+			if err := syncOrQueue(ctx, ev); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save to remote storage, change queued for next sync: %v\n", err)
+			}
+		}
+
+		// A session holding the old key can no longer open the vault
+		if sessionMgr != nil {
+			if err := sessionMgr.SaveSession(ctx, newKey); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update session: %v\n", err)
+			}
+		}
+
+		fmt.Printf("Vault key rotated successfully (version %d)\n", ev.Version)
+		return nil
+	},
+}
+
+// rewrapAttachmentKeys re-wraps the keys of every S3 attachment, including
+// those of entries in the trash
+func rewrapAttachmentKeys(v *vault.Vault, oldKey, newKey []byte) error {
+	for _, entries := range [][]vault.Entry{v.Entries, v.DeletedEntries} {
+		for i := range entries {
+			for j := range entries[i].Attachments {
+				a := &entries[i].Attachments[j]
+				if err := storage.RewrapAttachmentKey(a, oldKey, newKey); err != nil {
+					return fmt.Errorf("failed to rotate key of attachment '%s' on '%s': %w", a.Name, entries[i].Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rekeySyncBase re-encrypts the vault recorded at the last sync with the new
+// vault key, wrapped as in ev
+func rekeySyncBase(oldKey, newKey []byte, ev *storage.EncryptedVault) error {
+	base, err := localStore.LoadSyncBase()
+	if err != nil || base == nil {
+		return err
+	}
+
+	v, err := storage.OpenVault(base, oldKey)
+	if err != nil {
+		return fmt.Errorf("failed to open sync base: %w", err)
+	}
+	if err := rewrapAttachmentKeys(v, oldKey, newKey); err != nil {
+		return err
+	}
+	base.SaltMaster = ev.SaltMaster
+	base.KDFParams = ev.KDFParams
+	base.EncVaultKey = ev.EncVaultKey
+	base.VaultKeyNonce = ev.VaultKeyNonce
+	if err := base.SealVault(v, newKey); err != nil {
+		return fmt.Errorf("failed to encrypt sync base: %w", err)
+	}
+	if err := base.Sign(newKey); err != nil {
+		return fmt.Errorf("failed to sign sync base: %w", err)
+	}
+	return localStore.SaveSyncBase(base)
+}
+
+func init() {
+	rootCmd.AddCommand(rotateKeyCmd)
+}
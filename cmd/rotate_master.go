@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vaultctl/vaultctl/internal/crypto"
-	"golang.org/x/term"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/storage"
 )
 
 var rotateMasterCmd = &cobra.Command{
@@ -18,80 +18,56 @@ var rotateMasterCmd = &cobra.Command{
 	Long:  `Change the master password by re-encrypting the vault key with a new master key.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !localStore.Exists() {
-			return fmt.Errorf("vault not found. Run 'vaultctl init' first")
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
 		}
 
 		// Load encrypted vault
-		ev, err := localStore.LoadEncryptedVault()
+		ev, err := loadLocalVault(cmd)
 		if err != nil {
 			return fmt.Errorf("failed to load vault: %w", err)
 		}
 
-		// Prompt for current master password
-		fmt.Print("Enter current master password: ")
-		currentPassword, err := term.ReadPassword(int(syscall.Stdin))
+		// The key file stays the same, only the password changes
+		keyFile, err := readKeyFile()
 		if err != nil {
-			return fmt.Errorf("failed to read password: %w", err)
+			return err
 		}
-		fmt.Println()
+		defer releaseSecret(keyFile)
 
-		// Decrypt vault key with current password
-		salt, err := crypto.DecodeBase64(ev.SaltMaster)
-		if err != nil {
-			return fmt.Errorf("failed to decode salt: %w", err)
-		}
-
-		encVaultKey, err := crypto.DecodeBase64(ev.EncVaultKey)
-		if err != nil {
-			return fmt.Errorf("failed to decode encrypted vault key: %w", err)
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
 		}
-
-		kdfParams := crypto.KDFParams{
-			Algo:       ev.KDFParams.Algo,
-			Memory:     ev.KDFParams.Memory,
-			Iterations: ev.KDFParams.Iterations,
-			Parallelism: ev.KDFParams.Parallelism,
+		if err := waitForUnlockAttempt(ctx); err != nil {
+			return err
 		}
-		currentMasterKey := crypto.DeriveMasterKey(currentPassword, salt, kdfParams)
 
-		var vaultKeyNonce []byte
-		if ev.VaultKeyNonce != "" {
-			vaultKeyNonce, err = crypto.DecodeBase64(ev.VaultKeyNonce)
-			if err != nil {
-				return fmt.Errorf("failed to decode vault key nonce: %w", err)
-			}
-		} else {
-			// Backward compatibility
-			vaultKeyNonce, err = crypto.DecodeBase64(ev.Nonce)
-			if err != nil {
-				return fmt.Errorf("failed to decode nonce: %w", err)
-			}
+		// Prompt for current master password
+		currentPassword, err := prompt.ReadPassword("Enter current master password: ")
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
 		}
+		lockSecret(currentPassword)
 
-		vaultKey, err := crypto.DecryptVaultKey(encVaultKey, vaultKeyNonce, currentMasterKey)
+		// Decrypt vault key with current password
+		vaultKey, err := storage.UnlockVaultKey(ev, currentPassword, keyFile)
+		releaseSecret(currentPassword)
 		if err != nil {
-			crypto.Zeroize(currentPassword)
-			return fmt.Errorf("failed to decrypt vault key: %w", err)
+			return unlockFailed(err)
 		}
-		
-		// Zeroize current password and master key after use
-		crypto.Zeroize(currentPassword)
-		crypto.Zeroize(currentMasterKey)
+		lockSecret(vaultKey)
+		unlockSucceeded()
 
 		// Prompt for new master password
-		fmt.Print("Enter new master password: ")
-		newPassword1, err := term.ReadPassword(int(syscall.Stdin))
+		newPassword1, err := prompt.ReadPassword("Enter new master password: ")
 		if err != nil {
 			return fmt.Errorf("failed to read password: %w", err)
 		}
-		fmt.Println()
 
-		fmt.Print("Confirm new master password: ")
-		newPassword2, err := term.ReadPassword(int(syscall.Stdin))
+		newPassword2, err := prompt.ReadPassword("Confirm new master password: ")
 		if err != nil {
 			return fmt.Errorf("failed to read password: %w", err)
 		}
-		fmt.Println()
 
 		if !crypto.ConstantTimeCompare(newPassword1, newPassword2) {
 			crypto.Zeroize(newPassword1)
@@ -105,11 +81,25 @@ var rotateMasterCmd = &cobra.Command{
 			return fmt.Errorf("failed to generate salt: %w", err)
 		}
 
-		// Derive new master key
-		newMasterKey := crypto.DeriveMasterKey(newPassword1, newSalt, kdfParams)
+		// Derive new master key with the vault's KDF parameters
+		kdfParams := crypto.KDFParams{
+			Algo:        ev.KDFParams.Algo,
+			Memory:      ev.KDFParams.Memory,
+			Iterations:  ev.KDFParams.Iterations,
+			Parallelism: ev.KDFParams.Parallelism,
+		}
+		newMasterKey, err := crypto.DeriveMasterKey(newPassword1, newSalt, kdfParams)
+		if err != nil {
+			return fmt.Errorf("failed to derive master key: %w", err)
+		}
+		newMasterKey, err = ev.WithKeyFile(newMasterKey, keyFile)
+		if err != nil {
+			return err
+		}
+		lockSecret(newMasterKey)
 
 		// Re-encrypt vault key with new master key
-		newEncVaultKey, newNonceVK, err := crypto.EncryptVaultKey(vaultKey, newMasterKey)
+		newEncVaultKey, newNonceVK, err := crypto.EncryptVaultKey(vaultKey, newMasterKey, ev.Cipher)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt vault key: %w", err)
 		}
@@ -120,28 +110,31 @@ var rotateMasterCmd = &cobra.Command{
 		ev.VaultKeyNonce = crypto.EncodeBase64(newNonceVK)
 		ev.SetModifiedAt(time.Now())
 		ev.Version++
+		if err := ev.Sign(vaultKey); err != nil {
+			return fmt.Errorf("failed to sign vault: %w", err)
+		}
+
+		if err := backupBeforeWrite("rotate-master"); err != nil {
+			return err
+		}
 
 		// Save locally
 		if err := localStore.SaveEncryptedVault(ev); err != nil {
 			return fmt.Errorf("failed to save vault: %w", err)
 		}
 
-		// Save to DynamoDB if available
-		if dynamoStore != nil {
-			ctx := cmd.Context()
-			if ctx == nil {
-				ctx = context.Background()
-			}
-			if err := dynamoStore.SaveVault(ctx, ev, ev.Version-1); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save to DynamoDB: %v\n", err)
+		// Save to remote storage if available
+		if remoteStore != nil {
+			if err := syncOrQueue(ctx, ev); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save to remote storage, change queued for next sync: %v\n", err)
 			}
 		}
 
 		// Zeroize all passwords and keys from memory
 		crypto.Zeroize(newPassword1)
 		crypto.Zeroize(newPassword2)
-		crypto.Zeroize(newMasterKey)
-		crypto.Zeroize(vaultKey)
+		releaseSecret(newMasterKey)
+		releaseSecret(vaultKey)
 
 		fmt.Println("Master password rotated successfully")
 		return nil
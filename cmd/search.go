@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var (
+	searchRegex   bool
+	searchFields  []string
+	searchShowIDs bool
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search password entries",
+	Long: `Search entry names, usernames, URLs, notes and tags for a query.
+Matching is a case-insensitive substring unless --regex is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entries, err := unlockedVault.Search(args[0], vault.SearchOptions{
+			Regex:  searchRegex,
+			Fields: searchFields,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No matching entries found")
+			return nil
+		}
+
+		var shortIDs map[string]string
+		if searchShowIDs {
+			shortIDs = unlockedVault.ShortIDs()
+		}
+		printEntrySummaries(entries, shortIDs)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().BoolVar(&searchRegex, "regex", false, "Treat the query as a Go regular expression")
+	searchCmd.Flags().StringSliceVar(&searchFields, "field", nil, "Restrict search to these fields (name, username, url, notes, tags)")
+	searchCmd.Flags().BoolVar(&searchShowIDs, "show-ids", false, "Show each entry's short ID")
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var statsLimit int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the most and least used entries",
+	Long: `Show which entries are used most and which have gone unused longest, to
+pick favorites and spot dormant accounts worth closing.
+
+A use is counted whenever get shows or copies a password or field, or exec
+passes one to a command. Uses are logged next to the vault and added to it
+with the next change or sync, so counts sync between devices without reads
+saving the vault. Set "track_usage": false in the config to stop recording them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statsLimit < 1 {
+			return fmt.Errorf("--limit must be at least 1")
+		}
+		if err := ensureUnlockedMetadata(cmd); err != nil {
+			return err
+		}
+		if !cfg.UsageTracking() {
+			fmt.Fprintf(os.Stderr, "Note: usage tracking is off (track_usage in %s); counts are from before it was turned off\n", cfg.ConfigPath)
+		}
+
+		// Uses logged since the vault was last saved aren't in it yet
+		if vaultKey != nil {
+			uses, err := localStore.LoadUsageLog(vaultKey)
+			if err != nil {
+				return err
+			}
+			unlockedVault.ApplyUsage(uses)
+		}
+
+		entries := unlockedVault.ListEntries()
+		if len(entries) == 0 {
+			fmt.Println("No entries found")
+			return nil
+		}
+
+		var used []vault.EntrySummary
+		for _, entry := range entries {
+			if entry.UseCount > 0 {
+				used = append(used, entry)
+			}
+		}
+		sort.SliceStable(used, func(i, j int) bool {
+			if used[i].UseCount != used[j].UseCount {
+				return used[i].UseCount > used[j].UseCount
+			}
+			// Vaults written before last use times were kept may lack them
+			a, b := used[i].LastUsedAt, used[j].LastUsedAt
+			if a == nil || b == nil {
+				return b == nil && a != nil
+			}
+			return a.After(*b)
+		})
+
+		// Never used entries first, then the longest unused, oldest first
+		dormant := append([]vault.EntrySummary(nil), entries...)
+		sort.SliceStable(dormant, func(i, j int) bool {
+			a, b := dormant[i].LastUsedAt, dormant[j].LastUsedAt
+			if a == nil || b == nil {
+				if a == nil && b == nil {
+					return dormant[i].CreatedAt.Before(dormant[j].CreatedAt)
+				}
+				return a == nil
+			}
+			return a.Before(*b)
+		})
+
+		fmt.Println("Most used:")
+		if len(used) == 0 {
+			fmt.Println("  No uses recorded yet")
+		} else {
+			printUsage(used[:min(statsLimit, len(used))])
+		}
+		fmt.Println()
+		fmt.Println("Least recently used:")
+		printUsage(dormant[:min(statsLimit, len(dormant))])
+		return nil
+	},
+}
+
+// printUsage prints entries with their use count and last use
+func printUsage(entries []vault.EntrySummary) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tUSERNAME\tUSES\tLAST USED")
+	for _, entry := range entries {
+		lastUsed := "never"
+		if entry.LastUsedAt != nil {
+			lastUsed = formatTime(*entry.LastUsedAt)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%s\n", entry.Name, entry.Username, entry.UseCount, lastUsed)
+	}
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().IntVar(&statsLimit, "limit", 10, "How many entries to show in each list")
+}
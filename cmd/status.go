@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/config"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/storage"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show vault, session and sync status",
+	Long: `Show where the vault is stored, whether a session is active, the local
+version and when it was last modified and, if remote storage is configured,
+the remote version and whether the two are in sync, and with DynamoDB the
+vaults stored for the user. The vault's KDF parameters are shown too, with a
+warning if they are below the recommended minimum.
+
+status never prompts for the master password and changes nothing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !localStore.Exists() {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		fmt.Printf("Vault:          %s\n", cfg.VaultPath)
+		if cfg.Profile != "" {
+			fmt.Printf("Profile:        %s\n", cfg.Profile)
+		}
+
+		sessionState := "none (locked)"
+		if sessionMgr != nil && sessionMgr.HasActiveSession(ctx) {
+			sessionState = "active (unlocked)"
+		}
+		fmt.Printf("Session:        %s\n", sessionState)
+
+		localEV, err := localStore.LoadEncryptedVault()
+		if err != nil {
+			return fmt.Errorf("failed to load local vault: %w", err)
+		}
+		fmt.Printf("Local version:  %d (modified %s)\n", localEV.Version, formatModifiedAt(localEV))
+		fmt.Printf("Format:         %s\n", localEV.Format())
+
+		kdfParams := crypto.KDFParams{
+			Algo:        localEV.KDFParams.Algo,
+			Memory:      localEV.KDFParams.Memory,
+			Iterations:  localEV.KDFParams.Iterations,
+			Parallelism: localEV.KDFParams.Parallelism,
+		}
+		fmt.Printf("KDF:            %s, %d MiB, %d iterations, parallelism %d\n",
+			kdfParams.Algo, kdfParams.Memory/1024, kdfParams.Iterations, kdfParams.Parallelism)
+		if localEV.RequiresKeyFile() {
+			fmt.Println("Key file:       required")
+		}
+		if !kdfParams.MeetsRecommended() {
+			fmt.Fprintf(os.Stderr, "Warning: the KDF parameters are below the recommended %d MiB and %d iterations. Run 'vaultctl rekdf' to upgrade them\n",
+				crypto.RecommendedMemory/1024, crypto.RecommendedIterations)
+		}
+
+		if remoteStore == nil && offlineMode() {
+			fmt.Println("Remote:         disabled (offline)")
+			return nil
+		}
+		if remoteStore == nil {
+			fmt.Println("Remote:         not configured")
+			return nil
+		}
+		fmt.Printf("Remote:         %s\n", describeRemote())
+
+		remoteEV, err := remoteStore.LoadVault(ctx)
+		if errors.Is(err, storage.ErrRemoteVaultNotFound) {
+			fmt.Println("Remote version: none (run 'vaultctl sync' to upload the vault)")
+			return nil
+		}
+		if err != nil {
+			fmt.Printf("Remote version: unavailable (%v)\n", err)
+			return nil
+		}
+		fmt.Printf("Remote version: %d (modified %s)\n", remoteEV.Version, formatModifiedAt(remoteEV))
+
+		state, err := describeSyncState(localEV, remoteEV)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Sync:           %s\n", state)
+
+		if dynamoStore, ok := remoteStore.(*storage.DynamoDBStorage); ok {
+			vaults, err := dynamoStore.ListRemoteVaults(ctx)
+			if err != nil {
+				fmt.Printf("Remote vaults:  unavailable (%v)\n", err)
+				return nil
+			}
+			names := make([]string, len(vaults))
+			for i, rv := range vaults {
+				names[i] = rv.Name
+				if rv.Name == "" {
+					names[i] = "(default)"
+				}
+			}
+			fmt.Printf("Remote vaults:  %s\n", strings.Join(names, ", "))
+		}
+		return nil
+	},
+}
+
+// describeRemote names the configured remote backend and where it stores the vault
+func describeRemote() string {
+	if cfg.RemoteBackend == config.BackendFilesystem {
+		return fmt.Sprintf("filesystem (%s)", cfg.RemoteDir)
+	}
+	return fmt.Sprintf("DynamoDB (table %s)", cfg.TableName)
+}
+
+// describeSyncState compares the local and remote vaults using the same rules
+// as sync
+func describeSyncState(localEV, remoteEV *storage.EncryptedVault) (string, error) {
+	pending, err := localStore.LoadPendingWrites()
+	if err != nil {
+		return "", err
+	}
+	base, err := localStore.LoadSyncBase()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case localEV.Ciphertext == remoteEV.Ciphertext:
+		return "in sync", nil
+	case len(pending) > 0:
+		return fmt.Sprintf("%d local changes queued, run 'vaultctl sync' to push them", len(pending)), nil
+	case base != nil && base.Ciphertext == remoteEV.Ciphertext:
+		return "local changes not yet pushed, run 'vaultctl sync'", nil
+	case base != nil && base.Ciphertext == localEV.Ciphertext:
+		return "remote changes not yet pulled, run 'vaultctl sync'", nil
+	case localEV.Version == remoteEV.Version:
+		return "versions match but contents differ, run 'vaultctl sync' to merge", nil
+	default:
+		return "both sides changed since the last sync, run 'vaultctl sync' to merge", nil
+	}
+}
+
+// formatModifiedAt formats a vault's modification time with formatTime
+func formatModifiedAt(ev *storage.EncryptedVault) string {
+	t, err := ev.GetModifiedAtTime()
+	if err != nil {
+		return ev.ModifiedAt
+	}
+	return formatTime(t)
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
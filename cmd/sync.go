@@ -1,19 +1,59 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"github.com/vaultctl/vaultctl/internal/vaultctl"
+	"golang.org/x/term"
+)
+
+// Conflict resolution strategies for sync
+const (
+	resolveAsk    = "ask"
+	resolveNewer  = "newer"
+	resolveLocal  = "local"
+	resolveRemote = "remote"
+)
+
+var (
+	syncResolve string
+	syncDryRun  bool
 )
 
 var syncCmd = &cobra.Command{
 	Use:   "sync",
-	Short: "Sync vault with DynamoDB",
-	Long:  `Sync the local vault with the remote vault in DynamoDB.`,
+	Short: "Sync vault with remote storage",
+	Long: `Sync the local vault with the remote vault (DynamoDB by default).
+If both sides changed since the last sync, the vaults are merged entry by
+entry. Entries edited on both sides are conflicts; by default you are asked
+which side to keep for each one (see --resolve).
+
+Changes that failed to reach remote storage earlier are queued locally and
+replayed first.
+
+With --dry-run nothing is written: sync reports the local and remote
+versions and what it would do. If a session is active it also lists the
+entries that would be added, updated or removed on each side.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if dynamoStore == nil {
-			return fmt.Errorf("DynamoDB not configured")
+		if remoteStore == nil {
+			if offlineMode() {
+				return fmt.Errorf("remote storage is disabled in offline mode")
+			}
+			return fmt.Errorf("remote storage not configured")
+		}
+		switch syncResolve {
+		case resolveAsk, resolveNewer, resolveLocal, resolveRemote:
+		default:
+			return fmt.Errorf("invalid --resolve value: %s (use ask, newer, local or remote)", syncResolve)
 		}
 
 		ctx := cmd.Context()
@@ -21,37 +61,230 @@ var syncCmd = &cobra.Command{
 			ctx = context.Background()
 		}
 
-		// Load local encrypted vault
-		localEV, err := localStore.LoadEncryptedVault()
-		if err != nil {
+		if syncDryRun {
+			return runSyncDryRun(ctx, cmd)
+		}
+
+		// Merging needs the vault key
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		// Offer to repair a corrupt local file before syncing it
+		if _, err := loadLocalVault(cmd); err != nil {
 			return fmt.Errorf("failed to load local vault: %w", err)
 		}
 
-		// Sync with remote
-		syncedEV, err := dynamoStore.SyncVault(ctx, localEV)
+		lib := library()
+		reader := bufio.NewReader(os.Stdin)
+		result, err := lib.Sync(ctx, func(c vault.Conflict) (vaultctl.Resolution, error) {
+			return resolveConflict(reader, c)
+		})
 		if err != nil {
-			return fmt.Errorf("failed to sync vault: %w", err)
-		}
-
-		// If remote was newer, we need to reload the vault
-		if syncedEV.Version > localEV.Version {
-			fmt.Println("Remote vault is newer. Please unlock to reload.")
-			// Reset unlocked state to force re-unlock
-			unlockedVault = nil
-			vaultKey = nil
-		} else {
-			// Save synced vault locally
-			if err := localStore.SaveEncryptedVault(syncedEV); err != nil {
-				return fmt.Errorf("failed to save synced vault: %w", err)
-			}
-			fmt.Printf("Vault synced successfully (version %d)\n", syncedEV.Version)
+			return err
 		}
+		unlockedVault = lib.Data()
 
+		switch result.Action {
+		case vaultctl.SyncReplayed:
+			fmt.Printf("Replayed %d queued changes (version %d)\n", result.Replayed, result.Version)
+		case vaultctl.SyncUpToDate:
+			fmt.Printf("Vault already up to date (version %d)\n", result.Version)
+		case vaultctl.SyncPushed:
+			fmt.Printf("Vault synced successfully (version %d)\n", result.Version)
+		case vaultctl.SyncPulled:
+			fmt.Printf("Pulled remote changes (version %d)\n", result.Version)
+			if result.Rekeyed {
+				// The old key is wiped and no longer opens the vault
+				vaultKey = nil
+				if sessionMgr != nil {
+					sessionMgr.ClearSession()
+				}
+				if !term.IsTerminal(int(syscall.Stdin)) {
+					fmt.Println("The vault key was rotated on another device. Run 'vaultctl unlock' to continue")
+					return nil
+				}
+				fmt.Println("The vault key was rotated on another device. Unlock to reload it")
+				return ensureUnlocked(cmd)
+			}
+		case vaultctl.SyncMerged:
+			if result.Replayed > 0 {
+				fmt.Printf("Remote changed since %d queued changes were made, so they were merged\n", result.Replayed)
+			}
+			fmt.Printf("Merged local and remote changes (%d conflicts, version %d)\n", len(result.Conflicts), result.Version)
+		}
 		return nil
 	},
 }
 
+// Actions sync can take once the queue has been considered
+const (
+	syncActionPush  = "push"
+	syncActionPull  = "pull"
+	syncActionMerge = "merge"
+)
+
+// runSyncDryRun reports what sync would do without writing anything. Entry
+// changes are only listed if the vault is unlocked or a session is active,
+// so a dry run never prompts for the master password.
+func runSyncDryRun(ctx context.Context, cmd *cobra.Command) error {
+	localEV, err := localStore.LoadEncryptedVault()
+	if err != nil {
+		return fmt.Errorf("failed to load local vault: %w", err)
+	}
+
+	remoteEV, err := remoteStore.LoadVault(ctx)
+	if err != nil {
+		if !errors.Is(err, storage.ErrRemoteVaultNotFound) {
+			return fmt.Errorf("failed to load remote vault: %w", err)
+		}
+		fmt.Printf("No remote vault; sync would push the local vault (version %d)\n", localEV.Version)
+		return nil
+	}
+	fmt.Printf("Local version %d, remote version %d\n", localEV.Version, remoteEV.Version)
+
+	pending, err := localStore.LoadPendingWrites()
+	if err != nil {
+		return err
+	}
+	base, err := localStore.LoadSyncBase()
+	if err != nil {
+		return err
+	}
+
+	// Follow the same decisions as a real sync
+	var action string
+	switch {
+	case len(pending) > 0 && pending[0].ExpectedVersion == remoteEV.Version:
+		action = syncActionPush
+		fmt.Printf("Sync would replay %d queued changes to remote\n", len(pending))
+	case localEV.Ciphertext == remoteEV.Ciphertext:
+		fmt.Println("Vault already up to date; sync would change nothing")
+		return nil
+	case base != nil && base.Ciphertext == remoteEV.Ciphertext:
+		action = syncActionPush
+		fmt.Println("Only the local vault changed; sync would push it to remote")
+	case base != nil && base.Ciphertext == localEV.Ciphertext:
+		action = syncActionPull
+		fmt.Println("Only the remote vault changed; sync would pull it")
+	default:
+		action = syncActionMerge
+		fmt.Println("Both vaults changed; sync would merge them")
+	}
+
+	if unlockedVault == nil && (sessionMgr == nil || !sessionMgr.HasActiveSession(ctx)) {
+		fmt.Println("Unlock the vault to see which entries would change")
+		return nil
+	}
+	if err := ensureUnlocked(cmd); err != nil {
+		return err
+	}
+
+	remoteVault, err := storage.OpenVault(remoteEV, vaultKey)
+	if err != nil {
+		return fmt.Errorf("failed to open remote vault: %w", err)
+	}
+
+	switch action {
+	case syncActionPush:
+		printSyncChanges("Remote", vault.Diff(remoteVault, unlockedVault))
+	case syncActionPull:
+		printSyncChanges("Local", vault.Diff(unlockedVault, remoteVault))
+	case syncActionMerge:
+		var baseVault *vault.Vault
+		if base != nil {
+			baseVault, err = storage.OpenVault(base, vaultKey)
+			if err != nil {
+				return fmt.Errorf("failed to open sync base: %w", err)
+			}
+		}
+		merged, conflicts := vault.Merge(baseVault, unlockedVault, remoteVault)
+		for _, c := range conflicts {
+			switch syncResolve {
+			case resolveLocal:
+				merged.ResolveConflict(c, c.Local)
+			case resolveRemote:
+				merged.ResolveConflict(c, c.Remote)
+			}
+		}
+
+		printSyncChanges("Local", vault.Diff(unlockedVault, merged))
+		printSyncChanges("Remote", vault.Diff(remoteVault, merged))
+		if len(conflicts) > 0 {
+			fmt.Printf("%d entries changed on both sides:\n", len(conflicts))
+			for _, c := range conflicts {
+				fmt.Printf("  %s (local: %s, remote: %s)\n", c.Name, describeConflictSide(c.Local), describeConflictSide(c.Remote))
+			}
+			if syncResolve == resolveAsk {
+				fmt.Println("Sync would ask which side to keep; the newer side is shown above")
+			}
+		}
+	}
+	return nil
+}
+
+// printSyncChanges lists the entry changes a sync would make to one side
+func printSyncChanges(side string, c vault.Changes) {
+	if c.Empty() {
+		fmt.Printf("%s: no entry changes\n", side)
+		return
+	}
+	fmt.Printf("%s would change:\n", side)
+	for _, e := range c.Added {
+		fmt.Printf("  + %s (added)\n", e.Name)
+	}
+	for _, e := range c.Updated {
+		fmt.Printf("  ~ %s (updated)\n", e.Name)
+	}
+	for _, e := range c.Removed {
+		fmt.Printf("  - %s (removed)\n", e.Name)
+	}
+}
+
+// resolveConflict applies the --resolve strategy to a conflict, asking the
+// user when the strategy is "ask"
+func resolveConflict(reader *bufio.Reader, c vault.Conflict) (vaultctl.Resolution, error) {
+	switch syncResolve {
+	case resolveNewer:
+		return vaultctl.KeepNewer, nil
+	case resolveLocal:
+		return vaultctl.KeepLocal, nil
+	case resolveRemote:
+		return vaultctl.KeepRemote, nil
+	}
+
+	fmt.Printf("Conflict: '%s' changed on both sides\n", c.Name)
+	fmt.Printf("  local:  %s\n", describeConflictSide(c.Local))
+	fmt.Printf("  remote: %s\n", describeConflictSide(c.Remote))
+	fmt.Print("Keep [l]ocal, [r]emote or [n]ewer? (default newer): ")
+
+	response, err := reader.ReadString('\n')
+	if err != nil && response == "" {
+		return 0, fmt.Errorf("failed to read choice: %w", err)
+	}
+	switch strings.TrimSpace(strings.ToLower(response)) {
+	case "l", "local":
+		return vaultctl.KeepLocal, nil
+	case "r", "remote":
+		return vaultctl.KeepRemote, nil
+	case "", "n", "newer":
+		// Merge already kept the newer side
+		return vaultctl.KeepNewer, nil
+	default:
+		return 0, fmt.Errorf("invalid choice: %s", strings.TrimSpace(response))
+	}
+}
+
+// describeConflictSide summarizes one side of a conflict for the prompt
+func describeConflictSide(e *vault.Entry) string {
+	if e == nil {
+		return "removed"
+	}
+	return fmt.Sprintf("updated %s", formatTime(e.UpdatedAt))
+}
+
 func init() {
 	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would change without writing anything")
+	syncCmd.Flags().StringVar(&syncResolve, "resolve", resolveAsk, "How to resolve entries edited on both sides (ask, newer, local or remote)")
 }
-
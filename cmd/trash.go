@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage removed entries",
+	Long:  `View or permanently purge entries that were removed with 'vaultctl remove'.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List entries in the trash",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entries := unlockedVault.ListDeletedEntries()
+		if len(entries) == 0 {
+			fmt.Println("Trash is empty")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tUSERNAME\tDELETED")
+		for _, entry := range entries {
+			deleted := ""
+			if entry.DeletedAt != nil {
+				deleted = formatTime(*entry.DeletedAt)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				entry.ID,
+				entry.Name,
+				entry.Username,
+				deleted)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete all entries in the trash",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		n := unlockedVault.EmptyTrash()
+		if n == 0 {
+			fmt.Println("Trash is already empty")
+			return nil
+		}
+
+		// Save vault
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		fmt.Printf("Permanently deleted %d entries\n", n)
+		return nil
+	},
+}
+
+var restoreEntryCmd = &cobra.Command{
+	Use:   "restore-entry <name_or_id>",
+	Short: "Restore a removed entry from the trash",
+	Long:  `Move an entry from the trash back into the vault by name or ID.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		entry, err := unlockedVault.RestoreEntry(args[0])
+		if errors.Is(err, vault.ErrNameTaken) {
+			return fmt.Errorf("%w. Rename it before restoring", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Save vault
+		sync := !cmd.Flags().Changed("no-sync")
+		if err := saveVault(cmd, sync); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		fmt.Printf("Entry '%s' restored successfully\n", entry.Name)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	trashEmptyCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+
+	rootCmd.AddCommand(restoreEntryCmd)
+	restoreEntryCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/clipboard"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/session"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"golang.org/x/term"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse entries interactively",
+	Long: `Open an interactive view of the vault. Type to search, use the arrow keys
+to select an entry and Enter to open it. In an entry, r reveals the password
+and secret fields, c copies the password, and u, p, l or n edit the username,
+password, URL or notes. Esc goes back and Ctrl+C quits.
+
+The vault is locked if no key is pressed for the session timeout.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return fmt.Errorf("tui requires an interactive terminal")
+		}
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		timeout, err := cfg.GetSessionTimeout()
+		if err != nil {
+			return err
+		}
+		if timeout == 0 {
+			timeout = session.DefaultSessionTimeout
+		}
+
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+		}
+		// Use the alternate screen so nothing is left in scrollback
+		fmt.Print("\x1b[?1049h\x1b[?25l")
+
+		ui := &tui{
+			cmd:     cmd,
+			fd:      fd,
+			keys:    readKeys(os.Stdin),
+			timeout: timeout,
+			idle:    time.NewTimer(timeout),
+		}
+		err = ui.run()
+
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		term.Restore(fd, oldState)
+		ui.idle.Stop()
+
+		// Don't leave a copied password behind
+		if ui.clipboardTimer != nil && ui.clipboardTimer.Stop() {
+			clipboard.Clear()
+		}
+
+		if errors.Is(err, errIdleLock) {
+			fmt.Printf("No activity for %s\n", timeout)
+			return lockCmd.RunE(cmd, nil)
+		}
+		return err
+	},
+}
+
+// errIdleLock ends the TUI when the session timeout passes without a key press
+var errIdleLock = errors.New("idle timeout")
+
+// Keys the TUI distinguishes
+const (
+	keyRune = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyCtrlC
+)
+
+type tuiKey struct {
+	code int
+	r    rune // Set for keyRune
+}
+
+// tui holds the state of the interactive view
+type tui struct {
+	cmd            *cobra.Command
+	fd             int
+	keys           <-chan tuiKey
+	timeout        time.Duration
+	idle           *time.Timer
+	lastTouch      time.Time
+	query          string
+	entries        []vault.EntrySummary
+	cursor         int
+	offset         int
+	selected       *vault.Entry // Entry open in the detail view, nil in the list
+	revealed       bool
+	status         string
+	clipboardTimer *time.Timer
+}
+
+// run processes key presses until the user quits or the idle timeout passes
+func (t *tui) run() error {
+	t.filter()
+	for {
+		t.render("")
+		key, err := t.nextKey()
+		quit := false
+		if err == nil {
+			quit, err = t.handle(key)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if quit || err != nil {
+			return err
+		}
+	}
+}
+
+// nextKey waits for a key press and restarts the idle timer. It returns
+// errIdleLock if the timer expires first and io.EOF if input is closed.
+func (t *tui) nextKey() (tuiKey, error) {
+	select {
+	case <-t.idle.C:
+		return tuiKey{}, errIdleLock
+	case key, ok := <-t.keys:
+		if !ok {
+			return tuiKey{}, io.EOF
+		}
+		t.idle.Reset(t.timeout)
+		// Activity keeps the session alive; don't rewrite it on every key
+		if !readOnly && time.Since(t.lastTouch) > time.Minute {
+			sessionMgr.Touch()
+			t.lastTouch = time.Now()
+		}
+		return key, nil
+	}
+}
+
+// handle applies a key press. It returns true when the user quits.
+func (t *tui) handle(key tuiKey) (bool, error) {
+	t.status = ""
+	if key.code == keyCtrlC {
+		return true, nil
+	}
+
+	if t.selected == nil {
+		switch key.code {
+		case keyEsc:
+			if t.query == "" {
+				return true, nil
+			}
+			t.query = ""
+			t.filter()
+		case keyUp:
+			if t.cursor > 0 {
+				t.cursor--
+			}
+		case keyDown:
+			if t.cursor < len(t.entries)-1 {
+				t.cursor++
+			}
+		case keyEnter:
+			if len(t.entries) > 0 {
+				t.selected = unlockedVault.GetEntry(t.entries[t.cursor].ID)
+			}
+		case keyBackspace:
+			if t.query != "" {
+				_, size := utf8.DecodeLastRuneInString(t.query)
+				t.query = t.query[:len(t.query)-size]
+				t.filter()
+			}
+		case keyRune:
+			t.query += string(key.r)
+			t.filter()
+		}
+		return false, nil
+	}
+
+	switch {
+	case key.code == keyEsc || key.code == keyBackspace || (key.code == keyRune && key.r == 'q'):
+		t.selected = nil
+		t.revealed = false
+		t.filter()
+	case key.code != keyRune:
+	case key.r == 'r':
+		t.revealed = !t.revealed
+	case key.r == 'c':
+		t.copyPassword()
+	case key.r == 'u' || key.r == 'p' || key.r == 'l' || key.r == 'n':
+		return false, t.edit(key.r)
+	}
+	return false, nil
+}
+
+// filter refreshes the entry list from the search query
+func (t *tui) filter() {
+	if t.query == "" {
+		t.entries = unlockedVault.ListEntries()
+	} else {
+		// Substring searches can't fail
+		t.entries, _ = unlockedVault.Search(t.query, vault.SearchOptions{})
+	}
+	if t.cursor >= len(t.entries) {
+		t.cursor = max(len(t.entries)-1, 0)
+	}
+}
+
+// copyPassword copies the selected entry's password and clears the clipboard
+// after DefaultClipboardClearAfter
+func (t *tui) copyPassword() {
+	if !t.selected.Type.HasPassword() {
+		t.status = fmt.Sprintf("%s entries have no password to copy", t.selected.Type)
+		return
+	}
+	if err := clipboard.Copy(t.selected.Password); err != nil {
+		t.status = err.Error()
+		return
+	}
+	if t.clipboardTimer != nil {
+		t.clipboardTimer.Stop()
+	}
+	t.clipboardTimer = time.AfterFunc(DefaultClipboardClearAfter, func() { clipboard.Clear() })
+	t.status = fmt.Sprintf("Password copied; clipboard clears in %s", DefaultClipboardClearAfter)
+}
+
+// edit prompts for a new value of the field bound to key and saves the vault
+func (t *tui) edit(key rune) error {
+	if readOnly {
+		t.status = errReadOnly.Error()
+		return nil
+	}
+	labels := map[rune]string{'u': "username", 'p': "password", 'l': "URL", 'n': "notes"}
+	value, ok, err := t.prompt(fmt.Sprintf("New %s (empty to cancel): ", labels[key]), key != 'p')
+	if err != nil || !ok || len(value) == 0 {
+		return err
+	}
+	defer crypto.Zeroize(value)
+
+	id, text := t.selected.ID, string(value)
+	switch key {
+	case 'u':
+		unlockedVault.UpdateEntry(id, "", &text, nil, nil, nil, nil, nil)
+	case 'p':
+		unlockedVault.UpdateEntry(id, "", nil, value, nil, nil, nil, nil)
+	case 'l':
+		unlockedVault.UpdateEntry(id, "", nil, nil, &text, nil, nil, nil)
+	case 'n':
+		unlockedVault.UpdateEntry(id, "", nil, nil, nil, &text, nil, nil)
+	}
+
+	// The detail view shows a copy; refresh it
+	crypto.Zeroize(t.selected.Password)
+	t.selected = unlockedVault.GetEntry(id)
+
+	if err := saveVault(t.cmd, true); err != nil {
+		t.status = fmt.Sprintf("Failed to save: %v", err)
+		return nil
+	}
+	t.status = fmt.Sprintf("Updated %s", labels[key])
+	return nil
+}
+
+// prompt reads a line on the bottom of the screen. ok is false if the user
+// pressed Esc. Input is masked unless echo is set.
+func (t *tui) prompt(label string, echo bool) (value []byte, ok bool, err error) {
+	var buf []byte
+	for {
+		shown := string(buf)
+		if !echo {
+			shown = strings.Repeat("*", utf8.RuneCount(buf))
+		}
+		t.render(label + shown)
+
+		key, err := t.nextKey()
+		if err != nil {
+			crypto.Zeroize(buf)
+			return nil, false, err
+		}
+		switch key.code {
+		case keyEnter:
+			return buf, true, nil
+		case keyEsc, keyCtrlC:
+			crypto.Zeroize(buf)
+			return nil, false, nil
+		case keyBackspace:
+			if len(buf) > 0 {
+				_, size := utf8.DecodeLastRune(buf)
+				buf = buf[:len(buf)-size]
+			}
+		case keyRune:
+			buf = utf8.AppendRune(buf, key.r)
+		}
+	}
+}
+
+// render redraws the screen, with promptLine on the last line if set
+func (t *tui) render(promptLine string) {
+	_, height, err := term.GetSize(t.fd)
+	if err != nil || height < 8 {
+		height = 24
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	if t.selected == nil {
+		fmt.Fprintf(&b, "Search: %s\n\n", t.query)
+		rows := height - 5
+		if t.cursor < t.offset {
+			t.offset = t.cursor
+		}
+		if t.cursor >= t.offset+rows {
+			t.offset = t.cursor - rows + 1
+		}
+		if len(t.entries) == 0 {
+			b.WriteString("  No entries found\n")
+		}
+		for i := t.offset; i < len(t.entries) && i < t.offset+rows; i++ {
+			marker := "  "
+			if i == t.cursor {
+				marker = "> "
+			}
+			e := t.entries[i]
+			fmt.Fprintf(&b, "%s%-32s %-9s %s\n", marker, e.Name, e.Type, e.Username)
+		}
+		b.WriteString("\nType to search  Up/Down select  Enter open  Esc clear/quit  Ctrl+C quit\n")
+	} else {
+		t.renderEntry(&b)
+		b.WriteString("\nr reveal  c copy  u/p/l/n edit username/password/URL/notes  Esc back\n")
+	}
+
+	if t.status != "" {
+		b.WriteString(t.status + "\n")
+	}
+	if promptLine != "" {
+		b.WriteString(promptLine)
+	}
+
+	// Raw mode doesn't translate newlines
+	os.Stdout.WriteString(strings.ReplaceAll(b.String(), "\n", "\r\n"))
+}
+
+// renderEntry writes the selected entry, masking secrets unless revealed
+func (t *tui) renderEntry(b *strings.Builder) {
+	e := t.selected
+	mask := func(s string) string {
+		if t.revealed {
+			return s
+		}
+		return "********"
+	}
+
+	fmt.Fprintf(b, "Name: %s\n", e.Name)
+	fmt.Fprintf(b, "Type: %s\n", e.Type)
+	if e.Username != "" {
+		fmt.Fprintf(b, "Username: %s\n", e.Username)
+	}
+	if e.Type.HasPassword() {
+		fmt.Fprintf(b, "Password: %s\n", mask(string(e.Password)))
+	}
+	if e.URL != "" {
+		fmt.Fprintf(b, "URL: %s\n", e.URL)
+	}
+	if e.Notes != "" {
+		fmt.Fprintf(b, "Notes: %s\n", e.Notes)
+	}
+	for _, field := range e.Fields {
+		value := field.Value
+		if field.Secret {
+			value = mask(value)
+		}
+		fmt.Fprintf(b, "%s: %s\n", field.Name, value)
+	}
+	if len(e.Tags) > 0 {
+		fmt.Fprintf(b, "Tags: %s\n", strings.Join(e.Tags, ", "))
+	}
+	fmt.Fprintf(b, "Updated: %s\n", formatTime(e.UpdatedAt))
+}
+
+// readKeys decodes key presses from r until it fails
+func readKeys(r io.Reader) <-chan tuiKey {
+	keys := make(chan tuiKey)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, key := range decodeKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+	return keys
+}
+
+// decodeKeys splits one read from the terminal into key presses
+func decodeKeys(b []byte) []tuiKey {
+	var keys []tuiKey
+	for len(b) > 0 {
+		switch {
+		case bytes.HasPrefix(b, []byte("\x1b[A")):
+			keys = append(keys, tuiKey{code: keyUp})
+			b = b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[B")):
+			keys = append(keys, tuiKey{code: keyDown})
+			b = b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[")):
+			// Other escape sequences are ignored
+			return keys
+		case b[0] == 0x1b:
+			keys = append(keys, tuiKey{code: keyEsc})
+			b = b[1:]
+		case b[0] == 0x03 || b[0] == 0x04:
+			keys = append(keys, tuiKey{code: keyCtrlC})
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, tuiKey{code: keyEnter})
+			b = b[1:]
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, tuiKey{code: keyBackspace})
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= 0x20 && r != utf8.RuneError {
+				keys = append(keys, tuiKey{code: keyRune, r: r})
+			}
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -2,12 +2,19 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/config"
 	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/session"
+	"github.com/vaultctl/vaultctl/internal/storage"
 	"github.com/vaultctl/vaultctl/internal/vault"
 	"golang.org/x/term"
 )
@@ -15,79 +22,246 @@ import (
 var (
 	unlockedVault *vault.Vault
 	vaultKey      []byte
+
+	unlockRemoteSession bool
+	unlockTTL           time.Duration
 )
 
 var unlockCmd = &cobra.Command{
 	Use:   "unlock",
 	Short: "Unlock the vault",
-	Long:  `Unlock the vault by providing the master password.`,
+	Long: `Unlock the vault by providing the master password, and the key file for
+vaults created with 'init --key-file'.
+
+On shared CI runners, --remote-session also keeps the session in AWS Secrets
+Manager, in a secret named after $VAULTCTL_RUNNER_ID, so later jobs with the
+same runner ID can use the vault without the password until --ttl passes.
+'vaultctl lock' deletes it.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if unlockedVault != nil {
-			fmt.Println("Vault is already unlocked")
-			return nil
-		}
+		return runUnlock(cmd, cmd.OutOrStdout())
+	},
+}
 
-		if !localStore.Exists() {
-			return fmt.Errorf("vault not found. Run 'vaultctl init' first")
+// runUnlock prompts for the master password and unlocks the vault, writing
+// the prompt and messages to out
+func runUnlock(cmd *cobra.Command, out io.Writer) error {
+	if unlockedVault != nil {
+		fmt.Fprintln(out, "Vault is already unlocked")
+		return nil
+	}
+	if unlockRemoteSession {
+		if readOnly {
+			return fmt.Errorf("%w: --remote-session saves a session", errReadOnly)
+		}
+		if sessionMgr.RemoteSessionName() == "" {
+			return fmt.Errorf("--remote-session needs %s to be set to the runner's ID", config.RunnerIDEnvVar)
 		}
+	}
 
-		// Prompt for master password
-		fmt.Print("Enter master password: ")
-		password, err := term.ReadPassword(int(syscall.Stdin))
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// On a new device the vault only exists remotely; it is downloaded
+	// once the password opens it
+	var remoteOnly *storage.EncryptedVault
+	if !localStore.Exists() {
+		if remoteStore == nil {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+		ev, err := remoteStore.LoadVault(ctx)
+		if errors.Is(err, storage.ErrRemoteVaultNotFound) {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to read password: %w", err)
+			return fmt.Errorf("vault not found locally and failed to load from remote storage: %w", err)
 		}
-		fmt.Println()
+		remoteOnly = ev
+	} else if _, err := loadLocalVault(cmd); errors.Is(err, storage.ErrCorruptVault) && remoteStore == nil {
+		// Offer to repair a corrupted local file before asking for the
+		// password. Other errors are left to the remote fallback below.
+		return err
+	}
+
+	// Read before the prompt so a missing key file fails fast
+	keyFile, err := readKeyFile()
+	if err != nil {
+		return err
+	}
+	defer releaseSecret(keyFile)
+
+	if err := waitForUnlockAttempt(ctx); err != nil {
+		return err
+	}
+
+	// Prompt for master password
+	password, err := prompt.ReadPasswordTo(out, "Enter master password: ")
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	lockSecret(password)
 
-		// Try to load from local first
-		v, key, err := localStore.DecryptAndLoad(password)
+	var v *vault.Vault
+	var key []byte
+	if remoteOnly != nil {
+		v, key, err = storage.UnlockVault(remoteOnly, password, keyFile)
 		if err != nil {
-			// Try loading from DynamoDB if local fails
-			if dynamoStore != nil {
-				ctx := cmd.Context()
-				if ctx == nil {
-					ctx = context.Background()
-				}
-				ev, err2 := dynamoStore.LoadVault(ctx)
-				if err2 != nil {
-					return fmt.Errorf("failed to unlock vault: %w (also failed to load from DynamoDB: %v)", err, err2)
-				}
-				// Decrypt from DynamoDB vault
-				v, key, err = decryptVaultFromEncrypted(ev, password)
-				if err != nil {
-					return fmt.Errorf("failed to decrypt vault from DynamoDB: %w", err)
-				}
-			} else {
-				return fmt.Errorf("failed to unlock vault: %w", err)
+			return unlockFailed(fmt.Errorf("failed to unlock vault: %w", err))
+		}
+		if readOnly {
+			fmt.Fprintf(out, "Opened vault from remote storage (version %d) without saving it locally\n", remoteOnly.Version)
+		} else {
+			if err := localStore.SaveEncryptedVault(remoteOnly); err != nil {
+				return fmt.Errorf("failed to save vault locally: %w", err)
+			}
+			if err := localStore.SaveSyncBase(remoteOnly); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
+			fmt.Fprintf(out, "Downloaded vault from remote storage (version %d)\n", remoteOnly.Version)
 		}
+	} else {
+		v, key, err = localStore.DecryptAndLoad(password, keyFile)
+	}
+	if err != nil {
+		// Try loading from remote storage if local fails, unless the
+		// local vault is just newer than this version can read
+		if remoteStore != nil && !errors.Is(err, vault.ErrSchemaTooNew) {
+			ev, err2 := remoteStore.LoadVault(ctx)
+			if err2 != nil {
+				return unlockFailed(fmt.Errorf("failed to unlock vault: %w (also failed to load from remote storage: %v)", err, err2))
+			}
+			// Decrypt from remote vault
+			v, key, err = storage.UnlockVault(ev, password, keyFile)
+			if err != nil {
+				return unlockFailed(fmt.Errorf("failed to decrypt vault from remote storage: %w", err))
+			}
+		} else {
+			return unlockFailed(fmt.Errorf("failed to unlock vault: %w", err))
+		}
+	}
+	unlockSucceeded()
 
-		unlockedVault = v
-		vaultKey = key
+	unlockedVault = v
+	vaultKey = key
+	lockSecret(vaultKey)
 
-		// Save session for future commands
-		ctx := cmd.Context()
-		if ctx == nil {
-			ctx = context.Background()
+	if unlockRemoteSession {
+		ttl := unlockTTL
+		if ttl == 0 {
+			ttl, _ = cfg.GetSessionTimeout()
+			if ttl == 0 {
+				ttl = session.DefaultSessionTimeout
+			}
 		}
-		if err := sessionMgr.SaveSession(ctx, key); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", err)
+		if err := sessionMgr.SaveRemoteSession(ctx, key, ttl); err != nil {
+			return fmt.Errorf("failed to save remote session: %w", err)
 		}
-		
-		// Zeroize master password from memory
-		crypto.Zeroize(password)
+		fmt.Fprintf(out, "Remote session saved to %s for %s\n", sessionMgr.RemoteSessionName(), ttl)
+	}
 
-		fmt.Println("Vault unlocked successfully")
-		return nil
-	},
+	// Save session for future commands
+	if readOnly {
+		fmt.Fprintln(out, "Read-only: no session is saved, so the vault locks again when this command exits")
+	} else if err := sessionMgr.SaveSession(ctx, key); errors.Is(err, session.ErrSessionInMemory) {
+		fmt.Fprintf(os.Stderr, "Warning: %v. The vault locks again when this command exits; set %s to a writable directory to keep sessions\n", err, config.HomeEnvVar)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", err)
+	}
+
+	// Zeroize master password from memory
+	releaseSecret(password)
+
+	fmt.Fprintln(out, "Vault unlocked successfully")
+	warnRotationsDue()
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(unlockCmd)
+	unlockCmd.Flags().BoolVar(&unlockRemoteSession, "remote-session", false, "Also keep the session in AWS Secrets Manager for this runner ($"+config.RunnerIDEnvVar+")")
+	unlockCmd.Flags().DurationVar(&unlockTTL, "ttl", 0, "How long the remote session lasts (default: the session timeout)")
+}
+
+// waitForUnlockAttempt waits out the delay after recent wrong master
+// passwords, or fails while unlocking is locked out
+func waitForUnlockAttempt(ctx context.Context) error {
+	delay, err := sessionMgr.UnlockDelay(time.Now())
+	if err != nil || delay == 0 {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d wrong master passwords: waiting %s before the next attempt\n", sessionMgr.FailedUnlocks(), delay.Round(time.Second))
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlockFailed counts a wrong master password toward the unlock delay and
+// lockout, and returns err. With --read-only nothing is written, so the
+// count is left as is.
+func unlockFailed(err error) error {
+	if errors.Is(err, storage.ErrWrongPassword) && !readOnly {
+		if recordErr := sessionMgr.RecordFailedUnlock(); recordErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", recordErr)
+		}
+	}
+	return err
+}
+
+// staleSession explains that the session was cleared because its key no
+// longer opens the vault, then prompts for the master password on out.
+// Without a terminal it returns session.ErrSessionStale instead.
+func staleSession(cmd *cobra.Command, out io.Writer) error {
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return fmt.Errorf("%w. Run 'vaultctl unlock' again", session.ErrSessionStale)
+	}
+	fmt.Fprintf(os.Stderr, "Your %v, probably by 'vaultctl rotate-key' in another process or on another device. The session was cleared; unlock again with the master password.\n", session.ErrSessionStale)
+	return runUnlock(cmd, out)
+}
+
+// clearStaleSession removes a session whose key no longer opens the vault,
+// unless read-only
+func clearStaleSession() {
+	if !readOnly {
+		sessionMgr.ClearSession()
+	}
+}
+
+// unlockSucceeded resets the count of wrong master passwords
+func unlockSucceeded() {
+	if readOnly {
+		return
+	}
+	if err := sessionMgr.ResetUnlockAttempts(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 }
 
 // ensureUnlocked ensures the vault is unlocked, prompting if necessary
 func ensureUnlocked(cmd *cobra.Command) error {
+	return unlockVault(cmd, cmd.OutOrStdout(), false)
+}
+
+// ensureUnlockedQuietly is ensureUnlocked for commands whose stdout is meant
+// to be captured, such as $(vaultctl get name --password-only): the password
+// prompt and unlock messages go to stderr instead
+func ensureUnlockedQuietly(cmd *cobra.Command) error {
+	return unlockVault(cmd, cmd.ErrOrStderr(), false)
+}
+
+// ensureUnlockedMetadata is ensureUnlocked for commands that only read entry
+// metadata. With a session, split vaults leave every entry's secrets sealed;
+// the vault must not be saved.
+func ensureUnlockedMetadata(cmd *cobra.Command) error {
+	return unlockVault(cmd, cmd.OutOrStdout(), true)
+}
+
+// unlockVault unlocks the vault from the session or a password prompt,
+// writing the prompt and messages to out
+func unlockVault(cmd *cobra.Command, out io.Writer, metadataOnly bool) error {
 	// Check if already unlocked in memory
 	if unlockedVault != nil && vaultKey != nil {
 		return nil
@@ -99,17 +273,18 @@ func ensureUnlocked(cmd *cobra.Command) error {
 		if ctx == nil {
 			ctx = context.Background()
 		}
-		if key, err := sessionMgr.LoadSession(ctx); err == nil {
+		key, err := sessionMgr.LoadSession(ctx)
+		if err == nil {
 			// Session is valid, decrypt vault with the key
-			ev, err := localStore.LoadEncryptedVault()
+			ev, err := loadLocalVault(cmd)
 			if err != nil {
-				// Try DynamoDB if local fails
-				if dynamoStore != nil {
+				// Try remote storage if local fails
+				if remoteStore != nil {
 					ctx := cmd.Context()
 					if ctx == nil {
 						ctx = context.Background()
 					}
-					ev, err = dynamoStore.LoadVault(ctx)
+					ev, err = remoteStore.LoadVault(ctx)
 					if err != nil {
 						return fmt.Errorf("failed to load vault: %w", err)
 					}
@@ -118,37 +293,57 @@ func ensureUnlocked(cmd *cobra.Command) error {
 				}
 			}
 
-			// Decrypt vault using the session key
-			ciphertext, err := crypto.DecodeBase64(ev.Ciphertext)
-			if err != nil {
-				return fmt.Errorf("failed to decode ciphertext: %w", err)
+			// Verify envelope metadata before trusting it. A session key
+			// that no longer matches, e.g. after the vault key was rotated,
+			// falls back to the password prompt, which verifies again.
+			if err := ev.VerifyEnvelope(key); err != nil {
+				clearStaleSession()
+				if errors.Is(err, storage.ErrEnvelopeMACMismatch) {
+					return staleSession(cmd, out)
+				}
+				return runUnlock(cmd, out)
 			}
 
-			nonce, err := crypto.DecodeBase64(ev.Nonce)
-			if err != nil {
-				return fmt.Errorf("failed to decode nonce: %w", err)
+			// Decrypt vault using the session key
+			open := ev.OpenPayload
+			if metadataOnly {
+				open = ev.OpenMetadata
 			}
-
-			plaintext, err := crypto.Decrypt(ciphertext, nonce, key)
+			v, err := open(key)
 			if err != nil {
 				// Session key might be invalid, clear session and prompt
-				sessionMgr.ClearSession()
-				return unlockCmd.RunE(cmd, nil)
-			}
-
-			v, err := vault.FromJSON(plaintext)
-			if err != nil {
-				return fmt.Errorf("failed to deserialize vault: %w", err)
+				clearStaleSession()
+				if errors.Is(err, crypto.ErrAuthFailed) {
+					return staleSession(cmd, out)
+				}
+				return runUnlock(cmd, out)
 			}
 
 			unlockedVault = v
 			vaultKey = key
+			lockSecret(vaultKey)
+
+			// Activity keeps the session alive, but read-only leaves the
+			// session file untouched
+			if !readOnly {
+				if err := sessionMgr.Touch(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to refresh session: %v\n", err)
+				}
+			}
+			warnRotationsDue()
 			return nil
 		}
+		// Without a terminal to prompt on, scripts get a distinct error
+		// rather than a failed password read
+		if (errors.Is(err, session.ErrSessionExpired) || errors.Is(err, session.ErrSessionTokenMissing) || errors.Is(err, session.ErrSessionKeySource)) && !term.IsTerminal(int(syscall.Stdin)) {
+			return fmt.Errorf("%w. Run 'vaultctl unlock' again", err)
+		}
+		if errors.Is(err, session.ErrSessionKeySource) {
+			fmt.Fprintf(os.Stderr, "Session cleared: %v\n", err)
+		}
 		// Session expired or invalid, continue to prompt
 	}
 
 	// No valid session, prompt for password
-	return unlockCmd.RunE(cmd, nil)
+	return runUnlock(cmd, out)
 }
-
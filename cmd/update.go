@@ -2,68 +2,102 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
-	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vaultctl/vaultctl/internal/crypto"
-	"golang.org/x/term"
+	"github.com/vaultctl/vaultctl/internal/prompt"
+	"github.com/vaultctl/vaultctl/internal/vault"
+	"github.com/vaultctl/vaultctl/internal/vaultctl"
 )
 
 var (
-	updateName       string
-	updateUsername  string
-	updatePassword  string
-	updateURL       string
-	updateNotes     string
-	updateBackupCodes string
+	updateName         string
+	updateUsername     string
+	updatePassword     string
+	updateURL          string
+	updateNotes        string
+	updateBackupCodes  string
+	updateTags         string
+	updateFolder       string
+	updateFields       []string
+	updateSecretFields []string
+	updateRemoveFields []string
+	updateExpires      string
+	updateRotateEvery  string
 )
 
 var updateCmd = &cobra.Command{
 	Use:   "update <name_or_id>",
 	Short: "Update an existing password entry",
-	Long:  `Update fields of an existing password entry. Only provided fields will be updated.`,
+	Long: `Update fields of an existing password entry. Only provided fields will be updated;
+an empty --username, --url or --notes clears that field.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := ensureUnlocked(cmd); err != nil {
 			return err
 		}
 
-		entry := unlockedVault.GetEntry(args[0])
-		if entry == nil {
-			return fmt.Errorf("entry not found: %s", args[0])
+		lib := library()
+		entry, err := lib.Get(args[0])
+		if err != nil {
+			return err
 		}
+		crypto.Zeroize(entry.Password)
 
 		// Parse backup codes if provided
 		var backupCodes []string
 		if updateBackupCodes != "" {
-			// Split by comma, semicolon, or newline
-			codes := strings.FieldsFunc(updateBackupCodes, func(r rune) bool {
-				return r == ',' || r == ';' || r == '\n'
-			})
-			// Trim whitespace from each code
-			for _, code := range codes {
-				trimmed := strings.TrimSpace(code)
-				if trimmed != "" {
-					backupCodes = append(backupCodes, trimmed)
-				}
-			}
+			backupCodes = splitList(updateBackupCodes)
 		} else if cmd.Flags().Changed("backup-codes") {
 			// Flag was explicitly set to empty, clear backup codes
 			backupCodes = []string{}
 		}
 
+		// Parse custom fields
+		fields, err := parseFields(updateFields, false)
+		if err != nil {
+			return err
+		}
+		secretFields, err := parseFields(updateSecretFields, true)
+		if err != nil {
+			return err
+		}
+
+		// Parse tags if provided; an explicit empty value clears them
+		var tags []string
+		if cmd.Flags().Changed("tags") {
+			tags = splitList(updateTags)
+			if tags == nil {
+				tags = []string{}
+			}
+		}
+
+		// Parse rotation settings; an explicit empty value clears them
+		var expiresAt *time.Time
+		if updateExpires != "" {
+			t, err := parseExpiry(updateExpires)
+			if err != nil {
+				return err
+			}
+			expiresAt = &t
+		}
+		var rotateEvery time.Duration
+		if updateRotateEvery != "" {
+			if rotateEvery, err = parseRotationInterval(updateRotateEvery); err != nil {
+				return err
+			}
+		}
+
 		// Handle password update
 		var password []byte
 		if cmd.Flags().Changed("password") {
 			if updatePassword == "" {
 				// Password flag was set but empty, prompt for new password
-				fmt.Print("Enter new password: ")
-				pwd, err := term.ReadPassword(int(syscall.Stdin))
+				pwd, err := prompt.ReadPassword("Enter new password: ")
 				if err != nil {
 					return fmt.Errorf("failed to read password: %w", err)
 				}
-				fmt.Println()
 				password = pwd
 			} else {
 				// Password provided via flag (less secure, but supported)
@@ -71,24 +105,56 @@ var updateCmd = &cobra.Command{
 			}
 		}
 
-		// Update entry
-		var codesToUpdate []string
-		if backupCodes != nil {
-			codesToUpdate = backupCodes
-		}
-		
-		if !unlockedVault.UpdateEntry(args[0], updateName, updateUsername, password, updateURL, updateNotes, codesToUpdate) {
-			// Zeroize password if update failed
-			if password != nil {
-				crypto.Zeroize(password)
+		// Only pass the fields that were given, so an explicit empty value
+		// clears the field
+		changedString := func(flag string, value *string) *string {
+			if cmd.Flags().Changed(flag) {
+				return value
 			}
-			return fmt.Errorf("failed to update entry")
+			return nil
 		}
-		
+
+		// Update entry, then apply the rotation changes, which UpdateEntry
+		// would clear when the password changes
+		err = lib.Update(entry.ID, vaultctl.EntryUpdate{
+			Name:        updateName,
+			Username:    changedString("username", &updateUsername),
+			Password:    password,
+			URL:         changedString("url", &updateURL),
+			Notes:       changedString("notes", &updateNotes),
+			BackupCodes: backupCodes,
+			Tags:        tags,
+			Edit: func(e *vault.Entry) error {
+				if cmd.Flags().Changed("expires") {
+					e.ExpiresAt = expiresAt
+				}
+				if cmd.Flags().Changed("rotate-every") {
+					e.RotateEvery = rotateEvery
+				}
+				if cmd.Flags().Changed("folder") {
+					e.Folder = vault.NormalizeFolder(updateFolder)
+				}
+
+				// Apply custom field changes
+				for _, name := range updateRemoveFields {
+					if !e.RemoveField(name) {
+						return fmt.Errorf("field not found: %s", name)
+					}
+				}
+				for _, field := range append(fields, secretFields...) {
+					e.SetField(field.Name, field.Value, field.Secret)
+				}
+				return nil
+			},
+		})
+
 		// Zeroize password from memory after use
 		if password != nil {
 			crypto.Zeroize(password)
 		}
+		if err != nil {
+			return err
+		}
 
 		// Save vault
 		sync := !cmd.Flags().Changed("no-sync")
@@ -104,11 +170,17 @@ var updateCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(updateCmd)
 	updateCmd.Flags().StringVar(&updateName, "name", "", "Update entry name")
-	updateCmd.Flags().StringVar(&updateUsername, "username", "", "Update username")
+	updateCmd.Flags().StringVar(&updateUsername, "username", "", "Update username (or empty string to clear)")
 	updateCmd.Flags().StringVar(&updatePassword, "password", "", "Update password (leave empty to prompt securely)")
-	updateCmd.Flags().StringVar(&updateURL, "url", "", "Update URL")
-	updateCmd.Flags().StringVar(&updateNotes, "notes", "", "Update notes")
+	updateCmd.Flags().StringVar(&updateURL, "url", "", "Update URL (or empty string to clear)")
+	updateCmd.Flags().StringVar(&updateNotes, "notes", "", "Update notes (or empty string to clear)")
 	updateCmd.Flags().StringVar(&updateBackupCodes, "backup-codes", "", "Update backup codes (comma or semicolon separated, or empty string to clear)")
+	updateCmd.Flags().StringVar(&updateTags, "tags", "", "Update tags (comma or semicolon separated, or empty string to clear)")
+	updateCmd.Flags().StringVar(&updateFolder, "folder", "", "Move to this folder (or empty string for the top level)")
+	updateCmd.Flags().StringArrayVar(&updateFields, "field", nil, "Set custom field as NAME=VALUE (repeatable)")
+	updateCmd.Flags().StringArrayVar(&updateSecretFields, "secret-field", nil, "Set secret custom field as NAME=VALUE (repeatable)")
+	updateCmd.Flags().StringArrayVar(&updateRemoveFields, "remove-field", nil, "Remove custom field by name (repeatable)")
+	updateCmd.Flags().StringVar(&updateExpires, "expires", "", "Update the date the password must be changed by (YYYY-MM-DD, or empty string to clear)")
+	updateCmd.Flags().StringVar(&updateRotateEvery, "rotate-every", "", "Update how often the password must be changed, e.g. 90d (or empty string to clear)")
 	updateCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
 }
-
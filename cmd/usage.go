@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// usedEntries are the IDs of entries whose secrets this command revealed,
+// logged by saveUsage
+var usedEntries []string
+
+// recordUse notes that an entry's password or another secret was shown,
+// copied or exported, unless usage tracking is off
+func recordUse(id string) {
+	if readOnly || !cfg.UsageTracking() {
+		return
+	}
+	usedEntries = append(usedEntries, id)
+}
+
+// saveUsage adds the recorded uses to the usage log next to the vault, under
+// the vault lock since read commands don't hold it. The vault itself isn't
+// saved: the log is applied with the next change or sync, so reading stays
+// fast and doesn't bump the vault version. Usage is best effort: if the vault
+// is in use or anything fails it is dropped.
+func saveUsage() {
+	if len(usedEntries) == 0 || vaultKey == nil {
+		return
+	}
+	defer func() { usedEntries = nil }()
+
+	if releaseVaultLock == nil {
+		release, err := localStore.Lock()
+		if errors.Is(err, storage.ErrVaultInUse) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+			return
+		}
+		defer release()
+	}
+
+	uses := vault.UsageLog{}
+	now := time.Now()
+	for _, id := range usedEntries {
+		uses.Add(id, now)
+	}
+	if err := localStore.LogUsage(uses, vaultKey); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+	}
+}
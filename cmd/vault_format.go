@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vaultctl/vaultctl/internal/storage"
+)
+
+var vaultFormatCmd = &cobra.Command{
+	Use:   "vault-format [single|split]",
+	Short: "Show or change how entries are encrypted",
+	Long: `Show the vault format, or convert the vault to another one.
+
+In the single format the whole vault is one ciphertext. In the split format
+each entry's password, notes, backup codes, custom fields and attachment
+keys are also encrypted under a key of their own, so 'list' can run from a
+session without decrypting any of them.
+
+Split vaults can't be opened by vaultctl versions that predate the format;
+update vaultctl on every device before converting.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !localStore.Exists() {
+			return fmt.Errorf("%w. Run 'vaultctl init' first", storage.ErrVaultNotFound)
+		}
+
+		ev, err := loadLocalVault(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+		if len(args) == 0 {
+			fmt.Printf("Vault format: %s\n", ev.Format())
+			return nil
+		}
+
+		schemaVersion, err := storage.SchemaVersionFor(args[0])
+		if err != nil {
+			return err
+		}
+		if schemaVersion == ev.SchemaVersion {
+			fmt.Printf("Vault is already in the %s format\n", ev.Format())
+			return nil
+		}
+
+		if err := ensureUnlocked(cmd); err != nil {
+			return err
+		}
+
+		// Seals the payload in the new format, bumps the version and signs
+		ev.SchemaVersion = schemaVersion
+		if err := localStore.EncryptAndSave(unlockedVault, vaultKey, ev); err != nil {
+			return fmt.Errorf("failed to save vault: %w", err)
+		}
+
+		if remoteStore != nil && !cmd.Flags().Changed("no-sync") {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if err := syncOrQueue(ctx, ev); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save to remote storage, change queued for next sync: %v\n", err)
+			}
+		}
+
+		fmt.Printf("Vault converted to the %s format (version %d)\n", ev.Format(), ev.Version)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vaultFormatCmd)
+	vaultFormatCmd.Flags().Bool("no-sync", false, "Don't sync to DynamoDB")
+}
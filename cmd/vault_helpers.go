@@ -1,110 +1,291 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vaultctl/vaultctl/internal/crypto"
 	"github.com/vaultctl/vaultctl/internal/storage"
 	"github.com/vaultctl/vaultctl/internal/vault"
+	"github.com/vaultctl/vaultctl/internal/vaultctl"
 )
 
-// decryptVaultFromEncrypted decrypts a vault from an EncryptedVault structure
-func decryptVaultFromEncrypted(ev *storage.EncryptedVault, masterPassword []byte) (*vault.Vault, []byte, error) {
-	// Decode salt and encrypted vault key
-	salt, err := crypto.DecodeBase64(ev.SaltMaster)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode salt: %w", err)
+// splitList splits a comma, semicolon or newline separated flag value,
+// trimming whitespace and dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	}) {
+		trimmed := strings.TrimSpace(item)
+		if trimmed != "" {
+			items = append(items, trimmed)
+		}
 	}
+	return items
+}
 
-	encVaultKey, err := crypto.DecodeBase64(ev.EncVaultKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode encrypted vault key: %w", err)
+// Ways to handle an added or imported entry whose name is already taken
+const (
+	duplicateSkip      = "skip"
+	duplicateRename    = "rename"
+	duplicateOverwrite = "overwrite"
+	duplicateMerge     = "merge" // Import and restore --from-export only
+)
+
+// checkDuplicatePolicy validates an --on-duplicate value against the
+// policies a command supports
+func checkDuplicatePolicy(policy string, allowed ...string) error {
+	for _, a := range allowed {
+		if policy == a {
+			return nil
+		}
 	}
+	return fmt.Errorf("invalid --on-duplicate value: %s (use %s)", policy, strings.Join(allowed, ", "))
+}
 
-	// Derive master key
-	kdfParams := crypto.KDFParams{
-		Algo:       ev.KDFParams.Algo,
-		Memory:     ev.KDFParams.Memory,
-		Iterations: ev.KDFParams.Iterations,
-		Parallelism: ev.KDFParams.Parallelism,
+// optionalString returns a pointer to s, or nil if s is empty, for passing
+// values to UpdateEntry that should only overwrite when set
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
 	}
-	masterKey := crypto.DeriveMasterKey(masterPassword, salt, kdfParams)
+	return &s
+}
 
-	// Decrypt vault key
-	var vaultKeyNonce []byte
-	if ev.VaultKeyNonce != "" {
-		vaultKeyNonce, err = crypto.DecodeBase64(ev.VaultKeyNonce)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to decode vault key nonce: %w", err)
-		}
-	} else {
-		// Backward compatibility: if vault_key_nonce doesn't exist, use nonce
-		vaultKeyNonce, err = crypto.DecodeBase64(ev.Nonce)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to decode nonce: %w", err)
+// parseFields parses repeatable NAME=VALUE flag values into custom fields
+func parseFields(values []string, secret bool) ([]vault.CustomField, error) {
+	var fields []vault.CustomField
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			// Don't echo what may be a secret value
+			if secret {
+				return nil, fmt.Errorf("invalid secret field: expected NAME=VALUE")
+			}
+			return nil, fmt.Errorf("invalid field %q: expected NAME=VALUE", v)
 		}
+		fields = append(fields, vault.CustomField{Name: name, Value: value, Secret: secret})
 	}
-	
-	vaultKey, err := crypto.DecryptVaultKey(encVaultKey, vaultKeyNonce, masterKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt vault key: %w", err)
+	return fields, nil
+}
+
+// formatTime formats a timestamp for display. Times are shown in local time
+// with the UTC offset, or in UTC with a Z suffix with --utc, so the same
+// entry reads the same on devices in different time zones.
+func formatTime(t time.Time) string {
+	if useUTC {
+		return t.UTC().Format("2006-01-02 15:04:05Z")
 	}
+	return t.Local().Format("2006-01-02 15:04:05 -07:00")
+}
 
-	// Decode ciphertext and nonce
-	ciphertext, err := crypto.DecodeBase64(ev.Ciphertext)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+// formatDate formats a timestamp's date in the same zone as formatTime
+func formatDate(t time.Time) string {
+	if useUTC {
+		return t.UTC().Format("2006-01-02")
 	}
+	return t.Local().Format("2006-01-02")
+}
 
-	nonce, err := crypto.DecodeBase64(ev.Nonce)
+// parseExpiry parses an --expires value: a date (2006-01-02) or an RFC 3339
+// time. Dates expire at the start of that day in local time.
+func parseExpiry(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode nonce: %w", err)
+		return time.Time{}, fmt.Errorf("invalid expiry %q: use YYYY-MM-DD or RFC 3339", s)
 	}
+	return t, nil
+}
 
-	// Decrypt vault
-	plaintext, err := crypto.Decrypt(ciphertext, nonce, vaultKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt vault: %w", err)
+// parseRotationInterval parses a --rotate-every value: a number of days or
+// weeks such as "90d" or "12w", or a Go duration such as "720h"
+func parseRotationInterval(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1:]]; ok && len(s) > 1 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err == nil && n > 0 {
+			return time.Duration(n) * unit, nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid rotation interval %q: use e.g. 90d, 12w or 720h", s)
+}
+
+// warnRotationsDue reminds the user of entries whose password is due to be
+// changed. It prints to stderr so it doesn't mix with command output.
+func warnRotationsDue() {
+	if unlockedVault == nil {
+		return
+	}
+	if due := len(unlockedVault.ExpiredEntries(time.Now())); due > 0 {
+		fmt.Fprintf(os.Stderr, "Reminder: %d entries are due for a password change (see 'vaultctl list --due')\n", due)
 	}
+}
+
+// mlockWarned ensures the mlock failure warning is only printed once per run
+var mlockWarned bool
+
+// lockSecret pins a sensitive buffer in memory unless --no-mlock was given.
+// Failures only produce a warning since RLIMIT_MEMLOCK is often small.
+func lockSecret(b []byte) {
+	if noMlock {
+		return
+	}
+	if err := crypto.LockMemory(b); err != nil && !mlockWarned {
+		fmt.Fprintf(os.Stderr, "Warning: failed to lock memory (use --no-mlock to disable): %v\n", err)
+		mlockWarned = true
+	}
+}
 
-	// Deserialize vault
-	v, err := vault.FromJSON(plaintext)
+// releaseSecret zeroizes a sensitive buffer and releases its memory lock
+func releaseSecret(b []byte) {
+	crypto.Zeroize(b)
+	if !noMlock {
+		crypto.UnlockMemory(b)
+	}
+}
+
+// keyFileFor reads the key file if the vault is protected by one, and
+// returns nil otherwise
+func keyFileFor(ev *storage.EncryptedVault) ([]byte, error) {
+	if !ev.RequiresKeyFile() {
+		return nil, nil
+	}
+	return readKeyFile()
+}
+
+// readKeyFile reads the key file named by --key-file or key_file in the
+// config. It returns nil if neither is set.
+func readKeyFile() ([]byte, error) {
+	path := keyFilePath
+	if path == "" {
+		path = cfg.KeyFile
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return readKeyFileAt(path)
+}
+
+// readKeyFileAt reads a key file, checking it is long enough to be one
+func readKeyFileAt(path string) ([]byte, error) {
+	keyFile, err := os.ReadFile(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to deserialize vault: %w", err)
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if len(keyFile) < crypto.KeyFileSize {
+		crypto.Zeroize(keyFile)
+		return nil, fmt.Errorf("key file %s is too short: it must be at least %d bytes", path, crypto.KeyFileSize)
+	}
+	lockSecret(keyFile)
+	return keyFile, nil
+}
+
+// loadLocalVault loads the local encrypted vault. If the file is corrupted
+// and remote storage holds a valid vault, it offers to restore the local
+// file from remote, keeping the corrupted file next to it.
+func loadLocalVault(cmd *cobra.Command) (*storage.EncryptedVault, error) {
+	ev, err := localStore.LoadEncryptedVault()
+	if err == nil || !errors.Is(err, storage.ErrCorruptVault) || remoteStore == nil {
+		return ev, err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	remoteEV, remoteErr := remoteStore.LoadVault(ctx)
+	if remoteErr == nil {
+		remoteErr = remoteEV.Validate()
+	}
+	if remoteErr != nil {
+		return nil, fmt.Errorf("%w (no usable copy in remote storage: %v)", err, remoteErr)
+	}
+	if readOnly {
+		return nil, fmt.Errorf("%w (a copy in remote storage can restore it without --read-only)", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	fmt.Printf("Restore the local vault from remote storage (version %d)? (y/N): ", remoteEV.Version)
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "y" && response != "yes" {
+		return nil, err
 	}
 
-	return v, vaultKey, nil
+	corruptPath := localStore.VaultPath + ".corrupt"
+	if err := os.Rename(localStore.VaultPath, corruptPath); err != nil {
+		return nil, fmt.Errorf("failed to move corrupted vault aside: %w", err)
+	}
+	if err := localStore.SaveEncryptedVault(remoteEV); err != nil {
+		return nil, fmt.Errorf("failed to restore vault from remote storage: %w", err)
+	}
+	if err := localStore.SaveSyncBase(remoteEV); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Printf("Local vault restored from remote storage; the corrupted file was kept at %s\n", corruptPath)
+	return remoteEV, nil
 }
 
-// saveVault saves the unlocked vault to local storage and optionally syncs to DynamoDB
-func saveVault(cmd *cobra.Command, syncToDynamo bool) error {
+// saveVault saves the unlocked vault to local storage and optionally syncs to remote storage
+func saveVault(cmd *cobra.Command, syncToRemote bool) error {
 	if unlockedVault == nil {
 		return fmt.Errorf("vault is not unlocked")
 	}
+	if readOnly {
+		return errReadOnly
+	}
 
-	// Load current encrypted vault to preserve metadata
-	ev, err := localStore.LoadEncryptedVault()
-	if err != nil {
+	// Offer to repair a corrupt local file before saving over it
+	if _, err := loadLocalVault(cmd); err != nil {
 		return fmt.Errorf("failed to load encrypted vault: %w", err)
 	}
 
-	// Encrypt and save locally
-	if err := localStore.EncryptAndSave(unlockedVault, vaultKey, ev); err != nil {
-		return fmt.Errorf("failed to save vault: %w", err)
+	lib := library()
+	if !syncToRemote || remoteStore == nil {
+		return lib.SaveLocal()
 	}
 
-	// Sync to DynamoDB if requested
-	if syncToDynamo && dynamoStore != nil {
-		ctx := cmd.Context()
-		if ctx == nil {
-			ctx = cmd.Root().Context()
-		}
-		if err := dynamoStore.SaveVault(ctx, ev, ev.Version-1); err != nil {
-			return fmt.Errorf("failed to sync to DynamoDB: %w", err)
-		}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = cmd.Root().Context()
 	}
+	err := lib.Save(ctx)
+	if errors.Is(err, vaultctl.ErrChangeQueued) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to sync to remote storage: %v\n", err)
+		return nil
+	}
+	return err
+}
 
-	return nil
+// syncOrQueue pushes a just-saved vault to remote storage, queueing it for
+// the next sync if that fails
+func syncOrQueue(ctx context.Context, ev *storage.EncryptedVault) error {
+	if readOnly {
+		return errReadOnly
+	}
+	return library().PushOrQueue(ctx, ev)
 }
 
+// library wraps the unlocked vault and the configured stores in the public
+// vaultctl API, reporting its warnings on stderr
+func library() *vaultctl.Vault {
+	lib := vaultctl.New(localStore, remoteStore, unlockedVault, vaultKey)
+	lib.SetWarningHandler(func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	})
+	return lib
+}
@@ -0,0 +1,71 @@
+// Package atomic replaces files so that a crash leaves either the old or the
+// new contents on disk, never a truncated file.
+package atomic
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotWritable is returned by EnsureDir when files can't be created in a
+// directory
+var ErrNotWritable = errors.New("directory is not writable")
+
+// EnsureDir creates dir if needed and checks that files can be created in
+// it, so a read-only location is reported clearly before anything is written
+func EnsureDir(dir string, perm os.FileMode) error {
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotWritable, err)
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotWritable, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// WriteFile writes data to path.tmp with the given permissions, flushes it to
+// disk and renames it over path
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+
+	// A leftover from an earlier crash may have other permissions
+	os.Remove(tmp)
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to flush %s: %w", tmp, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Make the rename itself durable. Not every platform can sync a
+	// directory, so this is best effort.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package breach
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+// stubClient answers every request with a fixed response and records the
+// requested URLs
+type stubClient struct {
+	status int
+	body   string
+	urls   []string
+}
+
+func (s *stubClient) Do(req *http.Request) (*http.Response, error) {
+	s.urls = append(s.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: s.status,
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+func newStubChecker(status int, body string) (*Checker, *stubClient) {
+	client := &stubClient{status: status, body: body}
+	c := NewChecker(client)
+	c.SetRateLimit(0)
+	return c, client
+}
+
+func TestCountMatchesSuffix(t *testing.T) {
+	body := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n" +
+		strings.ToLower(passwordSuffix) + ":3861493\r\n" +
+		"011053FD0102E94D6AE2F8B83D76FAF94F6:0\r\n"
+	c, client := newStubChecker(http.StatusOK, body)
+
+	count, err := c.Count(context.Background(), []byte("password"))
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != 3861493 {
+		t.Errorf("count = %d, want 3861493", count)
+	}
+
+	// Only the five character prefix leaves the machine
+	if len(client.urls) != 1 || client.urls[0] != DefaultAPIURL+passwordPrefix {
+		t.Errorf("requested %v, want only %s", client.urls, DefaultAPIURL+passwordPrefix)
+	}
+	for _, url := range client.urls {
+		if strings.Contains(strings.ToUpper(url), passwordSuffix) {
+			t.Errorf("request %s contains the hash suffix", url)
+		}
+	}
+}
+
+func TestCountNoMatch(t *testing.T) {
+	// Padding entries have a count of 0 and never match
+	body := "0018A45C4D1DEF81644B54AB7F969B88D65:4\r\n" + passwordSuffix + ":0\r\n"
+	c, _ := newStubChecker(http.StatusOK, body)
+
+	count, err := c.Count(context.Background(), []byte("password"))
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("count = %d, want 0", count)
+	}
+}
+
+func TestCountNon200(t *testing.T) {
+	c, _ := newStubChecker(http.StatusTooManyRequests, "Rate limit exceeded")
+
+	if _, err := c.Count(context.Background(), []byte("password")); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("Count error = %v, want one naming status 429", err)
+	}
+}
+
+func TestCountMalformedResponse(t *testing.T) {
+	c, _ := newStubChecker(http.StatusOK, "<html>not a range</html>")
+
+	if _, err := c.Count(context.Background(), []byte("password")); err == nil {
+		t.Error("Count succeeded on a malformed response")
+	}
+}
+
+func TestCountCachesRanges(t *testing.T) {
+	c, client := newStubChecker(http.StatusOK, passwordSuffix+":2\r\n")
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Count(context.Background(), []byte("password")); err != nil {
+			t.Fatalf("Count: %v", err)
+		}
+	}
+	if len(client.urls) != 1 {
+		t.Errorf("made %d requests for one prefix, want 1", len(client.urls))
+	}
+}
@@ -0,0 +1,65 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// ErrUnavailable is returned when no supported clipboard tool is installed
+var ErrUnavailable = errors.New("no clipboard tool found (install pbcopy, wl-copy, xclip or xsel)")
+
+// copyCommand returns the command used to write to the system clipboard
+func copyCommand() (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("pbcopy"); err == nil {
+			return exec.Command(path), nil
+		}
+	case "windows":
+		if path, err := exec.LookPath("clip"); err == nil {
+			return exec.Command(path), nil
+		}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			if path, err := exec.LookPath("wl-copy"); err == nil {
+				return exec.Command(path), nil
+			}
+		}
+		if path, err := exec.LookPath("xclip"); err == nil {
+			return exec.Command(path, "-selection", "clipboard"), nil
+		}
+		if path, err := exec.LookPath("xsel"); err == nil {
+			return exec.Command(path, "--clipboard", "--input"), nil
+		}
+	}
+	return nil, ErrUnavailable
+}
+
+// Available reports whether a clipboard tool is installed
+func Available() bool {
+	_, err := copyCommand()
+	return err == nil
+}
+
+// Copy writes data to the system clipboard
+func Copy(data []byte) error {
+	c, err := copyCommand()
+	if err != nil {
+		return err
+	}
+
+	c.Stdin = bytes.NewReader(data)
+	if out, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Clear overwrites the system clipboard with empty content
+func Clear() error {
+	return Copy(nil)
+}
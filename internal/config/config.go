@@ -5,45 +5,317 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/atomic"
 )
 
 // Config holds application configuration
 type Config struct {
-	AWSRegion         string `json:"aws_region"`
-	TableName         string `json:"table_name"`
-	UserID            string `json:"user_id"`
-	VaultPath         string `json:"vault_path"`
-	SessionSecretName string `json:"session_secret_name,omitempty"` // AWS Secrets Manager secret name for session key
-	ConfigPath        string `json:"-"`                             // Not stored, just for reference
+	AWSRegion           string       `json:"aws_region"`
+	TableName           string       `json:"table_name"`
+	UserID              string       `json:"user_id"`
+	VaultPath           string       `json:"vault_path"`
+	SessionSecretName   string       `json:"session_secret_name,omitempty"`   // AWS Secrets Manager secret name for session key
+	SessionTimeout      string       `json:"session_timeout,omitempty"`       // Inactivity before the session locks, e.g. "15m"
+	SleepLockAfter      string       `json:"sleep_lock_after,omitempty"`      // Lock if the system sleeps longer than this while unlocked; "0" disables
+	RemoteBackend       string       `json:"remote_backend,omitempty"`        // "dynamodb" (default) or "filesystem"
+	RemoteDir           string       `json:"remote_dir,omitempty"`            // Directory used by the filesystem backend
+	DynamoDBRetry       *RetryConfig `json:"dynamodb_retry,omitempty"`        // Overrides for the DynamoDB retry policy
+	DynamoDBEndpoint    string       `json:"dynamodb_endpoint,omitempty"`     // Endpoint URL replacing the AWS one, e.g. DynamoDB Local
+	DynamoDBTimeout     string       `json:"dynamodb_timeout,omitempty"`      // Limit on each DynamoDB operation, e.g. "30s"; "0" disables
+	BackupKeep          int          `json:"backup_keep,omitempty"`           // Default for backup --keep
+	BackupKeepDays      int          `json:"backup_keep_days,omitempty"`      // Default for backup --keep-days
+	BackupBeforeWrite   bool         `json:"backup_before_write,omitempty"`   // Back up the vault before rotate-master, rotate-key, remove and restore
+	AttachmentBucket    string       `json:"attachment_bucket,omitempty"`     // S3 bucket for attachments too large to store inline
+	AttachmentInlineMax int          `json:"attachment_inline_max,omitempty"` // Largest attachment stored in the vault, in bytes
+	Offline             bool         `json:"offline,omitempty"`               // Never contact AWS: no DynamoDB, Secrets Manager or S3
+	TrackUsage          *bool        `json:"track_usage,omitempty"`           // Count uses of each entry's secrets; on unless false
+	KeyFile             string       `json:"key_file,omitempty"`              // Key file also needed to unlock the vault, see init --key-file
+	RevealPasswords     bool         `json:"reveal_passwords,omitempty"`      // get shows the password without --reveal, as before it was hidden
+	ConfigPath          string       `json:"-"`                               // Not stored, just for reference
+	DataDir             string       `json:"-"`                               // Holds the session and backups
+	Profile             string       `json:"-"`                               // Named vault, empty for the default vault
+
+	envOverrides []envOverride // Fields set from the environment, see applyEnv
+}
+
+// envOverride records a field set from the environment and the value it had
+// before, so SaveConfig doesn't write the environment into config.json
+type envOverride struct {
+	field     func(*Config) *string
+	value     string
+	fileValue string
+}
+
+// envSettings are the settings applyEnv takes from the environment
+var envSettings = []struct {
+	envVar string
+	field  func(*Config) *string
+}{
+	{AWSRegionEnvVar, func(c *Config) *string { return &c.AWSRegion }},
+	{TableNameEnvVar, func(c *Config) *string { return &c.TableName }},
+	{UserIDEnvVar, func(c *Config) *string { return &c.UserID }},
+}
+
+// ProfileEnvVar selects a named vault when --vault is not given
+const ProfileEnvVar = "VAULTCTL_PROFILE"
+
+// Environment variables overriding aws_region, table_name and user_id, for
+// containers and CI jobs configured through the environment
+const (
+	AWSRegionEnvVar = "VAULTCTL_AWS_REGION"
+	TableNameEnvVar = "VAULTCTL_TABLE_NAME"
+	UserIDEnvVar    = "VAULTCTL_USER_ID"
+)
+
+// HomeEnvVar sets the directory holding config, vault data and backups,
+// overriding ~/.vaultctl and the XDG directories
+const HomeEnvVar = "VAULTCTL_HOME"
+
+// DynamoDBEndpointEnvVar overrides dynamodb_endpoint
+const DynamoDBEndpointEnvVar = "VAULTCTL_DYNAMODB_ENDPOINT"
+
+// RunnerIDEnvVar names the CI runner whose remote session, kept in AWS
+// Secrets Manager, is used when there is no session file
+const RunnerIDEnvVar = "VAULTCTL_RUNNER_ID"
+
+// profileNamePattern restricts profile names to safe directory names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidateProfileName checks that a profile name is usable as a directory name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid vault name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// ConfigDir returns the directory holding a vault's config.json. The default
+// vault lives directly in it and named vaults under vaults/<name>.
+func ConfigDir(profile string) (string, error) {
+	base, err := baseDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return "", err
+	}
+	return profileDir(base, profile), nil
+}
+
+// DataDir returns the directory holding a vault's vault file, session and
+// backups, laid out like ConfigDir
+func DataDir(profile string) (string, error) {
+	base, err := baseDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	if err != nil {
+		return "", err
+	}
+	return profileDir(base, profile), nil
+}
+
+// baseDir returns $VAULTCTL_HOME if set, then ~/.vaultctl if it exists, so
+// existing setups keep working. Otherwise, if XDG_CONFIG_HOME or XDG_DATA_HOME is set, it returns
+// $xdgEnv/vaultctl, or ~/xdgDefault/vaultctl if xdgEnv itself isn't set.
+// Relative XDG paths are ignored, as the spec requires.
+func baseDir(xdgEnv, xdgDefault string) (string, error) {
+	if home := os.Getenv(HomeEnvVar); home != "" {
+		return filepath.Abs(home)
+	}
+
+	homeDir, homeErr := os.UserHomeDir()
+	legacy := filepath.Join(homeDir, ".vaultctl")
+	if homeErr == nil {
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy, nil
+		}
+	}
+
+	useXDG := filepath.IsAbs(os.Getenv("XDG_CONFIG_HOME")) || filepath.IsAbs(os.Getenv("XDG_DATA_HOME"))
+	if useXDG {
+		if xdg := os.Getenv(xdgEnv); filepath.IsAbs(xdg) {
+			return filepath.Join(xdg, "vaultctl"), nil
+		}
+	}
+	if homeErr != nil {
+		return "", fmt.Errorf("failed to find vaultctl directory, set HOME, %s or %s: %w", HomeEnvVar, xdgEnv, homeErr)
+	}
+	if useXDG {
+		return filepath.Join(homeDir, xdgDefault, "vaultctl"), nil
+	}
+	return legacy, nil
 }
 
+func profileDir(base, profile string) string {
+	if profile == "" {
+		return base
+	}
+	return filepath.Join(base, "vaults", profile)
+}
+
+// RetryConfig overrides the retry policy for remote calls. Unset fields keep
+// their defaults.
+type RetryConfig struct {
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+	BaseDelayMS int      `json:"base_delay_ms,omitempty"`
+	MaxDelayMS  int      `json:"max_delay_ms,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty"` // 0 to 1
+}
+
+// DefaultAttachmentInlineMax is the largest attachment stored in the vault
+// itself when attachment_inline_max is not set. It keeps vaults well under
+// the DynamoDB item limit.
+const DefaultAttachmentInlineMax = 32 * 1024
+
+// Remote backends
+const (
+	BackendDynamoDB   = "dynamodb"
+	BackendFilesystem = "filesystem"
+)
+
 // GetSessionPath returns the path to the session file
 func (c *Config) GetSessionPath() string {
-	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".vaultctl", "session.json")
+	return filepath.Join(c.DataDir, "session.json")
+}
+
+// GetBackupDir returns the directory backups are written to by default
+func (c *Config) GetBackupDir() string {
+	return filepath.Join(c.DataDir, "backups")
+}
+
+// RemoteUserID returns the user ID used to key the vault in the filesystem
+// remote and for S3 attachments. Named vaults get the profile name as a
+// suffix so they don't collide. DynamoDB keeps them under the plain user_id
+// with the profile name in the sort key instead.
+func (c *Config) RemoteUserID() string {
+	return c.RemoteUserIDFor(c.UserID)
+}
+
+// RemoteUserIDFor returns the remote user ID this vault would have if
+// user_id were userID
+func (c *Config) RemoteUserIDFor(userID string) string {
+	if c.Profile == "" {
+		return userID
+	}
+	return userID + "." + c.Profile
+}
+
+// GetDynamoDBEndpoint returns the DynamoDB endpoint URL from the environment
+// or config. It returns "" to use the AWS endpoint.
+func (c *Config) GetDynamoDBEndpoint() string {
+	if endpoint := os.Getenv(DynamoDBEndpointEnvVar); endpoint != "" {
+		return endpoint
+	}
+	return c.DynamoDBEndpoint
+}
+
+// UsageTracking reports whether uses of entries' secrets are recorded
+func (c *Config) UsageTracking() bool {
+	return c.TrackUsage == nil || *c.TrackUsage
+}
+
+// GetAttachmentInlineMax returns the largest attachment, in bytes, that is
+// stored in the vault rather than in the attachment bucket
+func (c *Config) GetAttachmentInlineMax() int {
+	if c.AttachmentInlineMax <= 0 {
+		return DefaultAttachmentInlineMax
+	}
+	return c.AttachmentInlineMax
+}
+
+// GetSessionTimeout parses the configured session timeout. It returns 0 if
+// none is configured.
+func (c *Config) GetSessionTimeout() (time.Duration, error) {
+	if c.SessionTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.SessionTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid session_timeout %q: %w", c.SessionTimeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid session_timeout %q: must be positive", c.SessionTimeout)
+	}
+	return d, nil
+}
+
+// GetDynamoDBTimeout parses the configured DynamoDB operation timeout. ok is
+// false if none is configured.
+func (c *Config) GetDynamoDBTimeout() (d time.Duration, ok bool, err error) {
+	if c.DynamoDBTimeout == "" {
+		return 0, false, nil
+	}
+	d, err = time.ParseDuration(c.DynamoDBTimeout)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid dynamodb_timeout %q: %w", c.DynamoDBTimeout, err)
+	}
+	if d < 0 {
+		return 0, false, fmt.Errorf("invalid dynamodb_timeout %q: must not be negative", c.DynamoDBTimeout)
+	}
+	return d, true, nil
+}
+
+// GetSleepLockAfter parses the configured sleep lock threshold. ok is false
+// if none is configured.
+func (c *Config) GetSleepLockAfter() (d time.Duration, ok bool, err error) {
+	if c.SleepLockAfter == "" {
+		return 0, false, nil
+	}
+	d, err = time.ParseDuration(c.SleepLockAfter)
+	if err != nil || d < 0 {
+		return 0, false, fmt.Errorf("invalid sleep_lock_after %q", c.SleepLockAfter)
+	}
+	return d, true, nil
 }
 
 // DefaultConfig returns default configuration
-func DefaultConfig() *Config {
-	homeDir, _ := os.UserHomeDir()
+func DefaultConfig() (*Config, error) {
+	return DefaultProfileConfig("")
+}
+
+// DefaultProfileConfig returns default configuration for a named vault
+func DefaultProfileConfig(profile string) (*Config, error) {
+	configDir, err := ConfigDir(profile)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := DataDir(profile)
+	if err != nil {
+		return nil, err
+	}
 	return &Config{
 		AWSRegion:         "us-west-2",
 		TableName:         "vaultctl_vaults",
 		UserID:            "default",
-		VaultPath:         filepath.Join(homeDir, ".vaultctl", "vault.db"),
+		VaultPath:         filepath.Join(dataDir, "vault.db"),
 		SessionSecretName: "vaultctl/session-key",
-		ConfigPath:        filepath.Join(homeDir, ".vaultctl", "config.json"),
-	}
+		ConfigPath:        filepath.Join(configDir, "config.json"),
+		DataDir:           dataDir,
+		Profile:           profile,
+	}, nil
 }
 
 // LoadConfig loads configuration from file
 func LoadConfig() (*Config, error) {
-	cfg := DefaultConfig()
+	return LoadProfileConfig("")
+}
+
+// LoadProfileConfig loads the configuration of a named vault, or of the
+// default vault if profile is empty
+func LoadProfileConfig(profile string) (*Config, error) {
+	if profile != "" {
+		if err := ValidateProfileName(profile); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := DefaultProfileConfig(profile)
+	if err != nil {
+		return nil, err
+	}
 
 	data, err := os.ReadFile(cfg.ConfigPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Return default config if file doesn't exist
+			// Use the default config if the file doesn't exist
+			cfg.applyEnv()
 			return cfg, nil
 		}
 		return nil, fmt.Errorf("failed to read config: %w", err)
@@ -54,22 +326,46 @@ func LoadConfig() (*Config, error) {
 	}
 
 	cfg.ConfigPath = filepath.Join(filepath.Dir(cfg.ConfigPath), "config.json")
+	cfg.applyEnv()
 	return cfg, nil
 }
 
+// applyEnv overrides the settings that have an environment variable set,
+// which take precedence over config.json and the defaults
+func (c *Config) applyEnv() {
+	for _, s := range envSettings {
+		value := os.Getenv(s.envVar)
+		if value == "" {
+			continue
+		}
+		field := s.field(c)
+		c.envOverrides = append(c.envOverrides, envOverride{field: s.field, value: value, fileValue: *field})
+		*field = value
+	}
+}
+
 // SaveConfig saves configuration to file
 func (c *Config) SaveConfig() error {
 	dir := filepath.Dir(c.ConfigPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
+	if err := atomic.EnsureDir(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	// Keep the file's values of settings taken from the environment, unless
+	// they were changed since
+	saved := *c
+	for _, o := range c.envOverrides {
+		if field := o.field(&saved); *field == o.value {
+			*field = o.fileValue
+		}
+	}
+
+	data, err := json.MarshalIndent(&saved, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(c.ConfigPath, data, 0600); err != nil {
+	if err := atomic.WriteFile(c.ConfigPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
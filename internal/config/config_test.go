@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupHome points the config at an empty directory and clears the
+// environment overrides, returning the config file's path
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(HomeEnvVar, home)
+	for _, s := range envSettings {
+		t.Setenv(s.envVar, "")
+	}
+	return filepath.Join(home, "config.json")
+}
+
+// writeConfigFile writes settings as config.json at path
+func writeConfigFile(t *testing.T, path string, settings map[string]string) {
+	t.Helper()
+	data, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readConfigFile returns the settings saved in config.json at path
+func readConfigFile(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("saved config is not JSON: %v", err)
+	}
+	return settings
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileValue string // aws_region in config.json, none if empty
+		envValue  string // VAULTCTL_AWS_REGION, unset if empty
+		want      string
+	}{
+		{"default", "", "", "us-west-2"},
+		{"file", "eu-west-1", "", "eu-west-1"},
+		{"environment", "", "us-east-1", "us-east-1"},
+		{"environment over file", "eu-west-1", "us-east-1", "us-east-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupHome(t)
+			if tt.fileValue != "" {
+				writeConfigFile(t, path, map[string]string{"aws_region": tt.fileValue})
+			}
+			t.Setenv(AWSRegionEnvVar, tt.envValue)
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.AWSRegion != tt.want {
+				t.Errorf("AWSRegion = %q, want %q", cfg.AWSRegion, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveConfigLeavesEnvironmentOut(t *testing.T) {
+	tests := []struct {
+		name string
+		file map[string]string // config.json before loading, none if nil
+		edit func(*Config)     // Changes made after loading
+		want map[string]string // Settings expected in the saved file
+	}{
+		{
+			name: "file values kept",
+			file: map[string]string{"aws_region": "eu-west-1", "table_name": "team_vaults", "user_id": "alice"},
+			want: map[string]string{"aws_region": "eu-west-1", "table_name": "team_vaults", "user_id": "alice"},
+		},
+		{
+			name: "defaults kept without a file",
+			want: map[string]string{"aws_region": "us-west-2", "table_name": "vaultctl_vaults", "user_id": "default"},
+		},
+		{
+			name: "fields changed after load",
+			file: map[string]string{"aws_region": "eu-west-1", "table_name": "team_vaults", "user_id": "alice"},
+			edit: func(c *Config) {
+				c.TableName = "new_vaults"
+				c.KeyFile = "/keys/vault.key"
+			},
+			want: map[string]string{"aws_region": "eu-west-1", "table_name": "new_vaults", "user_id": "alice", "key_file": "/keys/vault.key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupHome(t)
+			if tt.file != nil {
+				writeConfigFile(t, path, tt.file)
+			}
+			t.Setenv(AWSRegionEnvVar, "us-east-1")
+			t.Setenv(TableNameEnvVar, "ci_vaults")
+			t.Setenv(UserIDEnvVar, "ci")
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.AWSRegion != "us-east-1" || cfg.TableName != "ci_vaults" || cfg.UserID != "ci" {
+				t.Fatalf("loaded %s/%s/%s, want the environment's us-east-1/ci_vaults/ci", cfg.AWSRegion, cfg.TableName, cfg.UserID)
+			}
+			if tt.edit != nil {
+				tt.edit(cfg)
+			}
+			if err := cfg.SaveConfig(); err != nil {
+				t.Fatalf("SaveConfig: %v", err)
+			}
+
+			saved := readConfigFile(t, path)
+			for key, want := range tt.want {
+				if saved[key] != want {
+					t.Errorf("saved %s = %v, want %q", key, saved[key], want)
+				}
+			}
+			if cfg.AWSRegion != "us-east-1" {
+				t.Errorf("SaveConfig changed the loaded AWSRegion to %q", cfg.AWSRegion)
+			}
+		})
+	}
+}
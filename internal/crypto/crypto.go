@@ -1,14 +1,22 @@
 package crypto
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
 )
 
 const (
@@ -22,13 +30,59 @@ const (
 	// Nonce size for XChaCha20-Poly1305
 	NonceSize = 24
 
+	// Nonce size for AES-256-GCM
+	GCMNonceSize = 12
+
+	// Authentication tag size, the same for both ciphers
+	TagSize = 16
+
 	// Argon2id parameters
 	DefaultMemory      = 64 * 1024 // 64 MB
 	DefaultIterations  = 3
 	DefaultParallelism = 1
+
+	// scrypt block size. With r = 8 each unit of N costs 1 KiB of memory,
+	// so KDFParams.Memory (in KiB) maps directly onto N.
+	ScryptBlockSize = 8
+
+	// Calibration defaults
+	DefaultCalibrationTarget = 500 * time.Millisecond
+	MaxCalibrationMemory     = 1024 * 1024 // 1 GB
+	MaxCalibrationIterations = 32
+
+	// Minimum accepted KDF parameters
+	MinMemory      = 8 * 1024 // 8 MB
+	MinIterations  = 1
+	MinParallelism = 1
+
+	// Recommended minimum KDF parameters. Vaults below them still open, but
+	// status suggests upgrading them with rekdf.
+	RecommendedMemory     = 64 * 1024 // 64 MB
+	RecommendedIterations = 3
+)
+
+// Supported AEAD ciphers
+const (
+	CipherXChaCha20Poly1305 = "xchacha20poly1305"
+	CipherAES256GCM         = "aes-256-gcm"
 )
 
-// KDFParams holds Argon2id parameters
+// ErrAuthFailed is returned by Decrypt and DecryptVaultKey when the
+// ciphertext doesn't authenticate: the key is wrong or the data was changed
+var ErrAuthFailed = errors.New("message authentication failed")
+
+// Supported KDF algorithms
+const (
+	AlgoArgon2id = "argon2id"
+	AlgoScrypt   = "scrypt"
+)
+
+// KDFParams holds key derivation parameters.
+//
+// For argon2id the fields are used as-is. For scrypt, Memory (KiB) is rounded
+// down to a power of two and used as N, r is fixed at ScryptBlockSize, and
+// Iterations is used as p (x/crypto/scrypt runs the p lanes sequentially, so
+// it scales cost like an iteration count). Parallelism is ignored by scrypt.
 type KDFParams struct {
 	Algo       string `json:"algo"`
 	Memory     uint32 `json:"memory"`
@@ -39,16 +93,88 @@ type KDFParams struct {
 // DefaultKDFParams returns sensible default parameters
 func DefaultKDFParams() KDFParams {
 	return KDFParams{
-		Algo:       "argon2id",
+		Algo:       AlgoArgon2id,
 		Memory:     DefaultMemory,
 		Iterations: DefaultIterations,
 		Parallelism: DefaultParallelism,
 	}
 }
 
-// DeriveMasterKey derives a master key from a password using Argon2id
-func DeriveMasterKey(password []byte, salt []byte, params KDFParams) []byte {
-	return argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, MasterKeySize)
+// Validate checks that the KDF parameters meet the minimum requirements
+func (p KDFParams) Validate() error {
+	if p.Algo != AlgoArgon2id && p.Algo != AlgoScrypt {
+		return fmt.Errorf("unsupported kdf algorithm: %q", p.Algo)
+	}
+	if p.Memory < MinMemory {
+		return fmt.Errorf("kdf memory must be at least %d KiB, got %d", MinMemory, p.Memory)
+	}
+	if p.Iterations < MinIterations {
+		return fmt.Errorf("kdf iterations must be at least %d, got %d", MinIterations, p.Iterations)
+	}
+	if p.Parallelism < MinParallelism {
+		return fmt.Errorf("kdf parallelism must be at least %d, got %d", MinParallelism, p.Parallelism)
+	}
+	return nil
+}
+
+// MeetsRecommended reports whether the parameters are at least
+// RecommendedMemory and RecommendedIterations
+func (p KDFParams) MeetsRecommended() bool {
+	return p.Memory >= RecommendedMemory && p.Iterations >= RecommendedIterations
+}
+
+// DeriveMasterKey derives a master key from a password using the KDF named in params.Algo
+func DeriveMasterKey(password []byte, salt []byte, params KDFParams) ([]byte, error) {
+	switch params.Algo {
+	case AlgoArgon2id:
+		return argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, MasterKeySize), nil
+	case AlgoScrypt:
+		key, err := scrypt.Key(password, salt, scryptN(params.Memory), ScryptBlockSize, int(params.Iterations), MasterKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive scrypt key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf algorithm: %q", params.Algo)
+	}
+}
+
+// CalibrateKDF benchmarks Argon2id on this machine and returns parameters for
+// which a single derivation takes at least roughly targetDuration. Memory is
+// doubled first, up to MaxCalibrationMemory, then iterations are increased.
+// The result is never weaker than DefaultKDFParams.
+func CalibrateKDF(targetDuration time.Duration) KDFParams {
+	params := DefaultKDFParams()
+	password := []byte("vaultctl-calibration")
+	salt := make([]byte, SaltSize)
+
+	for {
+		start := time.Now()
+		key, _ := DeriveMasterKey(password, salt, params)
+		elapsed := time.Since(start)
+		Zeroize(key)
+
+		if elapsed >= targetDuration {
+			return params
+		}
+
+		if params.Memory*2 <= MaxCalibrationMemory {
+			params.Memory *= 2
+		} else if params.Iterations < MaxCalibrationIterations {
+			params.Iterations++
+		} else {
+			return params
+		}
+	}
+}
+
+// scryptN returns the largest power of two not exceeding memory (in KiB)
+func scryptN(memory uint32) int {
+	n := 2
+	for uint64(n)*2 <= uint64(memory) {
+		n *= 2
+	}
+	return n
 }
 
 // GenerateSalt generates a random salt
@@ -69,76 +195,142 @@ func GenerateVaultKey() ([]byte, error) {
 	return key, nil
 }
 
+// newAEAD returns the AEAD implementation for the named cipher.
+// An empty name selects XChaCha20-Poly1305 for vaults written before the
+// cipher field was honored.
+func newAEAD(cipherName string, key []byte) (cipher.AEAD, error) {
+	switch cipherName {
+	case CipherXChaCha20Poly1305, "":
+		return chacha20poly1305.NewX(key)
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	default:
+		return nil, fmt.Errorf("unsupported cipher: %q", cipherName)
+	}
+}
+
+// ValidateCipher checks that the cipher name is supported
+func ValidateCipher(cipherName string) error {
+	if cipherName != CipherXChaCha20Poly1305 && cipherName != CipherAES256GCM {
+		return fmt.Errorf("unsupported cipher: %q", cipherName)
+	}
+	return nil
+}
+
+// NonceSizeFor returns the nonce size of the named cipher
+func NonceSizeFor(cipherName string) (int, error) {
+	switch cipherName {
+	case CipherXChaCha20Poly1305, "":
+		return NonceSize, nil
+	case CipherAES256GCM:
+		return GCMNonceSize, nil
+	default:
+		return 0, fmt.Errorf("unsupported cipher: %q", cipherName)
+	}
+}
+
 // EncryptVaultKey encrypts the vault key with the master key
-func EncryptVaultKey(vaultKey []byte, masterKey []byte) ([]byte, []byte, error) {
-	aead, err := chacha20poly1305.NewX(masterKey)
+func EncryptVaultKey(vaultKey []byte, masterKey []byte, cipherName string) ([]byte, []byte, error) {
+	aead, err := newAEAD(cipherName, masterKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
-	nonce := make([]byte, NonceSize)
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
+	if err := nonces.use(masterKey, nonce); err != nil {
+		return nil, nil, err
+	}
 
 	ciphertext := aead.Seal(nil, nonce, vaultKey, nil)
 	return ciphertext, nonce, nil
 }
 
 // DecryptVaultKey decrypts the vault key with the master key
-func DecryptVaultKey(encryptedVaultKey []byte, nonce []byte, masterKey []byte) ([]byte, error) {
-	aead, err := chacha20poly1305.NewX(masterKey)
+func DecryptVaultKey(encryptedVaultKey []byte, nonce []byte, masterKey []byte, cipherName string) ([]byte, error) {
+	aead, err := newAEAD(cipherName, masterKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
-	if len(nonce) != NonceSize {
+	if len(nonce) != aead.NonceSize() {
 		return nil, errors.New("invalid nonce size")
 	}
 
 	plaintext, err := aead.Open(nil, nonce, encryptedVaultKey, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt vault key: %w", err)
+		return nil, fmt.Errorf("failed to decrypt vault key: %w", ErrAuthFailed)
 	}
 
 	return plaintext, nil
 }
 
-// Encrypt encrypts data using XChaCha20-Poly1305
-func Encrypt(plaintext []byte, key []byte) ([]byte, []byte, error) {
-	aead, err := chacha20poly1305.NewX(key)
+// VaultAAD returns the associated data that binds a vault ciphertext to its
+// vault ID and schema version
+func VaultAAD(vaultID string, schemaVersion int) []byte {
+	return []byte(fmt.Sprintf("vaultctl:%s:%d", vaultID, schemaVersion))
+}
+
+// Encrypt encrypts data using the named AEAD cipher, authenticating aad alongside it
+func Encrypt(plaintext []byte, key []byte, cipherName string, aad []byte) ([]byte, []byte, error) {
+	aead, err := newAEAD(cipherName, key)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
-	nonce := make([]byte, NonceSize)
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
+	if err := nonces.use(key, nonce); err != nil {
+		return nil, nil, err
+	}
 
-	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
+	ciphertext := aead.Seal(nil, nonce, plaintext, aad)
 	return ciphertext, nonce, nil
 }
 
-// Decrypt decrypts data using XChaCha20-Poly1305
-func Decrypt(ciphertext []byte, nonce []byte, key []byte) ([]byte, error) {
-	aead, err := chacha20poly1305.NewX(key)
+// Decrypt decrypts data using the named AEAD cipher. aad must match the value passed to Encrypt.
+func Decrypt(ciphertext []byte, nonce []byte, key []byte, cipherName string, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(cipherName, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
-	if len(nonce) != NonceSize {
+	if len(nonce) != aead.NonceSize() {
 		return nil, errors.New("invalid nonce size")
 	}
 
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt: %w", err)
+		return nil, fmt.Errorf("failed to decrypt: %w", ErrAuthFailed)
 	}
 
 	return plaintext, nil
 }
 
+// DeriveSubkey derives a purpose-specific 32-byte key from key using HKDF-SHA256
+func DeriveSubkey(key []byte, info string) ([]byte, error) {
+	subkey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(info)), subkey); err != nil {
+		return nil, fmt.Errorf("failed to derive subkey: %w", err)
+	}
+	return subkey, nil
+}
+
+// ComputeHMAC returns the HMAC-SHA256 of data under key
+func ComputeHMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
 // EncodeBase64 encodes bytes to base64 string
 func EncodeBase64(data []byte) string {
 	return base64.StdEncoding.EncodeToString(data)
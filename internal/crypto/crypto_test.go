@@ -0,0 +1,25 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecryptWrongKey(t *testing.T) {
+	key, err := GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, nonce, err := Encrypt([]byte("secret"), key, CipherXChaCha20Poly1305, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Decrypt(ciphertext, nonce, otherKey, CipherXChaCha20Poly1305, nil); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Decrypt with another key error = %v, want %v", err, ErrAuthFailed)
+	}
+}
@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"math"
+	"strings"
+)
+
+// PasswordEntropy estimates the entropy of a password in bits as
+// length * log2(pool), where pool is the combined size of the character
+// classes the password draws from. Characters repeated beyond the first
+// occurrence only count for half, so "aaaaaaaa" scores lower than "abcdefgh".
+func PasswordEntropy(password []byte) float64 {
+	if len(password) == 0 {
+		return 0
+	}
+
+	var upper, lower, digit, symbol, other bool
+	for _, c := range password {
+		switch {
+		case strings.IndexByte(UppercaseChars, c) >= 0:
+			upper = true
+		case strings.IndexByte(LowercaseChars, c) >= 0:
+			lower = true
+		case strings.IndexByte(DigitChars, c) >= 0:
+			digit = true
+		case c >= 0x20 && c < 0x7f:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	if upper {
+		pool += len(UppercaseChars)
+	}
+	if lower {
+		pool += len(LowercaseChars)
+	}
+	if digit {
+		pool += len(DigitChars)
+	}
+	if symbol {
+		pool += 33 // printable ASCII symbols, including space
+	}
+	if other {
+		pool += 128 // bytes outside printable ASCII
+	}
+
+	seen := make(map[byte]bool, len(password))
+	effective := 0.0
+	for _, c := range password {
+		if seen[c] {
+			effective += 0.5
+		} else {
+			seen[c] = true
+			effective++
+		}
+	}
+
+	return effective * math.Log2(float64(pool))
+}
@@ -0,0 +1,25 @@
+package crypto
+
+import "testing"
+
+func TestMeetsRecommended(t *testing.T) {
+	tests := []struct {
+		name   string
+		params KDFParams
+		want   bool
+	}{
+		{"default", DefaultKDFParams(), true},
+		{"at recommended", KDFParams{Algo: AlgoArgon2id, Memory: RecommendedMemory, Iterations: RecommendedIterations, Parallelism: 1}, true},
+		{"above recommended", KDFParams{Algo: AlgoArgon2id, Memory: 2 * RecommendedMemory, Iterations: 10, Parallelism: 4}, true},
+		{"memory too low", KDFParams{Algo: AlgoArgon2id, Memory: RecommendedMemory - 1, Iterations: RecommendedIterations, Parallelism: 1}, false},
+		{"iterations too low", KDFParams{Algo: AlgoArgon2id, Memory: RecommendedMemory, Iterations: RecommendedIterations - 1, Parallelism: 1}, false},
+		{"hard minimum", KDFParams{Algo: AlgoArgon2id, Memory: MinMemory, Iterations: MinIterations, Parallelism: MinParallelism}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.MeetsRecommended(); got != tt.want {
+				t.Errorf("MeetsRecommended(%+v) = %v, want %v", tt.params, got, tt.want)
+			}
+		})
+	}
+}
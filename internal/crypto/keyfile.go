@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeyFileSize is the size of a generated key file, and the smallest file
+// accepted as one
+const KeyFileSize = 32
+
+// keyFileIDSize is the length of the fingerprint stored in the envelope
+const keyFileIDSize = 8
+
+// HKDF info strings for key file derivations
+const (
+	keyFileCombineInfo = "vaultctl key file v1"
+	keyFileIDInfo      = "vaultctl key file id v1"
+)
+
+// GenerateKeyFile returns the contents of a new random key file
+func GenerateKeyFile() ([]byte, error) {
+	key := make([]byte, KeyFileSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key file: %w", err)
+	}
+	return key, nil
+}
+
+// KeyFileID returns a short fingerprint of a key file, used to tell a wrong
+// key file from a wrong password. It reveals nothing useful about the file.
+func KeyFileID(keyFile []byte) ([]byte, error) {
+	digest := sha256.Sum256(keyFile)
+	defer Zeroize(digest[:])
+
+	id := make([]byte, keyFileIDSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, digest[:], nil, []byte(keyFileIDInfo)), id); err != nil {
+		return nil, fmt.Errorf("failed to fingerprint key file: %w", err)
+	}
+	return id, nil
+}
+
+// CombineKeyFile derives the key that wraps the vault key from both the
+// password-derived master key and a key file, so neither opens the vault
+// alone
+func CombineKeyFile(masterKey, keyFile []byte) ([]byte, error) {
+	digest := sha256.Sum256(keyFile)
+	defer Zeroize(digest[:])
+
+	secret := make([]byte, 0, len(masterKey)+len(digest))
+	secret = append(secret, masterKey...)
+	secret = append(secret, digest[:]...)
+	defer Zeroize(secret)
+
+	key := make([]byte, MasterKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(keyFileCombineInfo)), key); err != nil {
+		return nil, fmt.Errorf("failed to combine key file: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package crypto
+
+// LockMemory is a no-op on platforms without mlock support
+func LockMemory(b []byte) error {
+	return nil
+}
+
+// UnlockMemory is a no-op on platforms without mlock support
+func UnlockMemory(b []byte) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package crypto
+
+import "golang.org/x/sys/unix"
+
+// LockMemory pins a buffer in physical memory so it cannot be paged to swap
+func LockMemory(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return unix.Mlock(b)
+}
+
+// UnlockMemory releases a buffer previously pinned with LockMemory
+func UnlockMemory(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return unix.Munlock(b)
+}
@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sync"
+)
+
+// ErrNonceReuse is returned when encryption would reuse a nonce with the
+// same key. With random 24 or 12 byte nonces this only happens if the random
+// source or the caller is broken.
+var ErrNonceReuse = errors.New("nonce reused with the same key")
+
+// maxNoncesPerKey bounds how many recent nonces are remembered for each key
+const maxNoncesPerKey = 4096
+
+// nonceHistory is the recently used nonces of one key, oldest first
+type nonceHistory struct {
+	order []string
+	seen  map[string]struct{}
+}
+
+// nonceGuard enforces that a process never encrypts twice under the same
+// key and nonce. Keys are tracked by a SHA-256 fingerprint, not stored.
+type nonceGuard struct {
+	mu   sync.Mutex
+	keys map[[sha256.Size]byte]*nonceHistory
+}
+
+var nonces = &nonceGuard{keys: make(map[[sha256.Size]byte]*nonceHistory)}
+
+// use records nonce as used with key, or returns ErrNonceReuse if it already was
+func (g *nonceGuard) use(key, nonce []byte) error {
+	var fingerprint [sha256.Size]byte
+	h := sha256.New()
+	h.Write([]byte("vaultctl nonce guard:"))
+	h.Write(key)
+	h.Sum(fingerprint[:0])
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	history := g.keys[fingerprint]
+	if history == nil {
+		history = &nonceHistory{seen: make(map[string]struct{})}
+		g.keys[fingerprint] = history
+	}
+
+	n := string(nonce)
+	if _, ok := history.seen[n]; ok {
+		return ErrNonceReuse
+	}
+	if len(history.order) == maxNoncesPerKey {
+		delete(history.seen, history.order[0])
+		history.order = history.order[1:]
+	}
+	history.order = append(history.order, n)
+	history.seen[n] = struct{}{}
+	return nil
+}
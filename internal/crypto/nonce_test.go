@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestEncryptUsesDistinctNonces(t *testing.T) {
+	const n = 1000
+	for _, cipherName := range []string{CipherXChaCha20Poly1305, CipherAES256GCM} {
+		t.Run(cipherName, func(t *testing.T) {
+			key, err := GenerateVaultKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			plaintext := []byte("the same plaintext every time")
+			aad := []byte("aad")
+
+			seen := make(map[string]bool, n)
+			for i := 0; i < n; i++ {
+				ciphertext, nonce, err := Encrypt(plaintext, key, cipherName, aad)
+				if err != nil {
+					t.Fatalf("Encrypt %d: %v", i, err)
+				}
+				if seen[string(nonce)] {
+					t.Fatalf("nonce %x reused after %d encryptions", nonce, i)
+				}
+				seen[string(nonce)] = true
+
+				got, err := Decrypt(ciphertext, nonce, key, cipherName, aad)
+				if err != nil || !bytes.Equal(got, plaintext) {
+					t.Fatalf("Decrypt %d = %q, %v", i, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNonceGuardRejectsReuse(t *testing.T) {
+	g := &nonceGuard{keys: make(map[[sha256.Size]byte]*nonceHistory)}
+	key := bytes.Repeat([]byte{1}, VaultKeySize)
+	otherKey := bytes.Repeat([]byte{2}, VaultKeySize)
+	nonce := bytes.Repeat([]byte{3}, NonceSize)
+
+	if err := g.use(key, nonce); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := g.use(key, nonce); !errors.Is(err, ErrNonceReuse) {
+		t.Errorf("second use with the same key = %v, want ErrNonceReuse", err)
+	}
+	if err := g.use(otherKey, nonce); err != nil {
+		t.Errorf("use with another key: %v", err)
+	}
+}
+
+func TestNonceGuardForgetsOldest(t *testing.T) {
+	g := &nonceGuard{keys: make(map[[sha256.Size]byte]*nonceHistory)}
+	key := bytes.Repeat([]byte{1}, VaultKeySize)
+	nonce := func(i int) []byte {
+		return []byte{byte(i >> 16), byte(i >> 8), byte(i)}
+	}
+
+	for i := 0; i <= maxNoncesPerKey; i++ {
+		if err := g.use(key, nonce(i)); err != nil {
+			t.Fatalf("use %d: %v", i, err)
+		}
+	}
+	// The first nonce was evicted to stay within the bound; the last wasn't
+	if err := g.use(key, nonce(0)); err != nil {
+		t.Errorf("evicted nonce: %v", err)
+	}
+	if err := g.use(key, nonce(maxNoncesPerKey)); !errors.Is(err, ErrNonceReuse) {
+		t.Errorf("recent nonce = %v, want ErrNonceReuse", err)
+	}
+}
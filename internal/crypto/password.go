@@ -0,0 +1,194 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultPasswordLength is the length used when none is specified
+	DefaultPasswordLength = 20
+
+	// Character classes for password generation
+	UppercaseChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	LowercaseChars = "abcdefghijklmnopqrstuvwxyz"
+	DigitChars     = "0123456789"
+	SymbolChars    = "!@#$%^&*()-_=+[]{};:,.<>?/~"
+
+	// AmbiguousChars are characters that are easily confused with each other
+	AmbiguousChars = "Il1O0o|`'\""
+)
+
+// MaxGenerateAttempts bounds the retries GeneratePassword makes to draw a
+// password that contains every required class
+const MaxGenerateAttempts = 10000
+
+// Policy controls the passwords GeneratePassword produces. Every enabled
+// class is required: a generated password has at least one character of it.
+type Policy struct {
+	MinLength        int    `json:"min_length,omitempty"`
+	Uppercase        bool   `json:"uppercase"`
+	Lowercase        bool   `json:"lowercase"`
+	Digits           bool   `json:"digits"`
+	Symbols          bool   `json:"symbols"`
+	SymbolSet        string `json:"symbol_set"` // Symbols allowed when Symbols is set
+	ExcludeAmbiguous bool   `json:"exclude_ambiguous,omitempty"`
+}
+
+// DefaultPolicy returns a policy with every character class enabled and the
+// full symbol set
+func DefaultPolicy() Policy {
+	return Policy{
+		Uppercase: true,
+		Lowercase: true,
+		Digits:    true,
+		Symbols:   true,
+		SymbolSet: SymbolChars,
+	}
+}
+
+// Length returns the length to generate when none is asked for: the default,
+// or the policy's minimum if that is longer
+func (p Policy) Length() int {
+	return max(DefaultPasswordLength, p.MinLength)
+}
+
+// classes returns the character set of each enabled class
+func (p Policy) classes() []string {
+	var classes []string
+	if p.Uppercase {
+		classes = append(classes, UppercaseChars)
+	}
+	if p.Lowercase {
+		classes = append(classes, LowercaseChars)
+	}
+	if p.Digits {
+		classes = append(classes, DigitChars)
+	}
+	if p.Symbols {
+		classes = append(classes, dedupe(p.SymbolSet))
+	}
+	if p.ExcludeAmbiguous {
+		for i, class := range classes {
+			classes[i] = removeChars(class, AmbiguousChars)
+		}
+	}
+	return classes
+}
+
+// Validate checks that a password of the given length can satisfy the policy
+func (p Policy) Validate(length int) error {
+	if !p.Uppercase && !p.Lowercase && !p.Digits && !p.Symbols {
+		return errors.New("at least one character class must be enabled")
+	}
+	for _, r := range p.SymbolSet {
+		if r > unicode.MaxASCII || !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			return fmt.Errorf("symbol set may only contain ASCII punctuation, got %q", r)
+		}
+	}
+	if p.MinLength < 0 {
+		return fmt.Errorf("minimum length can't be negative")
+	}
+	if p.Symbols && p.SymbolSet == "" {
+		return errors.New("symbols are required but the symbol set is empty")
+	}
+	for _, class := range p.classes() {
+		if class == "" {
+			return errors.New("a required character class is empty once ambiguous characters are excluded")
+		}
+	}
+	if length < p.MinLength {
+		return fmt.Errorf("password length %d is below the policy minimum of %d", length, p.MinLength)
+	}
+	if n := len(p.classes()); length < n {
+		return fmt.Errorf("password length must be at least %d for the selected character classes", n)
+	}
+	return nil
+}
+
+// GeneratePassword generates a random password of the given length using
+// crypto/rand. At least one character from each class the policy enables is
+// included.
+//
+// Characters are drawn uniformly from the combined set and passwords missing
+// a required class are rejected and drawn again, so every password that
+// satisfies the policy is equally likely.
+func GeneratePassword(length int, policy Policy) ([]byte, error) {
+	if err := policy.Validate(length); err != nil {
+		return nil, err
+	}
+	classes := policy.classes()
+	all := strings.Join(classes, "")
+
+	password := make([]byte, length)
+	for attempt := 0; attempt < MaxGenerateAttempts; attempt++ {
+		for i := range password {
+			c, err := randomChar(all)
+			if err != nil {
+				Zeroize(password)
+				return nil, err
+			}
+			password[i] = c
+		}
+		if hasEveryClass(password, classes) {
+			return password, nil
+		}
+	}
+	Zeroize(password)
+	return nil, fmt.Errorf("failed to generate a password with every required class in %d attempts; use a longer length", MaxGenerateAttempts)
+}
+
+// hasEveryClass reports whether password contains a character of each class
+func hasEveryClass(password []byte, classes []string) bool {
+	for _, class := range classes {
+		if !bytes.ContainsAny(password, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// randomChar returns a uniformly random byte from charset
+func randomChar(charset string) (byte, error) {
+	i, err := randomInt(len(charset))
+	if err != nil {
+		return 0, err
+	}
+	return charset[i], nil
+}
+
+// randomInt returns a uniformly random int in [0, n)
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
+// removeChars returns s with every character in remove dropped
+func removeChars(s, remove string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(remove, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// dedupe drops repeated characters from s so none is drawn more often than
+// the others
+func dedupe(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if !strings.ContainsRune(b.String(), r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
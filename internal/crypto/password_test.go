@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePasswordHasEveryRequiredClass(t *testing.T) {
+	type classes struct{ upper, lower, digits, symbols bool }
+	var combinations []classes
+	for mask := 1; mask < 16; mask++ {
+		combinations = append(combinations, classes{mask&1 != 0, mask&2 != 0, mask&4 != 0, mask&8 != 0})
+	}
+
+	for _, c := range combinations {
+		for _, symbolSet := range []string{SymbolChars, "-_"} {
+			for _, excludeAmbiguous := range []bool{false, true} {
+				policy := Policy{
+					Uppercase:        c.upper,
+					Lowercase:        c.lower,
+					Digits:           c.digits,
+					Symbols:          c.symbols,
+					SymbolSet:        symbolSet,
+					ExcludeAmbiguous: excludeAmbiguous,
+				}
+				if !c.symbols && symbolSet != SymbolChars {
+					continue // The symbol set doesn't matter without symbols
+				}
+				name := fmt.Sprintf("upper=%t,lower=%t,digits=%t,symbols=%t,set=%q,unambiguous=%t",
+					c.upper, c.lower, c.digits, c.symbols, symbolSet, excludeAmbiguous)
+				t.Run(name, func(t *testing.T) {
+					// The shortest possible length is the hardest case
+					for _, length := range []int{len(policy.classes()), 8, 32} {
+						for i := 0; i < 50; i++ {
+							password, err := GeneratePassword(length, policy)
+							if err != nil {
+								t.Fatalf("GeneratePassword(%d): %v", length, err)
+							}
+							checkPassword(t, string(password), length, policy)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// checkPassword fails t unless password has the length, a character of
+// every required class and only characters the policy allows
+func checkPassword(t *testing.T, password string, length int, policy Policy) {
+	t.Helper()
+	if len(password) != length {
+		t.Fatalf("password %q has length %d, want %d", password, len(password), length)
+	}
+	required := map[string]bool{
+		UppercaseChars:   policy.Uppercase,
+		LowercaseChars:   policy.Lowercase,
+		DigitChars:       policy.Digits,
+		policy.SymbolSet: policy.Symbols,
+	}
+	for class, want := range required {
+		if want && !strings.ContainsAny(password, class) {
+			t.Fatalf("password %q has no character of required class %q", password, class)
+		}
+	}
+	allowed := strings.Join(policy.classes(), "")
+	for _, r := range password {
+		if !strings.ContainsRune(allowed, r) {
+			t.Fatalf("password %q contains %q, which the policy doesn't allow", password, r)
+		}
+		if policy.ExcludeAmbiguous && strings.ContainsRune(AmbiguousChars, r) {
+			t.Fatalf("password %q contains ambiguous %q", password, r)
+		}
+	}
+}
+
+func TestGeneratePasswordRejectsImpossiblePolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		policy Policy
+	}{
+		{"no classes", 20, Policy{}},
+		{"symbols without a set", 20, Policy{Lowercase: true, Symbols: true}},
+		{"non-ASCII symbol", 20, Policy{Symbols: true, SymbolSet: "€"}},
+		{"letter as symbol", 20, Policy{Symbols: true, SymbolSet: "a"}},
+		{"only ambiguous symbols", 20, Policy{Symbols: true, SymbolSet: "|`", ExcludeAmbiguous: true}},
+		{"below minimum length", 10, Policy{Lowercase: true, MinLength: 12}},
+		{"negative minimum length", 10, Policy{Lowercase: true, MinLength: -1}},
+		{"shorter than the classes", 3, DefaultPolicy()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if password, err := GeneratePassword(tt.length, tt.policy); err == nil {
+				t.Errorf("GeneratePassword = %q, want an error", password)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// EncryptedFormat identifies passphrase-encrypted export files
+const EncryptedFormat = "vaultctl-encrypted-export"
+
+// ErrNotEncrypted is returned when data is not an encrypted export
+var ErrNotEncrypted = errors.New("not an encrypted vaultctl export")
+
+// EncryptedExport wraps an export with a key derived from a passphrase.
+// It is self-contained: the salt and KDF parameters travel with the data.
+type EncryptedExport struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	Salt       string           `json:"salt"`
+	KDFParams  crypto.KDFParams `json:"kdf_params"`
+	Cipher     string           `json:"cipher"`
+	Nonce      string           `json:"nonce"`
+	Ciphertext string           `json:"ciphertext"`
+}
+
+// exportAAD binds the ciphertext to the export format and version
+func exportAAD(version int) []byte {
+	return []byte(fmt.Sprintf("%s:%d", EncryptedFormat, version))
+}
+
+// Encrypt encrypts plaintext under a key derived from passphrase with a fresh salt
+func Encrypt(plaintext, passphrase []byte) ([]byte, error) {
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	params := crypto.DefaultKDFParams()
+	key, err := crypto.DeriveMasterKey(passphrase, salt, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive export key: %w", err)
+	}
+	defer crypto.Zeroize(key)
+
+	ciphertext, nonce, err := crypto.Encrypt(plaintext, key, crypto.CipherXChaCha20Poly1305, exportAAD(DocumentVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt export: %w", err)
+	}
+
+	ee := EncryptedExport{
+		Format:     EncryptedFormat,
+		Version:    DocumentVersion,
+		Salt:       crypto.EncodeBase64(salt),
+		KDFParams:  params,
+		Cipher:     crypto.CipherXChaCha20Poly1305,
+		Nonce:      crypto.EncodeBase64(nonce),
+		Ciphertext: crypto.EncodeBase64(ciphertext),
+	}
+	data, err := json.MarshalIndent(ee, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encrypted export: %w", err)
+	}
+	return data, nil
+}
+
+// IsEncrypted reports whether data looks like an encrypted export
+func IsEncrypted(data []byte) bool {
+	var probe struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Format == EncryptedFormat
+}
+
+// Decrypt reverses Encrypt
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	var ee EncryptedExport
+	if err := json.Unmarshal(data, &ee); err != nil || ee.Format != EncryptedFormat {
+		return nil, ErrNotEncrypted
+	}
+	if err := ee.KDFParams.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid export kdf parameters: %w", err)
+	}
+
+	salt, err := crypto.DecodeBase64(ee.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	nonce, err := crypto.DecodeBase64(ee.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nonce: %w", err)
+	}
+	ciphertext, err := crypto.DecodeBase64(ee.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	key, err := crypto.DeriveMasterKey(passphrase, salt, ee.KDFParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive export key: %w", err)
+	}
+	defer crypto.Zeroize(key)
+
+	plaintext, err := crypto.Decrypt(ciphertext, nonce, key, ee.Cipher, exportAAD(ee.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt export (wrong passphrase?): %w", err)
+	}
+	return plaintext, nil
+}
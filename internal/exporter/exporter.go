@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// CSVHeader is the fixed column order of CSV exports
+var CSVHeader = []string{"name", "username", "password", "url", "notes"}
+
+// Entry is the plaintext form of a vault entry in JSON exports
+type Entry struct {
+	Name        string              `json:"name"`
+	Type        vault.EntryType     `json:"type"`
+	Username    string              `json:"username"`
+	Password    string              `json:"password"`
+	URL         string              `json:"url"`
+	Notes       string              `json:"notes"`
+	BackupCodes []string            `json:"backup_codes,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Fields      []vault.CustomField `json:"fields,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// Document is the top-level structure of a JSON export
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Entries    []Entry   `json:"entries"`
+}
+
+// DocumentVersion is the current JSON export format version
+const DocumentVersion = 1
+
+// NewDocument builds a JSON export document from vault entries
+func NewDocument(entries []vault.Entry) *Document {
+	doc := &Document{
+		Version:    DocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Entries:    make([]Entry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		doc.Entries = append(doc.Entries, Entry{
+			Name:        e.Name,
+			Type:        e.Type,
+			Username:    e.Username,
+			Password:    string(e.Password),
+			URL:         e.URL,
+			Notes:       e.Notes,
+			BackupCodes: e.BackupCodes,
+			Tags:        e.Tags,
+			Fields:      e.Fields,
+			CreatedAt:   e.CreatedAt,
+			UpdatedAt:   e.UpdatedAt,
+		})
+	}
+	return doc
+}
+
+// MarshalJSON serializes entries as an indented JSON export document
+func MarshalJSON(entries []vault.Entry) ([]byte, error) {
+	data, err := json.MarshalIndent(NewDocument(entries), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export: %w", err)
+	}
+	return data, nil
+}
+
+// ParseJSON parses a plaintext JSON export document
+func ParseJSON(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse export: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > DocumentVersion {
+		return nil, fmt.Errorf("unsupported export version %d (this vaultctl reads up to %d)", doc.Version, DocumentVersion)
+	}
+	for i, e := range doc.Entries {
+		if e.Name == "" {
+			return nil, fmt.Errorf("export entry %d has no name", i+1)
+		}
+	}
+	return &doc, nil
+}
+
+// WriteCSV writes entries as CSV with the CSVHeader column order
+func WriteCSV(w io.Writer, entries []vault.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, e := range entries {
+		record := []string{e.Name, e.Username, string(e.Password), e.URL, e.Notes}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv record: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
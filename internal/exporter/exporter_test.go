@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+func TestParseJSONRoundTrip(t *testing.T) {
+	v := vault.NewVault()
+	v.AddEntryWith("github", "alice", []byte("hunter2"), "https://github.com", "notes", []string{"111-222"}, []string{"work"}, vault.EntryOptions{
+		Fields: []vault.CustomField{{Name: "pin", Value: "1234", Secret: true}},
+	})
+
+	data, err := MarshalJSON(v.Entries)
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+	doc, err := ParseJSON(data)
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("parsed %d entries, want 1", len(doc.Entries))
+	}
+	got := doc.Entries[0]
+	if got.Name != "github" || got.Username != "alice" || got.Password != "hunter2" || got.URL != "https://github.com" || got.Notes != "notes" {
+		t.Errorf("parsed entry = %+v", got)
+	}
+	if len(got.BackupCodes) != 1 || len(got.Tags) != 1 || len(got.Fields) != 1 || !got.Fields[0].Secret {
+		t.Errorf("parsed entry lost backup codes, tags or fields: %+v", got)
+	}
+}
+
+func TestParseJSONRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"malformed", `{"version": 1, "entries": [`},
+		{"no version", `{"entries": []}`},
+		{"newer version", `{"version": 2, "entries": []}`},
+		{"unnamed entry", `{"version": 1, "entries": [{"name": "github"}, {"username": "alice"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJSON([]byte(tt.data)); err == nil {
+				t.Errorf("ParseJSON(%s) succeeded", tt.data)
+			}
+		})
+	}
+}
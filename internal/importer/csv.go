@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Default column mappings for known password manager CSV exports
+var (
+	lastPassMapping = map[string]string{
+		FieldName:     "name",
+		FieldUsername: "username",
+		FieldPassword: "password",
+		FieldURL:      "url",
+		FieldNotes:    "extra",
+		FieldTags:     "grouping",
+	}
+	bitwardenMapping = map[string]string{
+		FieldName:     "name",
+		FieldUsername: "login_username",
+		FieldPassword: "login_password",
+		FieldURL:      "login_uri",
+		FieldNotes:    "notes",
+		FieldTags:     "folder",
+	}
+	onePasswordMapping = map[string]string{
+		FieldName:     "title",
+		FieldUsername: "username",
+		FieldPassword: "password",
+		FieldURL:      "url",
+		FieldNotes:    "notes",
+		FieldTags:     "tags",
+	}
+)
+
+// headerAliases are the column names recognised when a field is not mapped
+// explicitly, in order of preference
+var headerAliases = map[string][]string{
+	FieldName:     {"name", "title", "account"},
+	FieldUsername: {"username", "login_username", "login", "user", "email"},
+	FieldPassword: {"password", "login_password", "pass"},
+	FieldURL:      {"url", "login_uri", "website", "uri"},
+	FieldNotes:    {"notes", "note", "extra", "comments"},
+	FieldTags:     {"tags", "grouping", "folder", "group"},
+}
+
+// CSVAdapter reads CSV files with a header row
+type CSVAdapter struct {
+	// Mapping maps record fields to header names. Fields that are not mapped
+	// are auto-detected from common header names.
+	Mapping map[string]string
+}
+
+// Parse reads records from CSV. Rows without a name fall back to the URL.
+func (a *CSVAdapter) Parse(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("csv file is empty")
+		}
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns, err := a.resolveColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv line %d: %w", line, err)
+		}
+
+		get := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		record := Record{
+			Name:     get(FieldName),
+			Username: get(FieldUsername),
+			Password: []byte(get(FieldPassword)),
+			URL:      get(FieldURL),
+			Notes:    get(FieldNotes),
+			Tags:     splitTags(get(FieldTags)),
+		}
+		if record.Name == "" {
+			record.Name = record.URL
+		}
+		if record.Name == "" {
+			return nil, fmt.Errorf("csv line %d has no name or url", line)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// resolveColumns maps each record field to its column index
+func (a *CSVAdapter) resolveColumns(header []string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, h := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range Fields {
+		if column, ok := a.Mapping[field]; ok {
+			i, found := index[strings.ToLower(column)]
+			if !found {
+				return nil, fmt.Errorf("column %q mapped to %s not found in csv header", column, field)
+			}
+			columns[field] = i
+			continue
+		}
+		for _, alias := range headerAliases[field] {
+			if i, found := index[alias]; found {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	if _, ok := columns[FieldPassword]; !ok {
+		return nil, errors.New("could not find a password column; use a column mapping such as password=<column>")
+	}
+	if _, ok := columns[FieldName]; !ok {
+		if _, ok := columns[FieldURL]; !ok {
+			return nil, errors.New("could not find a name or url column; use a column mapping such as name=<column>")
+		}
+	}
+	return columns, nil
+}
+
+// splitTags splits a tag or folder column. Folder paths like "Work/Email"
+// become a single tag.
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
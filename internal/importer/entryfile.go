@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// entrySpec is one entry in an entry file
+type entrySpec struct {
+	Name     string   `json:"name" yaml:"name"`
+	Username string   `json:"username" yaml:"username"`
+	Password string   `json:"password" yaml:"password"`
+	Generate bool     `json:"generate" yaml:"generate"`
+	URL      string   `json:"url" yaml:"url"`
+	Notes    string   `json:"notes" yaml:"notes"`
+	Tags     []string `json:"tags" yaml:"tags"`
+}
+
+// isYAML reports whether path names a YAML entry file
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// ParseEntryFile reads an array of entry specs, each with a name and either
+// a password or "generate": true. The file at path is YAML if its name ends
+// in .yaml or .yml, and JSON otherwise. Only a malformed file is an error;
+// check each record with Validate so one bad entry doesn't stop the rest.
+func ParseEntryFile(path string, r io.Reader) ([]Record, error) {
+	var specs []entrySpec
+	if isYAML(path) {
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&specs); err != nil {
+			return nil, fmt.Errorf("failed to parse entry file: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&specs); err != nil {
+			return nil, fmt.Errorf("failed to parse entry file: %w", err)
+		}
+	}
+
+	records := make([]Record, 0, len(specs))
+	for _, spec := range specs {
+		records = append(records, Record{
+			Name:     strings.TrimSpace(spec.Name),
+			Username: spec.Username,
+			Password: []byte(spec.Password),
+			URL:      spec.URL,
+			Notes:    spec.Notes,
+			Tags:     spec.Tags,
+			Generate: spec.Generate,
+		})
+	}
+	return records, nil
+}
+
+// Validate checks that a record has a name and exactly one password source
+func (r Record) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.Generate && len(r.Password) > 0 {
+		return errors.New("give either a password or generate, not both")
+	}
+	if !r.Generate && len(r.Password) == 0 {
+		return errors.New("a password or generate is required")
+	}
+	return nil
+}
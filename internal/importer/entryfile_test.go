@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEntryFile(t *testing.T) {
+	want := []Record{
+		{Name: "github", Username: "alice", Password: []byte("hunter2"), URL: "https://github.com", Tags: []string{"work"}},
+		{Name: "bank", Password: []byte{}, Notes: "checking", Generate: true},
+	}
+	tests := []struct {
+		path string
+		data string
+	}{
+		{"entries.json", `[
+			{"name": " github ", "username": "alice", "password": "hunter2", "url": "https://github.com", "tags": ["work"]},
+			{"name": "bank", "generate": true, "notes": "checking"}
+		]`},
+		{"entries.yaml", `
+- name: " github "
+  username: alice
+  password: hunter2
+  url: https://github.com
+  tags: [work]
+- name: bank
+  generate: true
+  notes: checking
+`},
+		// YAML is a superset of JSON, but the extension decides
+		{"entries.YML", `[{"name": " github ", "username": "alice", "password": "hunter2", "url": "https://github.com", "tags": ["work"]},
+			{"name": "bank", "generate": true, "notes": "checking"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			records, err := ParseEntryFile(tt.path, strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("ParseEntryFile: %v", err)
+			}
+			if !reflect.DeepEqual(records, want) {
+				t.Errorf("records = %+v, want %+v", records, want)
+			}
+		})
+	}
+}
+
+func TestParseEntryFileRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		data string
+	}{
+		{"not an array", "entries.json", `{"name": "github"}`},
+		{"unknown field", "entries.json", `[{"name": "github", "pasword": "hunter2"}]`},
+		{"truncated", "entries.json", `[{"name": "github"`},
+		{"YAML in a JSON file", "entries.json", "- name: github\n"},
+		{"YAML not a list", "entries.yaml", "name: github\n"},
+		{"YAML unknown field", "entries.yaml", "- name: github\n  pasword: hunter2\n"},
+		{"YAML empty", "entries.yml", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseEntryFile(tt.path, strings.NewReader(tt.data)); err == nil {
+				t.Errorf("ParseEntryFile(%s, %q) succeeded", tt.path, tt.data)
+			}
+		})
+	}
+}
+
+func TestRecordValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  Record
+		wantErr bool
+	}{
+		{"password", Record{Name: "github", Password: []byte("hunter2")}, false},
+		{"generate", Record{Name: "github", Generate: true}, false},
+		{"no name", Record{Password: []byte("hunter2")}, true},
+		{"both", Record{Name: "github", Password: []byte("hunter2"), Generate: true}, true},
+		{"neither", Record{Name: "github"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.record.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Record is a single entry read from another password manager's export
+type Record struct {
+	Name     string
+	Username string
+	Password []byte
+	URL      string
+	Notes    string
+	Tags     []string
+	Generate bool // Generate a password instead of using Password
+}
+
+// Adapter parses one export format into records
+type Adapter interface {
+	Parse(r io.Reader) ([]Record, error)
+}
+
+// Importable record fields, used as keys in column mappings
+const (
+	FieldName     = "name"
+	FieldUsername = "username"
+	FieldPassword = "password"
+	FieldURL      = "url"
+	FieldNotes    = "notes"
+	FieldTags     = "tags"
+)
+
+// Fields lists every record field a column can be mapped to
+var Fields = []string{FieldName, FieldUsername, FieldPassword, FieldURL, FieldNotes, FieldTags}
+
+// Supported import formats
+const (
+	FormatCSV       = "csv"
+	FormatLastPass  = "lastpass"
+	FormatBitwarden = "bitwarden-csv"
+	Format1Password = "1password-csv"
+)
+
+// adapters maps format names to constructors taking an optional column mapping
+var adapters = map[string]func(mapping map[string]string) Adapter{
+	FormatCSV: func(mapping map[string]string) Adapter {
+		return &CSVAdapter{Mapping: mapping}
+	},
+	FormatLastPass: func(mapping map[string]string) Adapter {
+		return &CSVAdapter{Mapping: withDefaults(mapping, lastPassMapping)}
+	},
+	FormatBitwarden: func(mapping map[string]string) Adapter {
+		return &CSVAdapter{Mapping: withDefaults(mapping, bitwardenMapping)}
+	},
+	Format1Password: func(mapping map[string]string) Adapter {
+		return &CSVAdapter{Mapping: withDefaults(mapping, onePasswordMapping)}
+	},
+}
+
+// Formats returns the supported format names, sorted
+func Formats() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAdapter returns the adapter for format. mapping maps record fields to
+// source column names and overrides the format's defaults; it may be nil.
+func NewAdapter(format string, mapping map[string]string) (Adapter, error) {
+	newAdapter, ok := adapters[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported import format: %s (supported: %s)", format, strings.Join(Formats(), ", "))
+	}
+	for field := range mapping {
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field in column mapping: %s (valid fields: %s)", field, strings.Join(Fields, ", "))
+		}
+	}
+	return newAdapter(mapping), nil
+}
+
+// ParseMapping parses FIELD=COLUMN pairs into a column mapping
+func ParseMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range pairs {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q: expected FIELD=COLUMN", pair)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// withDefaults returns defaults overridden by mapping
+func withDefaults(mapping, defaults map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(mapping))
+	for field, column := range defaults {
+		merged[field] = column
+	}
+	for field, column := range mapping {
+		merged[field] = column
+	}
+	return merged
+}
+
+func isField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
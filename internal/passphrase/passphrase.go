@@ -0,0 +1,63 @@
+// Package passphrase generates diceware-style passphrases from an embedded
+// list of 2048 short, common English words.
+package passphrase
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// DefaultWords is the number of words used when none is specified. Six
+	// words give 66 bits of entropy.
+	DefaultWords = 6
+
+	// DefaultSeparator joins the words
+	DefaultSeparator = "-"
+
+	// BitsPerWord is the entropy each uniformly chosen word adds: log2(2048)
+	BitsPerWord = 11
+)
+
+//go:embed wordlist.txt
+var wordlist string
+
+var words = strings.Fields(wordlist)
+
+// Bits returns the entropy of a passphrase of n words
+func Bits(n int) int {
+	return n * BitsPerWord
+}
+
+// GeneratePassphrase picks n words uniformly at random from the wordlist
+// using crypto/rand and joins them with sep
+func GeneratePassphrase(n int, sep string) ([]byte, error) {
+	if n < 1 {
+		return nil, errors.New("a passphrase needs at least one word")
+	}
+
+	chosen := make([]string, n)
+	size := len(sep) * (n - 1)
+	for i := range chosen {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate random number: %w", err)
+		}
+		chosen[i] = words[j.Int64()]
+		size += len(chosen[i])
+	}
+
+	// Sized up front so no partial copy is left behind by a reallocation
+	passphrase := make([]byte, 0, size)
+	for i, word := range chosen {
+		if i > 0 {
+			passphrase = append(passphrase, sep...)
+		}
+		passphrase = append(passphrase, word...)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,64 @@
+// Package prompt reads secrets from the terminal.
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Bracketed paste escape sequences. A terminal in bracketed paste mode wraps
+// pasted text in them, and term.ReadPassword passes them through as input.
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+
+	disableBracketedPaste = "\x1b[?2004l"
+)
+
+// ReadPassword prints label and reads a line from the terminal without
+// echoing it. Bracketed paste is turned off first and any paste markers the
+// terminal sends anyway are removed, so a pasted password matches what was
+// copied.
+func ReadPassword(label string) ([]byte, error) {
+	return ReadPasswordTo(os.Stdout, label)
+}
+
+// ReadPasswordTo is ReadPassword printing the label to w instead of stdout,
+// for commands whose stdout is meant to be captured
+func ReadPasswordTo(w io.Writer, label string) ([]byte, error) {
+	fmt.Fprint(w, label)
+	// Shells turn bracketed paste back on for their own prompt
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(w, disableBracketedPaste)
+	}
+
+	password, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(w)
+	if err != nil {
+		return nil, err
+	}
+	return StripPasteMarkers(password), nil
+}
+
+// StripPasteMarkers removes bracketed paste markers from b in place and
+// returns the shortened slice. The bytes left over at the end are zeroed.
+func StripPasteMarkers(b []byte) []byte {
+	n := len(b)
+	for _, marker := range [][]byte{pasteStart, pasteEnd} {
+		for {
+			i := bytes.Index(b[:n], marker)
+			if i < 0 {
+				break
+			}
+			copy(b[i:], b[i+len(marker):n])
+			n -= len(marker)
+		}
+	}
+	clear(b[n:])
+	return b[:n]
+}
@@ -0,0 +1,37 @@
+package prompt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStripPasteMarkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no markers", "hunter2", "hunter2"},
+		{"wrapped", "\x1b[200~hunter2\x1b[201~", "hunter2"},
+		{"start only", "\x1b[200~hunter2", "hunter2"},
+		{"end only", "hunter2\x1b[201~", "hunter2"},
+		{"typed around a paste", "ab\x1b[200~cd\x1b[201~ef", "abcdef"},
+		{"two pastes", "\x1b[200~ab\x1b[201~\x1b[200~cd\x1b[201~", "abcd"},
+		{"only markers", "\x1b[200~\x1b[201~", ""},
+		{"other escape kept", "\x1b[A\x1b[200~pw\x1b[201~", "\x1b[Apw"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := []byte(tt.input)
+			got := StripPasteMarkers(b)
+			if string(got) != tt.want {
+				t.Errorf("StripPasteMarkers(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			// The bytes past the result are wiped, not left as a copy
+			if tail := b[len(got):]; !bytes.Equal(tail, make([]byte, len(tail))) {
+				t.Errorf("StripPasteMarkers(%q) left %q after the result", tt.input, tail)
+			}
+		})
+	}
+}
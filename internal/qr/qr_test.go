@@ -0,0 +1,221 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// formatInfoM holds the format information words for error correction level
+// M with masks 0 to 7, from ISO/IEC 18004 table C.1
+var formatInfoM = [8]int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+
+// versionInfo holds the version information words of some versions, from
+// ISO/IEC 18004 table D.1
+var versionInfo = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3, 11: 0x0BBF6, 40: 0x28C69}
+
+// readBits reads n modules as bits, the first module giving bit 0
+func readBits(c *Code, n int, pos func(i int) (x, y int)) int {
+	bits := 0
+	for i := 0; i < n; i++ {
+		if c.Dark(pos(i)) {
+			bits |= 1 << i
+		}
+	}
+	return bits
+}
+
+// formatInfo returns both copies of the code's format information
+func formatInfo(c *Code) (first, second int) {
+	first = readBits(c, 15, func(i int) (int, int) {
+		switch {
+		case i < 6:
+			return 8, i
+		case i < 8:
+			return 8, i + 1
+		case i == 8:
+			return 7, 8
+		}
+		return 14 - i, 8
+	})
+	second = readBits(c, 15, func(i int) (int, int) {
+		if i < 8 {
+			return c.Size - 1 - i, 8
+		}
+		return 8, c.Size - 15 + i
+	})
+	return first, second
+}
+
+// versionInfoBits returns both copies of the code's version information
+func versionInfoBits(c *Code) (first, second int) {
+	first = readBits(c, 18, func(i int) (int, int) { return c.Size - 11 + i%3, i / 3 })
+	second = readBits(c, 18, func(i int) (int, int) { return i / 3, c.Size - 11 + i%3 })
+	return first, second
+}
+
+func TestEncodeVersionAndLevel(t *testing.T) {
+	// Byte mode capacities at level M are 14, 26, 42, 62, 84, 106, 122,
+	// 152, 180, 213 ... 2331 bytes for versions 1 to 10 ... 40
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{0, 1}, {1, 1}, {14, 1}, {15, 2}, {26, 2}, {27, 3}, {42, 3}, {43, 4},
+		{62, 4}, {63, 5}, {84, 5}, {85, 6}, {106, 6}, {107, 7}, {122, 7},
+		{123, 8}, {152, 8}, {153, 9}, {180, 9}, {181, 10}, {213, 10},
+		{214, 11}, {2331, 40},
+	}
+	for _, tt := range tests {
+		c, err := Encode(bytes.Repeat([]byte("a"), tt.length))
+		if err != nil {
+			t.Errorf("Encode(%d bytes): %v", tt.length, err)
+			continue
+		}
+		if want := 4*tt.version + 17; c.Size != want {
+			t.Errorf("%d bytes: size %d, want %d (version %d)", tt.length, c.Size, want, tt.version)
+			continue
+		}
+
+		first, second := formatInfo(c)
+		if first != second {
+			t.Errorf("%d bytes: format information copies differ: %#x, %#x", tt.length, first, second)
+		}
+		found := false
+		for _, word := range formatInfoM {
+			found = found || first == word
+		}
+		if !found {
+			t.Errorf("%d bytes: format information %#x is not level M", tt.length, first)
+		}
+
+		if want, ok := versionInfo[tt.version]; ok {
+			first, second := versionInfoBits(c)
+			if first != want || second != want {
+				t.Errorf("%d bytes: version information %#x, %#x, want %#x", tt.length, first, second, want)
+			}
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(bytes.Repeat([]byte("a"), 2332)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode(2332 bytes) error = %v, want ErrTooLong", err)
+	}
+}
+
+// Reference matrices from github.com/skip2/go-qrcode at level Medium,
+// without the quiet zone. Encoders may pick different masks where the
+// penalty rules leave room; these are payloads where both pick the same.
+var referenceCodes = []struct {
+	data   string
+	matrix string
+}{
+	{"otpauth://totp/alice", `
+#######..#.#####..#######
+#.....#.#.#..#.#..#.....#
+#.###.#.....#.....#.###.#
+#.###.#..####.#...#.###.#
+#.###.#.#..#..##..#.###.#
+#.....#....##..##.#.....#
+#######.#.#.#.#.#.#######
+.........#######.........
+#.#.#.#..#..##..#...#..#.
+####.#.#.#..##..####.#..#
+#.#..##.#.#......#.#..###
+........#.#..##.#.#.#..#.
+...#.####..#..#####....##
+....#...#..#......##.#..#
+#.##.##..#...#..###..####
+.#.......###...#.#.##...#
+#.###.##..#.#...######.#.
+........##.###..#...#..##
+#######....##.###.#.#####
+#.....#...#.###.#...#..#.
+#.###.#.####..#.######.##
+#.###.#..#.#..##....#....
+#.###.#.#.#..#.##...#.#.#
+#.....#..#.#....#.##...#.
+#######.##..#..###.....##
+`},
+	{"otpauth://totp/vaultctl:alice@example.com?secret=jbswy3dpehpk3pxpjbswy3dpehpk3pxp&issuer=vaultctl&algorithm=sha1&digits=6&period=30", `
+#######..###.##.......###..#...#.##.....#.#######
+#.....#.#.##...##.#.#.#....##.#####.#####.#.....#
+#.###.#.##..#.##.#...##.#..####.#..#...##.#.###.#
+#.###.#.#.#..###.##.####.#.##.#.#..###.#..#.###.#
+#.###.#..#....#.###############....###....#.###.#
+#.....#.....##.##...#.#...#....##.#.###...#.....#
+#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######
+........##....#.#..####...#.#.###.##.#.##........
+#.....#.#####.###.#.#.#####...##...##...###..###.
+##..#...#.#..###.#####...##.###.#.##..###.###....
+#.##..#..#..#..####.#..#..##....#.#####..#.##.###
+##.###.#..#.#.##...#.#.#..#.######.#.#.#.##.#..#.
+#...#.#.###.....#.#.#..###..##..#.#.#.#..####..##
+#.####.....####..##....###.#.#####.#.#.#.##..#.#.
+#....###...#..#.####...#..###....###..#.#.....###
+##.##..####.......#.#.##...##.###.##..#.###..###.
+#.##.##.##.#.#...##.#..###.#.##..#..#.#.####.###.
+#.......##....##.#.....#.#.#..##...###.#.#####...
+###.#.####..##...##.#.#.#..##.#####..#..##.####.#
+...###..#######..##..#.#...###..#....#.#.#####.##
+.#.#######..##..##..######....##...##..##..#..#..
+..####..#..##.#.#..##.###.##..#.###..##.######...
+##..#####.#.######.#..#####.##.#..###.#.#####.###
+#####...#.#.#....#.#.##...###...#..#.####...##..#
+.#.##.#.#.#...##...#.##.#.#.#...#...#.#.#.#.##.##
+#...#...##.#..#.##.#.##...#...##.#.#.#.##...#....
+.#.######..##...#.....########.#.##.#.#######.###
+.......#.###.##.#..#..#####...#..#...###...####..
+.#######..###..#.......###...###.#..##.#.####.##.
+#.#....#.#.####.#.###.#.##...##..#..##........#..
+####.##.#..#.##.#.##....#.#..#..#..##..#.##...#.#
+#..###..##.##.###.####..#....#...###..###....#.#.
+##.#..#.#.#...#.#####.#.....#..####.##.##..##.#.#
+.....#.###..#...#...#####....#...#..#####.###.#..
+##.##.#.#..#.##....#.##.##..#.####.##.##..#..#.##
+####.....###.###.....#...##.#..###.#.#####..##..#
+....#.#.#.##.##..###.#....#.##..#.#.#..####..#..#
+.#.#...#.#..###.####..###..#######.###.#.#.#.###.
+.#...##..#.....#.##...##.#.......###.###.####..##
+.###...##..######...#...#..#..#..###...###.#..#.#
+###...#..###...#....#.#####..#.#.#.##.#######.#..
+........#####.######..#...#..##..#...#..#...#....
+#######..#....#..######.#.###..##..###..#.#.#####
+#.....#..#..#.#...#...#...#.##.###.#....#...##.##
+#.###.#...###...#..#..#####...##.#.##.#.#####.###
+#.###.#..#...#.##...##...#.####.###.#.##...#.##.#
+#.###.#..#.#..#....#.####.##...########..###.#...
+#.....#..#.##..###......####..##.....##.####.#..#
+#######.##..#.###.##..#######.###.#.#...#..##...#
+`},
+}
+
+func TestEncodeMatchesReference(t *testing.T) {
+	for _, ref := range referenceCodes {
+		rows := strings.Fields(ref.matrix)
+		c, err := Encode([]byte(ref.data))
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", ref.data, err)
+		}
+		if c.Size != len(rows) {
+			t.Errorf("Encode(%q) size = %d, want %d", ref.data, c.Size, len(rows))
+			continue
+		}
+		mismatches := 0
+		for y, row := range rows {
+			for x, module := range row {
+				if c.Dark(x, y) != (module == '#') {
+					if mismatches < 5 {
+						t.Errorf("Encode(%q): module %d,%d dark = %v, want %v", ref.data, x, y, c.Dark(x, y), module == '#')
+					}
+					mismatches++
+				}
+			}
+		}
+		if mismatches > 0 {
+			t.Errorf("Encode(%q): %d modules differ from the reference", ref.data, mismatches)
+		}
+	}
+}
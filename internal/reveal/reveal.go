@@ -0,0 +1,75 @@
+package reveal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal is returned by Overlay when stdin or stdout isn't a terminal
+var ErrNotTerminal = errors.New("not an interactive terminal")
+
+// Line is a labelled secret shown by Show
+type Line struct {
+	Label string
+	Value []byte
+}
+
+// IsTerminal reports whether an overlay can be shown on stdin and stdout
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Overlay draws text on the terminal's alternate screen and clears it when a
+// key is pressed, so nothing is left in scrollback
+func Overlay(text []byte) error {
+	if !IsTerminal() {
+		return ErrNotTerminal
+	}
+
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, oldState)
+
+	// Raw mode doesn't translate newlines
+	screen := bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n"))
+	defer crypto.Zeroize(screen)
+
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l\x1b[H")
+	os.Stdout.Write(screen)
+	os.Stdout.WriteString("\r\nPress any key to clear\r\n")
+
+	key := make([]byte, 1)
+	os.Stdin.Read(key)
+
+	os.Stdout.WriteString("\x1b[2J\x1b[?25h\x1b[?1049l")
+	return nil
+}
+
+// Show displays the lines on an overlay, or prints them to stdout if it
+// can't be shown
+func Show(lines ...Line) error {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line.Label)
+		buf.WriteString(": ")
+		buf.Write(line.Value)
+		buf.WriteByte('\n')
+	}
+	text := buf.Bytes()
+	defer crypto.Zeroize(text)
+
+	if !IsTerminal() {
+		_, err := io.Copy(os.Stdout, bytes.NewReader(text))
+		return err
+	}
+	return Overlay(text)
+}
@@ -2,7 +2,9 @@ package secrets
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -34,31 +36,109 @@ func NewSecretsManagerClient(secretName, region string) (*SecretsManagerClient,
 	}, nil
 }
 
+// ErrSecretNotFound is returned when the session key secret does not exist
+var ErrSecretNotFound = errors.New("secret not found in AWS Secrets Manager")
+
+// SessionKeySize is the size of a generated session master key
+const SessionKeySize = 32
+
 // GetSessionKey retrieves the session master key from Secrets Manager
 // The secret must exist beforehand - it will not be created automatically
 func (smc *SecretsManagerClient) GetSessionKey(ctx context.Context) ([]byte, error) {
-	// Get the secret from Secrets Manager
+	return smc.GetSecret(ctx, smc.secretName)
+}
+
+// GetSecret retrieves and decodes the value of the named secret
+func (smc *SecretsManagerClient) GetSecret(ctx context.Context, name string) ([]byte, error) {
 	result, err := smc.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(smc.secretName),
+		SecretId: aws.String(name),
 	})
 	if err != nil {
-		// Check if it's a ResourceNotFoundException
-		if _, ok := err.(*types.ResourceNotFoundException); ok {
-			return nil, fmt.Errorf("secret '%s' not found in AWS Secrets Manager. Please create it first", smc.secretName)
-		}
-		// Check error code as fallback
-		if err, ok := err.(interface{ ErrorCode() string }); ok && err.ErrorCode() == "ResourceNotFoundException" {
-			return nil, fmt.Errorf("secret '%s' not found in AWS Secrets Manager. Please create it first", smc.secretName)
+		if isNotFound(err) {
+			return nil, fmt.Errorf("secret '%s': %w", name, ErrSecretNotFound)
 		}
 		return nil, fmt.Errorf("failed to get secret: %w", err)
 	}
+	if result.SecretString == nil {
+		return nil, fmt.Errorf("secret '%s' has no string value", name)
+	}
 
 	// Decode the secret value (stored as base64)
-	key, err := base64.StdEncoding.DecodeString(*result.SecretString)
+	value, err := base64.StdEncoding.DecodeString(*result.SecretString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode secret: %w", err)
 	}
 
+	return value, nil
+}
+
+// PutSecret stores value in the named secret, creating the secret if it
+// does not exist yet
+func (smc *SecretsManagerClient) PutSecret(ctx context.Context, name string, value []byte) error {
+	encoded := aws.String(base64.StdEncoding.EncodeToString(value))
+
+	_, err := smc.client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: encoded,
+	})
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("failed to update secret: %w", err)
+	}
+
+	_, err = smc.client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: encoded,
+		Description:  aws.String("vaultctl remote session"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	return nil
+}
+
+// DeleteSecret deletes the named secret immediately, without a recovery
+// window. A secret that does not exist is not an error.
+func (smc *SecretsManagerClient) DeleteSecret(ctx context.Context, name string) error {
+	_, err := smc.client.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(name),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return nil
+}
+
+// GetOrCreateSessionKey retrieves the session master key, creating the
+// secret with a random key if it does not exist yet
+func (smc *SecretsManagerClient) GetOrCreateSessionKey(ctx context.Context) ([]byte, error) {
+	key, err := smc.GetSessionKey(ctx)
+	if err == nil || !errors.Is(err, ErrSecretNotFound) {
+		return key, err
+	}
+
+	key = make([]byte, SessionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+
+	_, err = smc.client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(smc.secretName),
+		SecretString: aws.String(base64.StdEncoding.EncodeToString(key)),
+		Description:  aws.String("vaultctl session master key"),
+	})
+	if err != nil {
+		// Another process created it first; use theirs
+		var existsErr *types.ResourceExistsException
+		if errors.As(err, &existsErr) {
+			return smc.GetSessionKey(ctx)
+		}
+		return nil, fmt.Errorf("failed to create secret: %w", err)
+	}
+
 	return key, nil
 }
 
@@ -67,21 +147,17 @@ func (smc *SecretsManagerClient) IsAvailable(ctx context.Context) bool {
 	_, err := smc.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
 		SecretId: aws.String(smc.secretName),
 	})
-	if err != nil {
-		// Check if it's a ResourceNotFoundException (secret doesn't exist, but SM is available)
-		if _, ok := err.(*types.ResourceNotFoundException); ok {
-			return true
-		}
-		// Check error code as fallback
-		if err, ok := err.(interface{ ErrorCode() string }); ok {
-			code := err.ErrorCode()
-			if code == "ResourceNotFoundException" {
-				return true
-			}
-		}
-		// Other errors might indicate Secrets Manager is not available
-		return false
-	}
-	return true
+	// A missing secret still means Secrets Manager is reachable
+	return err == nil || isNotFound(err)
 }
 
+// isNotFound reports whether err is a ResourceNotFoundException
+func isNotFound(err error) bool {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return true
+	}
+	// Check error code as fallback
+	var coded interface{ ErrorCode() string }
+	return errors.As(err, &coded) && coded.ErrorCode() == "ResourceNotFoundException"
+}
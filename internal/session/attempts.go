@@ -0,0 +1,105 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/atomic"
+)
+
+// Limits on wrong master passwords. They are kept in a local file, so
+// someone who can delete it can reset them: they slow down guessing at the
+// prompt, not an attack on a copied vault file.
+const (
+	// FreeUnlockAttempts wrong passwords are allowed before delays start
+	FreeUnlockAttempts = 3
+	// LockoutAttempts wrong passwords lock unlocking for LockoutDuration
+	LockoutAttempts = 10
+	// LockoutDuration is how long unlocking stays locked after
+	// LockoutAttempts wrong passwords, and after each one after that
+	LockoutDuration = 15 * time.Minute
+)
+
+// ErrTooManyAttempts is returned by UnlockDelay while unlocking is locked
+// after too many wrong master passwords
+var ErrTooManyAttempts = errors.New("too many wrong master passwords")
+
+// unlockAttempts is the failed unlock counter kept next to the session file
+type unlockAttempts struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// UnlockAttemptsPath returns the path of the failed unlock counter
+func (sm *SessionManager) UnlockAttemptsPath() string {
+	return filepath.Join(filepath.Dir(sm.sessionPath), "unlock_attempts.json")
+}
+
+// loadUnlockAttempts reads the failed unlock counter. A missing or unreadable
+// file counts as no failures.
+func (sm *SessionManager) loadUnlockAttempts() unlockAttempts {
+	var attempts unlockAttempts
+	data, err := os.ReadFile(sm.UnlockAttemptsPath())
+	if err != nil || json.Unmarshal(data, &attempts) != nil {
+		return unlockAttempts{}
+	}
+	return attempts
+}
+
+// UnlockDelay returns how long to wait before the master password may be
+// tried again. After FreeUnlockAttempts wrong passwords the wait doubles
+// with each one, from 2s; after LockoutAttempts it returns
+// ErrTooManyAttempts until LockoutDuration has passed.
+func (sm *SessionManager) UnlockDelay(now time.Time) (time.Duration, error) {
+	attempts := sm.loadUnlockAttempts()
+	if attempts.Failures >= LockoutAttempts {
+		if left := attempts.LastFailure.Add(LockoutDuration).Sub(now); left > 0 {
+			return 0, fmt.Errorf("%w: try again in %s", ErrTooManyAttempts, left.Round(time.Second))
+		}
+		return 0, nil
+	}
+	if attempts.Failures < FreeUnlockAttempts {
+		return 0, nil
+	}
+
+	delay := time.Second << (attempts.Failures - FreeUnlockAttempts + 1)
+	return max(attempts.LastFailure.Add(delay).Sub(now), 0), nil
+}
+
+// FailedUnlocks returns how many wrong master passwords were given since
+// the last successful unlock
+func (sm *SessionManager) FailedUnlocks() int {
+	return sm.loadUnlockAttempts().Failures
+}
+
+// RecordFailedUnlock counts a wrong master password
+func (sm *SessionManager) RecordFailedUnlock() error {
+	attempts := sm.loadUnlockAttempts()
+	attempts.Failures++
+	attempts.LastFailure = time.Now()
+
+	data, err := json.Marshal(attempts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal unlock attempts: %w", err)
+	}
+	if err := atomic.EnsureDir(filepath.Dir(sm.sessionPath), 0700); err != nil {
+		return err
+	}
+	if err := atomic.WriteFile(sm.UnlockAttemptsPath(), data, SessionFileMode); err != nil {
+		return fmt.Errorf("failed to write unlock attempts: %w", err)
+	}
+	return nil
+}
+
+// ResetUnlockAttempts clears the failed unlock counter after the right
+// master password was given
+func (sm *SessionManager) ResetUnlockAttempts() error {
+	if err := os.Remove(sm.UnlockAttemptsPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to reset unlock attempts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUnlockDelay(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	delay, err := sm.UnlockDelay(time.Now())
+	if err != nil || delay != 0 {
+		t.Fatalf("UnlockDelay with no failures = %v, %v; want 0, nil", delay, err)
+	}
+
+	for i := 0; i < FreeUnlockAttempts; i++ {
+		if err := sm.RecordFailedUnlock(); err != nil {
+			t.Fatalf("RecordFailedUnlock: %v", err)
+		}
+	}
+	lastFailure := sm.loadUnlockAttempts().LastFailure
+	if delay, err := sm.UnlockDelay(lastFailure); err != nil || delay != 2*time.Second {
+		t.Errorf("UnlockDelay after %d failures = %v, %v; want 2s, nil", FreeUnlockAttempts, delay, err)
+	}
+	if delay, err := sm.UnlockDelay(lastFailure.Add(time.Minute)); err != nil || delay != 0 {
+		t.Errorf("UnlockDelay once the delay passed = %v, %v; want 0, nil", delay, err)
+	}
+
+	if err := sm.RecordFailedUnlock(); err != nil {
+		t.Fatalf("RecordFailedUnlock: %v", err)
+	}
+	lastFailure = sm.loadUnlockAttempts().LastFailure
+	if delay, _ := sm.UnlockDelay(lastFailure); delay != 4*time.Second {
+		t.Errorf("UnlockDelay after %d failures = %v, want 4s", FreeUnlockAttempts+1, delay)
+	}
+
+	for sm.FailedUnlocks() < LockoutAttempts {
+		if err := sm.RecordFailedUnlock(); err != nil {
+			t.Fatalf("RecordFailedUnlock: %v", err)
+		}
+	}
+	lastFailure = sm.loadUnlockAttempts().LastFailure
+	if _, err := sm.UnlockDelay(lastFailure.Add(time.Minute)); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("UnlockDelay during lockout error = %v, want %v", err, ErrTooManyAttempts)
+	}
+	if delay, err := sm.UnlockDelay(lastFailure.Add(LockoutDuration)); err != nil || delay != 0 {
+		t.Errorf("UnlockDelay after lockout = %v, %v; want 0, nil", delay, err)
+	}
+
+	if err := sm.ResetUnlockAttempts(); err != nil {
+		t.Fatalf("ResetUnlockAttempts: %v", err)
+	}
+	if n := sm.FailedUnlocks(); n != 0 {
+		t.Errorf("FailedUnlocks after reset = %d, want 0", n)
+	}
+}
@@ -3,30 +3,96 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/vaultctl/vaultctl/internal/atomic"
 	"github.com/vaultctl/vaultctl/internal/crypto"
 	"github.com/vaultctl/vaultctl/internal/secrets"
 )
 
 const (
-	// Default session timeout (30 minutes)
+	// Default session timeout (30 minutes of inactivity)
 	DefaultSessionTimeout = 30 * time.Minute
 	// Session file permissions (read/write for user only)
 	SessionFileMode = 0600
+	// Sessions are cleared if the system slept longer than this while unlocked
+	DefaultSleepThreshold = 1 * time.Minute
 )
 
+// ErrSleptWhileUnlocked is returned by LoadSession when the system was
+// suspended for longer than the sleep threshold since the session was last used
+var ErrSleptWhileUnlocked = errors.New("session locked because the system slept")
+
+// ErrSessionInMemory is returned by SaveSession when the session directory
+// isn't writable. The vault key is then kept in memory, so the session only
+// lasts as long as the process.
+var ErrSessionInMemory = errors.New("session kept in memory only")
+
+// ErrSessionExpired is returned by LoadSession and Touch once the session
+// timeout has passed
+var ErrSessionExpired = errors.New("session expired")
+
+// ErrSessionStale is returned when the session's vault key no longer opens
+// the vault, because the vault key was replaced, e.g. by rotate-key in
+// another process or on another device
+var ErrSessionStale = errors.New("session is stale: the vault key was changed since it was unlocked")
+
+// ErrSessionKeySource is returned by LoadSession when the session master key
+// can't be had from where the session was saved with, e.g. Secrets Manager
+// is no longer configured or reachable. The session is cleared.
+var ErrSessionKeySource = errors.New("session master key is unavailable")
+
+// Where the session master key comes from, recorded in SessionData
+const (
+	KeySourceSecretsManager = "secrets_manager" // AWS Secrets Manager
+	KeySourceLocal          = "local"           // Derived from the home directory and user name
+)
+
+// ErrNoRunner is returned by SaveRemoteSession when no runner is set
+var ErrNoRunner = errors.New("no runner ID set for a remote session")
+
 // SessionData represents the encrypted session data
 type SessionData struct {
+	Version           int       `json:"version,omitempty"`   // 2: session key also wrapped with the session token
 	EncryptedVaultKey string    `json:"encrypted_vault_key"` // base64
 	Nonce             string    `json:"nonce"`               // base64
 	SessionKey        string    `json:"session_key"`         // base64 - encrypted session key
 	SessionKeyNonce   string    `json:"session_key_nonce"`   // base64 - nonce for session key encryption
 	CreatedAt         time.Time `json:"created_at"`
 	ExpiresAt         time.Time `json:"expires_at"`
+	AwakeClock        int64     `json:"awake_clock_ns,omitempty"` // System awake time when last used
+	TotalClock        int64     `json:"total_clock_ns,omitempty"` // Time since boot, including sleep, when last used
+	KeySource         string    `json:"key_source,omitempty"`     // Where the session master key comes from
+}
+
+// recordClocks stores the current system clocks so a later load can tell how
+// long the system slept in between
+func (sd *SessionData) recordClocks() {
+	if awake, total, ok := systemClocks(); ok {
+		sd.AwakeClock = int64(awake)
+		sd.TotalClock = int64(total)
+	}
+}
+
+// sleptSince returns how long the system was suspended since the clocks were
+// recorded. rebooted is true if the clocks went backwards.
+func (sd *SessionData) sleptSince() (slept time.Duration, rebooted, ok bool) {
+	if sd.TotalClock == 0 {
+		return 0, false, false
+	}
+	awake, total, ok := systemClocks()
+	if !ok {
+		return 0, false, false
+	}
+	if int64(total) < sd.TotalClock {
+		return 0, true, true
+	}
+	return (total - time.Duration(sd.TotalClock)) - (awake - time.Duration(sd.AwakeClock)), false, true
 }
 
 // SessionManager handles session management
@@ -35,50 +101,144 @@ type SessionManager struct {
 	sessionKey    []byte
 	timeout       time.Duration
 	secretsClient *secrets.SecretsManagerClient
+	secretsProbed bool // Whether Secrets Manager availability has been checked
 	useSecretsMgr bool
+	sleepLimit    time.Duration
+	memoryKey     []byte // Vault key held when the session can't be saved to disk
+	secretName    string
+	runnerSecret  string // Secret holding this runner's remote session, if set
+	remoteLoaded  bool   // Whether the current session came from runnerSecret
+	readOnly      bool   // Leave the session file as is when loading or touching it
 }
 
-// NewSessionManager creates a new session manager
+// NewSessionManager creates a new session manager. If secretName and region
+// are set, the session master key is kept in AWS Secrets Manager; whether it
+// is reachable is checked on first use.
 func NewSessionManager(sessionPath string, timeout time.Duration, secretName, region string) *SessionManager {
 	sm := &SessionManager{
 		sessionPath:   sessionPath,
 		timeout:       timeout,
 		useSecretsMgr: false,
+		sleepLimit:    DefaultSleepThreshold,
+		secretName:    secretName,
 	}
 
-	// Try to initialize Secrets Manager client
 	if secretName != "" && region != "" {
 		client, err := secrets.NewSecretsManagerClient(secretName, region)
 		if err == nil {
-			// Check if Secrets Manager is available
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if client.IsAvailable(ctx) {
-				sm.secretsClient = client
-				sm.useSecretsMgr = true
-			}
+			sm.secretsClient = client
 		}
 	}
 
 	return sm
 }
 
-// getMasterKey retrieves the master key from AWS Secrets Manager or falls back to local derivation
-func (sm *SessionManager) getMasterKey(ctx context.Context) ([]byte, error) {
-	// Try to use AWS Secrets Manager first
-	if sm.useSecretsMgr && sm.secretsClient != nil {
-		key, err := sm.secretsClient.GetSessionKey(ctx)
-		if err == nil {
-			return key, nil
+// SetSleepThreshold sets how long the system may sleep while unlocked before
+// the session is cleared. 0 disables sleep detection.
+func (sm *SessionManager) SetSleepThreshold(d time.Duration) {
+	sm.sleepLimit = d
+}
+
+// SetReadOnly makes LoadSession and Touch leave the session untouched: an
+// expired or unusable session is reported but not removed, and activity
+// doesn't extend it. ClearSession still removes it.
+func (sm *SessionManager) SetReadOnly(readOnly bool) {
+	sm.readOnly = readOnly
+}
+
+// discard clears a session LoadSession found unusable, unless read-only
+func (sm *SessionManager) discard() {
+	if !sm.readOnly {
+		sm.ClearSession()
+	}
+}
+
+// SetRunner enables remote sessions for the runner with the given ID. The
+// session is kept in the Secrets Manager secret <secret name>/runners/<ID>,
+// so an IAM policy can restrict each role to its own runners' sessions.
+func (sm *SessionManager) SetRunner(runnerID string) error {
+	if sm.secretName == "" {
+		return errors.New("remote sessions need session_secret_name and aws_region in config")
+	}
+	for _, r := range runnerID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_+=.@-", r)) {
+			return fmt.Errorf("runner ID may only contain letters, digits and _+=.@-, got %q", runnerID)
+		}
+	}
+	sm.runnerSecret = sm.secretName + "/runners/" + runnerID
+	return nil
+}
+
+// RemoteSessionName returns the name of the secret holding the runner's
+// remote session, or "" if no runner is set
+func (sm *SessionManager) RemoteSessionName() string {
+	return sm.runnerSecret
+}
+
+// secretsAvailable reports whether Secrets Manager can be used, checking
+// once per process
+func (sm *SessionManager) secretsAvailable(ctx context.Context) bool {
+	if sm.secretsClient == nil {
+		return false
+	}
+	if !sm.secretsProbed {
+		probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		sm.useSecretsMgr = sm.secretsClient.IsAvailable(probeCtx)
+		sm.secretsProbed = true
+	}
+	return sm.useSecretsMgr
+}
+
+// getMasterKey retrieves the master key for a new session, and where it came
+// from. It comes from AWS Secrets Manager, created there if needed. Local
+// derivation is only used when no secret name is configured or Secrets
+// Manager cannot be reached at all: if it is reachable but the lookup fails,
+// falling back would protect the session file with guessable data, so an
+// error is returned instead.
+func (sm *SessionManager) getMasterKey(ctx context.Context) ([]byte, string, error) {
+	if sm.secretsAvailable(ctx) {
+		key, err := sm.secretsClient.GetOrCreateSessionKey(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to retrieve session key from AWS Secrets Manager: %w", err)
+		}
+		return key, KeySourceSecretsManager, nil
+	}
+
+	key, err := sm.deriveLocalMasterKey()
+	if err != nil {
+		return nil, "", err
+	}
+	return key, KeySourceLocal, nil
+}
+
+// masterKeyFrom retrieves the master key of a saved session from the source
+// it was saved with, rather than from whatever is reachable now
+func (sm *SessionManager) masterKeyFrom(ctx context.Context, source string) ([]byte, error) {
+	switch source {
+	case KeySourceSecretsManager:
+		if sm.secretsClient == nil {
+			return nil, fmt.Errorf("%w: the session was saved with AWS Secrets Manager, which is not configured", ErrSessionKeySource)
+		}
+		key, err := sm.secretsClient.GetOrCreateSessionKey(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to retrieve it from AWS Secrets Manager: %v", ErrSessionKeySource, err)
 		}
-		// If Secrets Manager fails, fall back to local derivation (for backward compatibility)
-		fmt.Fprintf(os.Stderr, "Warning: Failed to retrieve session key from AWS Secrets Manager: %v. Falling back to local derivation.\n", err)
+		return key, nil
+	case KeySourceLocal:
+		return sm.deriveLocalMasterKey()
 	}
+	return nil, fmt.Errorf("%w: unknown key source %q", ErrSessionKeySource, source)
+}
 
-	// Fallback: derive a master key from user-specific data (less secure, but backward compatible)
+// deriveLocalMasterKey derives a master key from user-specific data. It is
+// less secure than Secrets Manager, but needs no AWS access.
+func (sm *SessionManager) deriveLocalMasterKey() ([]byte, error) {
+	// Without a home directory, as in some containers, the session
+	// directory stands in for it
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		homeDir = filepath.Dir(sm.sessionPath)
 	}
 
 	username := os.Getenv("USER")
@@ -88,120 +248,198 @@ func (sm *SessionManager) getMasterKey(ctx context.Context) ([]byte, error) {
 
 	salt := []byte(fmt.Sprintf("%s:%s:vaultctl", homeDir, username))
 
-	key := crypto.DeriveMasterKey([]byte(homeDir+username), salt, crypto.KDFParams{
-		Algo:        "argon2id",
+	key, err := crypto.DeriveMasterKey([]byte(homeDir+username), salt, crypto.KDFParams{
+		Algo:        crypto.AlgoArgon2id,
 		Memory:      32 * 1024, // 32 MB
 		Iterations:  2,
 		Parallelism: 1,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session master key: %w", err)
+	}
 
 	return key, nil
 }
 
-// GetSessionKey gets or creates a session key, loading from session file if available
-func (sm *SessionManager) GetSessionKey(ctx context.Context) ([]byte, error) {
-	if sm.sessionKey != nil {
-		return sm.sessionKey, nil
+// SaveSession saves the vault key encrypted with a new session key. The
+// session key is wrapped with the session master key and a token kept
+// outside the home directory, so the session file alone doesn't open it.
+func (sm *SessionManager) SaveSession(ctx context.Context, vaultKey []byte) error {
+	// Fall back to an in-memory session on read-only or locked-down homes
+	dir := filepath.Dir(sm.sessionPath)
+	err := atomic.EnsureDir(dir, 0700)
+	if err != nil && !errors.Is(err, atomic.ErrNotWritable) {
+		return fmt.Errorf("failed to create session directory: %w", err)
 	}
-
-	// Try to load from session file
-	if _, err := os.Stat(sm.sessionPath); err == nil {
-		data, err := os.ReadFile(sm.sessionPath)
-		if err == nil {
-			var sessionData SessionData
-			if json.Unmarshal(data, &sessionData) == nil && sessionData.SessionKey != "" {
-				// Decrypt the session key
-				masterKey, err := sm.getMasterKey(ctx)
-				if err != nil {
-					return nil, fmt.Errorf("failed to get master key: %w", err)
-				}
-
-				encrypted, err := crypto.DecodeBase64(sessionData.SessionKey)
-				if err != nil {
-					return nil, fmt.Errorf("failed to decode session key: %w", err)
-				}
-
-				nonce, err := crypto.DecodeBase64(sessionData.SessionKeyNonce)
-				if err != nil {
-					return nil, fmt.Errorf("failed to decode session key nonce: %w", err)
-				}
-
-				sessionKey, err := crypto.Decrypt(encrypted, nonce, masterKey)
-				if err == nil {
-					sm.sessionKey = sessionKey
-					return sessionKey, nil
-				}
-			}
+	var token []byte
+	if err == nil {
+		token, err = sm.saveToken()
+		if err != nil && !errors.Is(err, atomic.ErrNotWritable) {
+			return err
 		}
 	}
-
-	// Generate new session key
-	sessionKey, err := crypto.GenerateVaultKey() // 32 bytes
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate session key: %w", err)
+		if sm.memoryKey != nil {
+			crypto.Zeroize(sm.memoryKey)
+		}
+		sm.memoryKey = append([]byte(nil), vaultKey...)
+		return fmt.Errorf("%w: %v", ErrSessionInMemory, err)
 	}
+	defer crypto.Zeroize(token)
 
+	sessionKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate session key: %w", err)
+	}
+	if sm.sessionKey != nil {
+		crypto.Zeroize(sm.sessionKey)
+	}
 	sm.sessionKey = sessionKey
-	return sessionKey, nil
-}
 
-// SaveSession saves the vault key encrypted with session key
-func (sm *SessionManager) SaveSession(ctx context.Context, vaultKey []byte) error {
-	sessionKey, err := sm.GetSessionKey(ctx)
+	sessionData, err := sm.sealSession(ctx, vaultKey, sessionKey, token, sm.timeout)
+	if err != nil {
+		return err
+	}
+	sessionData.recordClocks()
+
+	// Write session file
+	data, err := json.Marshal(sessionData)
 	if err != nil {
-		return fmt.Errorf("failed to get session key: %w", err)
+		return fmt.Errorf("failed to marshal session data: %w", err)
 	}
 
+	if err := atomic.WriteFile(sm.sessionPath, data, SessionFileMode); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return nil
+}
+
+// sealSession encrypts vaultKey with sessionKey, and sessionKey with the
+// session master key and token (if any), into session data that expires
+// after ttl
+func (sm *SessionManager) sealSession(ctx context.Context, vaultKey, sessionKey, token []byte, ttl time.Duration) (*SessionData, error) {
 	// Encrypt vault key with session key
-	encrypted, nonce, err := crypto.Encrypt(vaultKey, sessionKey)
+	encrypted, nonce, err := crypto.Encrypt(vaultKey, sessionKey, crypto.CipherXChaCha20Poly1305, nil)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt vault key: %w", err)
+		return nil, fmt.Errorf("failed to encrypt vault key: %w", err)
 	}
 
 	// Encrypt and store the session key itself (so it persists across processes)
-	masterKey, err := sm.getMasterKey(ctx)
+	masterKey, source, err := sm.getMasterKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get master key: %w", err)
+	}
+	defer crypto.Zeroize(masterKey)
+
+	wrappingKey, err := wrapKey(masterKey, token)
 	if err != nil {
-		return fmt.Errorf("failed to get master key: %w", err)
+		return nil, err
 	}
+	defer crypto.Zeroize(wrappingKey)
 
-	encryptedSessionKey, sessionKeyNonce, err := crypto.Encrypt(sessionKey, masterKey)
+	encryptedSessionKey, sessionKeyNonce, err := crypto.Encrypt(sessionKey, wrappingKey, crypto.CipherXChaCha20Poly1305, nil)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt session key: %w", err)
+		return nil, fmt.Errorf("failed to encrypt session key: %w", err)
 	}
 
+	version := 0
+	if token != nil {
+		version = tokenSessionVersion
+	}
 	now := time.Now()
-	sessionData := SessionData{
+	return &SessionData{
+		Version:           version,
 		EncryptedVaultKey: crypto.EncodeBase64(encrypted),
 		Nonce:             crypto.EncodeBase64(nonce),
 		SessionKey:        crypto.EncodeBase64(encryptedSessionKey),
 		SessionKeyNonce:   crypto.EncodeBase64(sessionKeyNonce),
 		CreatedAt:         now,
-		ExpiresAt:         now.Add(sm.timeout),
+		ExpiresAt:         now.Add(ttl),
+		KeySource:         source,
+	}, nil
+}
+
+// SaveRemoteSession stores the session in the runner's Secrets Manager
+// secret, so later invocations on any machine with the same runner ID can
+// use it until ttl passes. Unlike the session file, activity doesn't extend
+// it. The vault key is wrapped as in the session file, and the session
+// master key must come from Secrets Manager rather than local data.
+func (sm *SessionManager) SaveRemoteSession(ctx context.Context, vaultKey []byte, ttl time.Duration) error {
+	if sm.runnerSecret == "" {
+		return ErrNoRunner
+	}
+	if !sm.secretsAvailable(ctx) {
+		return errors.New("remote sessions need AWS Secrets Manager, which is not reachable")
 	}
 
-	// Ensure directory exists
-	dir := filepath.Dir(sm.sessionPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create session directory: %w", err)
+	sessionKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate session key: %w", err)
+	}
+	defer crypto.Zeroize(sessionKey)
+
+	sessionData, err := sm.sealSession(ctx, vaultKey, sessionKey, nil, ttl)
+	if err != nil {
+		return err
 	}
 
-	// Write session file
 	data, err := json.Marshal(sessionData)
 	if err != nil {
 		return fmt.Errorf("failed to marshal session data: %w", err)
 	}
+	if err := sm.secretsClient.PutSecret(ctx, sm.runnerSecret, data); err != nil {
+		return fmt.Errorf("failed to save remote session: %w", err)
+	}
+	return nil
+}
 
-	if err := os.WriteFile(sm.sessionPath, data, SessionFileMode); err != nil {
-		return fmt.Errorf("failed to write session file: %w", err)
+// loadRemoteSession loads and decrypts the vault key from the runner's
+// remote session, deleting it once it has expired
+func (sm *SessionManager) loadRemoteSession(ctx context.Context) ([]byte, error) {
+	if !sm.secretsAvailable(ctx) {
+		return nil, fmt.Errorf("no active session")
 	}
 
-	return nil
+	data, err := sm.secretsClient.GetSecret(ctx, sm.runnerSecret)
+	if errors.Is(err, secrets.ErrSecretNotFound) {
+		return nil, fmt.Errorf("no active session")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load remote session: %w", err)
+	}
+
+	var sessionData SessionData
+	if err := json.Unmarshal(data, &sessionData); err != nil {
+		return nil, fmt.Errorf("failed to parse remote session: %w", err)
+	}
+	if time.Now().After(sessionData.ExpiresAt) {
+		if !sm.readOnly {
+			sm.secretsClient.DeleteSecret(ctx, sm.runnerSecret)
+		}
+		return nil, ErrSessionExpired
+	}
+
+	vaultKey, err := sm.openSession(ctx, &sessionData, nil)
+	if err != nil {
+		return nil, err
+	}
+	sm.remoteLoaded = true
+	return vaultKey, nil
 }
 
-// LoadSession loads and decrypts the vault key from session
+// LoadSession loads and decrypts the vault key from session. Without a
+// session file, the runner's remote session is used if one is set.
 func (sm *SessionManager) LoadSession(ctx context.Context) ([]byte, error) {
+	if sm.memoryKey != nil {
+		return append([]byte(nil), sm.memoryKey...), nil
+	}
+
 	// Check if session file exists
 	if _, err := os.Stat(sm.sessionPath); os.IsNotExist(err) {
+		if sm.runnerSecret != "" {
+			return sm.loadRemoteSession(ctx)
+		}
 		return nil, fmt.Errorf("no active session")
 	}
 
@@ -218,19 +456,57 @@ func (sm *SessionManager) LoadSession(ctx context.Context) ([]byte, error) {
 
 	// Check if session expired
 	if time.Now().After(sessionData.ExpiresAt) {
-		sm.ClearSession()
-		return nil, fmt.Errorf("session expired")
+		sm.discard()
+		return nil, ErrSessionExpired
+	}
+
+	// A laptop that slept (or rebooted) while unlocked must not wake up unlocked
+	if err := sm.checkSleep(&sessionData); err != nil {
+		sm.discard()
+		return nil, err
+	}
+
+	// Sessions saved before tokens existed are only protected by the
+	// session master key; unlock again to replace them
+	if sessionData.Version < tokenSessionVersion {
+		sm.discard()
+		return nil, fmt.Errorf("no active session")
 	}
+	// Sessions saved before the key source was recorded can't tell which
+	// master key they need; unlock again to replace them
+	if sessionData.KeySource == "" {
+		sm.discard()
+		return nil, fmt.Errorf("%w: the session predates recording where its key is kept", ErrSessionKeySource)
+	}
+	token, err := sm.loadToken()
+	if err != nil {
+		if errors.Is(err, ErrSessionTokenMissing) {
+			sm.discard()
+		}
+		return nil, err
+	}
+	defer crypto.Zeroize(token)
 
+	vaultKey, err := sm.openSession(ctx, &sessionData, token)
+	if errors.Is(err, ErrSessionKeySource) {
+		sm.discard()
+	}
+	return vaultKey, err
+}
+
+// openSession decrypts the session key, with token for session files, and
+// then the vault key from session data
+func (sm *SessionManager) openSession(ctx context.Context, sessionData *SessionData, token []byte) ([]byte, error) {
 	// Decrypt the session key from session data
 	if sessionData.SessionKey == "" || sessionData.SessionKeyNonce == "" {
 		return nil, fmt.Errorf("session key not found in session data")
 	}
 
-	masterKey, err := sm.getMasterKey(ctx)
+	masterKey, err := sm.masterKeyFrom(ctx, sessionData.KeySource)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get master key: %w", err)
+		return nil, err
 	}
+	defer crypto.Zeroize(masterKey)
 
 	encrypted, err := crypto.DecodeBase64(sessionData.SessionKey)
 	if err != nil {
@@ -242,7 +518,13 @@ func (sm *SessionManager) LoadSession(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("failed to decode session key nonce: %w", err)
 	}
 
-	sessionKey, err := crypto.Decrypt(encrypted, nonce, masterKey)
+	wrappingKey, err := wrapKey(masterKey, token)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Zeroize(wrappingKey)
+
+	sessionKey, err := crypto.Decrypt(encrypted, nonce, wrappingKey, crypto.CipherXChaCha20Poly1305, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt session key: %w", err)
 	}
@@ -259,7 +541,7 @@ func (sm *SessionManager) LoadSession(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("failed to decode nonce: %w", err)
 	}
 
-	vaultKey, err := crypto.Decrypt(encryptedVaultKey, vaultKeyNonce, sessionKey)
+	vaultKey, err := crypto.Decrypt(encryptedVaultKey, vaultKeyNonce, sessionKey, crypto.CipherXChaCha20Poly1305, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt vault key: %w", err)
 	}
@@ -267,8 +549,55 @@ func (sm *SessionManager) LoadSession(ctx context.Context) ([]byte, error) {
 	return vaultKey, nil
 }
 
-// ClearSession removes the session file and zeroizes the session key
+// checkSleep returns ErrSleptWhileUnlocked if the system slept longer than the
+// sleep threshold, or rebooted, since the session was last used
+func (sm *SessionManager) checkSleep(sd *SessionData) error {
+	if sm.sleepLimit <= 0 {
+		return nil
+	}
+	slept, rebooted, ok := sd.sleptSince()
+	if !ok {
+		return nil
+	}
+	if rebooted || slept > sm.sleepLimit {
+		return ErrSleptWhileUnlocked
+	}
+	return nil
+}
+
+// ClearRemoteSession deletes the runner's remote session, if a runner is set
+func (sm *SessionManager) ClearRemoteSession(ctx context.Context) error {
+	sm.remoteLoaded = false
+	if sm.runnerSecret == "" || !sm.secretsAvailable(ctx) {
+		return nil
+	}
+	if err := sm.secretsClient.DeleteSecret(ctx, sm.runnerSecret); err != nil {
+		return fmt.Errorf("failed to remove remote session: %w", err)
+	}
+	return nil
+}
+
+// ClearSession removes the session file and zeroizes the session key. A
+// remote session is only removed if it is the one in use, e.g. because its
+// key no longer opens the vault.
 func (sm *SessionManager) ClearSession() error {
+	if sm.memoryKey != nil {
+		crypto.Zeroize(sm.memoryKey)
+		sm.memoryKey = nil
+	}
+
+	if sm.remoteLoaded {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := sm.ClearRemoteSession(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := sm.removeToken(); err != nil {
+		return err
+	}
+
 	if _, err := os.Stat(sm.sessionPath); os.IsNotExist(err) {
 		// Zeroize session key even if file doesn't exist
 		if sm.sessionKey != nil {
@@ -291,10 +620,54 @@ func (sm *SessionManager) ClearSession() error {
 	return nil
 }
 
+// Touch slides the session's expiry forward by the timeout, so the session
+// only expires after a period of inactivity
+func (sm *SessionManager) Touch() error {
+	// Remote sessions keep the TTL they were saved with
+	if sm.remoteLoaded || sm.readOnly {
+		return nil
+	}
+
+	data, err := os.ReadFile(sm.sessionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no active session")
+		}
+		return fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	var sessionData SessionData
+	if err := json.Unmarshal(data, &sessionData); err != nil {
+		return fmt.Errorf("failed to parse session file: %w", err)
+	}
+
+	now := time.Now()
+	if now.After(sessionData.ExpiresAt) {
+		return ErrSessionExpired
+	}
+	sessionData.ExpiresAt = now.Add(sm.timeout)
+	sessionData.recordClocks()
+
+	data, err = json.Marshal(sessionData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session data: %w", err)
+	}
+
+	if err := atomic.WriteFile(sm.sessionPath, data, SessionFileMode); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return nil
+}
+
 // HasActiveSession checks if there's an active session
 func (sm *SessionManager) HasActiveSession(ctx context.Context) bool {
 	vaultKey, err := sm.LoadSession(ctx)
-	return err == nil && vaultKey != nil
+	if err != nil || vaultKey == nil {
+		return false
+	}
+	crypto.Zeroize(vaultKey)
+	return true
 }
 
 // GetSessionPath returns the session file path
@@ -0,0 +1,196 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// newTestSessionManager returns a session manager with its session file and
+// token in temporary directories and no Secrets Manager
+func newTestSessionManager(t *testing.T) *SessionManager {
+	t.Helper()
+	runtimeDir := t.TempDir()
+	if err := os.Chmod(runtimeDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	return NewSessionManager(filepath.Join(t.TempDir(), "session.json"), DefaultSessionTimeout, "", "")
+}
+
+func TestSaveLoadSession(t *testing.T) {
+	sm := newTestSessionManager(t)
+	key, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := sm.SaveSession(ctx, key); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+
+	// A new process has only the session file and token to go on
+	loaded, err := NewSessionManager(sm.sessionPath, DefaultSessionTimeout, "", "").LoadSession(ctx)
+	if err != nil {
+		t.Fatalf("LoadSession: %v", err)
+	}
+	if !bytes.Equal(loaded, key) {
+		t.Errorf("loaded key = %x, want %x", loaded, key)
+	}
+
+	data, err := os.ReadFile(sm.sessionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, key) || bytes.Contains(data, []byte(crypto.EncodeBase64(key))) {
+		t.Error("session file contains the vault key in the clear")
+	}
+}
+
+func TestLoadSessionWithoutToken(t *testing.T) {
+	sm := newTestSessionManager(t)
+	key, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := sm.SaveSession(ctx, key); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+
+	// As after a reboot, which empties the runtime directory
+	if err := os.Remove(tokenPath(sm.sessionPath)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.LoadSession(ctx); !errors.Is(err, ErrSessionTokenMissing) {
+		t.Errorf("LoadSession error = %v, want ErrSessionTokenMissing", err)
+	}
+	if _, err := os.Stat(sm.sessionPath); !os.IsNotExist(err) {
+		t.Error("session file kept after its token was found missing")
+	}
+}
+
+func TestClearSession(t *testing.T) {
+	sm := newTestSessionManager(t)
+	key, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := sm.SaveSession(ctx, key); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+
+	if err := sm.ClearSession(); err != nil {
+		t.Fatalf("ClearSession: %v", err)
+	}
+	if _, err := sm.LoadSession(ctx); err == nil {
+		t.Error("LoadSession succeeded after ClearSession")
+	}
+	if _, err := os.Stat(tokenPath(sm.sessionPath)); !os.IsNotExist(err) {
+		t.Error("session token kept after ClearSession")
+	}
+}
+
+func TestLoadSessionUsesSavedKeySource(t *testing.T) {
+	sm := newTestSessionManager(t)
+	key, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := sm.SaveSession(ctx, key); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+
+	data, err := os.ReadFile(sm.sessionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sd SessionData
+	if err := json.Unmarshal(data, &sd); err != nil {
+		t.Fatal(err)
+	}
+	if sd.KeySource != KeySourceLocal {
+		t.Fatalf("key source = %q, want %q", sd.KeySource, KeySourceLocal)
+	}
+
+	// A session whose key is in Secrets Manager must not be opened with
+	// the local key just because Secrets Manager isn't available now
+	for _, source := range []string{KeySourceSecretsManager, ""} {
+		sd.KeySource = source
+		data, err := json.Marshal(sd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(sm.sessionPath, data, SessionFileMode); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sm.LoadSession(ctx); !errors.Is(err, ErrSessionKeySource) {
+			t.Errorf("LoadSession with key source %q error = %v, want ErrSessionKeySource", source, err)
+		}
+		if _, err := os.Stat(sm.sessionPath); !os.IsNotExist(err) {
+			t.Errorf("session with key source %q was not cleared", source)
+		}
+	}
+}
+
+func TestReadOnlyLeavesSession(t *testing.T) {
+	sm := newTestSessionManager(t)
+	key, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := sm.SaveSession(ctx, key); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+	before, err := os.ReadFile(sm.sessionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readOnly := NewSessionManager(sm.sessionPath, DefaultSessionTimeout, "", "")
+	readOnly.SetReadOnly(true)
+	if !readOnly.HasActiveSession(ctx) {
+		t.Fatal("HasActiveSession = false, want true")
+	}
+	if err := readOnly.Touch(); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if touched, err := os.ReadFile(sm.sessionPath); err != nil || !bytes.Equal(touched, before) {
+		t.Error("read-only Touch rewrote the session file")
+	}
+
+	// An expired session is reported but not removed
+	var sd SessionData
+	if err := json.Unmarshal(before, &sd); err != nil {
+		t.Fatal(err)
+	}
+	sd.ExpiresAt = time.Now().Add(-time.Minute)
+	expired, err := json.Marshal(sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sm.sessionPath, expired, SessionFileMode); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readOnly.LoadSession(ctx); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("LoadSession error = %v, want ErrSessionExpired", err)
+	}
+	after, err := os.ReadFile(sm.sessionPath)
+	if err != nil {
+		t.Fatalf("session file removed: %v", err)
+	}
+	if !bytes.Equal(after, expired) {
+		t.Error("read-only session manager rewrote the session file")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package session
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// systemClocks returns the time the system has been awake and the time since
+// boot including sleep. CLOCK_UPTIME_RAW stops while asleep; CLOCK_MONOTONIC_RAW does not.
+func systemClocks() (awake, total time.Duration, ok bool) {
+	var uptime, mono unix.Timespec
+	if unix.ClockGettime(unix.CLOCK_UPTIME_RAW, &uptime) != nil || unix.ClockGettime(unix.CLOCK_MONOTONIC_RAW, &mono) != nil {
+		return 0, 0, false
+	}
+	return time.Duration(uptime.Nano()), time.Duration(mono.Nano()), true
+}
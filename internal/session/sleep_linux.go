@@ -0,0 +1,19 @@
+//go:build linux
+
+package session
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// systemClocks returns the time the system has been awake and the time since
+// boot including suspend. CLOCK_MONOTONIC stops while suspended; CLOCK_BOOTTIME does not.
+func systemClocks() (awake, total time.Duration, ok bool) {
+	var mono, boot unix.Timespec
+	if unix.ClockGettime(unix.CLOCK_MONOTONIC, &mono) != nil || unix.ClockGettime(unix.CLOCK_BOOTTIME, &boot) != nil {
+		return 0, 0, false
+	}
+	return time.Duration(mono.Nano()), time.Duration(boot.Nano()), true
+}
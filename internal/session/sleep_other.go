@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package session
+
+import "time"
+
+// systemClocks is not implemented on this platform, so sleep detection is
+// disabled and sessions only expire on inactivity
+func systemClocks() (awake, total time.Duration, ok bool) {
+	return 0, 0, false
+}
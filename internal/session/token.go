@@ -0,0 +1,105 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/vaultctl/vaultctl/internal/atomic"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// sessionWrapInfo is the HKDF info string for the key that wraps the session
+// key, derived from the session master key and the session token
+const sessionWrapInfo = "vaultctl session wrap v2"
+
+// tokenSessionVersion marks session files whose session key is wrapped with
+// the session token as well as the session master key
+const tokenSessionVersion = 2
+
+// ErrSessionTokenMissing is returned by LoadSession when the session file's
+// token is gone, e.g. after a reboot or logout or on another machine
+var ErrSessionTokenMissing = errors.New("session ended: its token is gone (reboot, logout or copied session file)")
+
+// tokenPath returns where the token of the session at sessionPath is kept.
+// The per-user runtime directory is in memory and emptied at logout and
+// reboot, and is never part of a home directory backup; without one, a
+// private directory under the system temp directory is used.
+func tokenPath(sessionPath string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir != "" {
+		dir = filepath.Join(dir, "vaultctl")
+	} else {
+		username := os.Getenv("USER")
+		if username == "" {
+			username = os.Getenv("USERNAME")
+		}
+		dir = filepath.Join(os.TempDir(), "vaultctl-"+username)
+	}
+
+	// One token per session file, so vaults with their own sessions don't
+	// share one
+	sum := sha256.Sum256([]byte(sessionPath))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".token")
+}
+
+// saveToken generates a new session token and writes it next to the other
+// tokens
+func (sm *SessionManager) saveToken() ([]byte, error) {
+	path := tokenPath(sm.sessionPath)
+	dir := filepath.Dir(path)
+	if err := atomic.EnsureDir(dir, 0700); err != nil {
+		return nil, err
+	}
+	// A shared temp directory could hold a directory someone else made
+	if info, err := os.Stat(dir); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("session token directory %s is accessible to other users", dir)
+	}
+
+	token, err := crypto.GenerateVaultKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	if err := atomic.WriteFile(path, token, SessionFileMode); err != nil {
+		crypto.Zeroize(token)
+		return nil, fmt.Errorf("failed to write session token: %w", err)
+	}
+	return token, nil
+}
+
+// loadToken reads the session token
+func (sm *SessionManager) loadToken() ([]byte, error) {
+	token, err := os.ReadFile(tokenPath(sm.sessionPath))
+	if os.IsNotExist(err) {
+		return nil, ErrSessionTokenMissing
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session token: %w", err)
+	}
+	return token, nil
+}
+
+// removeToken deletes the session token, if any
+func (sm *SessionManager) removeToken() error {
+	err := os.Remove(tokenPath(sm.sessionPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session token: %w", err)
+	}
+	return nil
+}
+
+// wrapKey returns the key that wraps the session key: the session master
+// key alone for remote sessions, or combined with the session token so the
+// session file can't be opened without it
+func wrapKey(masterKey, token []byte) ([]byte, error) {
+	if token == nil {
+		return append([]byte(nil), masterKey...), nil
+	}
+	material := append(append([]byte(nil), masterKey...), token...)
+	defer crypto.Zeroize(material)
+	return crypto.DeriveSubkey(material, sessionWrapInfo)
+}
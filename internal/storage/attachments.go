@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// attachmentAAD binds an attachment ciphertext to the object it is stored in,
+// so objects can't be swapped between attachments
+func attachmentAAD(objectKey string) []byte {
+	return []byte("vaultctl attachment:" + objectKey)
+}
+
+// SealAttachment encrypts data under a fresh attachment key for storage at
+// objectKey and records the nonce and the attachment key, wrapped with the
+// vault key, in a. It returns the ciphertext to upload.
+func SealAttachment(data, vaultKey []byte, objectKey string, a *vault.Attachment) ([]byte, error) {
+	attachmentKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate attachment key: %w", err)
+	}
+	defer crypto.Zeroize(attachmentKey)
+
+	ciphertext, nonce, err := crypto.Encrypt(data, attachmentKey, crypto.CipherXChaCha20Poly1305, attachmentAAD(objectKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt attachment: %w", err)
+	}
+
+	wrappedKey, keyNonce, err := crypto.Encrypt(attachmentKey, vaultKey, crypto.CipherXChaCha20Poly1305, attachmentAAD(objectKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap attachment key: %w", err)
+	}
+
+	a.ObjectKey = objectKey
+	a.Nonce = crypto.EncodeBase64(nonce)
+	a.WrappedKey = crypto.EncodeBase64(wrappedKey)
+	a.KeyNonce = crypto.EncodeBase64(keyNonce)
+	return ciphertext, nil
+}
+
+// OpenAttachment decrypts the content of an attachment stored outside the vault
+func OpenAttachment(ciphertext, vaultKey []byte, a *vault.Attachment) ([]byte, error) {
+	wrappedKey, err := crypto.DecodeBase64(a.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment key: %w", err)
+	}
+	keyNonce, err := crypto.DecodeBase64(a.KeyNonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment key nonce: %w", err)
+	}
+	nonce, err := crypto.DecodeBase64(a.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment nonce: %w", err)
+	}
+
+	attachmentKey, err := crypto.Decrypt(wrappedKey, keyNonce, vaultKey, crypto.CipherXChaCha20Poly1305, attachmentAAD(a.ObjectKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap attachment key: %w", err)
+	}
+	defer crypto.Zeroize(attachmentKey)
+
+	data, err := crypto.Decrypt(ciphertext, nonce, attachmentKey, crypto.CipherXChaCha20Poly1305, attachmentAAD(a.ObjectKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt attachment: %w", err)
+	}
+	return data, nil
+}
+
+// RewrapAttachmentKey re-encrypts the key of an attachment stored outside the
+// vault from oldKey to newKey, for vault key rotation. The stored object is
+// unchanged. Inline attachments have no key of their own and are skipped.
+func RewrapAttachmentKey(a *vault.Attachment, oldKey, newKey []byte) error {
+	if a.IsInline() {
+		return nil
+	}
+
+	wrappedKey, err := crypto.DecodeBase64(a.WrappedKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode attachment key: %w", err)
+	}
+	keyNonce, err := crypto.DecodeBase64(a.KeyNonce)
+	if err != nil {
+		return fmt.Errorf("failed to decode attachment key nonce: %w", err)
+	}
+
+	attachmentKey, err := crypto.Decrypt(wrappedKey, keyNonce, oldKey, crypto.CipherXChaCha20Poly1305, attachmentAAD(a.ObjectKey))
+	if err != nil {
+		return fmt.Errorf("failed to unwrap attachment key: %w", err)
+	}
+	defer crypto.Zeroize(attachmentKey)
+
+	wrappedKey, keyNonce, err = crypto.Encrypt(attachmentKey, newKey, crypto.CipherXChaCha20Poly1305, attachmentAAD(a.ObjectKey))
+	if err != nil {
+		return fmt.Errorf("failed to wrap attachment key: %w", err)
+	}
+
+	a.WrappedKey = crypto.EncodeBase64(wrappedKey)
+	a.KeyNonce = crypto.EncodeBase64(keyNonce)
+	return nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// ProtectedBackupFormat identifies backups wrapped with a backup passphrase
+const ProtectedBackupFormat = "vaultctl-protected-backup"
+
+// protectedBackupVersion is the current version of the wrapper format
+const protectedBackupVersion = 1
+
+// ErrNotProtectedBackup is returned when data is not a passphrase-protected backup
+var ErrNotProtectedBackup = errors.New("not a passphrase-protected vaultctl backup")
+
+// ProtectedBackup wraps a serialized EncryptedVault in a second layer of
+// encryption under a key derived from a passphrase independent of the master
+// password. The salt and KDF parameters travel in the header.
+type ProtectedBackup struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	Salt       string           `json:"salt"`
+	KDFParams  crypto.KDFParams `json:"kdf_params"`
+	Cipher     string           `json:"cipher"`
+	Nonce      string           `json:"nonce"`
+	Ciphertext string           `json:"ciphertext"`
+}
+
+// backupAAD binds the ciphertext to the backup format and version
+func backupAAD(version int) []byte {
+	return []byte(fmt.Sprintf("%s:%d", ProtectedBackupFormat, version))
+}
+
+// ProtectBackup encrypts a serialized vault under a key derived from passphrase
+func ProtectBackup(vaultData, passphrase []byte) ([]byte, error) {
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	params := crypto.DefaultKDFParams()
+	key, err := crypto.DeriveMasterKey(passphrase, salt, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive backup key: %w", err)
+	}
+	defer crypto.Zeroize(key)
+
+	ciphertext, nonce, err := crypto.Encrypt(vaultData, key, crypto.CipherXChaCha20Poly1305, backupAAD(protectedBackupVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	pb := ProtectedBackup{
+		Format:     ProtectedBackupFormat,
+		Version:    protectedBackupVersion,
+		Salt:       crypto.EncodeBase64(salt),
+		KDFParams:  params,
+		Cipher:     crypto.CipherXChaCha20Poly1305,
+		Nonce:      crypto.EncodeBase64(nonce),
+		Ciphertext: crypto.EncodeBase64(ciphertext),
+	}
+	data, err := json.MarshalIndent(pb, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal protected backup: %w", err)
+	}
+	return data, nil
+}
+
+// IsProtectedBackup reports whether data looks like a passphrase-protected backup
+func IsProtectedBackup(data []byte) bool {
+	var probe struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Format == ProtectedBackupFormat
+}
+
+// UnprotectBackup reverses ProtectBackup and returns the serialized vault
+func UnprotectBackup(data, passphrase []byte) ([]byte, error) {
+	var pb ProtectedBackup
+	if err := json.Unmarshal(data, &pb); err != nil || pb.Format != ProtectedBackupFormat {
+		return nil, ErrNotProtectedBackup
+	}
+	if err := pb.KDFParams.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid backup kdf parameters: %w", err)
+	}
+
+	salt, err := crypto.DecodeBase64(pb.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	nonce, err := crypto.DecodeBase64(pb.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nonce: %w", err)
+	}
+	ciphertext, err := crypto.DecodeBase64(pb.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	key, err := crypto.DeriveMasterKey(passphrase, salt, pb.KDFParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive backup key: %w", err)
+	}
+	defer crypto.Zeroize(key)
+
+	vaultData, err := crypto.Decrypt(ciphertext, nonce, key, pb.Cipher, backupAAD(pb.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup (wrong passphrase?): %w", err)
+	}
+	return vaultData, nil
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -18,31 +20,143 @@ type DynamoDBStorage struct {
 	client    *dynamodb.Client
 	tableName string
 	userID    string
+	vaultName string
+	retry     RetryPolicy
+	timeout   time.Duration
 }
 
 // DynamoDBItem represents the item structure in DynamoDB
 type DynamoDBItem struct {
-	PK        string `dynamodbav:"PK"`
-	SK        string `dynamodbav:"SK"`
-	VaultID   string `dynamodbav:"vault_id"`
-	VaultBlob string `dynamodbav:"vault_blob"` // JSON string of EncryptedVault
-	Version   int64  `dynamodbav:"version"`
+	PK         string `dynamodbav:"PK"`
+	SK         string `dynamodbav:"SK"`
+	VaultID    string `dynamodbav:"vault_id"`
+	VaultBlob  string `dynamodbav:"vault_blob"` // JSON string of EncryptedVault
+	Version    int64  `dynamodbav:"version"`
 	ModifiedAt string `dynamodbav:"modified_at"`
-	DeviceID  string `dynamodbav:"device_id"`
+	DeviceID   string `dynamodbav:"device_id"`
 }
 
-// NewDynamoDBStorage creates a new DynamoDB storage instance
-func NewDynamoDBStorage(tableName, userID string) (*DynamoDBStorage, error) {
+var (
+	_ RemoteStore  = (*DynamoDBStorage)(nil)
+	_ VaultDeleter = (*DynamoDBStorage)(nil)
+)
+
+// RemoteVaultInfo describes a vault stored in DynamoDB, as listed by
+// ListRemoteVaults
+type RemoteVaultInfo struct {
+	Name       string // Empty for the default vault
+	VaultID    string
+	Version    int64
+	ModifiedAt string
+	DeviceID   string
+}
+
+// vaultSK is the sort key of the default vault. Named vaults are stored
+// under vaultSK + "#" + name.
+const vaultSK = "VAULT"
+
+// DefaultDynamoDBTimeout bounds each DynamoDB operation, retries included,
+// so a hung network call can't block a command indefinitely
+const DefaultDynamoDBTimeout = 10 * time.Second
+
+// MaxItemSize is the largest item DynamoDB accepts, in bytes
+const MaxItemSize = 400 * 1024
+
+// ErrItemTooLarge is returned by SaveVault when the vault does not fit in a
+// single DynamoDB item
+var ErrItemTooLarge = errors.New("vault is too large for DynamoDB")
+
+// NewDynamoDBStorage creates a new DynamoDB storage instance for the vault
+// named vaultName of userID, or the default vault if vaultName is empty. All
+// of a user's vaults share a partition key. If endpoint is set it replaces
+// the AWS endpoint, e.g. for DynamoDB Local or a VPC endpoint.
+func NewDynamoDBStorage(tableName, userID, vaultName, endpoint string) (*DynamoDBStorage, error) {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return NewDynamoDBStorageWithClient(client, tableName, userID, vaultName), nil
+}
+
+// NewDynamoDBStorageWithClient creates a DynamoDB storage instance that uses
+// an already configured client
+func NewDynamoDBStorageWithClient(client *dynamodb.Client, tableName, userID, vaultName string) *DynamoDBStorage {
 	return &DynamoDBStorage{
-		client:    dynamodb.NewFromConfig(cfg),
+		client:    client,
 		tableName: tableName,
 		userID:    userID,
-	}, nil
+		vaultName: vaultName,
+		retry:     DefaultRetryPolicy(),
+		timeout:   DefaultDynamoDBTimeout,
+	}
+}
+
+// SetRetryPolicy sets how DynamoDB calls are retried after transient failures
+func (ds *DynamoDBStorage) SetRetryPolicy(p RetryPolicy) {
+	ds.retry = p
+}
+
+// SetTimeout sets how long each DynamoDB operation may take, retries
+// included. Zero removes the limit, leaving only the caller's context.
+func (ds *DynamoDBStorage) SetTimeout(d time.Duration) {
+	ds.timeout = d
+}
+
+// do runs a DynamoDB call with the retry policy under the operation timeout
+func (ds *DynamoDBStorage) do(ctx context.Context, call func(ctx context.Context) error) error {
+	if ds.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ds.timeout)
+		defer cancel()
+	}
+
+	err := ds.retry.Do(ctx, func() error {
+		return call(ctx)
+	})
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("no response from DynamoDB within %s: %w", ds.timeout, err)
+	}
+	return err
+}
+
+// pk returns the partition key holding all of the user's vaults
+func (ds *DynamoDBStorage) pk() string {
+	return fmt.Sprintf("USER#%s", ds.userID)
+}
+
+// sk returns the sort key of the vault
+func (ds *DynamoDBStorage) sk() string {
+	if ds.vaultName == "" {
+		return vaultSK
+	}
+	return vaultSK + "#" + ds.vaultName
+}
+
+// key returns the primary key of the vault item
+func (ds *DynamoDBStorage) key() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: ds.pk()},
+		"SK": &types.AttributeValueMemberS{Value: ds.sk()},
+	}
+}
+
+// legacyKey returns the primary key named vaults had before they shared the
+// user's partition key: the vault name appended to the user ID, under the
+// default sort key. It returns nil for the default vault, which never moved.
+func (ds *DynamoDBStorage) legacyKey() map[string]types.AttributeValue {
+	if ds.vaultName == "" {
+		return nil
+	}
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("USER#%s.%s", ds.userID, ds.vaultName)},
+		"SK": &types.AttributeValueMemberS{Value: vaultSK},
+	}
 }
 
 // GetDeviceID returns a unique device identifier
@@ -62,13 +176,13 @@ func (ds *DynamoDBStorage) SaveVault(ctx context.Context, ev *EncryptedVault, ex
 	}
 
 	item := DynamoDBItem{
-		PK:        fmt.Sprintf("USER#%s", ds.userID),
-		SK:        "VAULT",
-		VaultID:   ev.VaultID,
-		VaultBlob: string(vaultBlob),
-		Version:   ev.Version,
+		PK:         ds.pk(),
+		SK:         ds.sk(),
+		VaultID:    ev.VaultID,
+		VaultBlob:  string(vaultBlob),
+		Version:    ev.Version,
 		ModifiedAt: ev.ModifiedAt,
-		DeviceID:  GetDeviceID(),
+		DeviceID:   GetDeviceID(),
 	}
 
 	av, err := attributevalue.MarshalMap(item)
@@ -76,6 +190,17 @@ func (ds *DynamoDBStorage) SaveVault(ctx context.Context, ev *EncryptedVault, ex
 		return fmt.Errorf("failed to marshal item: %w", err)
 	}
 
+	// Refuse up front rather than let PutItem fail with a ValidationException
+	if size := itemSize(av); size > MaxItemSize {
+		return fmt.Errorf("%w: the item is %d bytes but DynamoDB allows at most %d. "+
+			"Remove large notes or attachments from the vault, or set remote_backend to \"filesystem\"",
+			ErrItemTooLarge, size, MaxItemSize)
+	}
+
+	if ds.legacyKey() != nil {
+		return ds.saveNamedVault(ctx, av, expectedVersion)
+	}
+
 	// Conditional write to prevent overwriting newer versions
 	conditionExpr := "attribute_not_exists(version) OR version = :expectedVersion"
 	exprAttrValues := map[string]types.AttributeValue{
@@ -89,11 +214,14 @@ func (ds *DynamoDBStorage) SaveVault(ctx context.Context, ev *EncryptedVault, ex
 		ExpressionAttributeValues: exprAttrValues,
 	}
 
-	_, err = ds.client.PutItem(ctx, input)
+	err = ds.do(ctx, func(ctx context.Context) error {
+		_, err := ds.client.PutItem(ctx, input)
+		return err
+	})
 	if err != nil {
 		var condCheckErr *types.ConditionalCheckFailedException
 		if errors.As(err, &condCheckErr) {
-			return fmt.Errorf("version conflict: remote vault has been updated. Run 'vaultctl sync' first")
+			return ErrVersionConflict
 		}
 		return fmt.Errorf("failed to save vault: %w", err)
 	}
@@ -101,23 +229,154 @@ func (ds *DynamoDBStorage) SaveVault(ctx context.Context, ev *EncryptedVault, ex
 	return nil
 }
 
-// LoadVault loads an encrypted vault from DynamoDB
+// saveNamedVault writes a named vault's item. The item is only created if
+// the vault isn't still under its legacy key; otherwise it is moved from
+// there in one transaction, deleting the legacy item if its version equals
+// expectedVersion, so a stale device can't fork the vault by creating the
+// new item.
+func (ds *DynamoDBStorage) saveNamedVault(ctx context.Context, item map[string]types.AttributeValue, expectedVersion int64) error {
+	expected := map[string]types.AttributeValue{
+		":expectedVersion": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expectedVersion)},
+	}
+
+	err := ds.transactWrite(ctx, []types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:                 aws.String(ds.tableName),
+			Item:                      item,
+			ConditionExpression:       aws.String("attribute_not_exists(version) OR version = :expectedVersion"),
+			ExpressionAttributeValues: expected,
+		}},
+		{ConditionCheck: &types.ConditionCheck{
+			TableName:           aws.String(ds.tableName),
+			Key:                 ds.legacyKey(),
+			ConditionExpression: aws.String("attribute_not_exists(version)"),
+		}},
+	})
+	failed, err := cancelledItems(err, 2)
+	if err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	switch {
+	case failed == nil:
+		return nil
+	case failed[0]:
+		return ErrVersionConflict
+	}
+
+	// The vault is still under its legacy key
+	failed, err = cancelledItems(ds.transactWrite(ctx, []types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:           aws.String(ds.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(version)"),
+		}},
+		{Delete: &types.Delete{
+			TableName:                 aws.String(ds.tableName),
+			Key:                       ds.legacyKey(),
+			ConditionExpression:       aws.String("version = :expectedVersion"),
+			ExpressionAttributeValues: expected,
+		}},
+	}), 2)
+	if err != nil {
+		return fmt.Errorf("failed to move vault from its legacy key: %w", err)
+	}
+	switch {
+	case failed == nil:
+		return nil
+	case failed[0]:
+		return fmt.Errorf("%w: the vault is also stored under its legacy key, written by an older vaultctl. "+
+			"Update vaultctl on every device", ErrVersionConflict)
+	default:
+		return ErrVersionConflict
+	}
+}
+
+// transactWrite runs a DynamoDB transaction with the retry policy
+func (ds *DynamoDBStorage) transactWrite(ctx context.Context, items []types.TransactWriteItem) error {
+	input := &dynamodb.TransactWriteItemsInput{TransactItems: items}
+	return ds.do(ctx, func(ctx context.Context) error {
+		_, err := ds.client.TransactWriteItems(ctx, input)
+		return err
+	})
+}
+
+// cancelledItems reports which of the n items of a cancelled transaction
+// failed their condition. It returns nil, nil if the transaction succeeded,
+// and err if it failed for another reason.
+func cancelledItems(err error, n int) ([]bool, error) {
+	if err == nil {
+		return nil, nil
+	}
+	var cancelled *types.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return nil, err
+	}
+
+	failed := make([]bool, n)
+	conditionFailed := false
+	for i, reason := range cancelled.CancellationReasons {
+		if i < len(failed) && aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			failed[i] = true
+			conditionFailed = true
+		}
+	}
+	if !conditionFailed {
+		return nil, err
+	}
+	return failed, nil
+}
+
+// itemSize estimates the size DynamoDB counts against MaxItemSize: the
+// length of every attribute name plus its value. Numbers are counted as
+// their decimal string, which overestimates slightly.
+func itemSize(item map[string]types.AttributeValue) int {
+	size := 0
+	for name, value := range item {
+		size += len(name)
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			size += len(v.Value)
+		case *types.AttributeValueMemberN:
+			size += len(v.Value)
+		case *types.AttributeValueMemberB:
+			size += len(v.Value)
+		case *types.AttributeValueMemberBOOL:
+			size++
+		}
+	}
+	return size
+}
+
+// LoadVault loads an encrypted vault from DynamoDB. A named vault not yet
+// saved under its sort key is read from its legacy key; the next SaveVault
+// moves it if the version it expects is still current.
 func (ds *DynamoDBStorage) LoadVault(ctx context.Context) (*EncryptedVault, error) {
+	ev, err := ds.loadItem(ctx, ds.key())
+	if errors.Is(err, ErrRemoteVaultNotFound) && ds.legacyKey() != nil {
+		return ds.loadItem(ctx, ds.legacyKey())
+	}
+	return ev, err
+}
+
+// loadItem reads and parses the vault item with the given key
+func (ds *DynamoDBStorage) loadItem(ctx context.Context, key map[string]types.AttributeValue) (*EncryptedVault, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(ds.tableName),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("USER#%s", ds.userID)},
-			"SK": &types.AttributeValueMemberS{Value: "VAULT"},
-		},
+		Key:       key,
 	}
 
-	result, err := ds.client.GetItem(ctx, input)
+	var result *dynamodb.GetItemOutput
+	err := ds.do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = ds.client.GetItem(ctx, input)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vault from DynamoDB: %w", err)
 	}
 
 	if result.Item == nil {
-		return nil, fmt.Errorf("vault not found in DynamoDB")
+		return nil, ErrRemoteVaultNotFound
 	}
 
 	var item DynamoDBItem
@@ -133,26 +392,124 @@ func (ds *DynamoDBStorage) LoadVault(ctx context.Context) (*EncryptedVault, erro
 	return ev, nil
 }
 
-// SyncVault handles syncing between local and remote vaults
-func (ds *DynamoDBStorage) SyncVault(ctx context.Context, localEV *EncryptedVault) (*EncryptedVault, error) {
-	remoteEV, err := ds.LoadVault(ctx)
-	if err != nil {
-		// If remote doesn't exist, push local
-		if err.Error() == "vault not found in DynamoDB" {
-			return localEV, ds.SaveVault(ctx, localEV, localEV.Version-1)
+// ListRemoteVaults returns every vault of the user stored in the table, the
+// default vault first. Named vaults still under their legacy key are not
+// listed until they are next saved.
+func (ds *DynamoDBStorage) ListRemoteVaults(ctx context.Context) ([]RemoteVaultInfo, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(ds.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: ds.pk()},
+			":sk": &types.AttributeValueMemberS{Value: vaultSK},
+		},
+		// Leave out the vault blobs
+		ProjectionExpression: aws.String("SK, vault_id, version, modified_at, device_id"),
+	}
+
+	var vaults []RemoteVaultInfo
+	for {
+		var result *dynamodb.QueryOutput
+		err := ds.do(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = ds.client.Query(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list vaults in DynamoDB: %w", err)
 		}
-		return nil, err
+
+		var items []DynamoDBItem
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal items: %w", err)
+		}
+		for _, item := range items {
+			name, ok := strings.CutPrefix(item.SK, vaultSK)
+			if ok && name != "" {
+				name, ok = strings.CutPrefix(name, "#")
+			}
+			if !ok {
+				continue
+			}
+			vaults = append(vaults, RemoteVaultInfo{
+				Name:       name,
+				VaultID:    item.VaultID,
+				Version:    item.Version,
+				ModifiedAt: item.ModifiedAt,
+				DeviceID:   item.DeviceID,
+			})
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	return vaults, nil
+}
+
+// DeleteVault deletes the vault item if its version equals expectedVersion.
+// A named vault's legacy item is deleted too, so it can't be read back.
+func (ds *DynamoDBStorage) DeleteVault(ctx context.Context, expectedVersion int64) error {
+	err := ds.deleteItem(ctx, ds.key(), expectedVersion)
+	legacy := ds.legacyKey()
+	if legacy == nil {
+		return err
+	}
+	if errors.Is(err, ErrVersionConflict) {
+		// The vault may not have been moved from its legacy key yet
+		if legacyErr := ds.deleteItem(ctx, legacy, expectedVersion); legacyErr == nil {
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(ds.tableName),
+		Key:       legacy,
+	}
+	err = ds.do(ctx, func(ctx context.Context) error {
+		_, err := ds.client.DeleteItem(ctx, input)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete the vault's legacy item: %w", err)
+	}
+	return nil
+}
+
+// deleteItem deletes the vault item with the given key if its version
+// equals expectedVersion
+func (ds *DynamoDBStorage) deleteItem(ctx context.Context, key map[string]types.AttributeValue, expectedVersion int64) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName:           aws.String(ds.tableName),
+		Key:                 key,
+		ConditionExpression: aws.String("version = :expectedVersion"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expectedVersion": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expectedVersion)},
+		},
 	}
 
-	// If local is newer or same, push local
-	if localEV.Version >= remoteEV.Version {
-		if err := ds.SaveVault(ctx, localEV, remoteEV.Version); err != nil {
-			return nil, err
+	err := ds.do(ctx, func(ctx context.Context) error {
+		_, err := ds.client.DeleteItem(ctx, input)
+		return err
+	})
+	if err != nil {
+		var condCheckErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condCheckErr) {
+			return ErrVersionConflict
 		}
-		return localEV, nil
+		return fmt.Errorf("failed to delete vault: %w", err)
 	}
 
-	// Remote is newer, return remote
-	return remoteEV, nil
+	return nil
 }
 
+// SyncVault handles syncing between local and remote vaults. The load and
+// save it makes are each bounded by the operation timeout.
+func (ds *DynamoDBStorage) SyncVault(ctx context.Context, localEV *EncryptedVault) (*EncryptedVault, error) {
+	return syncVault(ctx, ds, localEV)
+}
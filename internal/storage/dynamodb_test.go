@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// fakeDynamoDB answers DynamoDB JSON API calls with canned responses and
+// records the last request it received, keyed by operation. Responses queued
+// for an operation are served first, in order.
+type fakeDynamoDB struct {
+	responses map[string]fakeResponse
+	queued    map[string][]fakeResponse
+	requests  map[string]map[string]any
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// X-Amz-Target is e.g. DynamoDB_20120810.GetItem
+	_, op, _ := strings.Cut(r.Header.Get("X-Amz-Target"), ".")
+	body, _ := io.ReadAll(r.Body)
+	var req map[string]any
+	json.Unmarshal(body, &req)
+	f.requests[op] = req
+
+	resp, ok := f.responses[op]
+	if queue := f.queued[op]; len(queue) > 0 {
+		resp, ok = queue[0], true
+		f.queued[op] = queue[1:]
+	}
+	if !ok {
+		resp = fakeResponse{http.StatusBadRequest, `{"__type":"com.amazon.coral.validate#ValidationException","message":"unexpected call"}`}
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(resp.status)
+	io.WriteString(w, resp.body)
+}
+
+// newFakeDynamoDB starts a fake DynamoDB serving responses and returns its URL
+func newFakeDynamoDB(t *testing.T, responses map[string]fakeResponse) (*fakeDynamoDB, string) {
+	t.Helper()
+	fake := &fakeDynamoDB{
+		responses: responses,
+		queued:    make(map[string][]fakeResponse),
+		requests:  make(map[string]map[string]any),
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, srv.URL
+}
+
+// newFakeDynamoDBStorage returns storage for the default vault of user alice
+// backed by a fake DynamoDB serving responses
+func newFakeDynamoDBStorage(t *testing.T, responses map[string]fakeResponse) (*DynamoDBStorage, *fakeDynamoDB) {
+	t.Helper()
+	return newFakeNamedDynamoDBStorage(t, "", responses)
+}
+
+// newFakeNamedDynamoDBStorage is newFakeDynamoDBStorage for the vault named
+// vaultName
+func newFakeNamedDynamoDBStorage(t *testing.T, vaultName string, responses map[string]fakeResponse) (*DynamoDBStorage, *fakeDynamoDB) {
+	t.Helper()
+	fake, url := newFakeDynamoDB(t, responses)
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(url),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+		RetryMaxAttempts: 1,
+	})
+	ds := NewDynamoDBStorageWithClient(client, "vaults", "alice", vaultName)
+	ds.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	return ds, fake
+}
+
+// requestKey returns the PK and SK of a recorded GetItem or DeleteItem request
+func requestKey(t *testing.T, req map[string]any) (pk, sk string) {
+	t.Helper()
+	key, ok := req["Key"].(map[string]any)
+	if !ok {
+		t.Fatalf("request has no Key: %v", req)
+	}
+	attr := func(name string) string {
+		v, _ := key[name].(map[string]any)
+		s, _ := v["S"].(string)
+		return s
+	}
+	return attr("PK"), attr("SK")
+}
+
+func TestDynamoDBLoadVaultNotFound(t *testing.T) {
+	ds, fake := newFakeDynamoDBStorage(t, map[string]fakeResponse{
+		"GetItem": {http.StatusOK, `{}`},
+	})
+
+	_, err := ds.LoadVault(context.Background())
+	if !errors.Is(err, ErrRemoteVaultNotFound) {
+		t.Fatalf("LoadVault error = %v, want ErrRemoteVaultNotFound", err)
+	}
+	if pk, sk := requestKey(t, fake.requests["GetItem"]); pk != "USER#alice" || sk != "VAULT" {
+		t.Errorf("GetItem key = %s/%s, want USER#alice/VAULT", pk, sk)
+	}
+}
+
+// transactionCancelled answers TransactWriteItems with a cancellation whose
+// reasons are codes, one per item
+func transactionCancelled(codes ...string) fakeResponse {
+	reasons := make([]string, len(codes))
+	for i, code := range codes {
+		reasons[i] = fmt.Sprintf(`{"Code":%q}`, code)
+	}
+	return fakeResponse{http.StatusBadRequest, `{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException",` +
+		`"message":"Transaction cancelled","CancellationReasons":[` + strings.Join(reasons, ",") + `]}`}
+}
+
+// transactItem returns the action and its key, as PK/SK, of item i of a
+// recorded TransactWriteItems request
+func transactItem(t *testing.T, req map[string]any, i int) (action, key string) {
+	t.Helper()
+	items, _ := req["TransactItems"].([]any)
+	if i >= len(items) {
+		t.Fatalf("transaction has %d items, want more than %d", len(items), i)
+	}
+	for action, v := range items[i].(map[string]any) {
+		op, _ := v.(map[string]any)
+		attrs, ok := op["Key"].(map[string]any)
+		if !ok {
+			attrs, _ = op["Item"].(map[string]any)
+		}
+		pk, _ := attrs["PK"].(map[string]any)
+		sk, _ := attrs["SK"].(map[string]any)
+		return action, fmt.Sprintf("%v/%v", pk["S"], sk["S"])
+	}
+	return "", ""
+}
+
+func TestDynamoDBNamedVaultKeys(t *testing.T) {
+	ds, fake := newFakeNamedDynamoDBStorage(t, "work", map[string]fakeResponse{
+		"GetItem":            {http.StatusOK, `{}`},
+		"TransactWriteItems": {http.StatusOK, `{}`},
+	})
+
+	// A new named vault is created under its sort key, checking nothing is
+	// left under the legacy key
+	ev := &EncryptedVault{VaultID: "v1", Version: 1, ModifiedAt: "2025-01-01T00:00:00Z"}
+	if err := ds.SaveVault(context.Background(), ev, 0); err != nil {
+		t.Fatalf("SaveVault: %v", err)
+	}
+	req := fake.requests["TransactWriteItems"]
+	if action, key := transactItem(t, req, 0); action != "Put" || key != "USER#alice/VAULT#work" {
+		t.Errorf("item 0 = %s %s, want Put USER#alice/VAULT#work", action, key)
+	}
+	if action, key := transactItem(t, req, 1); action != "ConditionCheck" || key != "USER#alice.work/VAULT" {
+		t.Errorf("item 1 = %s %s, want ConditionCheck USER#alice.work/VAULT", action, key)
+	}
+
+	// Not found under its sort key, the vault is looked for where named
+	// vaults were stored before
+	if _, err := ds.LoadVault(context.Background()); !errors.Is(err, ErrRemoteVaultNotFound) {
+		t.Fatalf("LoadVault error = %v, want ErrRemoteVaultNotFound", err)
+	}
+	if pk, sk := requestKey(t, fake.requests["GetItem"]); pk != "USER#alice.work" || sk != "VAULT" {
+		t.Errorf("last GetItem key = %s/%s, want USER#alice.work/VAULT", pk, sk)
+	}
+}
+
+func TestDynamoDBMovesLegacyNamedVault(t *testing.T) {
+	ds, fake := newFakeNamedDynamoDBStorage(t, "work", map[string]fakeResponse{
+		"TransactWriteItems": {http.StatusOK, `{}`},
+	})
+	// The legacy item exists, so creating the new one is refused
+	fake.queued["TransactWriteItems"] = []fakeResponse{transactionCancelled("None", "ConditionalCheckFailed")}
+
+	ev := &EncryptedVault{VaultID: "v1", Version: 4, ModifiedAt: "2025-01-01T00:00:00Z"}
+	if err := ds.SaveVault(context.Background(), ev, 3); err != nil {
+		t.Fatalf("SaveVault: %v", err)
+	}
+	req := fake.requests["TransactWriteItems"]
+	if action, key := transactItem(t, req, 0); action != "Put" || key != "USER#alice/VAULT#work" {
+		t.Errorf("item 0 = %s %s, want Put USER#alice/VAULT#work", action, key)
+	}
+	if action, key := transactItem(t, req, 1); action != "Delete" || key != "USER#alice.work/VAULT" {
+		t.Errorf("item 1 = %s %s, want Delete USER#alice.work/VAULT", action, key)
+	}
+}
+
+func TestDynamoDBStaleSaveDoesNotMoveLegacyNamedVault(t *testing.T) {
+	// Only the legacy item exists, at a newer version than expected: the
+	// legacy check fails, and so does the delete in the move
+	ds, fake := newFakeNamedDynamoDBStorage(t, "work", map[string]fakeResponse{
+		"TransactWriteItems": transactionCancelled("None", "ConditionalCheckFailed"),
+	})
+
+	ev := &EncryptedVault{VaultID: "v1", Version: 3, ModifiedAt: "2025-01-01T00:00:00Z"}
+	if err := ds.SaveVault(context.Background(), ev, 2); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("SaveVault error = %v, want ErrVersionConflict", err)
+	}
+
+	req := fake.requests["TransactWriteItems"]
+	if action, key := transactItem(t, req, 1); action != "Delete" || key != "USER#alice.work/VAULT" {
+		t.Fatalf("item 1 = %s %s, want Delete USER#alice.work/VAULT", action, key)
+	}
+	items, _ := req["TransactItems"].([]any)
+	del, _ := items[1].(map[string]any)["Delete"].(map[string]any)
+	values, _ := del["ExpressionAttributeValues"].(map[string]any)
+	expected, _ := values[":expectedVersion"].(map[string]any)
+	if del["ConditionExpression"] != "version = :expectedVersion" || expected["N"] != "2" {
+		t.Errorf("legacy delete condition = %v with version %v, want version = 2", del["ConditionExpression"], expected["N"])
+	}
+}
+
+func TestDynamoDBListRemoteVaults(t *testing.T) {
+	ds, fake := newFakeDynamoDBStorage(t, map[string]fakeResponse{
+		"Query": {http.StatusOK, `{"Items":[
+			{"SK":{"S":"VAULT"},"vault_id":{"S":"v1"},"version":{"N":"7"},"modified_at":{"S":"2025-01-01T00:00:00Z"},"device_id":{"S":"laptop"}},
+			{"SK":{"S":"VAULT#work"},"vault_id":{"S":"v2"},"version":{"N":"2"},"modified_at":{"S":"2025-02-01T00:00:00Z"},"device_id":{"S":"desktop"}},
+			{"SK":{"S":"VAULTS"},"vault_id":{"S":"v3"},"version":{"N":"1"}}
+		]}`},
+	})
+
+	vaults, err := ds.ListRemoteVaults(context.Background())
+	if err != nil {
+		t.Fatalf("ListRemoteVaults: %v", err)
+	}
+	want := []RemoteVaultInfo{
+		{Name: "", VaultID: "v1", Version: 7, ModifiedAt: "2025-01-01T00:00:00Z", DeviceID: "laptop"},
+		{Name: "work", VaultID: "v2", Version: 2, ModifiedAt: "2025-02-01T00:00:00Z", DeviceID: "desktop"},
+	}
+	if len(vaults) != len(want) {
+		t.Fatalf("ListRemoteVaults = %+v, want %+v", vaults, want)
+	}
+	for i := range want {
+		if vaults[i] != want[i] {
+			t.Errorf("vault %d = %+v, want %+v", i, vaults[i], want[i])
+		}
+	}
+
+	values, _ := fake.requests["Query"]["ExpressionAttributeValues"].(map[string]any)
+	pk, _ := values[":pk"].(map[string]any)
+	if pk["S"] != "USER#alice" {
+		t.Errorf("Query partition key = %v, want USER#alice", pk["S"])
+	}
+}
+
+func TestDynamoDBSaveVaultConflict(t *testing.T) {
+	ds, fake := newFakeDynamoDBStorage(t, map[string]fakeResponse{
+		"PutItem": {http.StatusBadRequest, `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`},
+	})
+
+	ev := &EncryptedVault{VaultID: "v1", Version: 3, ModifiedAt: "2025-01-01T00:00:00Z"}
+	err := ds.SaveVault(context.Background(), ev, 2)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("SaveVault error = %v, want ErrVersionConflict", err)
+	}
+
+	req := fake.requests["PutItem"]
+	if req["TableName"] != "vaults" {
+		t.Errorf("PutItem table = %v, want vaults", req["TableName"])
+	}
+	values, _ := req["ExpressionAttributeValues"].(map[string]any)
+	expected, _ := values[":expectedVersion"].(map[string]any)
+	if expected["N"] != "2" {
+		t.Errorf("PutItem expected version = %v, want 2", expected["N"])
+	}
+}
+
+func TestNewDynamoDBStorageUsesEndpoint(t *testing.T) {
+	fake, url := newFakeDynamoDB(t, map[string]fakeResponse{
+		"GetItem": {http.StatusOK, `{}`},
+	})
+	// Keep the AWS config of the machine running the test out of it
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+
+	ds, err := NewDynamoDBStorage("vaults", "alice", "", url)
+	if err != nil {
+		t.Fatalf("NewDynamoDBStorage: %v", err)
+	}
+	ds.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	if _, err := ds.LoadVault(context.Background()); !errors.Is(err, ErrRemoteVaultNotFound) {
+		t.Fatalf("LoadVault error = %v, want ErrRemoteVaultNotFound", err)
+	}
+	if _, ok := fake.requests["GetItem"]; !ok {
+		t.Error("GetItem was not sent to the configured endpoint")
+	}
+}
@@ -2,7 +2,12 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
 )
 
 // EncryptedVault represents the encrypted vault format stored on disk and in DynamoDB
@@ -18,8 +23,16 @@ type EncryptedVault struct {
 	Nonce          string    `json:"nonce"`            // base64 - nonce for vault ciphertext
 	ModifiedAt     string    `json:"modified_at"`     // ISO 8601
 	Version        int64     `json:"version"`
+	KeyFileID      string    `json:"key_file_id,omitempty"` // base64 - fingerprint of the key file also needed to unwrap the vault key
+	EnvelopeMAC    string    `json:"envelope_mac,omitempty"` // base64 - HMAC-SHA256 over the fields above
 }
 
+// envelopeMACInfo is the HKDF info string for the envelope MAC subkey
+const envelopeMACInfo = "vaultctl envelope mac v1"
+
+// ErrEnvelopeMACMismatch is returned when the envelope MAC does not verify
+var ErrEnvelopeMACMismatch = errors.New("vault envelope MAC mismatch: vault metadata may have been tampered with")
+
 // KDFParams holds Argon2id parameters
 type KDFParams struct {
 	Algo       string `json:"algo"`
@@ -33,12 +46,16 @@ func (ev *EncryptedVault) ToJSON() ([]byte, error) {
 	return json.Marshal(ev)
 }
 
-// FromJSON deserializes the encrypted vault from JSON
+// FromJSON deserializes the encrypted vault from JSON. Envelopes written by
+// a newer vaultctl are refused with vault.ErrSchemaTooNew.
 func EncryptedVaultFromJSON(data []byte) (*EncryptedVault, error) {
 	var ev EncryptedVault
 	if err := json.Unmarshal(data, &ev); err != nil {
 		return nil, err
 	}
+	if err := checkSchemaVersion(ev.SchemaVersion); err != nil {
+		return nil, err
+	}
 	return &ev, nil
 }
 
@@ -47,8 +64,209 @@ func (ev *EncryptedVault) GetModifiedAtTime() (time.Time, error) {
 	return time.Parse(time.RFC3339, ev.ModifiedAt)
 }
 
+// Validate checks that the envelope is structurally sound without decrypting
+// it: required fields are set, KDF parameters and cipher are supported, and
+// base64 fields decode to the expected lengths
+func (ev *EncryptedVault) Validate() error {
+	if ev.SchemaVersion < 1 {
+		return fmt.Errorf("invalid schema version: %d", ev.SchemaVersion)
+	}
+	if err := checkSchemaVersion(ev.SchemaVersion); err != nil {
+		return err
+	}
+	if ev.VaultID == "" {
+		return errors.New("missing vault ID")
+	}
+
+	kdfParams := crypto.KDFParams{
+		Algo:        ev.KDFParams.Algo,
+		Memory:      ev.KDFParams.Memory,
+		Iterations:  ev.KDFParams.Iterations,
+		Parallelism: ev.KDFParams.Parallelism,
+	}
+	if err := kdfParams.Validate(); err != nil {
+		return fmt.Errorf("invalid kdf parameters: %w", err)
+	}
+
+	nonceSize, err := crypto.NonceSizeFor(ev.Cipher)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBase64Len("salt_master", ev.SaltMaster, crypto.SaltSize, true); err != nil {
+		return err
+	}
+	if err := checkBase64Len("enc_vault_key", ev.EncVaultKey, crypto.VaultKeySize+crypto.TagSize, true); err != nil {
+		return err
+	}
+	// Older vaults share the payload nonce for the vault key
+	if ev.VaultKeyNonce != "" {
+		if err := checkBase64Len("vault_key_nonce", ev.VaultKeyNonce, nonceSize, true); err != nil {
+			return err
+		}
+	}
+	if err := checkBase64Len("nonce", ev.Nonce, nonceSize, true); err != nil {
+		return err
+	}
+	if ev.KeyFileID != "" {
+		if err := checkBase64Len("key_file_id", ev.KeyFileID, 8, true); err != nil {
+			return err
+		}
+	}
+	if ev.EnvelopeMAC != "" {
+		if err := checkBase64Len("envelope_mac", ev.EnvelopeMAC, 32, true); err != nil {
+			return err
+		}
+	}
+	if err := checkBase64Len("ciphertext", ev.Ciphertext, crypto.TagSize, false); err != nil {
+		return err
+	}
+
+	if _, err := ev.GetModifiedAtTime(); err != nil {
+		return fmt.Errorf("invalid modified_at: %w", err)
+	}
+	return nil
+}
+
+// checkBase64Len decodes a base64 field and checks its length. If exact is
+// false, size is a minimum.
+func checkBase64Len(field, value string, size int, exact bool) error {
+	if value == "" {
+		return fmt.Errorf("missing %s", field)
+	}
+	data, err := crypto.DecodeBase64(value)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", field, err)
+	}
+	if (exact && len(data) != size) || len(data) < size {
+		return fmt.Errorf("invalid %s: %d bytes, expected %d", field, len(data), size)
+	}
+	return nil
+}
+
 // SetModifiedAt sets the ModifiedAt timestamp
 func (ev *EncryptedVault) SetModifiedAt(t time.Time) {
 	ev.ModifiedAt = t.Format(time.RFC3339)
 }
 
+// AssociatedData returns the AEAD associated data that binds the ciphertext to this vault
+func (ev *EncryptedVault) AssociatedData() []byte {
+	return crypto.VaultAAD(ev.VaultID, ev.SchemaVersion)
+}
+
+// SealPayload encrypts plaintext with the vault key and stores the ciphertext and nonce
+func (ev *EncryptedVault) SealPayload(plaintext []byte, vaultKey []byte) error {
+	ciphertext, nonce, err := crypto.Encrypt(plaintext, vaultKey, ev.Cipher, ev.AssociatedData())
+	if err != nil {
+		return err
+	}
+
+	ev.Ciphertext = crypto.EncodeBase64(ciphertext)
+	ev.Nonce = crypto.EncodeBase64(nonce)
+	return nil
+}
+
+// legacyEnvelope reports whether the envelope may have been written before
+// the payload was bound to the vault ID and the envelope was signed. Those
+// writers never set an envelope MAC, a key file or a schema version above 1,
+// so an envelope with any of them gets no compatibility fallback.
+func (ev *EncryptedVault) legacyEnvelope() bool {
+	return ev.EnvelopeMAC == "" && ev.KeyFileID == "" && ev.SchemaVersion <= SchemaVersionSingle
+}
+
+// DecryptPayload decrypts the vault ciphertext with the vault key.
+// Legacy vaults, sealed before the vault ID was bound as associated data,
+// are retried with empty associated data; they are re-sealed with it on the
+// next save.
+func (ev *EncryptedVault) DecryptPayload(vaultKey []byte) ([]byte, error) {
+	ciphertext, err := crypto.DecodeBase64(ev.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	nonce, err := crypto.DecodeBase64(ev.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nonce: %w", err)
+	}
+
+	plaintext, err := crypto.Decrypt(ciphertext, nonce, vaultKey, ev.Cipher, ev.AssociatedData())
+	if err != nil {
+		if !ev.legacyEnvelope() {
+			return nil, err
+		}
+		legacy, legacyErr := crypto.Decrypt(ciphertext, nonce, vaultKey, ev.Cipher, nil)
+		if legacyErr != nil {
+			return nil, err
+		}
+		return legacy, nil
+	}
+
+	return plaintext, nil
+}
+
+// canonicalMetadata returns a deterministic serialization of every envelope
+// field except the MAC itself
+func (ev *EncryptedVault) canonicalMetadata() ([]byte, error) {
+	unsigned := *ev
+	unsigned.EnvelopeMAC = ""
+	return json.Marshal(unsigned)
+}
+
+// computeMAC computes the envelope MAC. The MAC subkey is derived from the
+// vault key rather than the master key because the vault key is the only key
+// available when saving from a session; it can itself only be recovered with
+// the master password. For the same reason the MAC is verified once the vault
+// key is unwrapped, not when the envelope is loaded.
+func (ev *EncryptedVault) computeMAC(vaultKey []byte) ([]byte, error) {
+	data, err := ev.canonicalMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize envelope: %w", err)
+	}
+
+	macKey, err := crypto.DeriveSubkey(vaultKey, envelopeMACInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Zeroize(macKey)
+
+	return crypto.ComputeHMAC(macKey, data), nil
+}
+
+// Sign computes the envelope MAC and stores it in EnvelopeMAC.
+// Call it after every change to the envelope.
+func (ev *EncryptedVault) Sign(vaultKey []byte) error {
+	mac, err := ev.computeMAC(vaultKey)
+	if err != nil {
+		return err
+	}
+	ev.EnvelopeMAC = crypto.EncodeBase64(mac)
+	return nil
+}
+
+// VerifyEnvelope checks the envelope MAC. Legacy vaults, written before the
+// MAC was introduced, are accepted with a warning and signed on their next
+// save; any other envelope without a MAC was stripped of it.
+func (ev *EncryptedVault) VerifyEnvelope(vaultKey []byte) error {
+	if ev.EnvelopeMAC == "" {
+		if !ev.legacyEnvelope() {
+			return ErrEnvelopeMACMismatch
+		}
+		fmt.Fprintln(os.Stderr, "Warning: vault has no envelope MAC; metadata is unauthenticated until the next save")
+		return nil
+	}
+
+	expected, err := crypto.DecodeBase64(ev.EnvelopeMAC)
+	if err != nil {
+		return fmt.Errorf("failed to decode envelope MAC: %w", err)
+	}
+
+	mac, err := ev.computeMAC(vaultKey)
+	if err != nil {
+		return err
+	}
+
+	if !crypto.ConstantTimeCompare(mac, expected) {
+		return ErrEnvelopeMACMismatch
+	}
+	return nil
+}
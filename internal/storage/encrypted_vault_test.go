@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+func TestEncryptedVaultFromJSONSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemaVersion int
+		wantErr       bool
+	}{
+		{"oldest", 1, false},
+		{"current", MaxSchemaVersion, false},
+		{"newer", MaxSchemaVersion + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(fmt.Sprintf(`{"schema_version": %d}`, tt.schemaVersion))
+			_, err := EncryptedVaultFromJSON(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncryptedVaultFromJSON(%s) error = %v, want error %v", data, err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, vault.ErrSchemaTooNew) {
+				t.Errorf("EncryptedVaultFromJSON(%s) error = %v, want ErrSchemaTooNew", data, err)
+			}
+		})
+	}
+}
+
+func TestLoadEncryptedVaultNewerIsNotCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	data := fmt.Sprintf(`{"schema_version": %d}`, MaxSchemaVersion+1)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewLocalStorage(path).LoadEncryptedVault()
+	if !errors.Is(err, vault.ErrSchemaTooNew) {
+		t.Errorf("LoadEncryptedVault error = %v, want ErrSchemaTooNew", err)
+	}
+	if errors.Is(err, ErrCorruptVault) {
+		t.Errorf("LoadEncryptedVault error = %v, reported as a corrupted vault", err)
+	}
+}
+
+func TestDecryptPayloadLegacyAAD(t *testing.T) {
+	plaintext := []byte(`{"entries":[]}`)
+	resealWithoutAAD := func(t *testing.T, ev *EncryptedVault, key []byte) {
+		t.Helper()
+		ciphertext, nonce, err := crypto.Encrypt(plaintext, key, ev.Cipher, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev.Ciphertext = crypto.EncodeBase64(ciphertext)
+		ev.Nonce = crypto.EncodeBase64(nonce)
+	}
+
+	t.Run("legacy vault opens", func(t *testing.T) {
+		ev, key := newTestEncryptedVault(t, vault.NewVault(), []byte("password"), SchemaVersionSingle)
+		ev.EnvelopeMAC = ""
+		resealWithoutAAD(t, ev, key)
+		got, err := ev.DecryptPayload(key)
+		if err != nil {
+			t.Fatalf("DecryptPayload: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("DecryptPayload = %s, want %s", got, plaintext)
+		}
+	})
+
+	// A payload without associated data swapped into a newer envelope must
+	// not open through the compatibility path
+	for _, schemaVersion := range []int{SchemaVersionSingle, SchemaVersionSplit} {
+		t.Run(fmt.Sprintf("signed schema %d rejects", schemaVersion), func(t *testing.T) {
+			ev, key := newTestEncryptedVault(t, vault.NewVault(), []byte("password"), schemaVersion)
+			resealWithoutAAD(t, ev, key)
+			if _, err := ev.DecryptPayload(key); !errors.Is(err, crypto.ErrAuthFailed) {
+				t.Errorf("DecryptPayload error = %v, want ErrAuthFailed", err)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeMissingMAC(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemaVersion int
+		keyFileID     string
+		wantErr       bool
+	}{
+		{"legacy", SchemaVersionSingle, "", false},
+		{"split vault", SchemaVersionSplit, "", true},
+		{"key file", SchemaVersionSingle, crypto.EncodeBase64(make([]byte, 8)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, key := newTestEncryptedVault(t, vault.NewVault(), []byte("password"), tt.schemaVersion)
+			ev.KeyFileID = tt.keyFileID
+			ev.EnvelopeMAC = ""
+			err := ev.VerifyEnvelope(key)
+			if tt.wantErr && !errors.Is(err, ErrEnvelopeMACMismatch) {
+				t.Errorf("VerifyEnvelope error = %v, want ErrEnvelopeMACMismatch", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("VerifyEnvelope error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestUnlockVaultTamperedKDFParams(t *testing.T) {
+	password := []byte("password")
+	tests := []struct {
+		name    string
+		tamper  func(*EncryptedVault)
+		wantErr error // nil for any error other than ErrWrongPassword
+	}{
+		{"memory below the minimum", func(ev *EncryptedVault) { ev.KDFParams.Memory = crypto.MinMemory / 2 }, nil},
+		{"iterations below the minimum", func(ev *EncryptedVault) { ev.KDFParams.Iterations = 0 }, nil},
+		{"iterations changed", func(ev *EncryptedVault) { ev.KDFParams.Iterations++ }, ErrWrongPassword},
+		{"salt changed", func(ev *EncryptedVault) { ev.SaltMaster = crypto.EncodeBase64(make([]byte, crypto.SaltSize)) }, ErrWrongPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, _ := newTestEncryptedVault(t, vault.NewVault(), password, SchemaVersionSplit)
+			tt.tamper(ev)
+
+			_, _, err := UnlockVault(ev, password, nil)
+			if err == nil {
+				t.Fatal("UnlockVault of a tampered envelope succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnlockVault error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && errors.Is(err, ErrWrongPassword) {
+				t.Errorf("UnlockVault error = %v, want the parameters refused before deriving a key", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/atomic"
+)
+
+// staleLockAge is how old a lock file must be before it is assumed to have
+// been left behind by a crashed process
+const staleLockAge = 30 * time.Second
+
+// FilesystemStorage keeps the remote copy of the vault in a directory, such
+// as a Dropbox or Syncthing folder, instead of a cloud database
+type FilesystemStorage struct {
+	dir    string
+	userID string
+}
+
+var (
+	_ RemoteStore  = (*FilesystemStorage)(nil)
+	_ VaultDeleter = (*FilesystemStorage)(nil)
+)
+
+// NewFilesystemStorage creates a filesystem remote rooted at dir
+func NewFilesystemStorage(dir, userID string) (*FilesystemStorage, error) {
+	if dir == "" {
+		return nil, errors.New("remote_dir is not set in config")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create remote directory: %w", err)
+	}
+	return &FilesystemStorage{dir: dir, userID: userID}, nil
+}
+
+// vaultPath returns the path of the remote vault file
+func (fs *FilesystemStorage) vaultPath() string {
+	return filepath.Join(fs.dir, fmt.Sprintf("vaultctl-%s.json", fs.userID))
+}
+
+// SaveVault writes the vault if the stored version equals expectedVersion.
+// The compare and write happen under a lock file, and the write is an
+// atomic rename so sync clients never pick up a partial file.
+func (fs *FilesystemStorage) SaveVault(ctx context.Context, ev *EncryptedVault, expectedVersion int64) error {
+	unlock, err := fs.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := fs.LoadVault(ctx)
+	switch {
+	case errors.Is(err, ErrRemoteVaultNotFound):
+		// Nothing to compare against
+	case err != nil:
+		return err
+	case current.Version != expectedVersion:
+		return ErrVersionConflict
+	}
+
+	data, err := ev.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize vault: %w", err)
+	}
+
+	if err := atomic.WriteFile(fs.vaultPath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	return nil
+}
+
+// LoadVault loads the vault from the remote directory
+func (fs *FilesystemStorage) LoadVault(ctx context.Context) (*EncryptedVault, error) {
+	data, err := os.ReadFile(fs.vaultPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrRemoteVaultNotFound
+		}
+		return nil, fmt.Errorf("failed to read remote vault: %w", err)
+	}
+
+	ev, err := EncryptedVaultFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse remote vault: %w", err)
+	}
+	return ev, nil
+}
+
+// DeleteVault removes the vault file if its version equals expectedVersion
+func (fs *FilesystemStorage) DeleteVault(ctx context.Context, expectedVersion int64) error {
+	unlock, err := fs.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := fs.LoadVault(ctx)
+	if err != nil {
+		return err
+	}
+	if current.Version != expectedVersion {
+		return ErrVersionConflict
+	}
+
+	if err := os.Remove(fs.vaultPath()); err != nil {
+		return fmt.Errorf("failed to delete vault: %w", err)
+	}
+	return nil
+}
+
+// SyncVault handles syncing between local and remote vaults
+func (fs *FilesystemStorage) SyncVault(ctx context.Context, localEV *EncryptedVault) (*EncryptedVault, error) {
+	return syncVault(ctx, fs, localEV)
+}
+
+// lock takes an exclusive lock file next to the vault, waiting for other
+// writers on this machine. Locks older than staleLockAge are broken.
+func (fs *FilesystemStorage) lock(ctx context.Context) (func(), error) {
+	lockPath := fs.vaultPath() + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
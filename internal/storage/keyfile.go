@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// ErrKeyFileRequired is returned when a vault protected by a key file is
+// opened without one
+var ErrKeyFileRequired = errors.New("vault is protected by a key file, but none was given")
+
+// ErrWrongKeyFile is returned when the key file given is not the vault's
+var ErrWrongKeyFile = errors.New("wrong key file")
+
+// RequiresKeyFile reports whether the vault key is also wrapped with a key file
+func (ev *EncryptedVault) RequiresKeyFile() bool {
+	return ev.KeyFileID != ""
+}
+
+// SetKeyFile records that the vault key is wrapped with keyFile as well as
+// the master password. Wrap it with a master key passed through
+// WithKeyFile afterwards, and sign the envelope.
+func (ev *EncryptedVault) SetKeyFile(keyFile []byte) error {
+	id, err := crypto.KeyFileID(keyFile)
+	if err != nil {
+		return err
+	}
+	ev.KeyFileID = crypto.EncodeBase64(id)
+	return nil
+}
+
+// WithKeyFile returns the key that wraps the vault key, given the master key
+// derived from the password. For vaults protected by a key file it combines
+// the two and zeroizes masterKey, even on error; other vaults get masterKey
+// back and keyFile is ignored.
+func (ev *EncryptedVault) WithKeyFile(masterKey, keyFile []byte) ([]byte, error) {
+	if !ev.RequiresKeyFile() {
+		return masterKey, nil
+	}
+	defer crypto.Zeroize(masterKey)
+
+	if keyFile == nil {
+		return nil, ErrKeyFileRequired
+	}
+
+	expected, err := crypto.DecodeBase64(ev.KeyFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key file ID: %w", err)
+	}
+	id, err := crypto.KeyFileID(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if !crypto.ConstantTimeCompare(id, expected) {
+		return nil, ErrWrongKeyFile
+	}
+
+	return crypto.CombineKeyFile(masterKey, keyFile)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// newTestKeyFileVault is newTestEncryptedVault for a vault protected by keyFile
+func newTestKeyFileVault(t *testing.T, v *vault.Vault, password, keyFile []byte) *EncryptedVault {
+	t.Helper()
+	ev, vaultKey := newTestEncryptedVault(t, v, password, SchemaVersionSingle)
+	if err := ev.SetKeyFile(keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	salt, err := crypto.DecodeBase64(ev.SaltMaster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := crypto.DeriveMasterKey(password, salt, crypto.KDFParams{
+		Algo:        testKDFParams.Algo,
+		Memory:      testKDFParams.Memory,
+		Iterations:  testKDFParams.Iterations,
+		Parallelism: testKDFParams.Parallelism,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err = ev.WithKeyFile(masterKey, keyFile)
+	if err != nil {
+		t.Fatalf("WithKeyFile: %v", err)
+	}
+	defer crypto.Zeroize(masterKey)
+
+	encVaultKey, nonce, err := crypto.EncryptVaultKey(vaultKey, masterKey, ev.Cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.EncVaultKey = crypto.EncodeBase64(encVaultKey)
+	ev.VaultKeyNonce = crypto.EncodeBase64(nonce)
+	if err := ev.Sign(vaultKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return ev
+}
+
+func TestUnlockVaultKeyFile(t *testing.T) {
+	password := []byte("master password")
+	keyFile, err := crypto.GenerateKeyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyFile, err := crypto.GenerateKeyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vault.NewVault()
+	v.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, nil)
+	ev := newTestKeyFileVault(t, v, password, keyFile)
+
+	tests := []struct {
+		name     string
+		password []byte
+		keyFile  []byte
+		wantErr  error
+	}{
+		{"password and key file", password, keyFile, nil},
+		{"no key file", password, nil, ErrKeyFileRequired},
+		{"wrong key file", password, otherKeyFile, ErrWrongKeyFile},
+		{"wrong password", []byte("wrong"), keyFile, ErrWrongPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, _, err := UnlockVault(ev, tt.password, tt.keyFile)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UnlockVault error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && opened.GetEntry("github") == nil {
+				t.Error("opened vault lost its entry")
+			}
+		})
+	}
+
+	// Dropping the key file requirement from the envelope doesn't let the
+	// password alone unwrap the vault key
+	stripped := *ev
+	stripped.KeyFileID = ""
+	if _, _, err := UnlockVault(&stripped, password, nil); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("UnlockVault without key_file_id error = %v, want %v", err, ErrWrongPassword)
+	}
+}
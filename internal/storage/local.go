@@ -1,18 +1,45 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/vaultctl/vaultctl/internal/atomic"
 	"github.com/vaultctl/vaultctl/internal/crypto"
 	"github.com/vaultctl/vaultctl/internal/vault"
 )
 
+// ErrCorruptVault is returned when the local vault file exists but can't be
+// parsed, for example after a crash part way through a write
+var ErrCorruptVault = errors.New("local vault file is corrupted")
+
+// ErrVaultNotFound is returned when there is no vault to open
+var ErrVaultNotFound = errors.New("vault not found")
+
+// ErrWrongPassword is returned when the master password can't decrypt the
+// vault key
+var ErrWrongPassword = errors.New("wrong master password")
+
+// ErrVaultDataCorrupt is returned when the vault key decrypts, so the
+// password is right, but the vault contents don't
+var ErrVaultDataCorrupt = errors.New("vault data is corrupted")
+
+// ErrInsecurePermissions is returned in strict mode when a vault or backup
+// file can be accessed by users other than its owner
+var ErrInsecurePermissions = errors.New("insecure file permissions")
+
 // LocalStorage handles local encrypted vault file operations
 type LocalStorage struct {
 	VaultPath string
+
+	// StrictPerms refuses to load a vault file that is readable or
+	// writable beyond its owner, instead of warning
+	StrictPerms bool
+
+	permsChecked bool
 }
 
 // NewLocalStorage creates a new local storage instance
@@ -25,7 +52,7 @@ func NewLocalStorage(vaultPath string) *LocalStorage {
 // EnsureDir ensures the vault directory exists
 func (ls *LocalStorage) EnsureDir() error {
 	dir := filepath.Dir(ls.VaultPath)
-	return os.MkdirAll(dir, 0700)
+	return atomic.EnsureDir(dir, 0700)
 }
 
 // SaveEncryptedVault saves an encrypted vault to disk
@@ -39,7 +66,7 @@ func (ls *LocalStorage) SaveEncryptedVault(ev *EncryptedVault) error {
 		return fmt.Errorf("failed to serialize encrypted vault: %w", err)
 	}
 
-	if err := os.WriteFile(ls.VaultPath, data, 0600); err != nil {
+	if err := atomic.WriteFile(ls.VaultPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write vault file: %w", err)
 	}
 
@@ -51,19 +78,83 @@ func (ls *LocalStorage) LoadEncryptedVault() (*EncryptedVault, error) {
 	data, err := os.ReadFile(ls.VaultPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("vault not found at %s. Run 'vaultctl init' first", ls.VaultPath)
+			return nil, fmt.Errorf("%w at %s. Run 'vaultctl init' first", ErrVaultNotFound, ls.VaultPath)
 		}
 		return nil, fmt.Errorf("failed to read vault file: %w", err)
 	}
 
+	// Check once per process rather than on every load
+	if !ls.permsChecked {
+		if err := CheckFilePermissions(ls.VaultPath, ls.StrictPerms); err != nil {
+			return nil, err
+		}
+		ls.permsChecked = true
+	}
+
+	// A newer format isn't damage, and must not be repaired over
 	ev, err := EncryptedVaultFromJSON(data)
+	if errors.Is(err, vault.ErrSchemaTooNew) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse vault file: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrCorruptVault, err)
 	}
 
 	return ev, nil
 }
 
+// CheckFilePermissions warns on stderr if the file at path is accessible to
+// users other than its owner, like ssh does for private keys. With strict
+// set the problem is returned as an error instead.
+func CheckFilePermissions(path string, strict bool) error {
+	err := checkPermissions(path)
+	if !errors.Is(err, ErrInsecurePermissions) {
+		// Errors reading the file are reported by whoever reads it
+		return nil
+	}
+	if strict {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	return nil
+}
+
+// SyncBasePath returns the path of the copy of the vault as of the last
+// successful sync, used as the common ancestor when merging
+func (ls *LocalStorage) SyncBasePath() string {
+	return ls.VaultPath + ".base"
+}
+
+// SaveSyncBase records ev as the last vault known to match the remote
+func (ls *LocalStorage) SaveSyncBase(ev *EncryptedVault) error {
+	data, err := ev.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize sync base: %w", err)
+	}
+	if err := atomic.WriteFile(ls.SyncBasePath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write sync base: %w", err)
+	}
+	return nil
+}
+
+// LoadSyncBase loads the vault recorded at the last successful sync.
+// It returns nil without an error if no sync has been recorded.
+func (ls *LocalStorage) LoadSyncBase() (*EncryptedVault, error) {
+	data, err := os.ReadFile(ls.SyncBasePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sync base: %w", err)
+	}
+
+	ev, err := EncryptedVaultFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sync base: %w", err)
+	}
+	return ev, nil
+}
+
 // Exists checks if the vault file exists
 func (ls *LocalStorage) Exists() bool {
 	_, err := os.Stat(ls.VaultPath)
@@ -72,100 +163,138 @@ func (ls *LocalStorage) Exists() bool {
 
 // EncryptAndSave encrypts a vault and saves it locally
 func (ls *LocalStorage) EncryptAndSave(v *vault.Vault, vaultKey []byte, ev *EncryptedVault) error {
-	plaintext, err := v.ToJSON()
-	if err != nil {
-		return fmt.Errorf("failed to serialize vault: %w", err)
-	}
-
-	ciphertext, nonce, err := crypto.Encrypt(plaintext, vaultKey)
-	if err != nil {
+	if err := ev.SealVault(v, vaultKey); err != nil {
 		return fmt.Errorf("failed to encrypt vault: %w", err)
 	}
 
-	ev.Ciphertext = crypto.EncodeBase64(ciphertext)
-	ev.Nonce = crypto.EncodeBase64(nonce)
 	ev.SetModifiedAt(time.Now())
 	ev.Version++
 
+	if err := ev.Sign(vaultKey); err != nil {
+		return fmt.Errorf("failed to sign vault: %w", err)
+	}
+
 	return ls.SaveEncryptedVault(ev)
 }
 
-// DecryptAndLoad decrypts and loads a vault from local storage
-func (ls *LocalStorage) DecryptAndLoad(masterPassword []byte) (*vault.Vault, []byte, error) {
+// DecryptAndLoad decrypts and loads a vault from local storage. keyFile is
+// only needed for vaults protected by one and may be nil.
+func (ls *LocalStorage) DecryptAndLoad(masterPassword, keyFile []byte) (*vault.Vault, []byte, error) {
 	ev, err := ls.LoadEncryptedVault()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return decryptVault(ev, masterPassword)
+	return UnlockVault(ev, masterPassword, keyFile)
 }
 
-// decryptVault is a helper that decrypts a vault from an EncryptedVault
-func decryptVault(ev *EncryptedVault, masterPassword []byte) (*vault.Vault, []byte, error) {
-	// Decode salt and encrypted vault key
-	salt, err := crypto.DecodeBase64(ev.SaltMaster)
+// OpenVault verifies and decrypts an encrypted vault with an unwrapped vault key
+func OpenVault(ev *EncryptedVault, vaultKey []byte) (*vault.Vault, error) {
+	if err := ev.VerifyEnvelope(vaultKey); err != nil {
+		return nil, err
+	}
+	return ev.OpenPayload(vaultKey)
+}
+
+// UnlockVault unlocks an encrypted vault with the master password: it
+// unwraps the vault key, verifies the envelope MAC and decrypts the vault.
+// keyFile is only needed for vaults protected by one and may be nil. It
+// returns the vault and its key, which the caller must zeroize.
+func UnlockVault(ev *EncryptedVault, masterPassword, keyFile []byte) (*vault.Vault, []byte, error) {
+	vaultKey, err := UnlockVaultKey(ev, masterPassword, keyFile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode salt: %w", err)
+		return nil, nil, err
 	}
 
-	encVaultKey, err := crypto.DecodeBase64(ev.EncVaultKey)
+	// Verify envelope metadata before trusting it
+	if err := ev.VerifyEnvelope(vaultKey); err != nil {
+		crypto.Zeroize(vaultKey)
+		return nil, nil, err
+	}
+
+	// The vault key is authenticated, so from here on a failure means the
+	// password was right and the vault data is damaged
+	v, err := ev.OpenPayload(vaultKey)
+	if err != nil {
+		crypto.Zeroize(vaultKey)
+		if errors.Is(err, vault.ErrSchemaTooNew) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrVaultDataCorrupt, err)
+	}
+
+	return v, vaultKey, nil
+}
+
+// UnlockVaultKey derives the master key from the password and key file and
+// unwraps the vault key with it, without opening the vault. The master key
+// is zeroized before it returns.
+func UnlockVaultKey(ev *EncryptedVault, masterPassword, keyFile []byte) ([]byte, error) {
+	masterKey, err := ev.DeriveMasterKey(masterPassword, keyFile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode encrypted vault key: %w", err)
+		return nil, err
+	}
+	defer crypto.Zeroize(masterKey)
+
+	return ev.UnwrapVaultKey(masterKey)
+}
+
+// DeriveMasterKey derives the master key from the password with the vault's
+// stored salt and KDF parameters, combined with keyFile for vaults protected
+// by one. It is the key UnwrapVaultKey needs; the caller must zeroize it.
+//
+// The parameters are not covered by the envelope MAC until the vault key is
+// unwrapped, so parameters below the minimums are refused here rather than
+// used to hash the password. Other changes to them, or to the salt, derive a
+// key that doesn't unwrap the vault key.
+func (ev *EncryptedVault) DeriveMasterKey(masterPassword, keyFile []byte) ([]byte, error) {
+	salt, err := crypto.DecodeBase64(ev.SaltMaster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
 	}
 
-	// Derive master key
 	kdfParams := crypto.KDFParams{
-		Algo:       ev.KDFParams.Algo,
-		Memory:     ev.KDFParams.Memory,
-		Iterations: ev.KDFParams.Iterations,
+		Algo:        ev.KDFParams.Algo,
+		Memory:      ev.KDFParams.Memory,
+		Iterations:  ev.KDFParams.Iterations,
 		Parallelism: ev.KDFParams.Parallelism,
 	}
-	masterKey := crypto.DeriveMasterKey(masterPassword, salt, kdfParams)
+	if err := kdfParams.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kdf parameters: %w", err)
+	}
+	masterKey, err := crypto.DeriveMasterKey(masterPassword, salt, kdfParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive master key: %w", err)
+	}
+	return ev.WithKeyFile(masterKey, keyFile)
+}
+
+// UnwrapVaultKey decrypts the vault key with the master key. A key that
+// doesn't decrypt it means a wrong password, reported as ErrWrongPassword.
+func (ev *EncryptedVault) UnwrapVaultKey(masterKey []byte) ([]byte, error) {
+	encVaultKey, err := crypto.DecodeBase64(ev.EncVaultKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted vault key: %w", err)
+	}
 
-	// Decrypt vault key
 	var vaultKeyNonce []byte
 	if ev.VaultKeyNonce != "" {
 		vaultKeyNonce, err = crypto.DecodeBase64(ev.VaultKeyNonce)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to decode vault key nonce: %w", err)
+			return nil, fmt.Errorf("failed to decode vault key nonce: %w", err)
 		}
 	} else {
 		// Backward compatibility: if vault_key_nonce doesn't exist, use nonce
 		// This handles old vaults created before we added the separate nonce field
 		vaultKeyNonce, err = crypto.DecodeBase64(ev.Nonce)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to decode nonce: %w", err)
+			return nil, fmt.Errorf("failed to decode nonce: %w", err)
 		}
 	}
-	
-	vaultKey, err := crypto.DecryptVaultKey(encVaultKey, vaultKeyNonce, masterKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt vault key: %w", err)
-	}
 
-	// Decode ciphertext and nonce
-	ciphertext, err := crypto.DecodeBase64(ev.Ciphertext)
+	vaultKey, err := crypto.DecryptVaultKey(encVaultKey, vaultKeyNonce, masterKey, ev.Cipher)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+		return nil, fmt.Errorf("failed to decrypt vault key: %w", ErrWrongPassword)
 	}
-
-	nonce, err := crypto.DecodeBase64(ev.Nonce)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode nonce: %w", err)
-	}
-
-	// Decrypt vault
-	plaintext, err := crypto.Decrypt(ciphertext, nonce, vaultKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt vault: %w", err)
-	}
-
-	// Deserialize vault
-	v, err := vault.FromJSON(plaintext)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to deserialize vault: %w", err)
-	}
-
-	return v, vaultKey, nil
+	return vaultKey, nil
 }
-
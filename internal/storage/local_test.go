@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// testKDFParams are the cheapest accepted KDF parameters, to keep tests fast
+var testKDFParams = KDFParams{
+	Algo:        crypto.AlgoArgon2id,
+	Memory:      crypto.MinMemory,
+	Iterations:  crypto.MinIterations,
+	Parallelism: crypto.MinParallelism,
+}
+
+// newTestEncryptedVault seals v with a new vault key wrapped by password,
+// as init does, and returns the envelope and vault key
+func newTestEncryptedVault(t *testing.T, v *vault.Vault, password []byte, schemaVersion int) (*EncryptedVault, []byte) {
+	t.Helper()
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := crypto.DeriveMasterKey(password, salt, crypto.KDFParams{
+		Algo:        testKDFParams.Algo,
+		Memory:      testKDFParams.Memory,
+		Iterations:  testKDFParams.Iterations,
+		Parallelism: testKDFParams.Parallelism,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crypto.Zeroize(masterKey)
+	ev := &EncryptedVault{
+		SchemaVersion: schemaVersion,
+		VaultID:       v.VaultID,
+		SaltMaster:    crypto.EncodeBase64(salt),
+		KDFParams:     testKDFParams,
+		Cipher:        crypto.CipherXChaCha20Poly1305,
+		Version:       1,
+	}
+	encVaultKey, nonce, err := crypto.EncryptVaultKey(vaultKey, masterKey, ev.Cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.EncVaultKey = crypto.EncodeBase64(encVaultKey)
+	ev.VaultKeyNonce = crypto.EncodeBase64(nonce)
+
+	if err := ev.SealVault(v, vaultKey); err != nil {
+		t.Fatalf("SealVault: %v", err)
+	}
+	if err := ev.Sign(vaultKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return ev, vaultKey
+}
+
+func TestUnlockVaultZeroizesPlaintext(t *testing.T) {
+	for _, format := range []string{FormatSingle, FormatSplit} {
+		t.Run(format, func(t *testing.T) {
+			schemaVersion, _ := SchemaVersionFor(format)
+			v := vault.NewVault()
+			v.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, nil)
+			password := []byte("master password")
+			ev, _ := newTestEncryptedVault(t, v, password, schemaVersion)
+
+			// Keep the buffer the payload is decrypted into
+			var plaintexts [][]byte
+			orig := decryptPayload
+			decryptPayload = func(ev *EncryptedVault, vaultKey []byte) ([]byte, error) {
+				plaintext, err := orig(ev, vaultKey)
+				plaintexts = append(plaintexts, plaintext)
+				return plaintext, err
+			}
+			t.Cleanup(func() { decryptPayload = orig })
+
+			opened, _, err := UnlockVault(ev, password, nil)
+			if err != nil {
+				t.Fatalf("UnlockVault: %v", err)
+			}
+			if e := opened.GetEntry("github"); e == nil || string(e.Password) != "hunter2" {
+				t.Fatalf("opened vault lost its entry: %+v", e)
+			}
+
+			if len(plaintexts) != 1 {
+				t.Fatalf("payload decrypted %d times, want 1", len(plaintexts))
+			}
+			if len(plaintexts[0]) == 0 {
+				t.Fatal("decrypted payload is empty")
+			}
+			if !bytes.Equal(plaintexts[0], make([]byte, len(plaintexts[0]))) {
+				t.Errorf("plaintext not zeroized after UnlockVault: %q", plaintexts[0])
+			}
+		})
+	}
+}
+
+func TestUsageLog(t *testing.T) {
+	ls := NewLocalStorage(filepath.Join(t.TempDir(), "vault.db"))
+	key, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for range 2 {
+		if err := ls.LogUsage(vault.UsageLog{"entry": {Count: 1, LastUsedAt: at}}, key); err != nil {
+			t.Fatalf("LogUsage: %v", err)
+		}
+	}
+	log, err := ls.LoadUsageLog(key)
+	if err != nil {
+		t.Fatalf("LoadUsageLog: %v", err)
+	}
+	if log["entry"].Count != 2 {
+		t.Errorf("logged count = %d, want 2", log["entry"].Count)
+	}
+
+	// A log from before the vault key changed is ignored
+	otherKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log, err := ls.LoadUsageLog(otherKey); err != nil || len(log) != 0 {
+		t.Errorf("LoadUsageLog with another key = %v, %v, want an empty log", log, err)
+	}
+
+	if err := ls.ClearUsageLog(); err != nil {
+		t.Fatalf("ClearUsageLog: %v", err)
+	}
+	if log, err := ls.LoadUsageLog(key); err != nil || len(log) != 0 {
+		t.Errorf("LoadUsageLog after clear = %v, %v, want an empty log", log, err)
+	}
+}
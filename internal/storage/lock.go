@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrVaultInUse is returned by Lock when another process holds the vault lock
+var ErrVaultInUse = errors.New("vault is in use by another vaultctl process")
+
+// errLockHeld is returned by the platform lockFile when the lock is taken
+var errLockHeld = errors.New("lock held")
+
+// LockPath returns the path of the lock file guarding the vault
+func (ls *LocalStorage) LockPath() string {
+	return ls.VaultPath + ".lock"
+}
+
+// Lock takes an exclusive advisory lock on the vault so that two processes
+// can't both read the vault, change it and save over each other's changes.
+// It fails with ErrVaultInUse instead of waiting. The lock is released by
+// the returned function, or by the OS if the process exits first.
+func (ls *LocalStorage) Lock() (func(), error) {
+	if err := ls.EnsureDir(); err != nil {
+		return nil, fmt.Errorf("failed to create vault directory: %w", err)
+	}
+
+	f, err := os.OpenFile(ls.LockPath(), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if !errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("failed to lock vault: %w", err)
+		}
+		if pid := readLockOwner(ls.LockPath()); pid != 0 {
+			return nil, fmt.Errorf("%w (pid %d). Try again once it finishes", ErrVaultInUse, pid)
+		}
+		return nil, fmt.Errorf("%w. Try again once it finishes", ErrVaultInUse)
+	}
+
+	// Record the owner for the error above; the lock itself is what counts
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// readLockOwner returns the PID recorded in the lock file, or 0
+func readLockOwner(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
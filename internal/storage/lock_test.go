@@ -0,0 +1,36 @@
+//go:build unix || windows
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockIsExclusive(t *testing.T) {
+	ls := NewLocalStorage(filepath.Join(t.TempDir(), "vault.db"))
+	release, err := ls.Lock()
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	// Another LocalStorage opens the lock file separately, as another
+	// process would
+	other := NewLocalStorage(ls.VaultPath)
+	if _, err := other.Lock(); !errors.Is(err, ErrVaultInUse) {
+		t.Fatalf("second Lock error = %v, want ErrVaultInUse", err)
+	} else if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("second Lock error = %v, want the holder's PID", err)
+	}
+
+	release()
+	releaseOther, err := other.Lock()
+	if err != nil {
+		t.Fatalf("Lock after release: %v", err)
+	}
+	releaseOther()
+}
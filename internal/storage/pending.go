@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/atomic"
+)
+
+// PendingWrite records a local save that could not be pushed to remote storage
+type PendingWrite struct {
+	Version         int64     `json:"version"`          // Local version that failed to push
+	ExpectedVersion int64     `json:"expected_version"` // Remote version the push was conditioned on
+	QueuedAt        time.Time `json:"queued_at"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// PendingQueuePath returns the path of the pending remote write queue
+func (ls *LocalStorage) PendingQueuePath() string {
+	return ls.VaultPath + ".pending"
+}
+
+// LoadPendingWrites returns the queued writes, oldest first
+func (ls *LocalStorage) LoadPendingWrites() ([]PendingWrite, error) {
+	data, err := os.ReadFile(ls.PendingQueuePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read pending queue: %w", err)
+	}
+
+	var pending []PendingWrite
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to parse pending queue: %w", err)
+	}
+	return pending, nil
+}
+
+// QueuePendingWrite appends a write to the pending queue
+func (ls *LocalStorage) QueuePendingWrite(pw PendingWrite) error {
+	pending, err := ls.LoadPendingWrites()
+	if err != nil {
+		return err
+	}
+	pending = append(pending, pw)
+
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize pending queue: %w", err)
+	}
+	if err := atomic.WriteFile(ls.PendingQueuePath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write pending queue: %w", err)
+	}
+	return nil
+}
+
+// ClearPendingWrites empties the pending queue
+func (ls *LocalStorage) ClearPendingWrites() error {
+	if err := os.Remove(ls.PendingQueuePath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear pending queue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPendingWriteQueue(t *testing.T) {
+	ls := NewLocalStorage(filepath.Join(t.TempDir(), "vault.db"))
+
+	pending, err := ls.LoadPendingWrites()
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("LoadPendingWrites without a queue = %+v, %v, want none", pending, err)
+	}
+
+	queuedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writes := []PendingWrite{
+		{Version: 4, ExpectedVersion: 3, QueuedAt: queuedAt, Error: "network down"},
+		{Version: 5, ExpectedVersion: 4, QueuedAt: queuedAt.Add(time.Minute)},
+	}
+	for _, pw := range writes {
+		if err := ls.QueuePendingWrite(pw); err != nil {
+			t.Fatalf("QueuePendingWrite: %v", err)
+		}
+	}
+
+	pending, err = ls.LoadPendingWrites()
+	if err != nil {
+		t.Fatalf("LoadPendingWrites: %v", err)
+	}
+	if len(pending) != len(writes) {
+		t.Fatalf("queue = %+v, want %+v", pending, writes)
+	}
+	for i := range writes {
+		if pending[i].Version != writes[i].Version || pending[i].ExpectedVersion != writes[i].ExpectedVersion ||
+			!pending[i].QueuedAt.Equal(writes[i].QueuedAt) || pending[i].Error != writes[i].Error {
+			t.Errorf("queue[%d] = %+v, want %+v", i, pending[i], writes[i])
+		}
+	}
+
+	if err := ls.ClearPendingWrites(); err != nil {
+		t.Fatalf("ClearPendingWrites: %v", err)
+	}
+	if err := ls.ClearPendingWrites(); err != nil {
+		t.Errorf("ClearPendingWrites of an empty queue: %v", err)
+	}
+	if pending, err := ls.LoadPendingWrites(); err != nil || len(pending) != 0 {
+		t.Errorf("LoadPendingWrites after clearing = %+v, %v, want none", pending, err)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package storage
+
+// checkPermissions is a no-op on platforms without Unix file modes
+func checkPermissions(path string) error {
+	return nil
+}
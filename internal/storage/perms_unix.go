@@ -0,0 +1,21 @@
+//go:build unix
+
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkPermissions returns an error wrapping ErrInsecurePermissions if the
+// file at path grants any access to its group or other users
+func checkPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		return fmt.Errorf("%w: %s has mode %04o, run 'chmod 600 %s'", ErrInsecurePermissions, path, mode, path)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrRemoteVaultNotFound is returned by RemoteStore.LoadVault when no vault
+// has been stored remotely yet
+var ErrRemoteVaultNotFound = errors.New("vault not found in remote storage")
+
+// ErrVersionConflict is returned by RemoteStore.SaveVault when the remote
+// vault's version does not match the expected version
+var ErrVersionConflict = errors.New("version conflict: remote vault has been updated. Run 'vaultctl sync' first")
+
+// RemoteStore is a backend that holds a copy of the encrypted vault for
+// syncing between devices. Implementations only ever see encrypted data.
+type RemoteStore interface {
+	// SaveVault stores ev if the remote version equals expectedVersion
+	// (or no remote vault exists), and returns ErrVersionConflict otherwise
+	SaveVault(ctx context.Context, ev *EncryptedVault, expectedVersion int64) error
+
+	// LoadVault returns the remote vault, or ErrRemoteVaultNotFound
+	LoadVault(ctx context.Context) (*EncryptedVault, error)
+
+	// SyncVault reconciles localEV with the remote vault and returns the
+	// vault that should be kept locally
+	SyncVault(ctx context.Context, localEV *EncryptedVault) (*EncryptedVault, error)
+}
+
+// VaultDeleter is implemented by remote stores that can delete the vault
+type VaultDeleter interface {
+	// DeleteVault deletes the remote vault if its version equals
+	// expectedVersion, and returns ErrVersionConflict otherwise
+	DeleteVault(ctx context.Context, expectedVersion int64) error
+}
+
+// syncVault implements SyncVault on top of SaveVault and LoadVault: the
+// newer version wins, and local is pushed when it is at least as new
+func syncVault(ctx context.Context, rs RemoteStore, localEV *EncryptedVault) (*EncryptedVault, error) {
+	remoteEV, err := rs.LoadVault(ctx)
+	if err != nil {
+		// If remote doesn't exist, push local
+		if errors.Is(err, ErrRemoteVaultNotFound) {
+			return localEV, rs.SaveVault(ctx, localEV, localEV.Version-1)
+		}
+		return nil, err
+	}
+
+	// If local is newer or same, push local
+	if localEV.Version >= remoteEV.Version {
+		if err := rs.SaveVault(ctx, localEV, remoteEV.Version); err != nil {
+			return nil, err
+		}
+		return localEV, nil
+	}
+
+	// Remote is newer, return remote
+	return remoteEV, nil
+}
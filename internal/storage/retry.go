@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Retry defaults
+const (
+	DefaultRetryMaxAttempts = 5
+	DefaultRetryBaseDelay   = 100 * time.Millisecond
+	DefaultRetryMaxDelay    = 5 * time.Second
+	DefaultRetryJitter      = 0.5
+)
+
+// RetryPolicy controls how remote calls are retried after transient failures
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled on each further retry
+	MaxDelay    time.Duration // Upper bound on a single delay
+	Jitter      float64       // Fraction of each delay that is randomized, from 0 to 1
+}
+
+// DefaultRetryPolicy returns the default retry policy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryMaxAttempts,
+		BaseDelay:   DefaultRetryBaseDelay,
+		MaxDelay:    DefaultRetryMaxDelay,
+		Jitter:      DefaultRetryJitter,
+	}
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the
+// attempts run out. The last error is returned.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !isRetryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(p.delay(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// delay returns the backoff before retry number attempt (starting at 1)
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		jitter := time.Duration(float64(d) * p.Jitter * rand.Float64())
+		d -= jitter
+	}
+	return d
+}
+
+// isRetryable reports whether err is a throttling, server-side or network
+// error. Conditional check failures are never retried: they mean another
+// device won the write.
+func isRetryable(err error) bool {
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) || errors.Is(err, ErrVersionConflict) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var throughputErr *types.ProvisionedThroughputExceededException
+	var limitErr *types.RequestLimitExceeded
+	var internalErr *types.InternalServerError
+	if errors.As(err, &throughputErr) || errors.As(err, &limitErr) || errors.As(err, &internalErr) {
+		return true
+	}
+
+	// The SDK's response errors expose the HTTP status code
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		code := statusErr.HTTPStatusCode()
+		return code == 429 || code >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
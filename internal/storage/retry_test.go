@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// statusError is an error carrying an HTTP status code, as SDK response
+// errors do
+type statusError int
+
+func (e statusError) Error() string       { return fmt.Sprintf("http status %d", int(e)) }
+func (e statusError) HTTPStatusCode() int { return int(e) }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throughput exceeded", &types.ProvisionedThroughputExceededException{}, true},
+		{"request limit exceeded", fmt.Errorf("save: %w", &types.RequestLimitExceeded{}), true},
+		{"internal server error", &types.InternalServerError{}, true},
+		{"too many requests", statusError(429), true},
+		{"service unavailable", statusError(503), true},
+		{"network", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"conditional check failed", &types.ConditionalCheckFailedException{}, false},
+		{"version conflict", fmt.Errorf("save: %w", ErrVersionConflict), false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", fmt.Errorf("load: %w", context.DeadlineExceeded), false},
+		{"bad request", statusError(400), false},
+		{"other", errors.New("validation failed"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3}
+	tests := []struct {
+		name      string
+		errs      []error // Returned by successive calls, then nil
+		wantCalls int
+		wantErr   error
+	}{
+		{"succeeds first", nil, 1, nil},
+		{"retries throttling", []error{&types.RequestLimitExceeded{}, statusError(500)}, 3, nil},
+		{"gives up", []error{statusError(500), statusError(500), statusError(500), statusError(500)}, 3, statusError(500)},
+		{"conflict not retried", []error{ErrVersionConflict}, 1, ErrVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := p.Do(context.Background(), func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do: err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Do called fn %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	slow := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	if err := slow.Do(ctx, func() error { calls++; return statusError(503) }); calls != 1 || err == nil {
+		t.Errorf("Do with a canceled context = %v after %d calls, want the error after 1", err, calls)
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrAttachmentNotFound is returned when an attachment object does not exist
+var ErrAttachmentNotFound = errors.New("attachment not found in S3")
+
+// S3AttachmentStore stores encrypted attachment content in an S3 bucket.
+// It only ever sees ciphertext.
+type S3AttachmentStore struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3AttachmentStore creates a new S3 attachment store
+func NewS3AttachmentStore(bucket, region string) (*S3AttachmentStore, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &S3AttachmentStore{
+		client: s3.NewFromConfig(cfg),
+		bucket: bucket,
+	}, nil
+}
+
+// PutAttachment uploads encrypted attachment content under key
+func (s *S3AttachmentStore) PutAttachment(ctx context.Context, key string, ciphertext []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(ciphertext),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload attachment: %w", err)
+	}
+	return nil
+}
+
+// GetAttachment downloads the encrypted attachment content stored under key
+func (s *S3AttachmentStore) GetAttachment(ctx context.Context, key string) ([]byte, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", ErrAttachmentNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to download attachment: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return data, nil
+}
+
+// DeleteAttachment removes the attachment object stored under key
+func (s *S3AttachmentStore) DeleteAttachment(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// Envelope schema versions
+const (
+	// SchemaVersionSingle seals the whole vault as one ciphertext
+	SchemaVersionSingle = 1
+
+	// SchemaVersionSplit also seals each entry's secret fields under a key
+	// of their own, so entry metadata can be read without decrypting any
+	// password
+	SchemaVersionSplit = 2
+
+	// MaxSchemaVersion is the newest envelope this version can read
+	MaxSchemaVersion = SchemaVersionSplit
+)
+
+// checkSchemaVersion refuses envelopes written by a newer vaultctl, which
+// this version would misread or save over with data lost. Older envelopes
+// are read as they are; their payload is migrated when opened.
+func checkSchemaVersion(schemaVersion int) error {
+	if schemaVersion > MaxSchemaVersion {
+		return fmt.Errorf("%w (envelope version %d, this version supports %d); upgrade vaultctl", vault.ErrSchemaTooNew, schemaVersion, MaxSchemaVersion)
+	}
+	return nil
+}
+
+// Vault formats by name, as shown to users
+const (
+	FormatSingle = "single"
+	FormatSplit  = "split"
+)
+
+// entrySecretsInfo is the HKDF info prefix for per-entry secret keys
+const entrySecretsInfo = "vaultctl entry secrets v1:"
+
+// errSecretsSealed is returned when a vault whose entry secrets were never
+// decrypted would be written in the single format, losing them
+var errSecretsSealed = errors.New("vault was opened without its entry secrets and can't be saved in the single format")
+
+// SchemaVersionFor returns the envelope schema version of a format name
+func SchemaVersionFor(format string) (int, error) {
+	switch format {
+	case FormatSingle:
+		return SchemaVersionSingle, nil
+	case FormatSplit:
+		return SchemaVersionSplit, nil
+	}
+	return 0, fmt.Errorf("unknown vault format: %s (use %s or %s)", format, FormatSingle, FormatSplit)
+}
+
+// Format returns the name of the envelope's vault format
+func (ev *EncryptedVault) Format() string {
+	if ev.IsSplit() {
+		return FormatSplit
+	}
+	return FormatSingle
+}
+
+// IsSplit reports whether entry secrets are sealed separately
+func (ev *EncryptedVault) IsSplit() bool {
+	return ev.SchemaVersion >= SchemaVersionSplit
+}
+
+// SealVault serializes v and encrypts it into the payload. In split vaults
+// each entry's secret fields are sealed first; entries whose secrets were
+// never decrypted keep their sealed copy.
+func (ev *EncryptedVault) SealVault(v *vault.Vault, vaultKey []byte) error {
+	payload := v
+	if ev.IsSplit() {
+		sealed, err := ev.sealEntries(v, vaultKey)
+		if err != nil {
+			return err
+		}
+		payload = sealed
+	} else if v.HasSealedSecrets() {
+		return errSecretsSealed
+	}
+
+	plaintext, err := payload.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize vault: %w", err)
+	}
+	defer crypto.Zeroize(plaintext)
+
+	return ev.SealPayload(plaintext, vaultKey)
+}
+
+// OpenPayload decrypts the payload into a vault, including every entry's
+// secret fields
+func (ev *EncryptedVault) OpenPayload(vaultKey []byte) (*vault.Vault, error) {
+	v, err := ev.OpenMetadata(vaultKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, entries := range [][]vault.Entry{v.Entries, v.DeletedEntries} {
+		for i := range entries {
+			if err := ev.openEntry(&entries[i], vaultKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// decryptPayload decrypts an envelope's payload. Tests wrap it to check
+// that the plaintext is zeroized once parsed.
+var decryptPayload = (*EncryptedVault).DecryptPayload
+
+// OpenMetadata decrypts the payload into a vault. In split vaults the
+// entries' secret fields stay sealed, so no password is decrypted; the
+// result is meant for listing and must not be edited.
+func (ev *EncryptedVault) OpenMetadata(vaultKey []byte) (*vault.Vault, error) {
+	if err := checkSchemaVersion(ev.SchemaVersion); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decryptPayload(ev, vaultKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt vault: %w", err)
+	}
+	defer crypto.Zeroize(plaintext)
+
+	v, err := vault.FromJSON(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize vault: %w", err)
+	}
+	return v, nil
+}
+
+// sealEntries returns a copy of v with every entry's secret fields sealed
+func (ev *EncryptedVault) sealEntries(v *vault.Vault, vaultKey []byte) (*vault.Vault, error) {
+	sealed := *v
+	sealed.Entries = make([]vault.Entry, len(v.Entries))
+	sealed.DeletedEntries = nil
+	if v.DeletedEntries != nil {
+		sealed.DeletedEntries = make([]vault.Entry, len(v.DeletedEntries))
+	}
+
+	for _, pair := range [][2][]vault.Entry{{v.Entries, sealed.Entries}, {v.DeletedEntries, sealed.DeletedEntries}} {
+		for i, e := range pair[0] {
+			if !e.SecretsSealed() {
+				if err := ev.sealEntry(&e, vaultKey); err != nil {
+					return nil, err
+				}
+			}
+			pair[1][i] = e
+		}
+	}
+	return &sealed, nil
+}
+
+// sealEntry encrypts e's secret fields into e.SealedSecrets. e must be a
+// copy: its secret fields are cleared.
+func (ev *EncryptedVault) sealEntry(e *vault.Entry, vaultKey []byte) error {
+	secrets := e.TakeSecrets()
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to serialize secrets of '%s': %w", e.Name, err)
+	}
+	defer crypto.Zeroize(plaintext)
+
+	key, err := crypto.DeriveSubkey(vaultKey, entrySecretsInfo+e.ID)
+	if err != nil {
+		return err
+	}
+	defer crypto.Zeroize(key)
+
+	ciphertext, nonce, err := crypto.Encrypt(plaintext, key, ev.Cipher, ev.entryAAD(e.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secrets of '%s': %w", e.Name, err)
+	}
+	e.SealedSecrets = crypto.EncodeBase64(append(nonce, ciphertext...))
+	return nil
+}
+
+// openEntry decrypts e.SealedSecrets back into e's secret fields
+func (ev *EncryptedVault) openEntry(e *vault.Entry, vaultKey []byte) error {
+	if !e.SecretsSealed() {
+		return nil
+	}
+
+	sealed, err := crypto.DecodeBase64(e.SealedSecrets)
+	if err != nil {
+		return fmt.Errorf("failed to decode secrets of '%s': %w", e.Name, err)
+	}
+	nonceSize, err := crypto.NonceSizeFor(ev.Cipher)
+	if err != nil {
+		return err
+	}
+	if len(sealed) < nonceSize+crypto.TagSize {
+		return fmt.Errorf("secrets of '%s' are truncated", e.Name)
+	}
+
+	key, err := crypto.DeriveSubkey(vaultKey, entrySecretsInfo+e.ID)
+	if err != nil {
+		return err
+	}
+	defer crypto.Zeroize(key)
+
+	plaintext, err := crypto.Decrypt(sealed[nonceSize:], sealed[:nonceSize], key, ev.Cipher, ev.entryAAD(e.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets of '%s': %w", e.Name, err)
+	}
+	defer crypto.Zeroize(plaintext)
+
+	var secrets vault.EntrySecrets
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("failed to deserialize secrets of '%s': %w", e.Name, err)
+	}
+	e.RestoreSecrets(secrets)
+	return nil
+}
+
+// entryAAD binds an entry's sealed secrets to the vault and the entry, so
+// they can't be moved to another entry
+func (ev *EncryptedVault) entryAAD(entryID string) []byte {
+	return append(ev.AssociatedData(), ":entry:"+entryID...)
+}
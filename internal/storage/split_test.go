@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// secretValues are the secrets newSplitTestVault stores, none of which may
+// show up in a split vault's metadata
+var secretValues = []string{"hunter2", "recovery notes", "111-222", "333-444", "pin-1234", "key-file-data", "s3cret"}
+
+// newSplitTestVault returns a vault whose entries use every secret field,
+// with one entry in the trash
+func newSplitTestVault() *vault.Vault {
+	v := vault.NewVault()
+	e := v.AddEntryWith("github", "alice", []byte("hunter2"), "https://github.com", "recovery notes", []string{"111-222", "333-444"}, []string{"work"}, vault.EntryOptions{
+		Fields: []vault.CustomField{{Name: "pin", Value: "pin-1234", Secret: true}},
+	})
+	v.EditEntry(e.ID, func(e *vault.Entry) error {
+		e.UsedBackupCodes = []string{"111-222"}
+		e.Attachments = []vault.Attachment{{Name: "key.pem", Size: 13, Data: []byte("key-file-data")}}
+		return nil
+	})
+	v.AddEntry("old", "bob", []byte("s3cret"), "", "", nil, nil)
+	v.RemoveEntry("old")
+	return v
+}
+
+// secretsOf returns the secret fields of every entry, trash included, by ID
+func secretsOf(v *vault.Vault) map[string]vault.EntrySecrets {
+	secrets := make(map[string]vault.EntrySecrets)
+	for _, entries := range [][]vault.Entry{v.Entries, v.DeletedEntries} {
+		for _, e := range entries {
+			secrets[e.ID] = e.TakeSecrets()
+		}
+	}
+	return secrets
+}
+
+func TestSplitVaultRoundTrip(t *testing.T) {
+	v := newSplitTestVault()
+	want := secretsOf(v.Clone())
+	ev, vaultKey := newTestEncryptedVault(t, v, []byte("master password"), SchemaVersionSplit)
+	if !ev.IsSplit() || ev.Format() != FormatSplit {
+		t.Fatalf("format = %s, want %s", ev.Format(), FormatSplit)
+	}
+
+	opened, err := ev.OpenPayload(vaultKey)
+	if err != nil {
+		t.Fatalf("OpenPayload: %v", err)
+	}
+	if opened.HasSealedSecrets() {
+		t.Error("OpenPayload left entry secrets sealed")
+	}
+	if len(opened.Entries) != 1 || len(opened.DeletedEntries) != 1 {
+		t.Fatalf("opened %d entries and %d in the trash, want 1 and 1", len(opened.Entries), len(opened.DeletedEntries))
+	}
+	if got := secretsOf(opened); !reflect.DeepEqual(got, want) {
+		t.Errorf("secrets after round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestOpenMetadataHasNoSecrets(t *testing.T) {
+	v := newSplitTestVault()
+	ev, vaultKey := newTestEncryptedVault(t, v, []byte("master password"), SchemaVersionSplit)
+
+	meta, err := ev.OpenMetadata(vaultKey)
+	if err != nil {
+		t.Fatalf("OpenMetadata: %v", err)
+	}
+	for _, entries := range [][]vault.Entry{meta.Entries, meta.DeletedEntries} {
+		for _, e := range entries {
+			if !e.SecretsSealed() {
+				t.Errorf("entry %s has no sealed secrets", e.Name)
+			}
+			if s := e.TakeSecrets(); !reflect.DeepEqual(s, vault.EntrySecrets{}) {
+				t.Errorf("entry %s metadata holds secrets: %+v", e.Name, s)
+			}
+		}
+	}
+	if e := meta.GetEntry("github"); e == nil || e.Username != "alice" || e.URL != "https://github.com" {
+		t.Errorf("metadata lost the entry's non-secret fields: %+v", e)
+	}
+
+	// Nor are they anywhere in the decrypted payload
+	plaintext, err := ev.DecryptPayload(vaultKey)
+	if err != nil {
+		t.Fatalf("DecryptPayload: %v", err)
+	}
+	for _, secret := range secretValues {
+		if bytes.Contains(plaintext, []byte(secret)) {
+			t.Errorf("decrypted payload contains %q", secret)
+		}
+	}
+}
+
+func TestSplitVaultSecretsBoundToEntry(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(meta *vault.Vault)
+	}{
+		{"secrets swapped", func(meta *vault.Vault) {
+			a, b := &meta.Entries[0], &meta.DeletedEntries[0]
+			a.SealedSecrets, b.SealedSecrets = b.SealedSecrets, a.SealedSecrets
+		}},
+		{"entry ID changed", func(meta *vault.Vault) {
+			meta.Entries[0].ID = meta.DeletedEntries[0].ID
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, vaultKey := newTestEncryptedVault(t, newSplitTestVault(), []byte("master password"), SchemaVersionSplit)
+			meta, err := ev.OpenMetadata(vaultKey)
+			if err != nil {
+				t.Fatalf("OpenMetadata: %v", err)
+			}
+
+			// Entries with sealed secrets are written back as they are
+			tt.tamper(meta)
+			if err := ev.SealVault(meta, vaultKey); err != nil {
+				t.Fatalf("SealVault: %v", err)
+			}
+			if _, err := ev.OpenPayload(vaultKey); err == nil {
+				t.Error("OpenPayload opened secrets moved to another entry")
+			}
+		})
+	}
+}
+
+func TestSealSingleRefusesSealedSecrets(t *testing.T) {
+	ev, vaultKey := newTestEncryptedVault(t, newSplitTestVault(), []byte("master password"), SchemaVersionSplit)
+	meta, err := ev.OpenMetadata(vaultKey)
+	if err != nil {
+		t.Fatalf("OpenMetadata: %v", err)
+	}
+
+	ev.SchemaVersion = SchemaVersionSingle
+	if err := ev.SealVault(meta, vaultKey); !errors.Is(err, errSecretsSealed) {
+		t.Errorf("SealVault error = %v, want errSecretsSealed", err)
+	}
+}
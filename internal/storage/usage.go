@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/vaultctl/vaultctl/internal/atomic"
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// usageLogAAD binds the usage log ciphertext to its purpose
+var usageLogAAD = []byte("vaultctl usage log")
+
+// usageLogFile is the usage log as stored, encrypted with the vault key so
+// it doesn't reveal which entries are used
+type usageLogFile struct {
+	Nonce      string `json:"nonce"`      // base64
+	Ciphertext string `json:"ciphertext"` // base64
+}
+
+// UsageLogPath returns the path of the log of entry uses not yet recorded
+// in the vault
+func (ls *LocalStorage) UsageLogPath() string {
+	return ls.VaultPath + ".usage"
+}
+
+// LoadUsageLog returns the logged uses. A log the vault key can't open, e.g.
+// after rotate-key, is ignored, and removed by the next ClearUsageLog.
+func (ls *LocalStorage) LoadUsageLog(vaultKey []byte) (vault.UsageLog, error) {
+	log := vault.UsageLog{}
+	data, err := os.ReadFile(ls.UsageLogPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return log, nil
+		}
+		return nil, fmt.Errorf("failed to read usage log: %w", err)
+	}
+
+	var file usageLogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return log, nil
+	}
+	nonce, err := crypto.DecodeBase64(file.Nonce)
+	if err != nil {
+		return log, nil
+	}
+	ciphertext, err := crypto.DecodeBase64(file.Ciphertext)
+	if err != nil {
+		return log, nil
+	}
+	plaintext, err := crypto.Decrypt(ciphertext, nonce, vaultKey, crypto.CipherXChaCha20Poly1305, usageLogAAD)
+	if err != nil {
+		return log, nil
+	}
+	if err := json.Unmarshal(plaintext, &log); err != nil {
+		return vault.UsageLog{}, nil
+	}
+	return log, nil
+}
+
+// LogUsage adds uses to the usage log. Callers hold the vault lock, since
+// the log is read and rewritten.
+func (ls *LocalStorage) LogUsage(uses vault.UsageLog, vaultKey []byte) error {
+	log, err := ls.LoadUsageLog(vaultKey)
+	if err != nil {
+		return err
+	}
+	log.Merge(uses)
+
+	plaintext, err := json.Marshal(log)
+	if err != nil {
+		return fmt.Errorf("failed to serialize usage log: %w", err)
+	}
+	ciphertext, nonce, err := crypto.Encrypt(plaintext, vaultKey, crypto.CipherXChaCha20Poly1305, usageLogAAD)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt usage log: %w", err)
+	}
+	data, err := json.Marshal(usageLogFile{
+		Nonce:      crypto.EncodeBase64(nonce),
+		Ciphertext: crypto.EncodeBase64(ciphertext),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize usage log: %w", err)
+	}
+	if err := atomic.WriteFile(ls.UsageLogPath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write usage log: %w", err)
+	}
+	return nil
+}
+
+// ClearUsageLog empties the usage log once it was applied to a saved vault
+func (ls *LocalStorage) ClearUsageLog() error {
+	if err := os.Remove(ls.UsageLogPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear usage log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package vault
+
+import "time"
+
+// Attachment is a file attached to an entry. Small files are stored inline in
+// Data and encrypted along with the rest of the vault. Larger files are
+// stored outside the vault as an object encrypted with their own key; the
+// vault keeps the object key and the attachment key wrapped with the vault key.
+type Attachment struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Data       []byte    `json:"data,omitempty"`        // Inline content
+	ObjectKey  string    `json:"object_key,omitempty"`  // S3 object holding the encrypted content
+	Nonce      string    `json:"nonce,omitempty"`       // base64 - nonce for the object ciphertext
+	WrappedKey string    `json:"wrapped_key,omitempty"` // base64 - attachment key encrypted with the vault key
+	KeyNonce   string    `json:"key_nonce,omitempty"`   // base64 - nonce for WrappedKey
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsInline reports whether the attachment content is stored in the vault
+func (a *Attachment) IsInline() bool {
+	return a.ObjectKey == ""
+}
+
+// GetAttachment returns the attachment with the given name, or nil
+func (e *Entry) GetAttachment(name string) *Attachment {
+	for i := range e.Attachments {
+		if e.Attachments[i].Name == name {
+			return &e.Attachments[i]
+		}
+	}
+	return nil
+}
+
+// SetAttachment adds an attachment or replaces the one with the same name.
+// It returns the attachment it replaced, if any, so its object can be removed.
+func (e *Entry) SetAttachment(a Attachment) *Attachment {
+	e.UpdatedAt = time.Now()
+	if existing := e.GetAttachment(a.Name); existing != nil {
+		old := *existing
+		*existing = a
+		return &old
+	}
+	e.Attachments = append(e.Attachments, a)
+	return nil
+}
@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// Audit defaults
+const (
+	DefaultAuditMinEntropy = 60.0
+	DefaultAuditMaxAge     = 365 * 24 * time.Hour
+)
+
+// Severity ranks how urgently an audit issue should be addressed
+type Severity int
+
+const (
+	SeverityLow Severity = iota + 1
+	SeverityMedium
+	SeverityHigh
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityHigh:
+		return "high"
+	case SeverityMedium:
+		return "medium"
+	case SeverityLow:
+		return "low"
+	default:
+		return "unknown"
+	}
+}
+
+// Audit issue kinds
+const (
+	IssueWeak   = "weak"
+	IssueReused = "reused"
+	IssueOld    = "old"
+	IssueDue    = "rotation-due"
+)
+
+// AuditIssue is a single problem found with an entry's password
+type AuditIssue struct {
+	EntryID   string
+	EntryName string
+	Kind      string
+	Severity  Severity
+	Detail    string
+}
+
+// AuditReport holds the result of auditing a vault
+type AuditReport struct {
+	EntriesChecked int
+	Issues         []AuditIssue // Sorted by severity, highest first
+}
+
+// AuditOptions controls the thresholds Audit applies
+type AuditOptions struct {
+	MinEntropy float64       // Passwords below this many bits are weak
+	MaxAge     time.Duration // Passwords not updated within this long are old; 0 disables
+}
+
+// DefaultAuditOptions returns the default audit thresholds
+func DefaultAuditOptions() AuditOptions {
+	return AuditOptions{
+		MinEntropy: DefaultAuditMinEntropy,
+		MaxAge:     DefaultAuditMaxAge,
+	}
+}
+
+// Audit checks every entry with a password for weak, reused and old
+// passwords, and passwords past their expiry or rotation interval
+func (v *Vault) Audit(opts AuditOptions) AuditReport {
+	var report AuditReport
+	now := time.Now()
+
+	// Group entries by password to find reuse
+	byPassword := make(map[string][]*Entry)
+	for i := range v.Entries {
+		entry := &v.Entries[i]
+		if !entry.Type.HasPassword() {
+			continue
+		}
+		report.EntriesChecked++
+
+		if len(entry.Password) == 0 {
+			report.Issues = append(report.Issues, entry.auditIssue(IssueWeak, SeverityHigh, "empty password"))
+			continue
+		}
+		byPassword[string(entry.Password)] = append(byPassword[string(entry.Password)], entry)
+
+		if bits := crypto.PasswordEntropy(entry.Password); bits < opts.MinEntropy {
+			severity := SeverityMedium
+			if bits < opts.MinEntropy/2 {
+				severity = SeverityHigh
+			}
+			report.Issues = append(report.Issues, entry.auditIssue(IssueWeak, severity,
+				fmt.Sprintf("estimated entropy %.0f bits (minimum %.0f)", bits, opts.MinEntropy)))
+		}
+
+		if entry.IsRotationDue(now) {
+			due, _ := entry.RotationDue()
+			report.Issues = append(report.Issues, entry.auditIssue(IssueDue, SeverityMedium,
+				fmt.Sprintf("password change was due %s", due.Format("2006-01-02"))))
+		}
+
+		if opts.MaxAge > 0 && now.Sub(entry.UpdatedAt) > opts.MaxAge {
+			days := int(now.Sub(entry.UpdatedAt).Hours() / 24)
+			report.Issues = append(report.Issues, entry.auditIssue(IssueOld, SeverityLow,
+				fmt.Sprintf("not changed in %d days", days)))
+		}
+	}
+
+	for _, entries := range byPassword {
+		if len(entries) < 2 {
+			continue
+		}
+		for _, entry := range entries {
+			report.Issues = append(report.Issues, entry.auditIssue(IssueReused, SeverityHigh,
+				fmt.Sprintf("same password as %d other entries", len(entries)-1)))
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.EntryName != b.EntryName {
+			return a.EntryName < b.EntryName
+		}
+		return a.Kind < b.Kind
+	})
+
+	return report
+}
+
+func (e *Entry) auditIssue(kind string, severity Severity, detail string) AuditIssue {
+	return AuditIssue{
+		EntryID:   e.ID,
+		EntryName: e.Name,
+		Kind:      kind,
+		Severity:  severity,
+		Detail:    detail,
+	}
+}
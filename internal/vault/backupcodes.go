@@ -0,0 +1,41 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrNoBackupCodes is returned by UseBackupCode when every code has been used
+var ErrNoBackupCodes = errors.New("no unused backup codes left")
+
+// IsBackupCodeUsed reports whether the code has been marked used
+func (e *Entry) IsBackupCodeUsed(code string) bool {
+	return slices.Contains(e.UsedBackupCodes, code)
+}
+
+// UnusedBackupCodes returns the backup codes not yet marked used, in order
+func (e *Entry) UnusedBackupCodes() []string {
+	unused := make([]string, 0, len(e.BackupCodes))
+	for _, code := range e.BackupCodes {
+		if !e.IsBackupCodeUsed(code) {
+			unused = append(unused, code)
+		}
+	}
+	return unused
+}
+
+// UseBackupCode marks the entry's next unused backup code as used and returns
+// it. UpdatedAt is left alone since it drives password rotation.
+func (v *Vault) UseBackupCode(id string) (string, error) {
+	entry := v.entryRef(id)
+	if entry == nil {
+		return "", fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	unused := entry.UnusedBackupCodes()
+	if len(unused) == 0 {
+		return "", fmt.Errorf("%w on '%s'", ErrNoBackupCodes, entry.Name)
+	}
+	entry.UsedBackupCodes = append(entry.UsedBackupCodes, unused[0])
+	return unused[0], nil
+}
@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Changes lists the entries that differ between two versions of a vault
+type Changes struct {
+	Added   []EntrySummary
+	Updated []EntryChange
+	Removed []EntrySummary
+}
+
+// EntryChange is an entry present in both versions of a vault with
+// different contents. The summary is of the newer version.
+type EntryChange struct {
+	EntrySummary
+	Fields []string // As named by ChangedFields
+}
+
+// Empty reports whether there are no changes
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+// Diff returns the entries, matched by ID, that would be added, updated or
+// removed to turn from into to
+func Diff(from, to *Vault) Changes {
+	fromEntries := entriesByID(from.Entries)
+	toEntries := entriesByID(to.Entries)
+
+	var c Changes
+	for _, id := range unionIDs(from.Entries, to.Entries) {
+		f, t := fromEntries[id], toEntries[id]
+		switch {
+		case f == nil:
+			c.Added = append(c.Added, t.Summary())
+		case t == nil:
+			c.Removed = append(c.Removed, f.Summary())
+		default:
+			if fields := ChangedFields(f, t); len(fields) > 0 {
+				c.Updated = append(c.Updated, EntryChange{EntrySummary: t.Summary(), Fields: fields})
+			}
+		}
+	}
+	return c
+}
+
+// ChangedFields returns the fields that differ between two versions of an
+// entry, by their JSON names such as "username" or "password". Custom fields
+// are listed one by one as "fields.<name>". Usage is not part of an entry's
+// contents and is never listed.
+func ChangedFields(a, b *Entry) []string {
+	var changed []string
+	av, bv := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	t := av.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		switch name {
+		case "", "-", "last_used_at", "use_count":
+			continue
+		case "fields":
+			changed = append(changed, changedCustomFields(a.Fields, b.Fields)...)
+			continue
+		}
+		if !valuesEqual(av.Field(i), bv.Field(i)) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// changedCustomFields returns "fields.<name>" for each custom field added,
+// changed or removed between a and b
+func changedCustomFields(a, b []CustomField) []string {
+	byName := make(map[string]CustomField, len(a))
+	for _, f := range a {
+		byName[f.Name] = f
+	}
+
+	var changed []string
+	for _, f := range b {
+		if old, ok := byName[f.Name]; !ok || old != f {
+			changed = append(changed, "fields."+f.Name)
+		}
+		delete(byName, f.Name)
+	}
+	for _, f := range a {
+		if _, ok := byName[f.Name]; ok {
+			changed = append(changed, "fields."+f.Name)
+		}
+	}
+	return changed
+}
+
+// valuesEqual compares two field values as they are saved. Empty and nil
+// slices are equal, as they mean the same.
+func valuesEqual(a, b reflect.Value) bool {
+	if isEmpty(a) && isEmpty(b) {
+		return true
+	}
+	aj, errA := json.Marshal(a.Interface())
+	bj, errB := json.Marshal(b.Interface())
+	return errA == nil && errB == nil && bytes.Equal(aj, bj)
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChangedFields(t *testing.T) {
+	base := NewVault()
+	base.AddEntryWith("github", "alice", []byte("hunter2"), "https://github.com", "", nil, nil, EntryOptions{
+		Fields: []CustomField{{Name: "pin", Value: "1234", Secret: true}, {Name: "team", Value: "infra"}},
+	})
+
+	tests := []struct {
+		name   string
+		change func(e *Entry)
+		want   []string
+	}{
+		{"unchanged", func(e *Entry) {}, nil},
+		{"username and password", func(e *Entry) {
+			e.Username = "bob"
+			e.Password = []byte("correct horse")
+		}, []string{"username", "password"}},
+		{"custom fields", func(e *Entry) {
+			e.SetField("pin", "0000", true)
+			e.RemoveField("team")
+			e.SetField("region", "eu", false)
+		}, []string{"fields.pin", "fields.region", "fields.team"}},
+		{"empty tags", func(e *Entry) { e.Tags = []string{} }, nil},
+		{"usage only", func(e *Entry) {
+			now := time.Now()
+			e.UseCount = 5
+			e.LastUsedAt = &now
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base.Clone()
+			tt.change(&changed.Entries[0])
+			got := ChangedFields(&base.Entries[0], &changed.Entries[0])
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ChangedFields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := NewVault()
+	from.AddEntry("kept", "alice", []byte("pw"), "", "", nil, nil)
+	edited := from.AddEntry("edited", "alice", []byte("pw"), "", "", nil, nil).ID
+	removed := from.AddEntry("removed", "alice", []byte("pw"), "", "", nil, nil).ID
+
+	to := from.Clone()
+	to.entryRef(edited).URL = "https://example.com"
+	to.RemoveEntry(removed)
+	added := to.AddEntry("added", "bob", []byte("pw"), "", "", nil, nil).ID
+
+	c := Diff(from, to)
+	if len(c.Added) != 1 || c.Added[0].ID != added {
+		t.Errorf("Diff added = %+v, want %s", c.Added, added)
+	}
+	if len(c.Removed) != 1 || c.Removed[0].ID != removed {
+		t.Errorf("Diff removed = %+v, want %s", c.Removed, removed)
+	}
+	if len(c.Updated) != 1 || c.Updated[0].ID != edited || !slices.Equal(c.Updated[0].Fields, []string{"url"}) {
+		t.Errorf("Diff updated = %+v, want %s with url changed", c.Updated, edited)
+	}
+	if !Diff(to, to.Clone()).Empty() {
+		t.Error("Diff of a vault with a copy of itself is not empty")
+	}
+}
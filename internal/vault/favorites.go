@@ -0,0 +1,25 @@
+package vault
+
+import "fmt"
+
+// SetFavorite pins or unpins an entry. UpdatedAt is left alone since it
+// drives password rotation and pinning doesn't change the password.
+func (v *Vault) SetFavorite(id string, favorite bool) error {
+	entry := v.entryRef(id)
+	if entry == nil {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	entry.Favorite = favorite
+	return nil
+}
+
+// FavoriteEntries returns summaries of pinned entries
+func (v *Vault) FavoriteEntries() []EntrySummary {
+	summaries := make([]EntrySummary, 0)
+	for i := range v.Entries {
+		if v.Entries[i].Favorite {
+			summaries = append(summaries, v.Entries[i].Summary())
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFieldNotFound is returned when an entry has no field with a given name
+var ErrFieldNotFound = errors.New("field not found")
+
+// CustomField is an extra named value on an entry, such as a PIN or a
+// security question. Secret fields are masked when displayed.
+type CustomField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// GetField returns the custom field with the given name, or nil
+func (e *Entry) GetField(name string) *CustomField {
+	for i := range e.Fields {
+		if e.Fields[i].Name == name {
+			return &e.Fields[i]
+		}
+	}
+	return nil
+}
+
+// SetField adds a custom field or replaces the one with the same name
+func (e *Entry) SetField(name, value string, secret bool) {
+	if field := e.GetField(name); field != nil {
+		field.Value = value
+		field.Secret = secret
+		return
+	}
+	e.Fields = append(e.Fields, CustomField{Name: name, Value: value, Secret: secret})
+}
+
+// RemoveField removes the custom field with the given name
+func (e *Entry) RemoveField(name string) bool {
+	for i := range e.Fields {
+		if e.Fields[i].Name == name {
+			e.Fields = append(e.Fields[:i], e.Fields[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// FieldValue returns a copy of the value of the named field. The built-in
+// fields username, password, url and notes are matched case-insensitively
+// and take precedence over custom fields of the same name.
+func (e *Entry) FieldValue(name string) ([]byte, error) {
+	switch strings.ToLower(name) {
+	case "username":
+		return []byte(e.Username), nil
+	case "password":
+		return append([]byte(nil), e.Password...), nil
+	case "url":
+		return []byte(e.URL), nil
+	case "notes":
+		return []byte(e.Notes), nil
+	}
+	if field := e.GetField(name); field != nil {
+		return []byte(field.Value), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, name)
+}
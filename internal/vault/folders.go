@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeFolder cleans a slash-separated folder path: surrounding spaces
+// and empty segments are dropped, so " /work//aws/ " becomes "work/aws". The
+// root folder is "".
+func NormalizeFolder(folder string) string {
+	var parts []string
+	for _, part := range strings.Split(folder, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// FolderContains reports whether path is folder or one of its subfolders.
+// The root folder "" contains everything.
+func FolderContains(folder, path string) bool {
+	folder = NormalizeFolder(folder)
+	return folder == "" || path == folder || strings.HasPrefix(path, folder+"/")
+}
+
+// InFolder reports whether the entry is in folder or one of its subfolders
+func (e *Entry) InFolder(folder string) bool {
+	return FolderContains(folder, e.Folder)
+}
+
+// Folders returns every folder holding entries, including their parents,
+// sorted so each folder comes right before its subfolders
+func (v *Vault) Folders() []string {
+	seen := make(map[string]bool)
+	for i := range v.Entries {
+		folder := v.Entries[i].Folder
+		for folder != "" && !seen[folder] {
+			seen[folder] = true
+			if i := strings.LastIndex(folder, "/"); i >= 0 {
+				folder = folder[:i]
+			} else {
+				folder = ""
+			}
+		}
+	}
+
+	folders := make([]string, 0, len(seen))
+	for folder := range seen {
+		folders = append(folders, folder)
+	}
+	// Compare segment by segment so "work/aws" sorts before "work-old"
+	sort.Slice(folders, func(i, j int) bool {
+		return strings.ReplaceAll(folders[i], "/", "\x00") < strings.ReplaceAll(folders[j], "/", "\x00")
+	})
+	return folders
+}
+
+// EntriesInFolder returns summaries of entries in folder or its subfolders
+func (v *Vault) EntriesInFolder(folder string) []EntrySummary {
+	summaries := make([]EntrySummary, 0)
+	for i := range v.Entries {
+		if v.Entries[i].InFolder(folder) {
+			summaries = append(summaries, v.Entries[i].Summary())
+		}
+	}
+	return summaries
+}
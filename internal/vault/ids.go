@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ShortIDLen is the length of the IDs shown by list --show-ids. Longer
+// prefixes are shown when needed to tell entries apart.
+const ShortIDLen = 8
+
+// MinIDPrefixLen is the shortest ID prefix LookupEntry accepts, so a short
+// name that isn't found doesn't match an ID by accident
+const MinIDPrefixLen = 4
+
+// AmbiguousIDError is returned by LookupEntry when an ID prefix matches
+// several entries
+type AmbiguousIDError struct {
+	Prefix string
+	IDs    []string
+}
+
+func (e *AmbiguousIDError) Error() string {
+	return fmt.Sprintf("ID prefix '%s' matches several entries: %s", e.Prefix, strings.Join(e.IDs, ", "))
+}
+
+// ShortIDs maps every entry's ID to its shortest prefix, at least
+// ShortIDLen long, that no other entry's ID starts with
+func (v *Vault) ShortIDs() map[string]string {
+	ids := make([]string, len(v.Entries))
+	for i := range v.Entries {
+		ids[i] = v.Entries[i].ID
+	}
+	slices.Sort(ids)
+
+	// In sorted order an ID shares its longest prefix with a neighbour
+	short := make(map[string]string, len(ids))
+	for i, id := range ids {
+		n := ShortIDLen
+		if i > 0 {
+			n = max(n, commonPrefixLen(id, ids[i-1])+1)
+		}
+		if i < len(ids)-1 {
+			n = max(n, commonPrefixLen(id, ids[i+1])+1)
+		}
+		short[id] = id[:min(n, len(id))]
+	}
+	return short
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// positionsByIDPrefix returns the positions of the entries whose ID starts
+// with prefix
+func (v *Vault) positionsByIDPrefix(prefix string) []int {
+	var positions []int
+	for i := range v.Entries {
+		if strings.HasPrefix(v.Entries[i].ID, prefix) {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// lookupIDPrefix returns a copy of the one entry whose ID starts with prefix
+func (v *Vault) lookupIDPrefix(prefix string) (*Entry, error) {
+	if len(prefix) < MinIDPrefixLen {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, prefix)
+	}
+	positions := v.positionsByIDPrefix(prefix)
+	switch len(positions) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, prefix)
+	case 1:
+		return v.Entries[positions[0]].Clone(), nil
+	}
+	ids := make([]string, len(positions))
+	for i, pos := range positions {
+		ids[i] = v.Entries[pos].ID
+	}
+	return nil, &AmbiguousIDError{Prefix: prefix, IDs: ids}
+}
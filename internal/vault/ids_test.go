@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newIDTestVault returns a vault whose entries have fixed IDs
+func newIDTestVault() *Vault {
+	v := NewVault()
+	v.Entries = append(v.Entries,
+		Entry{ID: "abcd1111-0000-0000-0000-000000000000", Name: "github"},
+		Entry{ID: "abcd2222-0000-0000-0000-000000000000", Name: "gitlab"},
+		Entry{ID: "ef01aaaa-0000-0000-0000-000000000000", Name: "bank"},
+		Entry{ID: "99990000-0000-0000-0000-000000000000", Name: "ef01"},
+	)
+	return v
+}
+
+func TestLookupEntryIDPrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		want       string // name of the entry found
+		wantErr    error
+	}{
+		{"full ID", "abcd2222-0000-0000-0000-000000000000", "gitlab", nil},
+		{"name", "bank", "bank", nil},
+		{"unique prefix", "abcd1", "github", nil},
+		{"name before prefix", "ef01", "ef01", nil},
+		{"prefix too short", "999", "", ErrEntryNotFound},
+		{"no match", "1234", "", ErrEntryNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := newIDTestVault().LookupEntry(tt.identifier)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("LookupEntry(%q) error = %v, want %v", tt.identifier, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LookupEntry(%q): %v", tt.identifier, err)
+			}
+			if e.Name != tt.want {
+				t.Errorf("LookupEntry(%q) = %s, want %s", tt.identifier, e.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupEntryAmbiguousIDPrefix(t *testing.T) {
+	_, err := newIDTestVault().LookupEntry("abcd")
+	var ambiguous *AmbiguousIDError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("LookupEntry error = %v, want an *AmbiguousIDError", err)
+	}
+	want := []string{"abcd1111-0000-0000-0000-000000000000", "abcd2222-0000-0000-0000-000000000000"}
+	if !reflect.DeepEqual(ambiguous.IDs, want) {
+		t.Errorf("ambiguous IDs = %v, want %v", ambiguous.IDs, want)
+	}
+}
+
+func TestShortIDs(t *testing.T) {
+	v := NewVault()
+	v.Entries = append(v.Entries,
+		Entry{ID: "abcdef0123", Name: "a"},
+		Entry{ID: "abcdef0199", Name: "b"},
+		Entry{ID: "12345678ff", Name: "c"},
+		Entry{ID: "xyz", Name: "d"},
+	)
+	want := map[string]string{
+		"abcdef0123": "abcdef012",
+		"abcdef0199": "abcdef019",
+		"12345678ff": "12345678",
+		"xyz":        "xyz",
+	}
+	if got := v.ShortIDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ShortIDs = %v, want %v", got, want)
+	}
+}
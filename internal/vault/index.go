@@ -0,0 +1,77 @@
+package vault
+
+// entryIndex maps entry IDs and names to positions in Vault.Entries, so
+// lookups don't scan the whole vault
+type entryIndex struct {
+	size   int              // len(Entries) when the index was last updated
+	byID   map[string]int   // ID -> position
+	byName map[string][]int // Name -> positions, ascending
+}
+
+// entryIndex returns the index over v.Entries, rebuilding it if it is
+// missing or Entries was resized behind the vault's back. Code in this
+// package that moves or renames entries calls invalidateIndex, or
+// indexAppended after appending one.
+func (v *Vault) entryIndex() *entryIndex {
+	if v.index != nil && v.index.size == len(v.Entries) {
+		return v.index
+	}
+	idx := &entryIndex{
+		size:   len(v.Entries),
+		byID:   make(map[string]int, len(v.Entries)),
+		byName: make(map[string][]int, len(v.Entries)),
+	}
+	for i := range v.Entries {
+		e := &v.Entries[i]
+		if _, dup := idx.byID[e.ID]; !dup {
+			idx.byID[e.ID] = i
+		}
+		idx.byName[e.Name] = append(idx.byName[e.Name], i)
+	}
+	v.index = idx
+	return idx
+}
+
+// invalidateIndex drops the index after entries were removed, reordered or
+// renamed; it is rebuilt on the next lookup
+func (v *Vault) invalidateIndex() {
+	v.index = nil
+}
+
+// indexAppended records the entry just appended to v.Entries
+func (v *Vault) indexAppended() {
+	if v.index == nil || v.index.size != len(v.Entries)-1 {
+		v.index = nil
+		return
+	}
+	i := len(v.Entries) - 1
+	e := &v.Entries[i]
+	if _, dup := v.index.byID[e.ID]; !dup {
+		v.index.byID[e.ID] = i
+	}
+	v.index.byName[e.Name] = append(v.index.byName[e.Name], i)
+	v.index.size++
+}
+
+// positionByID returns the position of the entry with the given ID
+func (v *Vault) positionByID(id string) (int, bool) {
+	i, ok := v.entryIndex().byID[id]
+	if ok && v.Entries[i].ID != id {
+		// An entry was changed in place; start over
+		v.invalidateIndex()
+		i, ok = v.entryIndex().byID[id]
+	}
+	return i, ok
+}
+
+// positionsByName returns the positions of the entries named name, ascending
+func (v *Vault) positionsByName(name string) []int {
+	positions := v.entryIndex().byName[name]
+	for _, i := range positions {
+		if v.Entries[i].Name != name {
+			v.invalidateIndex()
+			return v.entryIndex().byName[name]
+		}
+	}
+	return positions
+}
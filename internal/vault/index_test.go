@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"fmt"
+	"testing"
+)
+
+const benchmarkEntries = 5000
+
+// benchmarkVault returns a vault with n entries named entry-0 to entry-<n-1>
+func benchmarkVault(n int) *Vault {
+	v := NewVault()
+	for i := 0; i < n; i++ {
+		v.AddEntry(fmt.Sprintf("entry-%d", i), "user", []byte("password"), "", "", nil, nil)
+	}
+	return v
+}
+
+func BenchmarkGetEntry(b *testing.B) {
+	v := benchmarkVault(benchmarkEntries)
+	last := v.Entries[benchmarkEntries-1]
+
+	b.Run("ByID", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if v.GetEntry(last.ID) == nil {
+				b.Fatal("entry not found")
+			}
+		}
+	})
+	b.Run("ByName", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if v.GetEntry(last.Name) == nil {
+				b.Fatal("entry not found")
+			}
+		}
+	})
+	// The scan lookups did before the index, for comparison
+	b.Run("LinearScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var found *Entry
+			for j := range v.Entries {
+				if v.Entries[j].ID == last.ID || v.Entries[j].Name == last.ID {
+					found = v.Entries[j].Clone()
+					break
+				}
+			}
+			if found == nil {
+				b.Fatal("entry not found")
+			}
+		}
+	})
+}
+
+func TestIndexFollowsChanges(t *testing.T) {
+	v := benchmarkVault(10)
+	removed := v.Entries[3]
+	if !v.RemoveEntry(removed.ID) {
+		t.Fatal("RemoveEntry returned false")
+	}
+	if v.GetEntry(removed.ID) != nil || v.GetEntry(removed.Name) != nil {
+		t.Error("removed entry is still found")
+	}
+
+	moved := v.Entries[8]
+	if got := v.GetEntry(moved.ID); got == nil || got.Name != moved.Name {
+		t.Errorf("entry after the removed one = %v, want %s", got, moved.Name)
+	}
+
+	v.UpdateEntry(moved.ID, "renamed", nil, nil, nil, nil, nil, nil)
+	if v.GetEntry(moved.Name) != nil {
+		t.Error("old name is still found after renaming")
+	}
+	if got := v.GetEntry("renamed"); got == nil || got.ID != moved.ID {
+		t.Errorf("GetEntry(renamed) = %v, want entry %s", got, moved.ID)
+	}
+
+	added := v.AddEntry("new", "", nil, "", "", nil, nil)
+	if got := v.GetEntry("new"); got == nil || got.ID != added.ID {
+		t.Errorf("GetEntry(new) = %v, want entry %s", got, added.ID)
+	}
+}
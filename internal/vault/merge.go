@@ -0,0 +1,166 @@
+package vault
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Conflict is an entry that was changed differently on both sides since the
+// common base. A nil Local or Remote means the entry was removed on that side.
+type Conflict struct {
+	ID     string
+	Name   string
+	Local  *Entry
+	Remote *Entry
+}
+
+// Merge performs a three-way merge of local and remote against their common
+// ancestor base, matching entries by ID. base may be nil when no common
+// ancestor is known, in which case entries present on both sides with
+// different contents are treated as conflicts.
+//
+// Changes made on only one side are applied. Conflicts are resolved
+// provisionally in favour of the more recent UpdatedAt (an edit beats a
+// removal) and returned so the caller can let the user pick instead.
+func Merge(base, local, remote *Vault) (*Vault, []Conflict) {
+	if base == nil {
+		base = &Vault{}
+	}
+
+	baseEntries := entriesByID(base.Entries)
+	localEntries := entriesByID(local.Entries)
+	remoteEntries := entriesByID(remote.Entries)
+
+	merged := &Vault{
+		SchemaVersion: local.SchemaVersion,
+		VaultID:       local.VaultID,
+		Entries:       make([]Entry, 0, len(local.Entries)),
+	}
+
+	// The vault's policy follows whichever side changed it, local if both did
+	merged.PasswordPolicy = local.PasswordPolicy
+	if reflect.DeepEqual(base.PasswordPolicy, local.PasswordPolicy) {
+		merged.PasswordPolicy = remote.PasswordPolicy
+	}
+	var conflicts []Conflict
+
+	for _, id := range unionIDs(local.Entries, remote.Entries, base.Entries) {
+		b, l, r := baseEntries[id], localEntries[id], remoteEntries[id]
+
+		var result *Entry
+		switch {
+		case entriesEqual(l, r):
+			result = l
+		case entriesEqual(b, l):
+			// Only remote changed
+			result = r
+		case entriesEqual(b, r):
+			// Only local changed
+			result = l
+		default:
+			result = newerEntry(l, r)
+			conflicts = append(conflicts, Conflict{ID: id, Name: result.Name, Local: l, Remote: r})
+		}
+
+		// Copy, so wiping either side's secrets leaves the merge intact
+		if result != nil {
+			merged.Entries = append(merged.Entries, *result.Clone())
+			merged.Entries[len(merged.Entries)-1].setUsage(mergeUsage(b, l, r))
+		}
+	}
+
+	// Keep the union of both trashes, minus anything that is live again
+	live := entriesByID(merged.Entries)
+	trash := entriesByID(local.DeletedEntries)
+	for id, e := range entriesByID(remote.DeletedEntries) {
+		if existing, ok := trash[id]; !ok || e.DeletedAt != nil && existing.DeletedAt != nil && e.DeletedAt.After(*existing.DeletedAt) {
+			trash[id] = e
+		}
+	}
+	for _, id := range sortedIDs(trash) {
+		if live[id] == nil {
+			merged.DeletedEntries = append(merged.DeletedEntries, *trash[id].Clone())
+		}
+	}
+
+	return merged, conflicts
+}
+
+// ResolveConflict replaces the provisional result of a conflict with a copy
+// of choice. A nil choice moves the entry to the trash, as RemoveEntry does.
+func (v *Vault) ResolveConflict(c Conflict, choice *Entry) {
+	defer v.invalidateIndex()
+	for i := range v.Entries {
+		if v.Entries[i].ID == c.ID {
+			if choice == nil {
+				v.trashAt(i)
+			} else {
+				// Uses counted on both sides still happened
+				merged := v.Entries[i].usage()
+				v.Entries[i] = *choice.Clone()
+				v.Entries[i].setUsage(merged)
+			}
+			return
+		}
+	}
+	if choice != nil {
+		v.Entries = append(v.Entries, *choice.Clone())
+	}
+}
+
+// newerEntry returns whichever entry was updated more recently, preferring
+// an existing entry over a removed one
+func newerEntry(a, b *Entry) *Entry {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if b.UpdatedAt.After(a.UpdatedAt) {
+		return b
+	}
+	return a
+}
+
+// entriesEqual reports whether two entries have identical contents, not
+// counting their usage. Two nil entries are equal.
+func entriesEqual(a, b *Entry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return len(ChangedFields(a, b)) == 0
+}
+
+func entriesByID(entries []Entry) map[string]*Entry {
+	byID := make(map[string]*Entry, len(entries))
+	for i := range entries {
+		byID[entries[i].ID] = &entries[i]
+	}
+	return byID
+}
+
+// unionIDs returns every entry ID in the lists, keeping the order of first
+// appearance so the merged vault preserves the local ordering
+func unionIDs(lists ...[]Entry) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, entries := range lists {
+		for _, e := range entries {
+			if !seen[e.ID] {
+				seen[e.ID] = true
+				ids = append(ids, e.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func sortedIDs(m map[string]*Entry) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// mergeEntry returns the stored entry with the given ID, or nil
+func mergeEntry(v *Vault, id string) *Entry {
+	for i := range v.Entries {
+		if v.Entries[i].ID == id {
+			return &v.Entries[i]
+		}
+	}
+	return nil
+}
+
+// newMergeBase returns a vault with two entries, github and gitlab, both
+// last updated at t0
+func newMergeBase(t0 time.Time) (v *Vault, github, gitlab string) {
+	v = NewVault()
+	github = v.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, nil).ID
+	gitlab = v.AddEntry("gitlab", "alice", []byte("hunter3"), "", "", nil, nil).ID
+	for i := range v.Entries {
+		v.Entries[i].CreatedAt = t0
+		v.Entries[i].UpdatedAt = t0
+	}
+	return v, github, gitlab
+}
+
+func TestMerge(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Hour), t0.Add(2*time.Hour)
+
+	// Changes made to the github entry on one side
+	edit := func(username string, at time.Time) func(*testing.T, *Vault, string) {
+		return func(t *testing.T, v *Vault, id string) {
+			e := mergeEntry(v, id)
+			e.Username = username
+			e.UpdatedAt = at
+		}
+	}
+	remove := func(t *testing.T, v *Vault, id string) {
+		if !v.RemoveEntry(id) {
+			t.Fatalf("RemoveEntry(%s) failed", id)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		noBase        bool
+		local, remote func(*testing.T, *Vault, string)
+		want          string // Username of the merged github entry, "" if removed
+		wantConflict  bool
+	}{
+		{"unchanged", false, nil, nil, "alice", false},
+		{"edited locally", false, edit("local", t1), nil, "local", false},
+		{"edited remotely", false, nil, edit("remote", t1), "remote", false},
+		{"same edit on both", false, edit("same", t1), edit("same", t1), "same", false},
+		{"edited on both, local newer", false, edit("local", t2), edit("remote", t1), "local", true},
+		{"edited on both, remote newer", false, edit("local", t1), edit("remote", t2), "remote", true},
+		{"removed locally", false, remove, nil, "", false},
+		{"removed remotely", false, nil, remove, "", false},
+		{"removed locally, edited remotely", false, remove, edit("remote", t1), "remote", true},
+		{"edited locally, removed remotely", false, edit("local", t1), remove, "local", true},
+		{"removed on both", false, remove, remove, "", false},
+		{"no base, edited on one side", true, edit("local", t1), nil, "local", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, github, gitlab := newMergeBase(t0)
+			local, remote := base.Clone(), base.Clone()
+			if tt.local != nil {
+				tt.local(t, local, github)
+			}
+			if tt.remote != nil {
+				tt.remote(t, remote, github)
+			}
+			if tt.noBase {
+				base = nil
+			}
+
+			merged, conflicts := Merge(base, local, remote)
+
+			got := mergeEntry(merged, github)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("merged github = %s, want it removed", got.Username)
+			case tt.want != "" && got == nil:
+				t.Errorf("merged github removed, want %s", tt.want)
+			case got != nil && got.Username != tt.want:
+				t.Errorf("merged github = %s, want %s", got.Username, tt.want)
+			}
+			if mergeEntry(merged, gitlab) == nil {
+				t.Error("untouched gitlab entry missing from the merge")
+			}
+
+			if !tt.wantConflict {
+				if len(conflicts) != 0 {
+					t.Errorf("Merge reported conflicts %+v, want none", conflicts)
+				}
+				return
+			}
+			if len(conflicts) != 1 || conflicts[0].ID != github {
+				t.Fatalf("Merge reported conflicts %+v, want one for github", conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeTrash(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Hour), t0.Add(2*time.Hour)
+	trashed := func(v *Vault, id string, at time.Time) {
+		v.RemoveEntry(id)
+		v.DeletedEntries[len(v.DeletedEntries)-1].DeletedAt = &at
+	}
+
+	t.Run("union", func(t *testing.T) {
+		base, github, gitlab := newMergeBase(t0)
+		local, remote := base.Clone(), base.Clone()
+		trashed(local, github, t1)
+		trashed(remote, gitlab, t1)
+
+		merged, _ := Merge(base, local, remote)
+		if len(merged.Entries) != 0 {
+			t.Errorf("merged entries = %d, want none", len(merged.Entries))
+		}
+		if len(merged.DeletedEntries) != 2 {
+			t.Fatalf("merged trash has %d entries, want 2", len(merged.DeletedEntries))
+		}
+		if a, b := merged.DeletedEntries[0].ID, merged.DeletedEntries[1].ID; a > b {
+			t.Errorf("merged trash ordered %s, %s, want it sorted by ID", a, b)
+		}
+	})
+
+	t.Run("removed on both keeps the later removal", func(t *testing.T) {
+		base, github, _ := newMergeBase(t0)
+		local, remote := base.Clone(), base.Clone()
+		trashed(local, github, t2)
+		trashed(remote, github, t1)
+
+		merged, _ := Merge(base, local, remote)
+		if len(merged.DeletedEntries) != 1 {
+			t.Fatalf("merged trash has %d entries, want 1", len(merged.DeletedEntries))
+		}
+		if at := merged.DeletedEntries[0].DeletedAt; at == nil || !at.Equal(t2) {
+			t.Errorf("merged DeletedAt = %v, want %v", at, t2)
+		}
+	})
+
+	t.Run("live again", func(t *testing.T) {
+		base, github, _ := newMergeBase(t0)
+		local, remote := base.Clone(), base.Clone()
+		mergeEntry(local, github).UpdatedAt = t2
+		trashed(remote, github, t1)
+
+		merged, _ := Merge(base, local, remote)
+		if mergeEntry(merged, github) == nil {
+			t.Fatal("edited github entry missing from the merge")
+		}
+		if len(merged.DeletedEntries) != 0 {
+			t.Errorf("merged trash = %+v, want the live entry dropped from it", merged.DeletedEntries)
+		}
+	})
+}
+
+func TestMergePasswordPolicy(t *testing.T) {
+	policy := func(length int) *crypto.Policy {
+		p := crypto.DefaultPolicy()
+		p.MinLength = length
+		return &p
+	}
+	base, local, remote := policy(16), policy(20), policy(24)
+
+	tests := []struct {
+		name          string
+		local, remote *crypto.Policy
+		want          *crypto.Policy
+	}{
+		{"unchanged", base, base, base},
+		{"changed locally", local, base, local},
+		{"changed remotely", base, remote, remote},
+		{"changed on both", local, remote, local},
+		{"cleared remotely", base, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, l, r := NewVault(), NewVault(), NewVault()
+			b.PasswordPolicy, l.PasswordPolicy, r.PasswordPolicy = base, tt.local, tt.remote
+
+			merged, _ := Merge(b, l, r)
+			if !reflect.DeepEqual(merged.PasswordPolicy, tt.want) {
+				t.Errorf("merged policy = %+v, want %+v", merged.PasswordPolicy, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	base, github, _ := newMergeBase(t0)
+	local, remote := base.Clone(), base.Clone()
+	e := mergeEntry(local, github)
+	e.Password = []byte("local-secret")
+	e.UpdatedAt = t0.Add(time.Hour)
+	remote.RemoveEntry(github)
+
+	merged, conflicts := Merge(base, local, remote)
+	if len(conflicts) != 1 {
+		t.Fatalf("Merge reported %d conflicts, want 1", len(conflicts))
+	}
+
+	// Wiping the inputs must not reach the merged vault
+	for i := range local.Entries {
+		clear(local.Entries[i].Password)
+	}
+	if got := mergeEntry(merged, github); got == nil || string(got.Password) != "local-secret" {
+		t.Fatalf("merged github = %+v after wiping the local vault, want its password intact", got)
+	}
+
+	merged.ResolveConflict(conflicts[0], nil)
+	if mergeEntry(merged, github) != nil {
+		t.Error("github entry still live after resolving to the removal")
+	}
+	if len(merged.DeletedEntries) != 1 || merged.DeletedEntries[0].ID != github {
+		t.Fatalf("trash = %+v, want the resolved github entry", merged.DeletedEntries)
+	}
+	if string(merged.DeletedEntries[0].Password) != "local-secret" || merged.DeletedEntries[0].DeletedAt == nil {
+		t.Errorf("trashed github = %+v, want its password and removal time", merged.DeletedEntries[0])
+	}
+
+	choice := mergeEntry(local.Clone(), github)
+	choice.Password = []byte("chosen")
+	merged.ResolveConflict(conflicts[0], choice)
+	clear(choice.Password)
+	if got := mergeEntry(merged, github); got == nil || string(got.Password) != "chosen" {
+		t.Errorf("resolved github = %+v after wiping the choice, want its password intact", got)
+	}
+}
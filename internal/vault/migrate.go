@@ -0,0 +1,50 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSchemaTooNew is returned for vault data written by a newer vaultctl
+var ErrSchemaTooNew = errors.New("vault data was written by a newer vaultctl")
+
+// migrations upgrade a vault's plaintext schema one version at a time:
+// migrations[i] turns version i+1 into version i+2. Append a function here
+// and bump SchemaVersion whenever the entry model changes in a way older
+// data has to be fixed up for.
+var migrations = []func(v *Vault){
+	migrateEntryTypes,
+}
+
+// Migrate upgrades v from its stored schema version to SchemaVersion.
+// Vaults written by a newer vaultctl are refused, since this version would
+// drop what it doesn't know about on the next save.
+func Migrate(v *Vault) error {
+	// Vaults written before the version was stored are version 1
+	if v.SchemaVersion < 1 {
+		v.SchemaVersion = 1
+	}
+	if v.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("%w (version %d, this version supports %d); upgrade vaultctl", ErrSchemaTooNew, v.SchemaVersion, SchemaVersion)
+	}
+
+	for ; v.SchemaVersion < SchemaVersion; v.SchemaVersion++ {
+		migrations[v.SchemaVersion-1](v)
+	}
+	return nil
+}
+
+// migrateEntryTypes (1 -> 2) makes entries written before types existed
+// logins and replaces a null entry list with an empty one
+func migrateEntryTypes(v *Vault) {
+	if v.Entries == nil {
+		v.Entries = make([]Entry, 0)
+	}
+	for _, entries := range [][]Entry{v.Entries, v.DeletedEntries} {
+		for i := range entries {
+			if entries[i].Type == "" {
+				entries[i].Type = TypeLogin
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMigrateV1ToV2(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		wantEntries int
+		wantTypes   []EntryType
+	}{
+		{
+			name:        "null entry list",
+			json:        `{"schema_version":1,"vault_id":"v","entries":null}`,
+			wantEntries: 0,
+		},
+		{
+			name:        "no schema version",
+			json:        `{"vault_id":"v","entries":[{"id":"1","name":"a","password":"cHc="}]}`,
+			wantEntries: 1,
+			wantTypes:   []EntryType{TypeLogin},
+		},
+		{
+			name:        "missing and existing types",
+			json:        `{"schema_version":1,"vault_id":"v","entries":[{"id":"1","name":"a"},{"id":"2","name":"b","type":"note"}]}`,
+			wantEntries: 2,
+			wantTypes:   []EntryType{TypeLogin, TypeNote},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := FromJSON([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("FromJSON: %v", err)
+			}
+			if v.SchemaVersion != SchemaVersion {
+				t.Errorf("schema version = %d, want %d", v.SchemaVersion, SchemaVersion)
+			}
+			if v.Entries == nil {
+				t.Fatal("entries are nil, want an empty list")
+			}
+			if len(v.Entries) != tt.wantEntries {
+				t.Fatalf("got %d entries, want %d", len(v.Entries), tt.wantEntries)
+			}
+			for i, want := range tt.wantTypes {
+				if v.Entries[i].Type != want {
+					t.Errorf("entry %d type = %q, want %q", i, v.Entries[i].Type, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateDeletedEntries(t *testing.T) {
+	v, err := FromJSON([]byte(`{"schema_version":1,"vault_id":"v","entries":[],"deleted_entries":[{"id":"1","name":"old"}]}`))
+	if err != nil {
+		t.Fatalf("FromJSON: %v", err)
+	}
+	if got := v.DeletedEntries[0].Type; got != TypeLogin {
+		t.Errorf("deleted entry type = %q, want %q", got, TypeLogin)
+	}
+}
+
+func TestMigrateCurrentVersionUnchanged(t *testing.T) {
+	v := &Vault{SchemaVersion: SchemaVersion, Entries: []Entry{{ID: "1", Type: TypeCard}}}
+	if err := Migrate(v); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if v.SchemaVersion != SchemaVersion || v.Entries[0].Type != TypeCard {
+		t.Errorf("vault changed to version %d, type %q", v.SchemaVersion, v.Entries[0].Type)
+	}
+}
+
+func TestMigrateSchemaTooNew(t *testing.T) {
+	_, err := FromJSON([]byte(`{"schema_version":99,"vault_id":"v","entries":[]}`))
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("FromJSON error = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	if len(migrations) != SchemaVersion-1 {
+		t.Errorf("%d migrations for schema version %d, want %d", len(migrations), SchemaVersion, SchemaVersion-1)
+	}
+}
@@ -0,0 +1,32 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+// SetEntryPasswordPolicy sets the policy used when generating the entry's
+// password, or clears it when policy is nil. Like SetFavorite it leaves
+// UpdatedAt alone since the password itself doesn't change.
+func (v *Vault) SetEntryPasswordPolicy(id string, policy *crypto.Policy) error {
+	entry := v.entryRef(id)
+	if entry == nil {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	if policy != nil {
+		p := *policy
+		policy = &p
+	}
+	entry.PasswordPolicy = policy
+	return nil
+}
+
+// PasswordPolicyFor returns the policy for generating a password for entry:
+// the entry's own, else the vault's, else nil. A nil entry gets the vault's.
+func (v *Vault) PasswordPolicyFor(entry *Entry) *crypto.Policy {
+	if entry != nil && entry.PasswordPolicy != nil {
+		return entry.PasswordPolicy
+	}
+	return v.PasswordPolicy
+}
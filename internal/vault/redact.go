@@ -0,0 +1,63 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// redacted replaces secret values when entries are formatted
+const redacted = "[REDACTED]"
+
+// String describes the entry with its password, backup codes, notes, secret
+// fields and attachment contents masked, so an entry that ends up in an
+// error message or log doesn't leak them
+func (e Entry) String() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.String()
+	}
+	attachments := make([]string, len(e.Attachments))
+	for i, a := range e.Attachments {
+		attachments[i] = a.Name
+	}
+	return fmt.Sprintf("Entry{ID: %s, Name: %q, Type: %s, Username: %q, Password: %s, URL: %q, Notes: %s, BackupCodes: %s, Tags: %q, Fields: [%s], Attachments: %q}",
+		e.ID, e.Name, e.Type, e.Username, mask(len(e.Password) > 0), e.URL, mask(e.Notes != ""),
+		mask(len(e.BackupCodes) > 0), e.Tags, strings.Join(fields, ", "), attachments)
+}
+
+// GoString is used for %#v and masks the same values as String
+func (e Entry) GoString() string {
+	return "vault." + e.String()
+}
+
+// String formats the field as NAME=VALUE, masking the value of secret fields
+func (f CustomField) String() string {
+	if f.Secret {
+		return f.Name + "=" + redacted
+	}
+	return f.Name + "=" + f.Value
+}
+
+// GoString is used for %#v and masks the same values as String
+func (f CustomField) GoString() string {
+	return fmt.Sprintf("vault.CustomField{%s}", f.String())
+}
+
+// String describes the attachment with its inline content masked
+func (a Attachment) String() string {
+	return fmt.Sprintf("Attachment{Name: %q, Size: %d, Data: %s, ObjectKey: %q}",
+		a.Name, a.Size, mask(len(a.Data) > 0), a.ObjectKey)
+}
+
+// GoString is used for %#v and masks the same values as String
+func (a Attachment) GoString() string {
+	return "vault." + a.String()
+}
+
+// mask returns the redaction marker for a value that is set, or "" if not
+func mask(set bool) string {
+	if set {
+		return redacted
+	}
+	return `""`
+}
@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormattingRedactsSecrets(t *testing.T) {
+	entry := Entry{
+		ID:          "1",
+		Name:        "bank",
+		Username:    "alice",
+		Password:    []byte("hunter2-password"),
+		Notes:       "security answer: fluffy",
+		BackupCodes: []string{"backup-code-1"},
+		Fields:      []CustomField{{Name: "pin", Value: "secret-pin", Secret: true}, {Name: "branch", Value: "north"}},
+		Attachments: []Attachment{{Name: "card.txt", Size: 13, Data: []byte("inline-secret")}},
+	}
+	secrets := []string{"hunter2-password", "fluffy", "backup-code-1", "secret-pin", "inline-secret"}
+
+	values := map[string]any{
+		"entry":      entry,
+		"pointer":    &entry,
+		"attachment": entry.Attachments[0],
+		"field":      entry.Fields[0],
+	}
+	for name, value := range values {
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+			out := fmt.Sprintf(verb, value)
+			for _, secret := range secrets {
+				if leaks(out, secret) {
+					t.Errorf("%s formatted with %s contains %q: %s", name, verb, secret, out)
+				}
+			}
+		}
+	}
+
+	// Values that aren't secret stay readable
+	if out := fmt.Sprint(entry); !strings.Contains(out, "alice") || !strings.Contains(out, "branch=north") {
+		t.Errorf("entry formats as %s, want the username and public fields shown", out)
+	}
+}
+
+// leaks reports whether out contains secret as text or in the forms fmt
+// prints a []byte holding it
+func leaks(out, secret string) bool {
+	b := []byte(secret)
+	forms := []string{secret, strings.Trim(fmt.Sprint(b), "[]"), strings.Trim(fmt.Sprintf("%#v", b), "[]byte{}")}
+	for _, form := range forms {
+		if strings.Contains(out, form) {
+			return true
+		}
+	}
+	return false
+}
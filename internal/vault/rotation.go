@@ -0,0 +1,34 @@
+package vault
+
+import "time"
+
+// RotationDue returns when the entry's password must next be changed: its
+// expiry time, or its last update plus the rotation interval, whichever comes
+// first. ok is false if the entry has neither.
+func (e *Entry) RotationDue() (due time.Time, ok bool) {
+	if e.RotateEvery > 0 {
+		due, ok = e.UpdatedAt.Add(e.RotateEvery), true
+	}
+	if e.ExpiresAt != nil && (!ok || e.ExpiresAt.Before(due)) {
+		due, ok = *e.ExpiresAt, true
+	}
+	return due, ok
+}
+
+// IsRotationDue reports whether the entry's password is due to be changed at now
+func (e *Entry) IsRotationDue(now time.Time) bool {
+	due, ok := e.RotationDue()
+	return ok && !now.Before(due)
+}
+
+// ExpiredEntries returns summaries of entries whose password is due to be
+// changed at now
+func (v *Vault) ExpiredEntries(now time.Time) []EntrySummary {
+	summaries := make([]EntrySummary, 0)
+	for i := range v.Entries {
+		if v.Entries[i].IsRotationDue(now) {
+			summaries = append(summaries, v.Entries[i].Summary())
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,96 @@
+package vault
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Searchable entry fields
+const (
+	FieldName     = "name"
+	FieldUsername = "username"
+	FieldURL      = "url"
+	FieldNotes    = "notes"
+	FieldTags     = "tags"
+)
+
+// SearchFields lists every field Search can match against
+var SearchFields = []string{FieldName, FieldUsername, FieldURL, FieldNotes, FieldTags}
+
+// SearchOptions controls how Search matches entries
+type SearchOptions struct {
+	Regex  bool     // Treat the query as a Go regular expression
+	Fields []string // Fields to search; empty means all SearchFields
+}
+
+// Search returns summaries of entries whose fields match the query.
+// By default the query is a case-insensitive substring.
+func (v *Vault) Search(query string, opts SearchOptions) ([]EntrySummary, error) {
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = SearchFields
+	}
+	for _, field := range fields {
+		if !isSearchField(field) {
+			return nil, fmt.Errorf("unknown search field: %s (valid fields: %s)", field, strings.Join(SearchFields, ", "))
+		}
+	}
+
+	var match func(string) bool
+	if opts.Regex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		match = re.MatchString
+	} else {
+		lowered := strings.ToLower(query)
+		match = func(s string) bool {
+			return strings.Contains(strings.ToLower(s), lowered)
+		}
+	}
+
+	results := make([]EntrySummary, 0)
+	for i := range v.Entries {
+		entry := &v.Entries[i]
+		for _, field := range fields {
+			if entry.fieldMatches(field, match) {
+				results = append(results, entry.Summary())
+				break
+			}
+		}
+	}
+	return results, nil
+}
+
+// fieldMatches reports whether a searchable field matches. Tags match if
+// any one of them does.
+func (e *Entry) fieldMatches(field string, match func(string) bool) bool {
+	switch field {
+	case FieldName:
+		return match(e.Name)
+	case FieldUsername:
+		return match(e.Username)
+	case FieldURL:
+		return match(e.URL)
+	case FieldNotes:
+		return match(e.Notes)
+	case FieldTags:
+		for _, tag := range e.Tags {
+			if match(tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isSearchField(field string) bool {
+	for _, f := range SearchFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
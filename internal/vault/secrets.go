@@ -0,0 +1,63 @@
+package vault
+
+// EntrySecrets holds the fields of an entry that split-format vaults
+// encrypt under a key of their own, apart from the entry's metadata
+type EntrySecrets struct {
+	Password        []byte        `json:"password,omitempty"`
+	Notes           string        `json:"notes,omitempty"`
+	BackupCodes     []string      `json:"backup_codes,omitempty"`
+	UsedBackupCodes []string      `json:"used_backup_codes,omitempty"`
+	Fields          []CustomField `json:"fields,omitempty"`
+	Attachments     []Attachment  `json:"attachments,omitempty"`
+}
+
+// TakeSecrets moves the entry's secret fields out of it, leaving only its
+// metadata
+func (e *Entry) TakeSecrets() EntrySecrets {
+	s := EntrySecrets{
+		Password:        e.Password,
+		Notes:           e.Notes,
+		BackupCodes:     e.BackupCodes,
+		UsedBackupCodes: e.UsedBackupCodes,
+		Fields:          e.Fields,
+		Attachments:     e.Attachments,
+	}
+	e.Password = nil
+	e.Notes = ""
+	e.BackupCodes = nil
+	e.UsedBackupCodes = nil
+	e.Fields = nil
+	e.Attachments = nil
+	return s
+}
+
+// RestoreSecrets puts secret fields taken with TakeSecrets back and clears
+// SealedSecrets
+func (e *Entry) RestoreSecrets(s EntrySecrets) {
+	e.Password = s.Password
+	e.Notes = s.Notes
+	e.BackupCodes = s.BackupCodes
+	e.UsedBackupCodes = s.UsedBackupCodes
+	e.Fields = s.Fields
+	e.Attachments = s.Attachments
+	e.SealedSecrets = ""
+}
+
+// SecretsSealed reports whether the entry was loaded without decrypting its
+// secret fields
+func (e *Entry) SecretsSealed() bool {
+	return e.SealedSecrets != ""
+}
+
+// HasSealedSecrets reports whether any entry, including those in the trash,
+// still has its secret fields sealed
+func (v *Vault) HasSealedSecrets() bool {
+	for _, entries := range [][]Entry{v.Entries, v.DeletedEntries} {
+		for i := range entries {
+			if entries[i].SecretsSealed() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNameTaken is returned by RestoreEntry when a live entry already has the
+// removed entry's name
+var ErrNameTaken = errors.New("an entry with this name already exists")
+
+// GetDeletedEntry finds an entry in the trash by ID or name
+func (v *Vault) GetDeletedEntry(identifier string) *Entry {
+	for i := range v.DeletedEntries {
+		if v.DeletedEntries[i].ID == identifier || v.DeletedEntries[i].Name == identifier {
+			return &v.DeletedEntries[i]
+		}
+	}
+	return nil
+}
+
+// ListDeletedEntries returns summaries of all entries in the trash
+func (v *Vault) ListDeletedEntries() []EntrySummary {
+	summaries := make([]EntrySummary, len(v.DeletedEntries))
+	for i := range v.DeletedEntries {
+		summaries[i] = v.DeletedEntries[i].Summary()
+	}
+	return summaries
+}
+
+// RestoreEntry moves an entry, by ID or name, from the trash back into the
+// vault. It fails with ErrEntryNotFound if the trash has no such entry, and
+// with ErrNameTaken if a live entry has since taken its name.
+func (v *Vault) RestoreEntry(identifier string) (*Entry, error) {
+	for i, entry := range v.DeletedEntries {
+		if entry.ID == identifier || entry.Name == identifier {
+			if v.HasName(entry.Name) {
+				return nil, fmt.Errorf("%w: %s", ErrNameTaken, entry.Name)
+			}
+			entry.DeletedAt = nil
+			v.Entries = append(v.Entries, entry)
+			v.indexAppended()
+			v.DeletedEntries = append(v.DeletedEntries[:i], v.DeletedEntries[i+1:]...)
+			return &v.Entries[len(v.Entries)-1], nil
+		}
+	}
+	return nil, fmt.Errorf("%w in trash: %s", ErrEntryNotFound, identifier)
+}
+
+// EmptyTrash permanently removes all entries in the trash, zeroing their
+// passwords, and returns how many were removed
+func (v *Vault) EmptyTrash() int {
+	n := len(v.DeletedEntries)
+	for i := range v.DeletedEntries {
+		for j := range v.DeletedEntries[i].Password {
+			v.DeletedEntries[i].Password[j] = 0
+		}
+	}
+	v.DeletedEntries = nil
+	return n
+}
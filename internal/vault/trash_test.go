@@ -0,0 +1,52 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemoveAndRestoreEntry(t *testing.T) {
+	v := NewVault()
+	id := v.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, nil).ID
+
+	if !v.RemoveEntry("github") {
+		t.Fatal("RemoveEntry(github) failed")
+	}
+	if v.GetEntry("github") != nil {
+		t.Error("removed entry still live")
+	}
+	trash := v.ListDeletedEntries()
+	if len(trash) != 1 || trash[0].ID != id || trash[0].DeletedAt == nil {
+		t.Fatalf("trash = %+v, want the github entry with its removal time", trash)
+	}
+
+	// A new entry takes the name while the old one is in the trash
+	v.AddEntry("github", "bob", nil, "", "", nil, nil)
+	if _, err := v.RestoreEntry(id); !errors.Is(err, ErrNameTaken) {
+		t.Fatalf("RestoreEntry under a taken name: err = %v, want ErrNameTaken", err)
+	}
+	if len(v.DeletedEntries) != 1 || len(v.Entries) != 1 {
+		t.Fatalf("failed restore left %d live and %d trashed entries, want 1 and 1", len(v.Entries), len(v.DeletedEntries))
+	}
+
+	if !v.RemoveEntry("github") {
+		t.Fatal("RemoveEntry of the new github failed")
+	}
+	restored, err := v.RestoreEntry(id)
+	if err != nil {
+		t.Fatalf("RestoreEntry: %v", err)
+	}
+	if restored.ID != id || restored.DeletedAt != nil || string(restored.Password) != "hunter2" {
+		t.Errorf("restored = %+v, want the original github entry with no removal time", restored)
+	}
+	if got := v.GetEntry("github"); got == nil || got.Username != "alice" {
+		t.Errorf("GetEntry(github) = %+v, want alice's restored entry", got)
+	}
+	if trash := v.ListDeletedEntries(); len(trash) != 1 || trash[0].Username != "bob" {
+		t.Errorf("trash = %+v, want only bob's entry", trash)
+	}
+
+	if _, err := v.RestoreEntry(id); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("RestoreEntry of an entry not in the trash: err = %v, want ErrEntryNotFound", err)
+	}
+}
@@ -0,0 +1,67 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EntryType determines which fields an entry carries and how it is displayed
+type EntryType string
+
+const (
+	TypeLogin    EntryType = "login"
+	TypeNote     EntryType = "note"
+	TypeCard     EntryType = "card"
+	TypeIdentity EntryType = "identity"
+)
+
+// EntryTypes lists every supported entry type
+var EntryTypes = []EntryType{TypeLogin, TypeNote, TypeCard, TypeIdentity}
+
+// TemplateField describes a custom field an entry type expects
+type TemplateField struct {
+	Name     string
+	Secret   bool
+	Required bool
+}
+
+// ParseEntryType parses an entry type name
+func ParseEntryType(s string) (EntryType, error) {
+	for _, t := range EntryTypes {
+		if string(t) == strings.ToLower(s) {
+			return t, nil
+		}
+	}
+
+	names := make([]string, len(EntryTypes))
+	for i, t := range EntryTypes {
+		names[i] = string(t)
+	}
+	return "", fmt.Errorf("unknown entry type: %s (valid types: %s)", s, strings.Join(names, ", "))
+}
+
+// HasPassword reports whether entries of this type carry a password
+func (t EntryType) HasPassword() bool {
+	return t == TypeLogin
+}
+
+// TemplateFields returns the custom fields entries of this type expect
+func (t EntryType) TemplateFields() []TemplateField {
+	switch t {
+	case TypeCard:
+		return []TemplateField{
+			{Name: "cardholder"},
+			{Name: "number", Secret: true, Required: true},
+			{Name: "expiry", Required: true},
+			{Name: "cvv", Secret: true, Required: true},
+		}
+	case TypeIdentity:
+		return []TemplateField{
+			{Name: "full_name", Required: true},
+			{Name: "email"},
+			{Name: "phone"},
+			{Name: "address"},
+		}
+	}
+	return nil
+}
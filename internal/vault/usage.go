@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecordUse counts a use of an entry's secrets at the given time. Like
+// SetFavorite it leaves UpdatedAt alone, so using a password doesn't push
+// back its rotation.
+func (v *Vault) RecordUse(id string, at time.Time) error {
+	entry := v.entryRef(id)
+	if entry == nil {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	entry.UseCount++
+	if entry.LastUsedAt == nil || at.After(*entry.LastUsedAt) {
+		entry.LastUsedAt = &at
+	}
+	return nil
+}
+
+// UsageLog counts uses of entries, by ID, that are not yet recorded in the
+// vault. Reads add to it instead of saving the vault, and it is applied with
+// the next change or sync.
+type UsageLog map[string]LoggedUse
+
+// LoggedUse is how often an entry was used since the log was last applied,
+// and when last
+type LoggedUse struct {
+	Count      int       `json:"count"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// Add counts a use of the entry with the given ID at the given time
+func (l UsageLog) Add(id string, at time.Time) {
+	use := l[id]
+	use.Count++
+	if at.After(use.LastUsedAt) {
+		use.LastUsedAt = at
+	}
+	l[id] = use
+}
+
+// Merge adds the uses in other to l
+func (l UsageLog) Merge(other UsageLog) {
+	for id, o := range other {
+		use := l[id]
+		use.Count += o.Count
+		if o.LastUsedAt.After(use.LastUsedAt) {
+			use.LastUsedAt = o.LastUsedAt
+		}
+		l[id] = use
+	}
+}
+
+// ApplyUsage records the uses in log on the entries. Entries removed since
+// they were used are skipped.
+func (v *Vault) ApplyUsage(log UsageLog) {
+	for id, use := range log {
+		entry := v.entryRef(id)
+		if entry == nil || use.Count == 0 {
+			continue
+		}
+		entry.UseCount += use.Count
+		if entry.LastUsedAt == nil || use.LastUsedAt.After(*entry.LastUsedAt) {
+			at := use.LastUsedAt
+			entry.LastUsedAt = &at
+		}
+	}
+}
+
+// usage is the part of an entry that counts its uses. It changes on every
+// read, so merges combine it rather than treating it as an edit.
+type usage struct {
+	count    int
+	lastUsed *time.Time
+}
+
+func (e *Entry) usage() usage {
+	return usage{count: e.UseCount, lastUsed: e.LastUsedAt}
+}
+
+func (e *Entry) setUsage(u usage) {
+	e.UseCount = u.count
+	e.LastUsedAt = u.lastUsed
+}
+
+// mergeUsage adds up the uses each side counted since base and keeps the
+// latest use. Any of the entries may be nil.
+func mergeUsage(base, local, remote *Entry) usage {
+	var merged usage
+	for _, e := range []*Entry{local, remote} {
+		if e == nil {
+			continue
+		}
+		merged.count += e.UseCount
+		if e.LastUsedAt != nil && (merged.lastUsed == nil || e.LastUsedAt.After(*merged.lastUsed)) {
+			at := *e.LastUsedAt
+			merged.lastUsed = &at
+		}
+	}
+	// Uses from before base were counted by both sides
+	if base != nil && local != nil && remote != nil {
+		merged.count -= base.UseCount
+	}
+	return merged
+}
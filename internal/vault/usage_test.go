@@ -0,0 +1,96 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeUsage(t *testing.T) {
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
+	used := func(count int, at *time.Time) *Entry {
+		return &Entry{UseCount: count, LastUsedAt: at}
+	}
+
+	tests := []struct {
+		name                string
+		base, local, remote *Entry
+		wantCount           int
+		wantLast            *time.Time
+	}{
+		{"unused", used(0, nil), used(0, nil), used(0, nil), 0, nil},
+		{"used locally", used(2, &t1), used(5, &t2), used(2, &t1), 5, &t2},
+		{"used remotely", used(2, &t1), used(2, &t1), used(4, &t3), 4, &t3},
+		{"used on both", used(2, &t1), used(5, &t3), used(4, &t2), 7, &t3},
+		{"added on both", nil, used(1, &t1), used(3, &t2), 4, &t2},
+		{"local only", nil, used(3, &t2), nil, 3, &t2},
+		{"deleted remotely", used(2, &t1), used(3, &t2), nil, 3, &t2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeUsage(tt.base, tt.local, tt.remote)
+			if got.count != tt.wantCount {
+				t.Errorf("mergeUsage count = %d, want %d", got.count, tt.wantCount)
+			}
+			if (got.lastUsed == nil) != (tt.wantLast == nil) || (got.lastUsed != nil && !got.lastUsed.Equal(*tt.wantLast)) {
+				t.Errorf("mergeUsage last used = %v, want %v", got.lastUsed, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestMergeUsageIsNotAConflict(t *testing.T) {
+	base := NewVault()
+	e := base.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, nil)
+	local := base.Clone()
+	remote := base.Clone()
+
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := local.RecordUse(e.ID, t1); err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.RecordUse(e.ID, t1.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.RecordUse(e.ID, t1); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, conflicts := Merge(base, local, remote)
+	if len(conflicts) != 0 {
+		t.Fatalf("Merge reported %d conflicts, want none", len(conflicts))
+	}
+	got := merged.Entries[0]
+	if got.UseCount != 3 {
+		t.Errorf("merged UseCount = %d, want 3", got.UseCount)
+	}
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(t1.Add(time.Hour)) {
+		t.Errorf("merged LastUsedAt = %v, want %v", got.LastUsedAt, t1.Add(time.Hour))
+	}
+}
+
+func TestApplyUsage(t *testing.T) {
+	v := NewVault()
+	e := v.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, nil)
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := v.RecordUse(e.ID, t1.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	log := UsageLog{}
+	log.Add(e.ID, t1)
+	other := UsageLog{}
+	other.Add(e.ID, t1.Add(2*time.Hour))
+	other.Add("removed-entry", t1)
+	log.Merge(other)
+	v.ApplyUsage(log)
+
+	got := v.GetEntry(e.ID)
+	if got.UseCount != 3 {
+		t.Errorf("UseCount = %d, want 3", got.UseCount)
+	}
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(t1.Add(2*time.Hour)) {
+		t.Errorf("LastUsedAt = %v, want %v", got.LastUsedAt, t1.Add(2*time.Hour))
+	}
+}
@@ -3,24 +3,45 @@ package vault
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vaultctl/vaultctl/internal/crypto"
 )
 
-const SchemaVersion = 1
+// SchemaVersion is the version of the plaintext vault format. Older vaults
+// are upgraded by Migrate when they are loaded.
+const SchemaVersion = 2
 
 // Entry represents a single password entry
 type Entry struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Username    string    `json:"username"`
-	Password    []byte    `json:"password"` // Stored as base64 in JSON for security
-	URL         string    `json:"url"`
-	Notes       string    `json:"notes"`
-	BackupCodes []string  `json:"backup_codes,omitempty"` // 2FA/authenticator backup codes
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Type            EntryType      `json:"type,omitempty"`
+	Username        string         `json:"username"`
+	Password        []byte         `json:"password"` // Stored as base64 in JSON for security
+	URL             string         `json:"url"`
+	Notes           string         `json:"notes"`
+	BackupCodes     []string       `json:"backup_codes,omitempty"`      // 2FA/authenticator backup codes
+	UsedBackupCodes []string       `json:"used_backup_codes,omitempty"` // Backup codes already consumed
+	Tags            []string       `json:"tags,omitempty"`
+	Folder          string         `json:"folder,omitempty"` // Slash-separated path, e.g. "work/aws"
+	Fields          []CustomField  `json:"fields,omitempty"`
+	Attachments     []Attachment   `json:"attachments,omitempty"`
+	ExpiresAt       *time.Time     `json:"expires_at,omitempty"`   // Password must be changed by this time
+	RotateEvery     time.Duration  `json:"rotate_every,omitempty"` // Password must be changed this long after the last update
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       *time.Time     `json:"deleted_at,omitempty"`      // Set while the entry is in the trash
+	Favorite        bool           `json:"favorite,omitempty"`        // Pinned to the top of list
+	PasswordPolicy  *crypto.Policy `json:"password_policy,omitempty"` // Overrides the vault's policy when generating
+	SealedSecrets   string         `json:"sealed_secrets,omitempty"`  // Split vaults only: secret fields, encrypted separately
+	LastUsedAt      *time.Time     `json:"last_used_at,omitempty"`    // When a secret was last shown, copied or exported
+	UseCount        int            `json:"use_count,omitempty"`       // How often a secret was shown, copied or exported
 }
 
 // UnmarshalJSON custom unmarshaler for backward compatibility
@@ -62,9 +83,13 @@ func (e *Entry) UnmarshalJSON(data []byte) error {
 
 // Vault represents the plaintext vault structure
 type Vault struct {
-	SchemaVersion int     `json:"schema_version"`
-	VaultID       string  `json:"vault_id"`
-	Entries       []Entry `json:"entries"`
+	SchemaVersion  int            `json:"schema_version"`
+	VaultID        string         `json:"vault_id"`
+	Entries        []Entry        `json:"entries"`
+	DeletedEntries []Entry        `json:"deleted_entries,omitempty"` // Trash
+	PasswordPolicy *crypto.Policy `json:"password_policy,omitempty"` // Used when generating passwords
+
+	index *entryIndex // Built on first lookup, see index.go
 }
 
 // NewVault creates a new empty vault
@@ -76,101 +101,370 @@ func NewVault() *Vault {
 	}
 }
 
-// AddEntry adds a new entry to the vault
-func (v *Vault) AddEntry(name, username string, password []byte, url, notes string, backupCodes []string) *Entry {
+// AddEntry adds a new login entry to the vault and returns a copy of it.
+// The password is copied, so the caller may zeroize its buffer.
+func (v *Vault) AddEntry(name, username string, password []byte, url, notes string, backupCodes, tags []string) *Entry {
+	return v.AddEntryWith(name, username, password, url, notes, backupCodes, tags, EntryOptions{})
+}
+
+// EntryOptions are the fields of a new entry that AddEntry leaves at their
+// defaults. Zero values keep the default.
+type EntryOptions struct {
+	Type        EntryType
+	Folder      string
+	Fields      []CustomField
+	ExpiresAt   *time.Time
+	RotateEvery time.Duration
+	CreatedAt   time.Time // Defaults to now, e.g. set from an export
+	UpdatedAt   time.Time // Defaults to CreatedAt
+}
+
+// AddEntryWith is AddEntry for entries with further fields set
+func (v *Vault) AddEntryWith(name, username string, password []byte, url, notes string, backupCodes, tags []string, opts EntryOptions) *Entry {
 	now := time.Now()
 	// Make a copy of the password to avoid external modifications
 	passwordCopy := make([]byte, len(password))
 	copy(passwordCopy, password)
-	
+
 	entry := Entry{
 		ID:          uuid.New().String(),
 		Name:        name,
+		Type:        TypeLogin,
 		Username:    username,
 		Password:    passwordCopy,
 		URL:         url,
 		Notes:       notes,
 		BackupCodes: backupCodes,
+		Tags:        tags,
+		Folder:      opts.Folder,
+		ExpiresAt:   opts.ExpiresAt,
+		RotateEvery: opts.RotateEvery,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	if opts.Type != "" {
+		entry.Type = opts.Type
+	}
+	for _, field := range opts.Fields {
+		entry.SetField(field.Name, field.Value, field.Secret)
+	}
+	if !opts.CreatedAt.IsZero() {
+		entry.CreatedAt = opts.CreatedAt
+		entry.UpdatedAt = opts.CreatedAt
+	}
+	if !opts.UpdatedAt.IsZero() {
+		entry.UpdatedAt = opts.UpdatedAt
+	}
+
 	v.Entries = append(v.Entries, entry)
-	return &entry
+	v.indexAppended()
+	return v.Entries[len(v.Entries)-1].Clone()
 }
 
-// GetEntry finds an entry by ID or name
-func (v *Vault) GetEntry(identifier string) *Entry {
+// ErrEntryNotFound is returned by LookupEntry when nothing matches
+var ErrEntryNotFound = errors.New("entry not found")
+
+// AmbiguousNameError is returned by LookupEntry when several entries share
+// the name looked up
+type AmbiguousNameError struct {
+	Name string
+	IDs  []string
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("multiple entries named '%s', use an ID: %s", e.Name, strings.Join(e.IDs, ", "))
+}
+
+// Clone returns a deep copy of the entry
+func (e *Entry) Clone() *Entry {
+	c := *e
+	c.Password = append([]byte(nil), e.Password...)
+	c.BackupCodes = append([]string(nil), e.BackupCodes...)
+	c.UsedBackupCodes = append([]string(nil), e.UsedBackupCodes...)
+	c.Tags = append([]string(nil), e.Tags...)
+	c.Fields = append([]CustomField(nil), e.Fields...)
+	if e.Attachments != nil {
+		c.Attachments = make([]Attachment, len(e.Attachments))
+		for i, a := range e.Attachments {
+			a.Data = append([]byte(nil), a.Data...)
+			c.Attachments[i] = a
+		}
+	}
+	if e.ExpiresAt != nil {
+		t := *e.ExpiresAt
+		c.ExpiresAt = &t
+	}
+	if e.DeletedAt != nil {
+		t := *e.DeletedAt
+		c.DeletedAt = &t
+	}
+	if e.LastUsedAt != nil {
+		t := *e.LastUsedAt
+		c.LastUsedAt = &t
+	}
+	if e.PasswordPolicy != nil {
+		p := *e.PasswordPolicy
+		c.PasswordPolicy = &p
+	}
+	return &c
+}
+
+// Clone returns a deep copy of the vault, as another device would load it
+func (v *Vault) Clone() *Vault {
+	c := &Vault{
+		SchemaVersion: v.SchemaVersion,
+		VaultID:       v.VaultID,
+		Entries:       make([]Entry, len(v.Entries)),
+	}
 	for i := range v.Entries {
-		if v.Entries[i].ID == identifier || v.Entries[i].Name == identifier {
-			return &v.Entries[i]
+		c.Entries[i] = *v.Entries[i].Clone()
+	}
+	if v.DeletedEntries != nil {
+		c.DeletedEntries = make([]Entry, len(v.DeletedEntries))
+		for i := range v.DeletedEntries {
+			c.DeletedEntries[i] = *v.DeletedEntries[i].Clone()
 		}
 	}
+	if v.PasswordPolicy != nil {
+		p := *v.PasswordPolicy
+		c.PasswordPolicy = &p
+	}
+	return c
+}
+
+// entryRef finds the stored entry by ID or name, ID matches first. It is
+// the mutable counterpart of GetEntry for use inside the package.
+func (v *Vault) entryRef(identifier string) *Entry {
+	if i, ok := v.positionByID(identifier); ok {
+		return &v.Entries[i]
+	}
+	if positions := v.positionsByName(identifier); len(positions) > 0 {
+		return &v.Entries[positions[0]]
+	}
+	return nil
+}
+
+// GetEntry returns a copy of the entry with the given ID or name. An ID
+// match wins; if several entries share the name, the first is returned. Use
+// LookupEntry to address entries from user input, and UpdateEntry or
+// EditEntry to change them.
+func (v *Vault) GetEntry(identifier string) *Entry {
+	if entry := v.entryRef(identifier); entry != nil {
+		return entry.Clone()
+	}
 	return nil
 }
 
-// RemoveEntry removes an entry by ID or name
+// FindAll returns copies of every entry whose ID or name is identifier
+func (v *Vault) FindAll(identifier string) []*Entry {
+	positions := v.positionsByName(identifier)
+	if i, ok := v.positionByID(identifier); ok && !slices.Contains(positions, i) {
+		// Keep vault order
+		positions = append(slices.Clone(positions), i)
+		slices.Sort(positions)
+	}
+
+	var matches []*Entry
+	for _, i := range positions {
+		matches = append(matches, v.Entries[i].Clone())
+	}
+	return matches
+}
+
+// EditEntry applies edit to the stored entry with the given ID and marks it
+// updated if edit succeeds
+func (v *Vault) EditEntry(id string, edit func(*Entry) error) error {
+	entry := v.entryRef(id)
+	if entry == nil {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	name := entry.Name
+	err := edit(entry)
+	if entry.Name != name {
+		v.invalidateIndex()
+	}
+	if err != nil {
+		return err
+	}
+	entry.UpdatedAt = time.Now()
+	return nil
+}
+
+// LookupEntry returns a copy of exactly one entry by ID, name or, if neither
+// matches, unique ID prefix. It returns ErrEntryNotFound if nothing matches,
+// an *AmbiguousNameError if the identifier is a name shared by several
+// entries and an *AmbiguousIDError if it is a prefix of several IDs.
+func (v *Vault) LookupEntry(identifier string) (*Entry, error) {
+	matches := v.FindAll(identifier)
+	for _, entry := range matches {
+		if entry.ID == identifier {
+			return entry, nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return v.lookupIDPrefix(identifier)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, entry := range matches {
+		ids[i] = entry.ID
+	}
+	return nil, &AmbiguousNameError{Name: identifier, IDs: ids}
+}
+
+// HasName reports whether an entry has exactly this name. Unlike GetEntry
+// it doesn't match IDs.
+func (v *Vault) HasName(name string) bool {
+	return len(v.positionsByName(name)) > 0
+}
+
+// UniqueName returns name if no entry has it, or else name with the lowest
+// numeric suffix that is free: name-2, name-3 and so on
+func (v *Vault) UniqueName(name string) string {
+	if !v.HasName(name) {
+		return name
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", name, n)
+		if !v.HasName(candidate) {
+			return candidate
+		}
+	}
+}
+
+// RemoveEntry moves an entry, by ID or name, to the trash
 func (v *Vault) RemoveEntry(identifier string) bool {
-	for i, entry := range v.Entries {
-		if entry.ID == identifier || entry.Name == identifier {
-			v.Entries = append(v.Entries[:i], v.Entries[i+1:]...)
-			return true
+	i, ok := v.positionByID(identifier)
+	if !ok {
+		positions := v.positionsByName(identifier)
+		if len(positions) == 0 {
+			return false
 		}
+		i = positions[0]
 	}
-	return false
+	v.trashAt(i)
+	return true
+}
+
+// trashAt moves the entry at position i to the trash
+func (v *Vault) trashAt(i int) {
+	entry := v.Entries[i]
+	now := time.Now()
+	entry.DeletedAt = &now
+	v.DeletedEntries = append(v.DeletedEntries, entry)
+	v.Entries = append(v.Entries[:i], v.Entries[i+1:]...)
+	v.invalidateIndex()
 }
 
 // ListEntries returns all entries (without passwords for listing)
 type EntrySummary struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Username  string    `json:"username"`
-	URL       string    `json:"url"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Type        EntryType  `json:"type,omitempty"`
+	Username    string     `json:"username"`
+	URL         string     `json:"url"`
+	Tags        []string   `json:"tags,omitempty"`
+	Folder      string     `json:"folder,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	RotationDue *time.Time `json:"rotation_due,omitempty"`
+	Favorite    bool       `json:"favorite,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	UseCount    int        `json:"use_count,omitempty"`
+}
+
+// Summary returns the entry's summary (without password)
+func (e *Entry) Summary() EntrySummary {
+	summary := EntrySummary{
+		ID:         e.ID,
+		Name:       e.Name,
+		Type:       e.Type,
+		Username:   e.Username,
+		URL:        e.URL,
+		Tags:       e.Tags,
+		Folder:     e.Folder,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+		DeletedAt:  e.DeletedAt,
+		Favorite:   e.Favorite,
+		LastUsedAt: e.LastUsedAt,
+		UseCount:   e.UseCount,
+	}
+	if due, ok := e.RotationDue(); ok {
+		summary.RotationDue = &due
+	}
+	return summary
 }
 
 func (v *Vault) ListEntries() []EntrySummary {
 	summaries := make([]EntrySummary, len(v.Entries))
-	for i, entry := range v.Entries {
-		summaries[i] = EntrySummary{
-			ID:        entry.ID,
-			Name:      entry.Name,
-			Username:  entry.Username,
-			URL:       entry.URL,
-			CreatedAt: entry.CreatedAt,
-			UpdatedAt: entry.UpdatedAt,
+	for i := range v.Entries {
+		summaries[i] = v.Entries[i].Summary()
+	}
+	return summaries
+}
+
+// HasTag reports whether the entry carries the tag (case-insensitive)
+func (e *Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// EntriesByTag returns summaries of entries carrying the tag
+func (v *Vault) EntriesByTag(tag string) []EntrySummary {
+	summaries := make([]EntrySummary, 0)
+	for i := range v.Entries {
+		if v.Entries[i].HasTag(tag) {
+			summaries = append(summaries, v.Entries[i].Summary())
 		}
 	}
 	return summaries
 }
 
-// UpdateEntry updates an existing entry
-func (v *Vault) UpdateEntry(identifier string, name, username string, password []byte, url, notes string, backupCodes []string) bool {
-	entry := v.GetEntry(identifier)
+// UpdateEntry updates an existing entry. Empty name and password, and nil
+// username, url, notes, backupCodes and tags leave the field unchanged; a
+// pointer to an empty string clears it.
+func (v *Vault) UpdateEntry(identifier string, name string, username *string, password []byte, url, notes *string, backupCodes, tags []string) bool {
+	entry := v.entryRef(identifier)
 	if entry == nil {
 		return false
 	}
 
-	if name != "" {
+	if name != "" && name != entry.Name {
 		entry.Name = name
+		v.invalidateIndex()
 	}
-	if username != "" {
-		entry.Username = username
+	if username != nil {
+		entry.Username = *username
 	}
 	if password != nil && len(password) > 0 {
 		// Make a copy to avoid external modifications
 		passwordCopy := make([]byte, len(password))
 		copy(passwordCopy, password)
 		entry.Password = passwordCopy
+		// A fixed expiry applies to the password it was set for
+		entry.ExpiresAt = nil
 	}
-	if url != "" {
-		entry.URL = url
+	if url != nil {
+		entry.URL = *url
 	}
-	if notes != "" {
-		entry.Notes = notes
+	if notes != nil {
+		entry.Notes = *notes
 	}
 	if backupCodes != nil {
+		// New codes start out unused
 		entry.BackupCodes = backupCodes
+		entry.UsedBackupCodes = nil
+	}
+	if tags != nil {
+		entry.Tags = tags
 	}
 	entry.UpdatedAt = time.Now()
 	return true
@@ -181,11 +475,15 @@ func (v *Vault) ToJSON() ([]byte, error) {
 	return json.Marshal(v)
 }
 
-// FromJSON deserializes the vault from JSON
+// FromJSON deserializes the vault from JSON and migrates it to the current
+// schema version
 func FromJSON(data []byte) (*Vault, error) {
 	var v Vault
 	if err := json.Unmarshal(data, &v); err != nil {
 		return nil, err
 	}
+	if err := Migrate(&v); err != nil {
+		return nil, err
+	}
 	return &v, nil
 }
@@ -0,0 +1,227 @@
+package vault
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+)
+
+func TestAddEntryCopiesPassword(t *testing.T) {
+	v := NewVault()
+	password := []byte("correct horse battery staple")
+	want := bytes.Clone(password)
+
+	entry := v.AddEntry("github", "alice", password, "https://github.com", "", nil, nil)
+	id := entry.ID
+	crypto.Zeroize(password)
+
+	stored := v.GetEntry(id)
+	if stored == nil {
+		t.Fatal("added entry not found")
+	}
+	if !bytes.Equal(stored.Password, want) {
+		t.Errorf("stored password = %q, want %q", stored.Password, want)
+	}
+}
+
+func TestUpdateEntryClearsFields(t *testing.T) {
+	empty := ""
+	tests := []struct {
+		name     string
+		username *string
+		url      *string
+		notes    *string
+		want     Entry
+	}{
+		{"url", nil, &empty, nil, Entry{Username: "alice", URL: "", Notes: "old notes"}},
+		{"username", &empty, nil, nil, Entry{Username: "", URL: "https://example.com", Notes: "old notes"}},
+		{"notes", nil, nil, &empty, Entry{Username: "alice", URL: "https://example.com", Notes: ""}},
+		{"all", &empty, &empty, &empty, Entry{}},
+		{"none", nil, nil, nil, Entry{Username: "alice", URL: "https://example.com", Notes: "old notes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVault()
+			id := v.AddEntry("site", "alice", []byte("pw"), "https://example.com", "old notes", nil, nil).ID
+
+			if !v.UpdateEntry(id, "", tt.username, nil, tt.url, tt.notes, nil, nil) {
+				t.Fatal("UpdateEntry returned false")
+			}
+			got := v.GetEntry(id)
+			if got.Username != tt.want.Username || got.URL != tt.want.URL || got.Notes != tt.want.Notes {
+				t.Errorf("username, url, notes = %q, %q, %q; want %q, %q, %q",
+					got.Username, got.URL, got.Notes, tt.want.Username, tt.want.URL, tt.want.Notes)
+			}
+			if got.Name != "site" || string(got.Password) != "pw" {
+				t.Errorf("name and password changed to %q, %q", got.Name, got.Password)
+			}
+		})
+	}
+}
+
+func TestGetEntryReturnsCopy(t *testing.T) {
+	v := NewVault()
+	entry := v.AddEntryWith("site", "alice", []byte("secret"), "", "", nil, []string{"work"}, EntryOptions{
+		Fields: []CustomField{{Name: "pin", Value: "1234", Secret: true}},
+	})
+	id := entry.ID
+	if err := v.EditEntry(id, func(e *Entry) error {
+		e.Attachments = []Attachment{{Name: "key.pem", Size: 4, Data: []byte("data")}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entry AddEntry returns is a copy too
+	entry.Name = "renamed"
+	entry.Password[0] = 'X'
+	if stored := v.GetEntry(id); stored.Name != "site" || string(stored.Password) != "secret" {
+		t.Fatalf("editing the added entry changed the vault: %q, %q", stored.Name, stored.Password)
+	}
+	usedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := v.RecordUse(id, usedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	got := v.GetEntry(id)
+	*got.LastUsedAt = usedAt.Add(time.Hour)
+	got.Password[0] = 'X'
+	got.Tags[0] = "personal"
+	got.Fields[0].Value = "0000"
+	got.Attachments[0].Data[0] = 'X'
+	got.Name = "renamed"
+
+	stored := v.GetEntry(id)
+	if string(stored.Password) != "secret" {
+		t.Errorf("password = %q, want secret", stored.Password)
+	}
+	if stored.Tags[0] != "work" {
+		t.Errorf("tag = %q, want work", stored.Tags[0])
+	}
+	if stored.Fields[0].Value != "1234" {
+		t.Errorf("field value = %q, want 1234", stored.Fields[0].Value)
+	}
+	if string(stored.Attachments[0].Data) != "data" {
+		t.Errorf("attachment data = %q, want data", stored.Attachments[0].Data)
+	}
+	if stored.Name != "site" {
+		t.Errorf("name = %q, want site", stored.Name)
+	}
+	if !stored.LastUsedAt.Equal(usedAt) {
+		t.Errorf("last used = %v, want %v", stored.LastUsedAt, usedAt)
+	}
+	if v.GetEntry("renamed") != nil {
+		t.Error("the copy's new name is found in the vault")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	v := NewVault()
+	v.AddEntry("GitHub", "alice", nil, "https://github.com/login", "", nil, []string{"dev"})
+	v.AddEntry("bank", "Alice.Smith", nil, "https://bank.example", "Checking account", nil, []string{"Finance"})
+	v.AddEntry("wifi", "", nil, "", "router in the hall", nil, nil)
+
+	tests := []struct {
+		name  string
+		query string
+		opts  SearchOptions
+		want  []string
+	}{
+		{"name", "github", SearchOptions{}, []string{"GitHub"}},
+		{"username", "SMITH", SearchOptions{}, []string{"bank"}},
+		{"url", "BANK.EXAMPLE", SearchOptions{}, []string{"bank"}},
+		{"notes", "checking", SearchOptions{}, []string{"bank"}},
+		{"tags", "finance", SearchOptions{}, []string{"bank"}},
+		{"several fields", "alice", SearchOptions{}, []string{"GitHub", "bank"}},
+		{"restricted to a field", "alice", SearchOptions{Fields: []string{FieldUsername}}, []string{"GitHub", "bank"}},
+		{"other field skipped", "github", SearchOptions{Fields: []string{FieldNotes, FieldTags}}, nil},
+		{"regex", "^(wifi|bank)$", SearchOptions{Regex: true, Fields: []string{FieldName}}, []string{"bank", "wifi"}},
+		{"no matches", "gitlab", SearchOptions{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := v.Search(tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q, %+v) = %v, want %v", tt.query, tt.opts, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := v.Search("x", SearchOptions{Fields: []string{"password"}}); err == nil {
+		t.Error("Search of an unknown field succeeded")
+	}
+	if _, err := v.Search("(", SearchOptions{Regex: true}); err == nil {
+		t.Error("Search with an invalid regex succeeded")
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		trash []string // Names of removed entries, which don't count
+		want  string
+	}{
+		{"free", nil, nil, "github"},
+		{"taken", []string{"github"}, nil, "github-2"},
+		{"suffix taken", []string{"github", "github-2"}, nil, "github-3"},
+		{"gap reused", []string{"github", "github-3"}, nil, "github-2"},
+		{"only the suffix taken", []string{"github-2"}, nil, "github"},
+		{"only in the trash", nil, []string{"github"}, "github"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVault()
+			for _, name := range tt.trash {
+				v.RemoveEntry(v.AddEntry(name, "", nil, "", "", nil, nil).ID)
+			}
+			for _, name := range tt.taken {
+				v.AddEntry(name, "", nil, "", "", nil, nil)
+			}
+			if got := v.UniqueName("github"); got != tt.want {
+				t.Errorf("UniqueName(github) = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultClone(t *testing.T) {
+	v := NewVault()
+	policy := crypto.DefaultPolicy()
+	v.PasswordPolicy = &policy
+	v.AddEntry("github", "alice", []byte("hunter2"), "", "", nil, []string{"dev"})
+	v.RemoveEntry(v.AddEntry("bank", "alice", []byte("secret"), "", "", nil, nil).ID)
+
+	c := v.Clone()
+	if c.VaultID != v.VaultID || len(c.Entries) != 1 || len(c.DeletedEntries) != 1 {
+		t.Fatalf("clone = %+v, want the same vault", c)
+	}
+	c.Entries[0].Password[0] = 'X'
+	c.Entries[0].Tags[0] = "personal"
+	c.DeletedEntries[0].Password[0] = 'X'
+	c.PasswordPolicy.MinLength = 99
+	c.AddEntry("gitlab", "", nil, "", "", nil, nil)
+
+	if string(v.Entries[0].Password) != "hunter2" || v.Entries[0].Tags[0] != "dev" {
+		t.Errorf("original github = %+v after changing the clone", v.Entries[0])
+	}
+	if string(v.DeletedEntries[0].Password) != "secret" {
+		t.Errorf("original trash password = %q after changing the clone", v.DeletedEntries[0].Password)
+	}
+	if v.PasswordPolicy.MinLength == 99 {
+		t.Error("original password policy changed with the clone's")
+	}
+	if v.HasName("gitlab") {
+		t.Error("entry added to the clone found in the original")
+	}
+}
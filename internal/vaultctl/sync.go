@@ -0,0 +1,245 @@
+package vaultctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// SyncAction is what Sync did to bring the two sides together
+type SyncAction string
+
+// Sync actions
+const (
+	SyncUpToDate SyncAction = "up-to-date"
+	SyncPushed   SyncAction = "pushed"
+	SyncReplayed SyncAction = "replayed"
+	SyncPulled   SyncAction = "pulled"
+	SyncMerged   SyncAction = "merged"
+)
+
+// Resolution is the side a conflict is resolved in favour of
+type Resolution int
+
+// Conflict resolutions
+const (
+	KeepNewer Resolution = iota
+	KeepLocal
+	KeepRemote
+)
+
+// Resolver chooses how to resolve an entry changed on both sides. A nil
+// Resolver keeps the side that was updated more recently.
+type Resolver func(c Conflict) (Resolution, error)
+
+// SyncResult describes the outcome of Sync
+type SyncResult struct {
+	Action  SyncAction
+	Version int64
+
+	// Replayed is the number of queued changes that were pushed or, when
+	// the remote had moved on, merged
+	Replayed int
+
+	// Conflicts lists the entries that were changed on both sides
+	Conflicts []Conflict
+
+	// Rekeyed is set when the pulled vault's key was rotated on another
+	// device. The Vault is closed and must be opened again.
+	Rekeyed bool
+}
+
+// Sync brings the local vault and the remote store together. Queued changes
+// are replayed first; then whichever side changed since the last sync is
+// pushed or pulled, and if both changed they are merged entry by entry,
+// with resolve picking the winner of each conflict. Entries edited in the
+// open vault but not saved yet count as local changes, so they are merged
+// rather than lost when the remote changed.
+func (v *Vault) Sync(ctx context.Context, resolve Resolver) (*SyncResult, error) {
+	if v.data == nil {
+		return nil, ErrClosed
+	}
+	if v.remote == nil {
+		return nil, ErrNoRemote
+	}
+
+	// Entry uses logged since the last save go out with this sync
+	if uses, err := v.local.LoadUsageLog(v.key); err != nil {
+		v.warning(err)
+	} else if len(uses) > 0 {
+		if _, err := v.saveLocal(); err != nil {
+			return nil, err
+		}
+	}
+
+	localEV, err := v.local.LoadEncryptedVault()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load local vault: %w", err)
+	}
+
+	remoteEV, err := v.remote.LoadVault(ctx)
+	if err != nil {
+		if !errors.Is(err, storage.ErrRemoteVaultNotFound) {
+			return nil, fmt.Errorf("failed to load remote vault: %w", err)
+		}
+		// Nothing remote yet, push local
+		if err := v.push(ctx, localEV, localEV.Version-1); err != nil {
+			return nil, fmt.Errorf("failed to sync vault: %w", err)
+		}
+		return &SyncResult{Action: SyncPushed, Version: localEV.Version}, nil
+	}
+
+	// Replay writes that failed to reach the remote. The local vault
+	// already contains them, so one conditional push covers the queue.
+	pending, err := v.local.LoadPendingWrites()
+	if err != nil {
+		return nil, err
+	}
+	result := &SyncResult{Replayed: len(pending)}
+	if len(pending) > 0 {
+		err := v.push(ctx, localEV, pending[0].ExpectedVersion)
+		if err == nil {
+			result.Action = SyncReplayed
+			result.Version = localEV.Version
+			return result, nil
+		}
+		if !errors.Is(err, storage.ErrVersionConflict) {
+			return nil, fmt.Errorf("failed to replay queued changes: %w", err)
+		}
+	}
+
+	if localEV.Ciphertext == remoteEV.Ciphertext {
+		v.recordSynced(localEV)
+		result.Action = SyncUpToDate
+		result.Version = localEV.Version
+		return result, nil
+	}
+
+	base, err := v.local.LoadSyncBase()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only local changed since the last sync: push it
+	if base != nil && base.Ciphertext == remoteEV.Ciphertext {
+		if err := v.push(ctx, localEV, remoteEV.Version); err != nil {
+			return nil, fmt.Errorf("failed to sync vault: %w", err)
+		}
+		result.Action = SyncPushed
+		result.Version = localEV.Version
+		return result, nil
+	}
+
+	// Only remote changed since the last sync: take it, unless the open
+	// vault holds edits that were never saved, which a merge keeps
+	if base != nil && base.Ciphertext == localEV.Ciphertext {
+		unsaved, err := v.hasUnsavedChanges(localEV)
+		if err != nil {
+			return nil, err
+		}
+		if !unsaved {
+			return v.pull(localEV, remoteEV, result)
+		}
+	}
+
+	return v.merge(ctx, localEV, remoteEV, base, resolve, result)
+}
+
+// hasUnsavedChanges reports whether the open vault's entries differ from
+// those saved in localEV
+func (v *Vault) hasUnsavedChanges(localEV *EncryptedVault) (bool, error) {
+	saved, err := storage.OpenVault(localEV, v.key)
+	if err != nil {
+		return false, fmt.Errorf("failed to open local vault: %w", err)
+	}
+	return !vault.Diff(saved, v.data).Empty(), nil
+}
+
+// pull replaces the local vault with remoteEV
+func (v *Vault) pull(localEV, remoteEV *EncryptedVault, result *SyncResult) (*SyncResult, error) {
+	// A vault key rotated on another device can't be checked with the old
+	// key; the vault is verified when next opened
+	pulled, err := storage.OpenVault(remoteEV, v.key)
+	if err != nil {
+		if remoteEV.EncVaultKey == localEV.EncVaultKey {
+			return nil, fmt.Errorf("failed to open remote vault: %w", err)
+		}
+		result.Rekeyed = true
+	}
+
+	if err := v.local.SaveEncryptedVault(remoteEV); err != nil {
+		return nil, fmt.Errorf("failed to save synced vault: %w", err)
+	}
+	if err := v.local.SaveSyncBase(remoteEV); err != nil {
+		v.warning(err)
+	}
+
+	result.Action = SyncPulled
+	result.Version = remoteEV.Version
+	if result.Rekeyed {
+		v.Close()
+	} else {
+		v.data = pulled
+	}
+	return result, nil
+}
+
+// merge combines local and remote changes made since base and pushes the
+// result
+func (v *Vault) merge(ctx context.Context, localEV, remoteEV, base *EncryptedVault, resolve Resolver, result *SyncResult) (*SyncResult, error) {
+	remoteVault, err := storage.OpenVault(remoteEV, v.key)
+	if err != nil {
+		if remoteEV.EncVaultKey != localEV.EncVaultKey {
+			return nil, fmt.Errorf("failed to open remote vault: local and remote use different vault keys after rotate-key, so they can't be merged (%w)", err)
+		}
+		return nil, fmt.Errorf("failed to open remote vault: %w", err)
+	}
+	var baseVault *vault.Vault
+	if base != nil {
+		baseVault, err = storage.OpenVault(base, v.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sync base: %w", err)
+		}
+	}
+
+	merged, conflicts := vault.Merge(baseVault, v.data, remoteVault)
+	if resolve != nil {
+		for _, c := range conflicts {
+			choice, err := resolve(c)
+			if err != nil {
+				return nil, err
+			}
+			switch choice {
+			case KeepLocal:
+				merged.ResolveConflict(c, c.Local)
+			case KeepRemote:
+				merged.ResolveConflict(c, c.Remote)
+			}
+		}
+	}
+
+	// Keep the envelope (key wrapping, KDF parameters) of whichever side
+	// is newer, so a master password change on either device survives
+	template := *localEV
+	if remoteEV.Version > localEV.Version {
+		template = *remoteEV
+	}
+	template.Version = max(localEV.Version, remoteEV.Version)
+
+	if err := v.encryptAndSave(merged, &template); err != nil {
+		return nil, fmt.Errorf("failed to save merged vault: %w", err)
+	}
+	v.data = merged
+
+	if err := v.push(ctx, &template, remoteEV.Version); err != nil {
+		return nil, fmt.Errorf("failed to sync merged vault: %w", err)
+	}
+
+	result.Action = SyncMerged
+	result.Version = template.Version
+	result.Conflicts = conflicts
+	return result, nil
+}
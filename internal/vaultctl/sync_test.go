@@ -0,0 +1,199 @@
+package vaultctl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+const testPassword = "correct horse battery staple"
+
+// fakeRemote is a remote store held in memory. While down is set, pushes
+// fail with it.
+type fakeRemote struct {
+	ev   *storage.EncryptedVault
+	down error
+}
+
+func (f *fakeRemote) SaveVault(ctx context.Context, ev *storage.EncryptedVault, expectedVersion int64) error {
+	if f.down != nil {
+		return f.down
+	}
+	if f.ev != nil && f.ev.Version != expectedVersion {
+		return storage.ErrVersionConflict
+	}
+	saved := *ev
+	f.ev = &saved
+	return nil
+}
+
+func (f *fakeRemote) LoadVault(ctx context.Context) (*storage.EncryptedVault, error) {
+	if f.ev == nil {
+		return nil, storage.ErrRemoteVaultNotFound
+	}
+	loaded := *f.ev
+	return &loaded, nil
+}
+
+func (f *fakeRemote) SyncVault(ctx context.Context, localEV *storage.EncryptedVault) (*storage.EncryptedVault, error) {
+	return nil, errors.New("not implemented")
+}
+
+// newTestVault writes an empty vault sealed with testPassword, using the
+// cheapest accepted KDF parameters, and opens it
+func newTestVault(t *testing.T) *Vault {
+	t.Helper()
+	kdfParams := crypto.KDFParams{
+		Algo:        crypto.AlgoArgon2id,
+		Memory:      crypto.MinMemory,
+		Iterations:  crypto.MinIterations,
+		Parallelism: crypto.MinParallelism,
+	}
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := crypto.DeriveMasterKey([]byte(testPassword), salt, kdfParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crypto.Zeroize(masterKey)
+
+	data := vault.NewVault()
+	ev := &storage.EncryptedVault{
+		SchemaVersion: storage.SchemaVersionSingle,
+		VaultID:       data.VaultID,
+		SaltMaster:    crypto.EncodeBase64(salt),
+		KDFParams: storage.KDFParams{
+			Algo:        kdfParams.Algo,
+			Memory:      kdfParams.Memory,
+			Iterations:  kdfParams.Iterations,
+			Parallelism: kdfParams.Parallelism,
+		},
+		Cipher:  crypto.CipherXChaCha20Poly1305,
+		Version: 1,
+	}
+	encVaultKey, nonce, err := crypto.EncryptVaultKey(vaultKey, masterKey, ev.Cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.EncVaultKey = crypto.EncodeBase64(encVaultKey)
+	ev.VaultKeyNonce = crypto.EncodeBase64(nonce)
+	if err := ev.SealVault(data, vaultKey); err != nil {
+		t.Fatalf("SealVault: %v", err)
+	}
+	if err := ev.Sign(vaultKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "vault.db")
+	if err := storage.NewLocalStorage(path).SaveEncryptedVault(ev); err != nil {
+		t.Fatalf("SaveEncryptedVault: %v", err)
+	}
+	v, err := Open(path, []byte(testPassword))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(v.Close)
+	return v
+}
+
+func TestPendingWritesReplay(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("network down")
+
+	// addAndSave adds an entry and saves it while the remote is down
+	addAndSave := func(t *testing.T, v *Vault, name string) {
+		t.Helper()
+		if _, err := v.Add(name, "", nil, "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Save(ctx); !errors.Is(err, ErrChangeQueued) || !errors.Is(err, errDown) {
+			t.Fatalf("Save with the remote down: err = %v, want ErrChangeQueued wrapping the push error", err)
+		}
+	}
+
+	t.Run("queued on push failure", func(t *testing.T) {
+		remote := &fakeRemote{}
+		v := newTestVault(t)
+		v.SetRemote(remote)
+		if _, err := v.Sync(ctx, nil); err != nil {
+			t.Fatalf("first Sync: %v", err)
+		}
+		synced := remote.ev.Version
+
+		remote.down = errDown
+		addAndSave(t, v, "github")
+		addAndSave(t, v, "gitlab")
+
+		pending, err := v.local.LoadPendingWrites()
+		if err != nil {
+			t.Fatalf("LoadPendingWrites: %v", err)
+		}
+		if len(pending) != 2 {
+			t.Fatalf("queue = %+v, want both saves", pending)
+		}
+		if pending[0].ExpectedVersion != synced || pending[1].Version != synced+2 || pending[0].Error != errDown.Error() {
+			t.Errorf("queue = %+v, want versions %d and %d expecting %d", pending, synced+1, synced+2, synced)
+		}
+	})
+
+	t.Run("replayed on the next sync", func(t *testing.T) {
+		remote := &fakeRemote{}
+		v := newTestVault(t)
+		v.SetRemote(remote)
+		if _, err := v.Sync(ctx, nil); err != nil {
+			t.Fatalf("first Sync: %v", err)
+		}
+		remote.down = errDown
+		addAndSave(t, v, "github")
+		addAndSave(t, v, "gitlab")
+
+		remote.down = nil
+		result, err := v.Sync(ctx, nil)
+		if err != nil {
+			t.Fatalf("Sync: %v", err)
+		}
+		if result.Action != SyncReplayed || result.Replayed != 2 {
+			t.Errorf("Sync = %+v, want both queued changes replayed", result)
+		}
+		local, err := v.local.LoadEncryptedVault()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if remote.ev.Version != local.Version || remote.ev.Ciphertext != local.Ciphertext {
+			t.Errorf("remote at version %d after replay, want the local version %d", remote.ev.Version, local.Version)
+		}
+		if pending, err := v.local.LoadPendingWrites(); err != nil || len(pending) != 0 {
+			t.Errorf("queue after replay = %+v, %v, want it empty", pending, err)
+		}
+	})
+
+	t.Run("kept when the replay fails", func(t *testing.T) {
+		remote := &fakeRemote{}
+		v := newTestVault(t)
+		v.SetRemote(remote)
+		if _, err := v.Sync(ctx, nil); err != nil {
+			t.Fatalf("first Sync: %v", err)
+		}
+		remote.down = errDown
+		addAndSave(t, v, "github")
+
+		if _, err := v.Sync(ctx, nil); !errors.Is(err, errDown) {
+			t.Fatalf("Sync with the remote down: err = %v, want the push error", err)
+		}
+		pending, err := v.local.LoadPendingWrites()
+		if err != nil || len(pending) != 1 {
+			t.Errorf("queue after a failed replay = %+v, %v, want the queued save", pending, err)
+		}
+	})
+}
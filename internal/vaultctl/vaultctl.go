@@ -0,0 +1,402 @@
+// Package vaultctl opens, edits, saves and syncs a vault without depending
+// on the command line tool's flags or global state. The commands build on it
+// through New, and pkg/vaultctl exposes it to other programs.
+package vaultctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+// Types shared with the storage and vault packages
+type (
+	Entry          = vault.Entry
+	EntryOptions   = vault.EntryOptions
+	EntrySummary   = vault.EntrySummary
+	Conflict       = vault.Conflict
+	EncryptedVault = storage.EncryptedVault
+	RemoteStore    = storage.RemoteStore
+)
+
+// Errors returned by Vault methods. Errors from storage, such as
+// storage.ErrWrongPassword or storage.ErrVersionConflict, are wrapped and can
+// be checked with errors.Is.
+var (
+	ErrClosed       = errors.New("vault is closed")
+	ErrEntryExists  = errors.New("an entry with this name already exists")
+	ErrEntrySkipped = errors.New("an entry with this name already exists, skipped")
+	ErrNoRemote     = errors.New("remote storage not configured")
+	ErrChangeQueued = errors.New("change saved locally and queued for the next sync")
+
+	// ErrVaultInUse is returned by Open when another process has the vault
+	// open
+	ErrVaultInUse = storage.ErrVaultInUse
+)
+
+// Vault is an unlocked vault together with the local file it was read from
+// and, optionally, a remote store to sync with. A Vault is not safe for
+// concurrent use.
+type Vault struct {
+	local  *storage.LocalStorage
+	remote storage.RemoteStore
+	data   *vault.Vault
+	key    []byte
+	warn   func(error)
+	unlock func() // Releases the vault lock taken by Open, if any
+}
+
+// Open unlocks the vault file at path with the master password
+func Open(path string, password []byte) (*Vault, error) {
+	return OpenWithKeyFile(path, password, nil)
+}
+
+// OpenWithKeyFile unlocks the vault file at path with the master password
+// and the contents of its key file. Vaults not protected by a key file
+// ignore keyFile, so it may be nil. The vault stays locked against other
+// processes, the command line tool included, until Close.
+func OpenWithKeyFile(path string, password, keyFile []byte) (*Vault, error) {
+	local := storage.NewLocalStorage(path)
+	if !local.Exists() {
+		return nil, fmt.Errorf("%w: %s", storage.ErrVaultNotFound, path)
+	}
+
+	unlock, err := local.Lock()
+	if err != nil {
+		return nil, err
+	}
+	data, key, err := local.DecryptAndLoad(password, keyFile)
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to open vault: %w", err)
+	}
+	v := New(local, nil, data, key)
+	v.unlock = unlock
+	return v, nil
+}
+
+// New wraps a vault that is already unlocked. remote may be nil. The caller
+// holds the vault lock, if it needs one.
+func New(local *storage.LocalStorage, remote storage.RemoteStore, data *vault.Vault, key []byte) *Vault {
+	return &Vault{local: local, remote: remote, data: data, key: key}
+}
+
+// NewFilesystemRemote returns a remote store that keeps the vault in dir,
+// such as a mounted network share
+func NewFilesystemRemote(dir, userID string) (RemoteStore, error) {
+	return storage.NewFilesystemStorage(dir, userID)
+}
+
+// NewDynamoDBRemote returns a remote store backed by a DynamoDB table. An
+// empty endpoint uses the default AWS endpoint.
+func NewDynamoDBRemote(tableName, userID, endpoint string) (RemoteStore, error) {
+	return storage.NewDynamoDBStorage(tableName, userID, "", endpoint)
+}
+
+// NewNamedDynamoDBRemote is NewDynamoDBRemote for the vault named vaultName,
+// one of several the user keeps in the same table
+func NewNamedDynamoDBRemote(tableName, userID, vaultName, endpoint string) (RemoteStore, error) {
+	return storage.NewDynamoDBStorage(tableName, userID, vaultName, endpoint)
+}
+
+// SetRemote sets the store Save and Sync push to. nil disables syncing.
+func (v *Vault) SetRemote(remote RemoteStore) {
+	v.remote = remote
+}
+
+// SetWarningHandler sets a function called with errors that don't fail an
+// operation, such as failing to record the sync base after a push. By
+// default they are ignored.
+func (v *Vault) SetWarningHandler(fn func(error)) {
+	v.warn = fn
+}
+
+// Data returns the decrypted vault. It is replaced by Sync when changes are
+// merged, so don't keep it across calls.
+func (v *Vault) Data() *vault.Vault {
+	return v.data
+}
+
+// Policies for adding an entry under a name that is already taken
+const (
+	DuplicateFail      = ""
+	DuplicateSkip      = "skip"
+	DuplicateRename    = "rename"
+	DuplicateOverwrite = "overwrite"
+)
+
+// Add adds a new login entry and returns a copy of it. The password is
+// copied, so the caller may zeroize its buffer. The change is not written
+// until Save is called.
+func (v *Vault) Add(name, username string, password []byte, url, notes string, tags []string) (*Entry, error) {
+	return v.AddWith(name, username, password, url, notes, nil, tags, EntryOptions{}, DuplicateFail)
+}
+
+// AddName returns the name AddWith adds an entry called name under when
+// following onDuplicate: name itself if it is free or is to be overwritten,
+// a free name-2, name-3 and so on for DuplicateRename, and otherwise
+// ErrEntryExists or, for DuplicateSkip, ErrEntrySkipped. Callers can check
+// it before asking for the rest of the entry.
+func (v *Vault) AddName(name, onDuplicate string) (string, error) {
+	if v.data == nil {
+		return "", ErrClosed
+	}
+	if !v.data.HasName(name) {
+		return name, nil
+	}
+	switch onDuplicate {
+	case DuplicateFail:
+		return "", fmt.Errorf("%w: %s", ErrEntryExists, name)
+	case DuplicateSkip:
+		return "", fmt.Errorf("%w: %s", ErrEntrySkipped, name)
+	case DuplicateRename:
+		return v.data.UniqueName(name), nil
+	case DuplicateOverwrite:
+		return name, nil
+	}
+	return "", fmt.Errorf("invalid duplicate policy: %s", onDuplicate)
+}
+
+// AddWith is Add for entries of any type and with further fields set.
+// onDuplicate decides what happens if the name is taken, as for AddName;
+// an overwritten entry goes to the trash.
+func (v *Vault) AddWith(name, username string, password []byte, url, notes string, backupCodes, tags []string, opts EntryOptions, onDuplicate string) (*Entry, error) {
+	name, err := v.AddName(name, onDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	if onDuplicate == DuplicateOverwrite && v.data.HasName(name) {
+		existing := v.data.GetEntry(name)
+		crypto.Zeroize(existing.Password)
+		v.data.RemoveEntry(existing.ID)
+	}
+	return v.data.AddEntryWith(name, username, password, url, notes, backupCodes, tags, opts), nil
+}
+
+// Get returns a copy of the entry with the given name or ID
+func (v *Vault) Get(nameOrID string) (*Entry, error) {
+	if v.data == nil {
+		return nil, ErrClosed
+	}
+	return v.data.LookupEntry(nameOrID)
+}
+
+// ListFilter narrows down the entries List returns. Zero fields match every
+// entry.
+type ListFilter struct {
+	Favorites bool      // Only favorites
+	DueBy     time.Time // Only entries whose password is due for a change by then
+	Tag       string    // Only entries carrying the tag
+	Folder    string    // Only entries in the folder or its subfolders
+}
+
+// matches reports whether e passes every filter that is set
+func (f ListFilter) matches(e *Entry) bool {
+	switch {
+	case f.Favorites && !e.Favorite:
+		return false
+	case !f.DueBy.IsZero() && !e.IsRotationDue(f.DueBy):
+		return false
+	case f.Tag != "" && !e.HasTag(f.Tag):
+		return false
+	case f.Folder != "" && !e.InFolder(f.Folder):
+		return false
+	}
+	return true
+}
+
+// List returns a summary of every entry that matches filter
+func (v *Vault) List(filter ListFilter) ([]EntrySummary, error) {
+	if v.data == nil {
+		return nil, ErrClosed
+	}
+	summaries := make([]EntrySummary, 0, len(v.data.Entries))
+	for i := range v.data.Entries {
+		if filter.matches(&v.data.Entries[i]) {
+			summaries = append(summaries, v.data.Entries[i].Summary())
+		}
+	}
+	return summaries, nil
+}
+
+// ShortIDs maps each entry's ID to its shortest unique prefix of at least
+// vault.ShortIDLen characters
+func (v *Vault) ShortIDs() (map[string]string, error) {
+	if v.data == nil {
+		return nil, ErrClosed
+	}
+	return v.data.ShortIDs(), nil
+}
+
+// EntryUpdate lists the changes Update makes. An empty Name or Password and
+// nil fields are left unchanged; a pointer to an empty string clears the
+// field. A new password clears the entry's fixed expiry.
+type EntryUpdate struct {
+	Name        string
+	Username    *string
+	Password    []byte
+	URL         *string
+	Notes       *string
+	BackupCodes []string
+	Tags        []string
+
+	// Edit, if set, makes any other changes once the fields above are
+	// applied. If it fails the entry may be partly updated, so the vault
+	// should not be saved.
+	Edit func(*Entry) error
+}
+
+// Update changes the entry with the given name or ID. The password is
+// copied, so the caller may zeroize its buffer.
+func (v *Vault) Update(nameOrID string, u EntryUpdate) error {
+	entry, err := v.Get(nameOrID)
+	if err != nil {
+		return err
+	}
+	crypto.Zeroize(entry.Password)
+
+	v.data.UpdateEntry(entry.ID, u.Name, u.Username, u.Password, u.URL, u.Notes, u.BackupCodes, u.Tags)
+	if u.Edit != nil {
+		return v.data.EditEntry(entry.ID, u.Edit)
+	}
+	return nil
+}
+
+// SaveLocal encrypts the vault and writes it to the local file only
+func (v *Vault) SaveLocal() error {
+	_, err := v.saveLocal()
+	return err
+}
+
+// Save writes the vault to the local file and, if a remote store is set,
+// pushes it. If the push fails the change is queued for the next Sync and
+// the error wraps ErrChangeQueued as well as the cause.
+func (v *Vault) Save(ctx context.Context) error {
+	ev, err := v.saveLocal()
+	if err != nil {
+		return err
+	}
+	if v.remote == nil {
+		return nil
+	}
+	if err := v.PushOrQueue(ctx, ev); err != nil {
+		return fmt.Errorf("%w: %w", ErrChangeQueued, err)
+	}
+	return nil
+}
+
+// saveLocal encrypts the vault into the local file, keeping its envelope
+func (v *Vault) saveLocal() (*EncryptedVault, error) {
+	if v.data == nil {
+		return nil, ErrClosed
+	}
+
+	// Load current encrypted vault to preserve metadata
+	ev, err := v.local.LoadEncryptedVault()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encrypted vault: %w", err)
+	}
+	if err := v.encryptAndSave(v.data, ev); err != nil {
+		return nil, fmt.Errorf("failed to save vault: %w", err)
+	}
+	return ev, nil
+}
+
+// encryptAndSave writes data to the local file with the entry uses logged
+// since the last save, and empties the usage log once they are in the vault
+func (v *Vault) encryptAndSave(data *vault.Vault, ev *EncryptedVault) error {
+	uses, err := v.local.LoadUsageLog(v.key)
+	if err != nil {
+		v.warning(err)
+	} else {
+		data.ApplyUsage(uses)
+	}
+	if err := v.local.EncryptAndSave(data, v.key, ev); err != nil {
+		return err
+	}
+	if len(uses) > 0 {
+		if err := v.local.ClearUsageLog(); err != nil {
+			v.warning(err)
+		}
+	}
+	return nil
+}
+
+// PushOrQueue pushes a just-saved encrypted vault to the remote store. If
+// earlier writes are still queued, the push is conditioned on the remote
+// version they expected, so it replays them too. On failure the write is
+// queued for the next sync and the error is returned.
+func (v *Vault) PushOrQueue(ctx context.Context, ev *EncryptedVault) error {
+	if v.remote == nil {
+		return ErrNoRemote
+	}
+
+	expectedVersion := ev.Version - 1
+	pending, err := v.local.LoadPendingWrites()
+	if err != nil {
+		v.warning(err)
+	} else if len(pending) > 0 {
+		expectedVersion = pending[0].ExpectedVersion
+	}
+
+	pushErr := v.push(ctx, ev, expectedVersion)
+	if pushErr == nil {
+		return nil
+	}
+
+	if err := v.local.QueuePendingWrite(storage.PendingWrite{
+		Version:         ev.Version,
+		ExpectedVersion: ev.Version - 1,
+		QueuedAt:        time.Now(),
+		Error:           pushErr.Error(),
+	}); err != nil {
+		v.warning(err)
+	}
+	return pushErr
+}
+
+// push saves ev to the remote store, records it as the sync base for future
+// merges and clears the pending queue, which it supersedes
+func (v *Vault) push(ctx context.Context, ev *EncryptedVault, expectedVersion int64) error {
+	if err := v.remote.SaveVault(ctx, ev, expectedVersion); err != nil {
+		return err
+	}
+	v.recordSynced(ev)
+	return nil
+}
+
+// recordSynced marks ev as the state both sides agree on
+func (v *Vault) recordSynced(ev *EncryptedVault) {
+	if err := v.local.SaveSyncBase(ev); err != nil {
+		v.warning(err)
+	}
+	if err := v.local.ClearPendingWrites(); err != nil {
+		v.warning(err)
+	}
+}
+
+// warning reports an error that doesn't fail the current operation
+func (v *Vault) warning(err error) {
+	if v.warn != nil {
+		v.warn(err)
+	}
+}
+
+// Close wipes the vault key, drops the decrypted vault and releases the
+// vault lock taken by Open
+func (v *Vault) Close() {
+	if v.key != nil {
+		crypto.Zeroize(v.key)
+		v.key = nil
+	}
+	v.data = nil
+	if v.unlock != nil {
+		v.unlock()
+		v.unlock = nil
+	}
+}
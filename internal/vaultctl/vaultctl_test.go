@@ -0,0 +1,69 @@
+package vaultctl
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+func TestAddName(t *testing.T) {
+	tests := []struct {
+		name        string
+		taken       []string
+		onDuplicate string
+		want        string
+		wantErr     error
+	}{
+		{"free", nil, DuplicateFail, "github", nil},
+		{"free with an unknown policy", nil, "merge", "github", nil},
+		{"fail", []string{"github"}, DuplicateFail, "", ErrEntryExists},
+		{"skip", []string{"github"}, DuplicateSkip, "", ErrEntrySkipped},
+		{"rename", []string{"github"}, DuplicateRename, "github-2", nil},
+		{"rename with the suffix taken", []string{"github", "github-2"}, DuplicateRename, "github-3", nil},
+		{"overwrite", []string{"github"}, DuplicateOverwrite, "github", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := vault.NewVault()
+			for _, name := range tt.taken {
+				data.AddEntry(name, "", nil, "", "", nil, nil)
+			}
+			v := New(storage.NewLocalStorage(filepath.Join(t.TempDir(), "vault.db")), nil, data, make([]byte, 32))
+
+			got, err := v.AddName("github", tt.onDuplicate)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddName: err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AddName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	data := vault.NewVault()
+	data.AddEntry("github", "", nil, "", "", nil, nil)
+	v := New(storage.NewLocalStorage(filepath.Join(t.TempDir(), "vault.db")), nil, data, make([]byte, 32))
+	if _, err := v.AddName("github", "merge"); err == nil || errors.Is(err, ErrEntryExists) {
+		t.Errorf("AddName of a taken name with an unknown policy: err = %v, want an invalid policy error", err)
+	}
+}
+
+func TestAddWithOverwriteTrashesEntry(t *testing.T) {
+	data := vault.NewVault()
+	old := data.AddEntry("github", "alice", []byte("old"), "", "", nil, nil)
+	v := New(storage.NewLocalStorage(filepath.Join(t.TempDir(), "vault.db")), nil, data, make([]byte, 32))
+
+	added, err := v.AddWith("github", "bob", []byte("new"), "", "", nil, nil, EntryOptions{}, DuplicateOverwrite)
+	if err != nil {
+		t.Fatalf("AddWith: %v", err)
+	}
+	if len(data.Entries) != 1 || data.Entries[0].ID != added.ID {
+		t.Errorf("entries = %+v, want only the new github", data.Entries)
+	}
+	if len(data.DeletedEntries) != 1 || data.DeletedEntries[0].ID != old.ID {
+		t.Errorf("trash = %+v, want the overwritten github", data.DeletedEntries)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build unix || windows
+
+package vaultctl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOpenLocksVault(t *testing.T) {
+	path := newTestVaultFile(t)
+	v := openTestVault(t, path)
+
+	if _, err := Open(path, []byte(testPassword)); !errors.Is(err, ErrVaultInUse) {
+		t.Fatalf("second Open: err = %v, want ErrVaultInUse", err)
+	}
+	v.Close()
+	openTestVault(t, path)
+}
@@ -0,0 +1,202 @@
+// Package vaultctl is a library API for vaultctl vaults. It opens, edits,
+// saves and syncs a vault with the same storage and encryption as the
+// command line tool, without depending on its flags or global state.
+package vaultctl
+
+import (
+	"context"
+
+	"github.com/vaultctl/vaultctl/internal/storage"
+	impl "github.com/vaultctl/vaultctl/internal/vaultctl"
+)
+
+// Types shared with the command line tool
+type (
+	Entry        = impl.Entry
+	EntryOptions = impl.EntryOptions
+	EntrySummary = impl.EntrySummary
+	EntryUpdate  = impl.EntryUpdate
+	ListFilter   = impl.ListFilter
+	Conflict     = impl.Conflict
+	SyncAction   = impl.SyncAction
+	SyncResult   = impl.SyncResult
+	Resolution   = impl.Resolution
+	Resolver     = impl.Resolver
+)
+
+// Sync actions
+const (
+	SyncUpToDate = impl.SyncUpToDate
+	SyncPushed   = impl.SyncPushed
+	SyncReplayed = impl.SyncReplayed
+	SyncPulled   = impl.SyncPulled
+	SyncMerged   = impl.SyncMerged
+)
+
+// Conflict resolutions
+const (
+	KeepNewer  = impl.KeepNewer
+	KeepLocal  = impl.KeepLocal
+	KeepRemote = impl.KeepRemote
+)
+
+// Policies for adding an entry under a name that is already taken
+const (
+	DuplicateFail      = impl.DuplicateFail
+	DuplicateSkip      = impl.DuplicateSkip
+	DuplicateRename    = impl.DuplicateRename
+	DuplicateOverwrite = impl.DuplicateOverwrite
+)
+
+// Errors returned by Vault methods, which may wrap them
+var (
+	ErrClosed       = impl.ErrClosed
+	ErrEntryExists  = impl.ErrEntryExists
+	ErrEntrySkipped = impl.ErrEntrySkipped
+	ErrNoRemote     = impl.ErrNoRemote
+	ErrChangeQueued = impl.ErrChangeQueued
+	ErrVaultInUse   = impl.ErrVaultInUse
+
+	ErrVaultNotFound   = storage.ErrVaultNotFound
+	ErrWrongPassword   = storage.ErrWrongPassword
+	ErrVersionConflict = storage.ErrVersionConflict
+)
+
+// Vault is an unlocked vault together with the local file it was read from
+// and, optionally, a remote store to sync with. A Vault is not safe for
+// concurrent use.
+type Vault struct {
+	v *impl.Vault
+}
+
+// Open unlocks the vault file at path with the master password
+func Open(path string, password []byte) (*Vault, error) {
+	return OpenWithKeyFile(path, password, nil)
+}
+
+// OpenWithKeyFile unlocks the vault file at path with the master password
+// and the contents of its key file. Vaults not protected by a key file
+// ignore keyFile, so it may be nil. The vault stays locked against other
+// processes, the command line tool included, until Close; while another
+// process has it open, Open fails with ErrVaultInUse.
+func OpenWithKeyFile(path string, password, keyFile []byte) (*Vault, error) {
+	v, err := impl.OpenWithKeyFile(path, password, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{v: v}, nil
+}
+
+// RemoteStore is a remote copy of the vault that Save and Sync push to and
+// pull from. It is opaque: get one from NewFilesystemRemote,
+// NewDynamoDBRemote or NewNamedDynamoDBRemote.
+type RemoteStore struct {
+	r impl.RemoteStore
+}
+
+// NewFilesystemRemote returns a remote store that keeps the vault in dir,
+// such as a mounted network share
+func NewFilesystemRemote(dir, userID string) (*RemoteStore, error) {
+	return remoteStore(impl.NewFilesystemRemote(dir, userID))
+}
+
+// NewDynamoDBRemote returns a remote store backed by a DynamoDB table. An
+// empty endpoint uses the default AWS endpoint.
+func NewDynamoDBRemote(tableName, userID, endpoint string) (*RemoteStore, error) {
+	return remoteStore(impl.NewDynamoDBRemote(tableName, userID, endpoint))
+}
+
+// NewNamedDynamoDBRemote is NewDynamoDBRemote for the vault named vaultName,
+// one of several the user keeps in the same table
+func NewNamedDynamoDBRemote(tableName, userID, vaultName, endpoint string) (*RemoteStore, error) {
+	return remoteStore(impl.NewNamedDynamoDBRemote(tableName, userID, vaultName, endpoint))
+}
+
+// remoteStore wraps the result of one of the internal constructors
+func remoteStore(r impl.RemoteStore, err error) (*RemoteStore, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteStore{r: r}, nil
+}
+
+// SetRemote sets the store Save and Sync push to. nil disables syncing.
+func (v *Vault) SetRemote(remote *RemoteStore) {
+	if remote == nil {
+		v.v.SetRemote(nil)
+		return
+	}
+	v.v.SetRemote(remote.r)
+}
+
+// SetWarningHandler sets a function called with errors that don't fail an
+// operation, such as failing to record the sync base after a push. By
+// default they are ignored.
+func (v *Vault) SetWarningHandler(fn func(error)) {
+	v.v.SetWarningHandler(fn)
+}
+
+// Add adds a new login entry and returns a copy of it. The password is
+// copied, so the caller may zeroize its buffer. The change is not written
+// until Save is called.
+func (v *Vault) Add(name, username string, password []byte, url, notes string, tags []string) (*Entry, error) {
+	return v.v.Add(name, username, password, url, notes, tags)
+}
+
+// AddName returns the name AddWith adds an entry called name under when
+// following onDuplicate, or ErrEntryExists or ErrEntrySkipped if it adds
+// nothing
+func (v *Vault) AddName(name, onDuplicate string) (string, error) {
+	return v.v.AddName(name, onDuplicate)
+}
+
+// AddWith is Add for entries of any type and with further fields set.
+// onDuplicate decides what happens if the name is taken; an overwritten
+// entry goes to the trash.
+func (v *Vault) AddWith(name, username string, password []byte, url, notes string, backupCodes, tags []string, opts EntryOptions, onDuplicate string) (*Entry, error) {
+	return v.v.AddWith(name, username, password, url, notes, backupCodes, tags, opts, onDuplicate)
+}
+
+// Get returns a copy of the entry with the given name, ID or unique ID
+// prefix
+func (v *Vault) Get(nameOrID string) (*Entry, error) {
+	return v.v.Get(nameOrID)
+}
+
+// List returns a summary of every entry that matches filter
+func (v *Vault) List(filter ListFilter) ([]EntrySummary, error) {
+	return v.v.List(filter)
+}
+
+// Update changes the entry with the given name or ID. The change is not
+// written until Save is called.
+func (v *Vault) Update(nameOrID string, u EntryUpdate) error {
+	return v.v.Update(nameOrID, u)
+}
+
+// SaveLocal encrypts the vault and writes it to the local file only
+func (v *Vault) SaveLocal() error {
+	return v.v.SaveLocal()
+}
+
+// Save writes the vault to the local file and, if a remote store is set,
+// pushes it. If the push fails the change is queued for the next Sync and
+// the error wraps ErrChangeQueued as well as the cause.
+func (v *Vault) Save(ctx context.Context) error {
+	return v.v.Save(ctx)
+}
+
+// Sync brings the local vault and the remote store together. Queued changes
+// are replayed first; then whichever side changed since the last sync is
+// pushed or pulled, and if both changed they are merged entry by entry,
+// with resolve picking the winner of each conflict. A nil resolve keeps the
+// side updated more recently.
+func (v *Vault) Sync(ctx context.Context, resolve Resolver) (*SyncResult, error) {
+	return v.v.Sync(ctx, resolve)
+}
+
+// Close wipes the vault key, drops the decrypted vault and releases the
+// vault lock taken by Open
+func (v *Vault) Close() {
+	v.v.Close()
+}
@@ -0,0 +1,299 @@
+package vaultctl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/vaultctl/vaultctl/internal/crypto"
+	"github.com/vaultctl/vaultctl/internal/storage"
+	"github.com/vaultctl/vaultctl/internal/vault"
+)
+
+const testPassword = "correct horse battery staple"
+
+// newTestVaultFile writes an empty vault sealed with testPassword, using
+// the cheapest accepted KDF parameters, and returns its path
+func newTestVaultFile(t *testing.T) string {
+	t.Helper()
+	kdfParams := crypto.KDFParams{
+		Algo:        crypto.AlgoArgon2id,
+		Memory:      crypto.MinMemory,
+		Iterations:  crypto.MinIterations,
+		Parallelism: crypto.MinParallelism,
+	}
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultKey, err := crypto.GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := crypto.DeriveMasterKey([]byte(testPassword), salt, kdfParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer crypto.Zeroize(masterKey)
+
+	v := vault.NewVault()
+	ev := &storage.EncryptedVault{
+		SchemaVersion: storage.SchemaVersionSingle,
+		VaultID:       v.VaultID,
+		SaltMaster:    crypto.EncodeBase64(salt),
+		KDFParams: storage.KDFParams{
+			Algo:        kdfParams.Algo,
+			Memory:      kdfParams.Memory,
+			Iterations:  kdfParams.Iterations,
+			Parallelism: kdfParams.Parallelism,
+		},
+		Cipher:  crypto.CipherXChaCha20Poly1305,
+		Version: 1,
+	}
+	encVaultKey, nonce, err := crypto.EncryptVaultKey(vaultKey, masterKey, ev.Cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.EncVaultKey = crypto.EncodeBase64(encVaultKey)
+	ev.VaultKeyNonce = crypto.EncodeBase64(nonce)
+	if err := ev.SealVault(v, vaultKey); err != nil {
+		t.Fatalf("SealVault: %v", err)
+	}
+	if err := ev.Sign(vaultKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "vault.db")
+	if err := storage.NewLocalStorage(path).SaveEncryptedVault(ev); err != nil {
+		t.Fatalf("SaveEncryptedVault: %v", err)
+	}
+	return path
+}
+
+// openTestVault opens the vault at path with testPassword
+func openTestVault(t *testing.T, path string) *Vault {
+	t.Helper()
+	v, err := Open(path, []byte(testPassword))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(v.Close)
+	return v
+}
+
+// names returns the names of the entries in summaries
+func names(summaries []EntrySummary) []string {
+	var names []string
+	for _, s := range summaries {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestOpenAddSave(t *testing.T) {
+	path := newTestVaultFile(t)
+
+	if _, err := Open(path, []byte("wrong password")); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Open with a wrong password: err = %v, want ErrWrongPassword", err)
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.db"), []byte(testPassword)); !errors.Is(err, ErrVaultNotFound) {
+		t.Errorf("Open of a missing file: err = %v, want ErrVaultNotFound", err)
+	}
+
+	v := openTestVault(t, path)
+	password := []byte("s3cret")
+	if _, err := v.Add("github", "alice", password, "https://github.com", "", []string{"dev"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	crypto.Zeroize(password)
+	if err := v.Save(context.Background()); err != nil {
+		t.Fatalf("Save without a remote: %v", err)
+	}
+	v.Close()
+	if _, err := v.Get("github"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Get after Close: err = %v, want ErrClosed", err)
+	}
+
+	reopened := openTestVault(t, path)
+	entry, err := reopened.Get("github")
+	if err != nil {
+		t.Fatalf("Get after reopening: %v", err)
+	}
+	if entry.Username != "alice" || !bytes.Equal(entry.Password, []byte("s3cret")) {
+		t.Errorf("saved entry = %s/%q, want alice/%q", entry.Username, entry.Password, "s3cret")
+	}
+}
+
+func TestAddWithDuplicates(t *testing.T) {
+	tests := []struct {
+		onDuplicate string
+		wantErr     error
+		wantNames   []string
+		wantPass    string // Password of the entry named "github" afterwards
+	}{
+		{DuplicateFail, ErrEntryExists, []string{"github"}, "old"},
+		{DuplicateSkip, ErrEntrySkipped, []string{"github"}, "old"},
+		{DuplicateRename, nil, []string{"github", "github-2"}, "old"},
+		{DuplicateOverwrite, nil, []string{"github"}, "new"},
+	}
+
+	for _, tt := range tests {
+		t.Run("on-duplicate="+tt.onDuplicate, func(t *testing.T) {
+			v := openTestVault(t, newTestVaultFile(t))
+			if _, err := v.Add("github", "alice", []byte("old"), "", "", nil); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+
+			_, err := v.AddWith("github", "bob", []byte("new"), "", "", nil, nil, EntryOptions{}, tt.onDuplicate)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddWith: err = %v, want %v", err, tt.wantErr)
+			}
+
+			list, err := v.List(ListFilter{})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got := names(list); !slices.Equal(got, tt.wantNames) {
+				t.Errorf("entries = %v, want %v", got, tt.wantNames)
+			}
+			entry, err := v.Get("github")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if string(entry.Password) != tt.wantPass {
+				t.Errorf("github password = %q, want %q", entry.Password, tt.wantPass)
+			}
+		})
+	}
+
+	v := openTestVault(t, newTestVaultFile(t))
+	if _, err := v.AddName("github", "merge"); err != nil {
+		t.Errorf("AddName of a free name with an unknown policy: %v", err)
+	}
+	if _, err := v.Add("github", "alice", nil, "", "", nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := v.AddName("github", "merge"); err == nil {
+		t.Error("AddName of a taken name with an unknown policy succeeded")
+	}
+}
+
+func TestListAndUpdate(t *testing.T) {
+	v := openTestVault(t, newTestVaultFile(t))
+	for _, name := range []string{"github", "gitlab", "bank"} {
+		if _, err := v.AddWith(name, "", nil, "", "", nil, []string{"dev"}, EntryOptions{Folder: "work"}, DuplicateFail); err != nil {
+			t.Fatalf("AddWith %s: %v", name, err)
+		}
+	}
+
+	newName, username := "bank-of-x", "alice"
+	err := v.Update("bank", EntryUpdate{
+		Name:     newName,
+		Username: &username,
+		Tags:     []string{},
+		Edit: func(e *Entry) error {
+			e.Folder = "home"
+			e.Favorite = true
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := v.Update("missing", EntryUpdate{}); !errors.Is(err, vault.ErrEntryNotFound) {
+		t.Errorf("Update of a missing entry: err = %v, want ErrEntryNotFound", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"all", ListFilter{}, []string{"github", "gitlab", "bank-of-x"}},
+		{"favorites", ListFilter{Favorites: true}, []string{"bank-of-x"}},
+		{"tag", ListFilter{Tag: "DEV"}, []string{"github", "gitlab"}},
+		{"folder", ListFilter{Folder: "/home/"}, []string{"bank-of-x"}},
+		{"tag and folder", ListFilter{Tag: "dev", Folder: "home"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := v.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got := names(list); !slices.Equal(got, tt.want) {
+				t.Errorf("List(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncMergesChangesFromTwoDevices(t *testing.T) {
+	ctx := context.Background()
+	pathA := newTestVaultFile(t)
+	pathB := filepath.Join(t.TempDir(), "vault.db")
+	data, err := os.ReadFile(pathA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathB, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := NewFilesystemRemote(t.TempDir(), "alice")
+	if err != nil {
+		t.Fatalf("NewFilesystemRemote: %v", err)
+	}
+	a, b := openTestVault(t, pathA), openTestVault(t, pathB)
+	if _, err := a.Sync(ctx, nil); !errors.Is(err, ErrNoRemote) {
+		t.Errorf("Sync without a remote: err = %v, want ErrNoRemote", err)
+	}
+	a.SetRemote(remote)
+	b.SetRemote(remote)
+
+	if result, err := a.Sync(ctx, nil); err != nil || result.Action != SyncPushed {
+		t.Fatalf("first Sync = %+v, %v, want pushed", result, err)
+	}
+	if result, err := b.Sync(ctx, nil); err != nil || result.Action != SyncUpToDate {
+		t.Fatalf("Sync of the copy = %+v, %v, want up-to-date", result, err)
+	}
+
+	// Both devices add an entry; the second push conflicts and is queued
+	if _, err := a.Add("github", "", nil, "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Save(ctx); err != nil {
+		t.Fatalf("Save on A: %v", err)
+	}
+	if _, err := b.Add("bank", "", nil, "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(ctx); !errors.Is(err, ErrChangeQueued) || !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Save on B: err = %v, want ErrChangeQueued and ErrVersionConflict", err)
+	}
+
+	result, err := b.Sync(ctx, nil)
+	if err != nil {
+		t.Fatalf("Sync on B: %v", err)
+	}
+	if result.Action != SyncMerged || result.Replayed != 1 || len(result.Conflicts) != 0 {
+		t.Errorf("Sync on B = %+v, want merged with 1 replayed change and no conflicts", result)
+	}
+	if result, err := a.Sync(ctx, nil); err != nil || result.Action != SyncPulled {
+		t.Fatalf("Sync on A = %+v, %v, want pulled", result, err)
+	}
+
+	for name, v := range map[string]*Vault{"A": a, "B": b} {
+		list, err := v.List(ListFilter{})
+		if err != nil {
+			t.Fatalf("List on %s: %v", name, err)
+		}
+		if got := names(list); len(got) != 2 {
+			t.Errorf("entries on %s = %v, want github and bank", name, got)
+		}
+	}
+}
@@ -105,6 +105,8 @@ resource "aws_iam_user_policy" "vaultctl_dynamodb" {
           "dynamodb:GetItem",
           "dynamodb:PutItem",
           "dynamodb:UpdateItem",
+          "dynamodb:DeleteItem",
+          "dynamodb:ConditionCheckItem",
           "dynamodb:Query"
         ]
         Resource = [
@@ -130,19 +132,36 @@ resource "aws_iam_user_policy" "vaultctl_secretsmanager" {
 
   policy = jsonencode({
     Version = "2012-10-17"
-    Statement = [
-      {
-        Effect = "Allow"
-        Action = [
-          "secretsmanager:GetSecretValue",
-          "secretsmanager:DescribeSecret"
-        ]
-        Resource = aws_secretsmanager_secret.session_key.arn
-      }
-    ]
+    Statement = concat(
+      [
+        {
+          Effect = "Allow"
+          Action = [
+            "secretsmanager:GetSecretValue",
+            "secretsmanager:DescribeSecret"
+          ]
+          Resource = aws_secretsmanager_secret.session_key.arn
+        }
+      ],
+      # Per-runner sessions from 'vaultctl unlock --remote-session'
+      var.enable_remote_sessions ? [
+        {
+          Effect = "Allow"
+          Action = [
+            "secretsmanager:CreateSecret",
+            "secretsmanager:PutSecretValue",
+            "secretsmanager:GetSecretValue",
+            "secretsmanager:DeleteSecret"
+          ]
+          Resource = "arn:aws:secretsmanager:${var.aws_region}:${data.aws_caller_identity.current.account_id}:secret:${aws_secretsmanager_secret.session_key.name}/runners/*"
+        }
+      ] : []
+    )
   })
 }
 
+data "aws_caller_identity" "current" {}
+
 # Optional: S3 bucket for encrypted backups
 resource "aws_s3_bucket" "vault_backups" {
   count  = var.create_s3_backup_bucket ? 1 : 0
@@ -46,3 +46,8 @@ variable "s3_backup_bucket_name" {
   default     = ""
 }
 
+variable "enable_remote_sessions" {
+  description = "Allow the IAM user to keep per-runner sessions (vaultctl unlock --remote-session) under <session secret>/runners/*"
+  type        = bool
+  default     = false
+}